@@ -1,5 +1,7 @@
 package check
 
+import "context"
+
 type CheckState string
 
 const (
@@ -20,3 +22,32 @@ type Check interface {
 	Status() string
 	RequiresRoot() bool
 }
+
+// ContextRunner is implemented by checks that can be canceled while running,
+// for example when they shell out to commands that may hang. These checks
+// also record the evidence of their result in the trail of ctx, which the
+// explain command prints; other checks record none.
+type ContextRunner interface {
+	RunContext(ctx context.Context) error
+}
+
+// Dependent is implemented by checks that must run after other checks have
+// finished, identified by their UUIDs.
+type Dependent interface {
+	DependsOn() []string
+}
+
+// Facts exposes system properties that several checks probe. Implementations
+// gather each fact at most once, so checks sharing a Facts do not repeat the
+// same probes during a run.
+type Facts interface {
+	PortOpen(port int, proto string) bool
+	LookPath(name string) (string, error)
+}
+
+// FactConsumer is implemented by checks that read shared facts instead of
+// probing the system themselves. The runner injects the facts for a run
+// before the check runs and resets them to nil afterwards.
+type FactConsumer interface {
+	UseFacts(facts Facts)
+}
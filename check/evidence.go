@@ -0,0 +1,89 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Kinds of Evidence.
+const (
+	EvidenceCommand = "command"
+	EvidenceFile    = "file"
+	EvidenceRule    = "rule"
+)
+
+// MaxEvidence bounds the evidence kept for a single check run.
+const MaxEvidence = 64
+
+// Evidence is a fact a check relied on for its result: a command it ran, a
+// file it read or the rule that decided the result. Outputs and file contents
+// are not kept, only their size.
+type Evidence struct {
+	Kind     string `json:"kind"`
+	Subject  string `json:"subject"`
+	ExitCode int    `json:"exitCode,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// Trail collects the evidence of a check run. It is safe for concurrent use
+// and a nil Trail discards everything.
+type Trail struct {
+	mutex   sync.Mutex
+	entries []Evidence
+	dropped int
+}
+
+// Add appends evidence to the trail, dropping it once MaxEvidence is reached.
+func (t *Trail) Add(evidence Evidence) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(t.entries) >= MaxEvidence {
+		t.dropped++
+		return
+	}
+	t.entries = append(t.entries, evidence)
+}
+
+// Entries returns the evidence collected so far, followed by a note when
+// some was dropped.
+func (t *Trail) Entries() []Evidence {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	entries := append([]Evidence{}, t.entries...)
+	if t.dropped > 0 {
+		entries = append(entries, Evidence{Kind: EvidenceRule, Subject: "trail truncated", Detail: fmt.Sprintf("%d more entries were dropped", t.dropped)})
+	}
+	return entries
+}
+
+type trailKey struct{}
+
+// WithTrail returns a context that makes Record add evidence to trail.
+func WithTrail(ctx context.Context, trail *Trail) context.Context {
+	return context.WithValue(ctx, trailKey{}, trail)
+}
+
+// TrailFrom returns the trail set with WithTrail, or nil.
+func TrailFrom(ctx context.Context) *Trail {
+	trail, _ := ctx.Value(trailKey{}).(*Trail)
+	return trail
+}
+
+// Record adds evidence to the trail of ctx, if any.
+func Record(ctx context.Context, evidence Evidence) {
+	TrailFrom(ctx).Add(evidence)
+}
+
+// Decided records the rule that decided the result of a check, for example
+// Decided(ctx, "nftables", "input chain drops by default").
+func Decided(ctx context.Context, rule, format string, args ...any) {
+	Record(ctx, Evidence{Kind: EvidenceRule, Subject: rule, Detail: fmt.Sprintf(format, args...)})
+}
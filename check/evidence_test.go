@@ -0,0 +1,44 @@
+package check
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTrail(t *testing.T) {
+	trail := &Trail{}
+	ctx := WithTrail(context.Background(), trail)
+
+	Record(ctx, Evidence{Kind: EvidenceCommand, Subject: "nft list ruleset", ExitCode: 1})
+	Decided(ctx, "nftables", "policy %s", "drop")
+
+	entries := trail.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[1] != (Evidence{Kind: EvidenceRule, Subject: "nftables", Detail: "policy drop"}) {
+		t.Errorf("Unexpected decision %+v", entries[1])
+	}
+}
+
+func TestTrail_Limit(t *testing.T) {
+	trail := &Trail{}
+	for range MaxEvidence + 3 {
+		trail.Add(Evidence{Kind: EvidenceFile, Subject: "/etc/passwd"})
+	}
+	entries := trail.Entries()
+	if len(entries) != MaxEvidence+1 {
+		t.Fatalf("Expected %d entries, got %d", MaxEvidence+1, len(entries))
+	}
+	if last := entries[MaxEvidence]; last.Detail != "3 more entries were dropped" {
+		t.Errorf("Expected a truncation note, got %+v", last)
+	}
+}
+
+func TestTrail_Nil(t *testing.T) {
+	// Without a trail, evidence is discarded
+	Record(context.Background(), Evidence{Kind: EvidenceFile, Subject: "/etc/passwd"})
+	if entries := TrailFrom(context.Background()).Entries(); entries != nil {
+		t.Errorf("Expected no entries, got %+v", entries)
+	}
+}
@@ -0,0 +1,38 @@
+package check
+
+import "strings"
+
+// FixStep is a single step towards making a failing check pass.
+//
+// A step either runs Command, appends Append to File, or, when it has
+// neither, is an instruction the user has to follow by hand.
+type FixStep struct {
+	Description  string   `json:"description"`
+	Command      []string `json:"command,omitempty"`
+	File         string   `json:"file,omitempty"`
+	Append       string   `json:"append,omitempty"`
+	RequiresRoot bool     `json:"requiresRoot,omitempty"`
+}
+
+// Manual reports whether the step cannot be applied automatically.
+func (s FixStep) Manual() bool {
+	return len(s.Command) == 0 && s.File == ""
+}
+
+// String returns the step the way a user would perform it in a shell.
+func (s FixStep) String() string {
+	switch {
+	case len(s.Command) > 0:
+		return strings.Join(s.Command, " ")
+	case s.File != "":
+		return "append to " + s.File + ":\n" + s.Append
+	}
+	return s.Description
+}
+
+// Remediator is implemented by checks that know how to fix a failure on the
+// current system, for example by picking the commands of the running desktop
+// or distribution. The steps are only meaningful after the check failed.
+type Remediator interface {
+	Remediation() []FixStep
+}
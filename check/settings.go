@@ -0,0 +1,176 @@
+package check
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Setting describes a parameter of a check that can be tuned per check in
+// the config. Default holds the value used when nothing is configured; its
+// Go type is the type of the setting and is one of int, bool, string,
+// time.Duration, []int, []string or map[int]string.
+type Setting struct {
+	Key         string `json:"key"`
+	Description string `json:"description"`
+	Default     any    `json:"default"`
+}
+
+// Configurable is implemented by checks that read settings from the config.
+type Configurable interface {
+	Settings() []Setting
+}
+
+// SettingsOf returns the settings declared by chk.
+func SettingsOf(chk Check) []Setting {
+	if configurable, ok := chk.(Configurable); ok {
+		return configurable.Settings()
+	}
+	return nil
+}
+
+// ConvertSetting converts a raw value decoded from TOML to the type of def.
+// Durations are strings such as "36h" or "7d", or a number of seconds, and
+// port maps are tables keyed by port number.
+func ConvertSetting(raw, def any) (any, error) {
+	switch def.(type) {
+	case int:
+		return toInt(raw)
+	case bool:
+		if value, ok := raw.(bool); ok {
+			return value, nil
+		}
+	case string:
+		if value, ok := raw.(string); ok {
+			return value, nil
+		}
+	case time.Duration:
+		return toDuration(raw)
+	case []int:
+		items, ok := toList(raw)
+		if !ok {
+			break
+		}
+		values := make([]int, 0, len(items))
+		for _, item := range items {
+			value, err := toInt(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case []string:
+		items, ok := toList(raw)
+		if !ok {
+			break
+		}
+		values := make([]string, 0, len(items))
+		for _, item := range items {
+			value, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of strings, got %v", item)
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case map[int]string:
+		return toPortMap(raw)
+	default:
+		return nil, fmt.Errorf("unsupported setting type %T", def)
+	}
+	return nil, fmt.Errorf("expected %s, got %T", SettingType(def), raw)
+}
+
+// SettingType returns the name of the type of a setting with the given default.
+func SettingType(def any) string {
+	switch def.(type) {
+	case int:
+		return "integer"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case time.Duration:
+		return "duration"
+	case []int:
+		return "list of integers"
+	case []string:
+		return "list of strings"
+	case map[int]string:
+		return "port map"
+	}
+	return fmt.Sprintf("%T", def)
+}
+
+func toInt(raw any) (int, error) {
+	switch value := raw.(type) {
+	case int:
+		return value, nil
+	case int64:
+		return int(value), nil
+	}
+	return 0, fmt.Errorf("expected an integer, got %T", raw)
+}
+
+func toList(raw any) ([]any, bool) {
+	switch value := raw.(type) {
+	case []any:
+		return value, true
+	case []int64:
+		items := make([]any, len(value))
+		for i, item := range value {
+			items[i] = item
+		}
+		return items, true
+	case []string:
+		items := make([]any, len(value))
+		for i, item := range value {
+			items[i] = item
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+func toDuration(raw any) (time.Duration, error) {
+	switch value := raw.(type) {
+	case int64:
+		return time.Duration(value) * time.Second, nil
+	case int:
+		return time.Duration(value) * time.Second, nil
+	case string:
+		if days, found := strings.CutSuffix(value, "d"); found {
+			if n, err := strconv.Atoi(days); err == nil {
+				return time.Duration(n) * 24 * time.Hour, nil
+			}
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q, use for example \"36h\" or \"7d\"", value)
+		}
+		return duration, nil
+	}
+	return 0, fmt.Errorf("expected a duration, got %T", raw)
+}
+
+func toPortMap(raw any) (map[int]string, error) {
+	table, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a table of ports, got %T", raw)
+	}
+	ports := make(map[int]string, len(table))
+	for key, value := range table {
+		port, err := strconv.Atoi(key)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q", key)
+		}
+		service, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a service name for port %d, got %T", port, value)
+		}
+		ports[port] = service
+	}
+	return ports, nil
+}
@@ -0,0 +1,76 @@
+package check
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type configurableCheck struct {
+	MockCheck
+}
+
+func (c *configurableCheck) Settings() []Setting {
+	return []Setting{{Key: "Limit", Default: 3}}
+}
+
+func TestSettingsOf(t *testing.T) {
+	assert.Nil(t, SettingsOf(&MockCheck{}))
+	assert.Equal(t, []Setting{{Key: "Limit", Default: 3}}, SettingsOf(&configurableCheck{}))
+}
+
+func TestConvertSetting(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  any
+		def  any
+		want any
+	}{
+		{"integer", int64(5), 0, 5},
+		{"boolean", true, false, true},
+		{"string", "value", "", "value"},
+		{"duration", "36h", time.Duration(0), 36 * time.Hour},
+		{"duration in days", "7d", time.Duration(0), 7 * 24 * time.Hour},
+		{"duration in seconds", int64(60), time.Duration(0), time.Minute},
+		{"integer list", []any{int64(22), int64(2222)}, []int{}, []int{22, 2222}},
+		{"string list", []any{"ssh-ed25519"}, []string{}, []string{"ssh-ed25519"}},
+		{"port map", map[string]any{"2222": "SSH"}, map[int]string{}, map[int]string{2222: "SSH"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertSetting(tt.raw, tt.def)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertSetting_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  any
+		def  any
+	}{
+		{"string for integer", "5", 0},
+		{"integer for boolean", int64(1), false},
+		{"bad duration", "soon", time.Duration(0)},
+		{"mixed list", []any{int64(1), "two"}, []int{}},
+		{"scalar for list", "ssh-rsa", []string{}},
+		{"port out of range", map[string]any{"70000": "SSH"}, map[int]string{}},
+		{"port without service", map[string]any{"22": int64(1)}, map[int]string{}},
+		{"unsupported default", "x", 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ConvertSetting(tt.raw, tt.def)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSettingType(t *testing.T) {
+	assert.Equal(t, "duration", SettingType(time.Hour))
+	assert.Equal(t, "port map", SettingType(map[int]string{}))
+	assert.Equal(t, "list of strings", SettingType([]string{}))
+}
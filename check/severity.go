@@ -0,0 +1,86 @@
+package check
+
+import "math"
+
+// Severity describes how important a check is for the security of a device.
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+	SeverityLow      Severity = "low"
+)
+
+// DefaultSeverity is used for checks that do not declare a severity.
+const DefaultSeverity = SeverityMedium
+
+var severityWeights = map[Severity]int{
+	SeverityCritical: 10,
+	SeverityHigh:     5,
+	SeverityMedium:   3,
+	SeverityLow:      1,
+}
+
+// Rated is implemented by checks that declare their severity.
+type Rated interface {
+	Severity() Severity
+}
+
+// Weighted is implemented by checks that override the weight derived from
+// their severity when computing the device score.
+type Weighted interface {
+	Weight() int
+}
+
+// SeverityOf returns the severity declared by chk, or DefaultSeverity.
+func SeverityOf(chk Check) Severity {
+	if rated, ok := chk.(Rated); ok {
+		if _, known := severityWeights[rated.Severity()]; known {
+			return rated.Severity()
+		}
+	}
+	return DefaultSeverity
+}
+
+// WeightOf returns the weight of chk in the device score.
+func WeightOf(chk Check) int {
+	if weighted, ok := chk.(Weighted); ok && weighted.Weight() > 0 {
+		return weighted.Weight()
+	}
+	return SeverityWeight(SeverityOf(chk))
+}
+
+// SeverityWeight returns the default weight for a severity.
+func SeverityWeight(severity Severity) int {
+	if weight, ok := severityWeights[severity]; ok {
+		return weight
+	}
+	return severityWeights[DefaultSeverity]
+}
+
+// Scored is the state and weight of a single check in a score computation.
+type Scored struct {
+	State  CheckState
+	Weight int
+}
+
+// Score returns the weighted share of passing checks as a value from 0 to 100.
+// Checks that errored count as not passing, disabled checks are left out.
+// When no check was evaluated, Score returns 0.
+func Score(entries []Scored) int {
+	total, passed := 0, 0
+	for _, entry := range entries {
+		if entry.State == CheckStateDisabled || entry.Weight <= 0 {
+			continue
+		}
+		total += entry.Weight
+		if entry.State == CheckStatePassed {
+			passed += entry.Weight
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return int(math.Round(float64(passed) * 100 / float64(total)))
+}
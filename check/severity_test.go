@@ -0,0 +1,56 @@
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ratedCheck struct {
+	MockCheck
+	severity Severity
+	weight   int
+}
+
+func (r *ratedCheck) Severity() Severity { return r.severity }
+func (r *ratedCheck) Weight() int        { return r.weight }
+
+func TestSeverityOf(t *testing.T) {
+	assert.Equal(t, DefaultSeverity, SeverityOf(&MockCheck{}))
+	assert.Equal(t, SeverityCritical, SeverityOf(&ratedCheck{severity: SeverityCritical}))
+	assert.Equal(t, DefaultSeverity, SeverityOf(&ratedCheck{severity: "bogus"}))
+}
+
+func TestWeightOf(t *testing.T) {
+	assert.Equal(t, 3, WeightOf(&MockCheck{}))
+	assert.Equal(t, 10, WeightOf(&ratedCheck{severity: SeverityCritical}))
+	assert.Equal(t, 7, WeightOf(&ratedCheck{severity: SeverityLow, weight: 7}))
+}
+
+func TestSeverityWeight(t *testing.T) {
+	assert.Equal(t, 10, SeverityWeight(SeverityCritical))
+	assert.Equal(t, 5, SeverityWeight(SeverityHigh))
+	assert.Equal(t, 3, SeverityWeight(SeverityMedium))
+	assert.Equal(t, 1, SeverityWeight(SeverityLow))
+	assert.Equal(t, 3, SeverityWeight(""))
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  []Scored
+		expected int
+	}{
+		{"no checks", nil, 0},
+		{"all passed", []Scored{{CheckStatePassed, 10}, {CheckStatePassed, 1}}, 100},
+		{"critical failed", []Scored{{CheckStateFailed, 10}, {CheckStatePassed, 1}}, 9},
+		{"low failed", []Scored{{CheckStatePassed, 10}, {CheckStateFailed, 1}}, 91},
+		{"error counts as failure", []Scored{{CheckStateError, 5}, {CheckStatePassed, 5}}, 50},
+		{"disabled ignored", []Scored{{CheckStateDisabled, 10}, {CheckStatePassed, 3}}, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Score(tt.entries))
+		})
+	}
+}
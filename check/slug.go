@@ -0,0 +1,39 @@
+package check
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Slugged is implemented by checks that declare a stable, human-friendly
+// identifier, such as "linux.firewall", that can be used instead of the UUID.
+type Slugged interface {
+	Slug() string
+}
+
+// SlugOf returns the slug declared by chk, or a slug derived from its name.
+func SlugOf(chk Check) string {
+	if slugged, ok := chk.(Slugged); ok && slugged.Slug() != "" {
+		return slugged.Slug()
+	}
+	return Slugify(chk.Name())
+}
+
+// Slugify turns a name into a lower case slug of letters, digits and dashes,
+// for example "Firewall is on" into "firewall-is-on".
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
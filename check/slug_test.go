@@ -0,0 +1,33 @@
+package check
+
+import "testing"
+
+type sluggedCheck struct {
+	MockCheck
+	slug string
+}
+
+func (s *sluggedCheck) Slug() string { return s.slug }
+
+func TestSlugOf(t *testing.T) {
+	if slug := SlugOf(&sluggedCheck{slug: "linux.firewall"}); slug != "linux.firewall" {
+		t.Errorf("Expected declared slug, got %s", slug)
+	}
+	if slug := SlugOf(&sluggedCheck{}); slug != "mockcheck" {
+		t.Errorf("Expected slug derived from the name, got %s", slug)
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Firewall is on":             "firewall-is-on",
+		"  SSH keys: strong / algo ": "ssh-keys-strong-algo",
+		"IPv4 forwarding":            "ipv4-forwarding",
+		"---":                        "",
+	}
+	for name, expected := range tests {
+		if slug := Slugify(name); slug != expected {
+			t.Errorf("Slugify(%q) = %q, expected %q", name, slug, expected)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package checks
 import (
 	"testing"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/claims"
 	"github.com/samber/lo"
 )
@@ -30,3 +31,28 @@ func TestClaims(t *testing.T) {
 		}
 	}
 }
+
+func TestClaimsDeclareSeverity(t *testing.T) {
+	for _, claim := range claims.All {
+		for _, chk := range claim.Checks {
+			if _, ok := chk.(check.Rated); !ok {
+				t.Errorf("Check %s does not declare a severity", chk.Name())
+			}
+		}
+	}
+}
+
+func TestClaimsDeclareSlug(t *testing.T) {
+	slugs := []string{}
+	for _, claim := range claims.All {
+		for _, chk := range claim.Checks {
+			if _, ok := chk.(check.Slugged); !ok {
+				t.Errorf("Check %s does not declare a slug", chk.Name())
+			}
+			if lo.Contains(slugs, check.SlugOf(chk)) {
+				t.Errorf("Duplicate check slug %s", check.SlugOf(chk))
+			}
+			slugs = append(slugs, check.SlugOf(chk))
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/shared"
 	"github.com/samber/lo"
 )
 
@@ -43,7 +45,7 @@ func checkInstalledApplications(appNames []string) bool {
 	}
 
 	for _, path := range searchPaths {
-		if contents, err := os.ReadDir(path); err == nil {
+		if contents, err := shared.ReadDir(path); err == nil {
 			for _, entry := range contents {
 				if entry.IsDir() && lo.Contains(appNames, entry.Name()) {
 					return true
@@ -73,8 +75,8 @@ func checkForBrowserExtensions() bool {
 	}
 
 	for _, extPath := range extensionPaths {
-		if _, err := os.Stat(extPath); err == nil {
-			entries, err := os.ReadDir(extPath)
+		if _, err := shared.Stat(extPath); err == nil {
+			entries, err := shared.ReadDir(extPath)
 			if err == nil {
 				for _, entry := range entries {
 					name := strings.ToLower(entry.Name())
@@ -102,6 +104,11 @@ func (pmc *PasswordManagerCheck) UUID() string {
 	return "f962c423-fdf5-428a-a57a-827abc9b253e"
 }
 
+// Slug returns the stable identifier of the check
+func (pmc *PasswordManagerCheck) Slug() string {
+	return "darwin.password-manager"
+}
+
 func (pmc *PasswordManagerCheck) PassedMessage() string {
 	return "Password manager is present"
 }
@@ -114,6 +121,11 @@ func (pmc *PasswordManagerCheck) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (pmc *PasswordManagerCheck) Severity() check.Severity {
+	return check.SeverityLow
+}
+
 func (pmc *PasswordManagerCheck) Status() string {
 	if pmc.Passed() {
 		return pmc.PassedMessage()
@@ -1,8 +1,10 @@
 package checks
 
 import (
+	"context"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 	"github.com/samber/lo"
@@ -11,6 +13,7 @@ import (
 type ApplicationUpdates struct {
 	passed  bool
 	details string
+	facts   check.Facts
 }
 
 // Name returns the name of the check
@@ -41,17 +44,25 @@ func (f *ApplicationUpdates) parseFlatpak(updateLines string) (apps map[string]s
 	return
 }
 
-func (f *ApplicationUpdates) checkUpdates() (bool, string) {
+// lookPath finds a package manager binary, using the shared facts when available
+func (f *ApplicationUpdates) lookPath(name string) (string, error) {
+	if f.facts != nil {
+		return f.facts.LookPath(name)
+	}
+	return lookPath(name)
+}
+
+func (f *ApplicationUpdates) checkUpdates(ctx context.Context) (bool, string) {
 	updates := []string{}
 
 	// Check flatpak
-	if _, err := lookPath("flatpak"); err == nil {
-		updatesOutput, err := shared.RunCommand("flatpak", "remote-ls", "--app", "--updates", "--columns=application,version")
+	if _, err := f.lookPath("flatpak"); err == nil {
+		updatesOutput, err := shared.RunCommandContext(ctx, "flatpak", "remote-ls", "--app", "--updates", "--columns=application,version")
 		if err != nil {
 			log.WithError(err).Error("Failed to check flatpak updates")
 			return true, "Flatpak updates check failed"
 		}
-		installedOutput, err := shared.RunCommand("flatpak", "list", "--app", "--columns=application,version")
+		installedOutput, err := shared.RunCommandContext(ctx, "flatpak", "list", "--app", "--columns=application,version")
 		if err != nil {
 			log.WithError(err).Error("Failed to list installed flatpak apps")
 			return true, "Flatpak installed apps check failed"
@@ -65,8 +76,8 @@ func (f *ApplicationUpdates) checkUpdates() (bool, string) {
 	}
 
 	// Check apt
-	if _, err := lookPath("apt"); err == nil {
-		output, err := shared.RunCommand("apt", "list", "--upgradable")
+	if _, err := f.lookPath("apt"); err == nil {
+		output, err := shared.RunCommandContext(ctx, "apt", "list", "--upgradable")
 		log.WithField("output", string(output)).Debug("APT updates")
 		if err == nil && len(output) > 0 && strings.Contains(string(output), "upgradable") {
 			updates = append(updates, "APT")
@@ -74,8 +85,8 @@ func (f *ApplicationUpdates) checkUpdates() (bool, string) {
 	}
 
 	// Check dnf
-	if _, err := lookPath("dnf"); err == nil {
-		if out, _ := shared.RunCommand("dnf", "updateinfo", "list", "--security", "--quiet"); !lo.IsEmpty(out) {
+	if _, err := f.lookPath("dnf"); err == nil {
+		if out, _ := shared.RunCommandContext(ctx, "dnf", "updateinfo", "list", "--security", "--quiet"); !lo.IsEmpty(out) {
 			outStr := string(out)
 			if strings.Contains(outStr, "security") && strings.Count(outStr, "\n") > 0 {
 				updates = append(updates, "DNF")
@@ -84,8 +95,8 @@ func (f *ApplicationUpdates) checkUpdates() (bool, string) {
 	}
 
 	// Check pacman
-	if _, err := lookPath("pacman"); err == nil {
-		output, err := shared.RunCommand("pacman", "-Qu")
+	if _, err := f.lookPath("pacman"); err == nil {
+		output, err := shared.RunCommandContext(ctx, "pacman", "-Qu")
 		log.WithField("output", string(output)).Debug("Pacman updates")
 		if err == nil && len(output) > 0 {
 			updates = append(updates, "Pacman")
@@ -93,11 +104,11 @@ func (f *ApplicationUpdates) checkUpdates() (bool, string) {
 	}
 
 	// Check snap
-	if _, err := lookPath("snap"); err == nil {
+	if _, err := f.lookPath("snap"); err == nil {
 		// Check if snapd is running
-		snapdStatus, err := shared.RunCommand("systemctl", "is-active", "snapd")
+		snapdStatus, err := shared.RunCommandContext(ctx, "systemctl", "is-active", "snapd")
 		if err == nil && strings.TrimSpace(string(snapdStatus)) == "active" {
-			output, err := shared.RunCommand("snap", "refresh", "--list")
+			output, err := shared.RunCommandContext(ctx, "snap", "refresh", "--list")
 			log.WithField("output", string(output)).Debug("Snap updates")
 			if err == nil && !lo.IsEmpty(output) && !strings.Contains(string(output), "All snaps up to date") {
 				log.WithField("output", string(output)).Info("Snap updates found")
@@ -117,12 +128,22 @@ func (f *ApplicationUpdates) checkUpdates() (bool, string) {
 
 // Run executes the check
 func (f *ApplicationUpdates) Run() error {
+	return f.RunContext(context.Background())
+}
+
+// RunContext executes the check, killing package manager queries when ctx is done
+func (f *ApplicationUpdates) RunContext(ctx context.Context) error {
 	var ok bool
-	ok, f.details = f.checkUpdates()
+	ok, f.details = f.checkUpdates(ctx)
 	f.passed = ok
 	return nil
 }
 
+// UseFacts sets the facts shared by the checks of a run
+func (f *ApplicationUpdates) UseFacts(facts check.Facts) {
+	f.facts = facts
+}
+
 // Passed returns the status of the check
 func (f *ApplicationUpdates) Passed() bool {
 	return f.passed
@@ -138,6 +159,11 @@ func (f *ApplicationUpdates) UUID() string {
 	return "7436553a-ae52-479b-937b-2ae14d15a520"
 }
 
+// Slug returns the stable identifier of the check
+func (f *ApplicationUpdates) Slug() string {
+	return "linux.application-updates"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (f *ApplicationUpdates) PassedMessage() string {
 	return "All apps are up to date"
@@ -153,6 +179,11 @@ func (f *ApplicationUpdates) RequiresRoot() bool {
 	return true
 }
 
+// Severity returns how important the check is
+func (f *ApplicationUpdates) Severity() check.Severity {
+	return check.SeverityHigh
+}
+
 // Status returns the status of the check
 func (f *ApplicationUpdates) Status() string {
 	return f.details
@@ -1,6 +1,7 @@
 package checks
 
 import (
+	"context"
 	"os/exec"
 	"testing"
 
@@ -271,7 +272,7 @@ func TestApplicationUpdates_checkUpdates(t *testing.T) {
 			}
 
 			au := &ApplicationUpdates{}
-			passed, detail := au.checkUpdates()
+			passed, detail := au.checkUpdates(context.Background())
 
 			assert.Equal(t, tt.expected.passed, passed)
 			assert.Equal(t, tt.expected.detail, detail)
@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/samber/lo"
 )
@@ -293,6 +294,11 @@ func (f *Autologin) UUID() string {
 	return "f962c423-fdf5-428a-a57a-816abc9b253e"
 }
 
+// Slug returns the stable identifier of the check
+func (f *Autologin) Slug() string {
+	return "linux.autologin"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (f *Autologin) PassedMessage() string {
 	return "Automatic login is off"
@@ -308,6 +314,11 @@ func (f *Autologin) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (f *Autologin) Severity() check.Severity {
+	return check.SeverityHigh
+}
+
 // Status returns the status of the check
 func (f *Autologin) Status() string {
 	if !f.Passed() {
@@ -1,8 +1,10 @@
 package checks
 
 import (
+	"context"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/samber/lo"
 )
@@ -19,8 +21,13 @@ func (f *DockerAccess) Name() string {
 
 // Run executes the check
 func (f *DockerAccess) Run() error {
+	return f.RunContext(context.Background())
+}
+
+// RunContext executes the check, killing docker queries when ctx is done
+func (f *DockerAccess) RunContext(ctx context.Context) error {
 	// First check if Docker is installed
-	versionOut, versionErr := shared.RunCommand("docker", "version")
+	versionOut, versionErr := shared.RunCommandContext(ctx, "docker", "version")
 	if versionErr != nil || !strings.Contains(versionOut, "Version") {
 		// Docker is not installed - this is a secure state
 		f.passed = true
@@ -38,8 +45,8 @@ func (f *DockerAccess) Run() error {
 
 	// Check if we deprecate packages installed via apt
 	// https://docs.docker.com/engine/install/ubuntu/#uninstall-old-versions
-	if _, err := shared.RunCommand("which", "dpkg-query"); err == nil {
-		out, err := shared.RunCommand("dpkg-query", "-W", "-f='${Package}'", "docker.io")
+	if _, err := shared.RunCommandContext(ctx, "which", "dpkg-query"); err == nil {
+		out, err := shared.RunCommandContext(ctx, "dpkg-query", "-W", "-f='${Package}'", "docker.io")
 		if err == nil && strings.Contains(out, "docker") {
 			f.passed = false
 			f.status = "Deprecated docker.io package installed via apt"
@@ -47,7 +54,7 @@ func (f *DockerAccess) Run() error {
 		}
 	}
 
-	output, err := shared.RunCommand("docker", "info", "--format", "{{.SecurityOptions}}")
+	output, err := shared.RunCommandContext(ctx, "docker", "info", "--format", "{{.SecurityOptions}}")
 	if err != nil || lo.IsEmpty(output) {
 		f.passed = false
 		f.status = "Failed to get Docker info"
@@ -82,6 +89,11 @@ func (f *DockerAccess) UUID() string {
 	return "25443ceb-c1ec-408c-b4f3-2328ea0c84e1"
 }
 
+// Slug returns the stable identifier of the check
+func (f *DockerAccess) Slug() string {
+	return "linux.docker-access"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (f *DockerAccess) PassedMessage() string {
 	return "Docker is running in rootless mode"
@@ -97,6 +109,11 @@ func (f *DockerAccess) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (f *DockerAccess) Severity() check.Severity {
+	return check.SeverityMedium
+}
+
 // Status returns the status of the check
 func (f *DockerAccess) Status() string {
 	if !f.Passed() {
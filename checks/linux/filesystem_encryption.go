@@ -2,8 +2,10 @@ package checks
 
 import (
 	"bufio"
+	"context"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 )
@@ -32,6 +34,11 @@ func (f *EncryptingFS) UUID() string {
 	return "21830a4e-84f1-48fe-9c5b-beab436b2cdb"
 }
 
+// Slug returns the stable identifier of the check
+func (f *EncryptingFS) Slug() string {
+	return "linux.filesystem-encryption"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (f *EncryptingFS) PassedMessage() string {
 	return "Block device encryption is enabled"
@@ -47,26 +54,37 @@ func (f *EncryptingFS) RequiresRoot() bool {
 	return true
 }
 
+// Severity returns how important the check is
+func (f *EncryptingFS) Severity() check.Severity {
+	return check.SeverityCritical
+}
+
 // Run executes the check
 func (f *EncryptingFS) Run() error {
+	return f.RunContext(context.Background())
+}
+
+// RunContext executes the check, recording the probes as evidence in ctx
+func (f *EncryptingFS) RunContext(ctx context.Context) error {
 	f.passed = false
 
 	// Check if the system is using LUKS
-	if maybeCryptoViaLuks() {
+	if maybeCryptoViaLuks(ctx) {
 		f.passed = true
 		return nil
 	}
 	// Check if the system is using kernel parameters for encryption
-	if maybeCryptoViaKernel() {
+	if maybeCryptoViaKernel(ctx) {
 		f.passed = true
 		return nil
 	}
 	// Check if the system is using ZFS encryption
-	if maybeCryptoViaZFS() {
+	if maybeCryptoViaZFS(ctx) {
 		f.passed = true
 		return nil
 	}
 
+	check.Decided(ctx, "encryption", "no LUKS device, encrypted root or encrypted ZFS dataset found")
 	return nil
 }
 
@@ -78,9 +96,9 @@ func (f *EncryptingFS) Status() string {
 	return f.FailedMessage()
 }
 
-func maybeCryptoViaLuks() bool {
+func maybeCryptoViaLuks(ctx context.Context) bool {
 	// Check if the system is using LUKS
-	lsblk, err := shared.RunCommand("lsblk", "-o", "TYPE,MOUNTPOINT")
+	lsblk, err := shared.RunCommandContext(ctx, "lsblk", "-o", "TYPE,MOUNTPOINT")
 	if err != nil {
 		log.WithError(err).Warn("Failed to run lsblk command")
 		return false
@@ -91,16 +109,17 @@ func maybeCryptoViaLuks() bool {
 		line := scanner.Text()
 		if strings.Contains(line, "crypt") {
 			log.WithField("line", line).Debug("LUKS encryption detected")
+			check.Decided(ctx, "luks", "lsblk lists a crypt device: %s", strings.Join(strings.Fields(line), " "))
 			return true
 		}
 	}
-	log.WithField("output", lsblk).Warn("Failed to scan lsblk output")
+	log.Debug("No crypt device in lsblk output")
 	return false
 }
 
-func maybeCryptoViaKernel() bool {
+func maybeCryptoViaKernel(ctx context.Context) bool {
 	// Read kernel parameters to check if root is booted via crypt
-	cmdline, err := shared.ReadFile("/proc/cmdline")
+	cmdline, err := shared.ReadFileContext(ctx, "/proc/cmdline")
 	if err != nil {
 		log.WithError(err).Warn("Failed to read /proc/cmdline")
 	}
@@ -111,6 +130,7 @@ func maybeCryptoViaKernel() bool {
 			parts := strings.Split(param, ":")
 			if len(parts) == 3 && parts[2] == "root" {
 				log.WithField("param", param).Debug("Kernel crypto parameters detected")
+				check.Decided(ctx, "kernel", "the root device is opened by the cryptdevice kernel parameter")
 				return true
 			}
 		}
@@ -118,8 +138,8 @@ func maybeCryptoViaKernel() bool {
 	return false
 }
 
-func maybeCryptoViaZFS() bool {
-	out, err := shared.RunCommand("zfs", "get", "-H", "-o", "name,value", "encryption")
+func maybeCryptoViaZFS(ctx context.Context) bool {
+	out, err := shared.RunCommandContext(ctx, "zfs", "get", "-H", "-o", "name,value", "encryption")
 	if err != nil {
 		log.WithError(err).Debug("Failed to run zfs get encryption")
 		return false
@@ -132,10 +152,11 @@ func maybeCryptoViaZFS() bool {
 			enc := fields[1]
 			if enc != "off" && enc != "-" {
 				log.WithField("dataset", fields[0]).WithField("enc", enc).Debug("ZFS encryption detected")
+				check.Decided(ctx, "zfs", "dataset %s is encrypted with %s", fields[0], enc)
 				return true
 			}
 		}
 	}
-	log.WithField("output", out).Debug("No encrypted ZFS pools found")
+	log.Debug("No encrypted ZFS pools found")
 	return false
 }
@@ -1,8 +1,10 @@
 package checks
 
 import (
+	"context"
 	"testing"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/stretchr/testify/assert"
 )
@@ -125,7 +127,7 @@ func TestMaybeCryptoViaLuks(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			shared.RunCommandMocks = tt.mocks
-			result := maybeCryptoViaLuks()
+			result := maybeCryptoViaLuks(context.Background())
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -181,7 +183,7 @@ func TestMaybeCryptoViaKernel(t *testing.T) {
 				return nil, nil
 			}
 
-			result := maybeCryptoViaKernel()
+			result := maybeCryptoViaKernel(context.Background())
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -270,8 +272,23 @@ func TestMaybeCryptoViaZFS(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			shared.RunCommandMocks = tt.mocks
-			result := maybeCryptoViaZFS()
+			result := maybeCryptoViaZFS(context.Background())
 			assert.Equal(t, tt.expected, result)
 		})
 	}
 }
+
+func TestEncryptingFS_RunContextRecordsEvidence(t *testing.T) {
+	shared.RunCommandMocks = []shared.RunCommandMock{
+		{Command: "lsblk", Args: []string{"-o", "TYPE,MOUNTPOINT"}, Out: "TYPE MOUNTPOINT\npart /boot\ncrypt  /"},
+	}
+	trail := &check.Trail{}
+	f := &EncryptingFS{}
+
+	assert.NoError(t, f.RunContext(check.WithTrail(context.Background(), trail)))
+	assert.True(t, f.Passed())
+	assert.Equal(t, []check.Evidence{
+		{Kind: check.EvidenceCommand, Subject: "lsblk -o TYPE,MOUNTPOINT", Detail: "35 bytes of output"},
+		{Kind: check.EvidenceRule, Subject: "luks", Detail: "lsblk lists a crypt device: crypt /"},
+	}, trail.Entries())
+}
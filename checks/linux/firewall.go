@@ -2,9 +2,14 @@ package checks
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
 	"strconv"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 )
@@ -19,59 +24,69 @@ func (f *Firewall) Name() string {
 	return "Firewall is configured"
 }
 
-// checkNFTables verifies if NFTables is properly configured on the system.
-func (f *Firewall) checkNFTables() bool {
-	output, err := shared.RunCommand("nft", "list", "ruleset")
+// ipv6DisabledPath holds 1 when IPv6 is disabled on all interfaces.
+const ipv6DisabledPath = "/proc/sys/net/ipv6/conf/all/disable_ipv6"
+
+// ipv6Enabled reports whether the kernel handles IPv6 traffic. The sysctl is
+// missing when IPv6 is disabled at boot with ipv6.disable=1; IPv6 counts as
+// enabled when it cannot be read for other reasons.
+func ipv6Enabled(ctx context.Context) bool {
+	content, err := shared.ReadFileContext(ctx, ipv6DisabledPath)
+	if err != nil {
+		return !errors.Is(err, fs.ErrNotExist)
+	}
+	return strings.TrimSpace(string(content)) != "1"
+}
+
+// checkNFTables verifies that nftables drops incoming IPv4 traffic by
+// default, and incoming IPv6 traffic when IPv6 is enabled, see
+// analyzeNFTables.
+func (f *Firewall) checkNFTables(ctx context.Context) bool {
+	output, err := shared.RunCommandContext(ctx, "nft", "-j", "list", "ruleset")
 	if err != nil {
 		log.WithError(err).Warn("Failed to check nftables status")
 		return false
 	}
 	log.WithField("output", output).Debug("Nftables status")
 
-	// Check if the output contains input CHAIN
-	scanner := bufio.NewScanner(strings.NewReader(output))
-	inInputChain := false
-	hasDropPolicy := false
-
-	for scanner.Scan() {
-		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
-
-		// Check if we're entering the INPUT chain definition
-		if strings.HasPrefix(line, "chain input") || strings.HasPrefix(line, "chain filter_input") {
-			inInputChain = true
-			continue
-		}
-
-		// If we're in the INPUT chain and find policy information
-		if inInputChain && strings.Contains(line, "policy") {
-			if strings.Contains(line, "policy drop") {
-				hasDropPolicy = true
-				break // We've found the policy, no need to continue
-			}
-
-		}
-		// If we're in the INPUT chain and find policy information
-		if inInputChain && strings.Contains(line, "reject") {
-			if strings.Contains(line, "reject with") {
-				hasDropPolicy = true
-				break // We've found the policy, no need to continue
-			}
-
-		}
-		// Exit the INPUT chain section if we encounter a new chain
-		if inInputChain && (strings.HasPrefix(line, "chain") || strings.HasPrefix(line, "}")) {
-			inInputChain = false // Exit the INPUT chain section
-			break
-		}
+	var ruleset nftRuleset
+	if err := json.Unmarshal([]byte(output), &ruleset); err != nil {
+		log.WithError(err).Warn("Failed to parse nftables ruleset")
+		check.Decided(ctx, "nftables", "the ruleset could not be parsed: %v", err)
+		return false
 	}
 
-	return inInputChain && hasDropPolicy
+	ipv6 := ipv6Enabled(ctx)
+	analysis := analyzeNFTables(ruleset)
+	for _, acceptAll := range analysis.AcceptAll {
+		check.Decided(ctx, "nftables", "%s accepts all traffic, so %s has no effect", acceptAll.Rule, acceptAll.Policy)
+	}
+	if !ipv6 {
+		check.Decided(ctx, "nftables", "incoming IPv4 traffic is %s; IPv6 is disabled", nftOutcome(analysis.IPv4))
+		return analysis.IPv4.drops()
+	}
+	check.Decided(ctx, "nftables", "incoming IPv4 traffic is %s; incoming IPv6 traffic is %s", nftOutcome(analysis.IPv4), nftOutcome(analysis.IPv6))
+	return analysis.IPv4.drops() && analysis.IPv6.drops()
+}
 
+// nftOutcome describes what happens to traffic by decision.
+func nftOutcome(decision nftDecision) string {
+	if decision.Chain == "" {
+		return "accepted, no input chain filters it"
+	}
+	verb := "accepted"
+	switch decision.Verdict {
+	case nftDrop:
+		verb = "dropped"
+	case nftReject:
+		verb = "rejected"
+	}
+	return verb + " by " + decision.String()
 }
 
 // checkIptables checks if iptables is active
-func (f *Firewall) checkIptables() bool {
-	output, err := shared.RunCommand("iptables", "-L", "INPUT", "--line-numbers")
+func (f *Firewall) checkIptables(ctx context.Context) bool {
+	output, err := shared.RunCommandContext(ctx, "iptables", "-L", "INPUT", "--line-numbers")
 	if err != nil {
 		log.WithError(err).WithField("output", output).Warn("Failed to check iptables status")
 		return false
@@ -161,10 +176,12 @@ func (f *Firewall) checkIptables() bool {
 	// 2. There are explicit DROP/REJECT rules in the INPUT chain, OR
 	// 3. There's a custom chain AND it's a known firewall chain (nixos-fw, ufw-, etc)
 	if policy == "DROP" || policy == "REJECT" {
+		check.Decided(ctx, "iptables", "the INPUT chain policy is %s", policy)
 		return true
 	}
 
 	if hasRestrictiveRules {
+		check.Decided(ctx, "iptables", "the INPUT chain has DROP or REJECT rules")
 		return true
 	}
 
@@ -176,22 +193,58 @@ func (f *Firewall) checkIptables() bool {
 			strings.HasPrefix(target, "ufw") ||
 			strings.HasPrefix(target, "firewalld") ||
 			strings.HasPrefix(target, "iptables-") {
+			check.Decided(ctx, "iptables", "rule %d jumps to the firewall chain %s", rule.Number, rule.Target)
 			return true
 		}
 	}
 
+	if policy == "" {
+		policy = "unknown"
+	}
+	check.Decided(ctx, "iptables", "the INPUT chain policy is %s and no rule filters traffic", policy)
 	return false
 }
 
 // Run executes the check
 func (f *Firewall) Run() error {
-	f.passed = f.checkIptables()
+	return f.RunContext(context.Background())
+}
+
+// RunContext executes the check, killing firewall queries when ctx is done
+func (f *Firewall) RunContext(ctx context.Context) error {
+	f.passed = f.checkIptables(ctx)
 	if !f.passed {
-		f.passed = f.checkNFTables()
+		f.passed = f.checkNFTables(ctx)
 	}
 	return nil
 }
 
+// Remediation returns the steps that turn on the firewall shipped with the distribution.
+func (f *Firewall) Remediation() []check.FixStep {
+	if shared.IsNixOS() {
+		return []check.FixStep{{
+			Description: "Set `networking.firewall.enable = true;` in /etc/nixos/configuration.nix and run `nixos-rebuild switch`",
+		}}
+	}
+	if _, err := lookPath("ufw"); err == nil {
+		return []check.FixStep{{
+			Description:  "Enable ufw, which denies incoming connections by default",
+			Command:      []string{"ufw", "enable"},
+			RequiresRoot: true,
+		}}
+	}
+	if _, err := lookPath("firewall-cmd"); err == nil {
+		return []check.FixStep{{
+			Description:  "Start firewalld and enable it on boot",
+			Command:      []string{"systemctl", "enable", "--now", "firewalld"},
+			RequiresRoot: true,
+		}}
+	}
+	return []check.FixStep{{
+		Description: "Install and enable a firewall such as ufw or firewalld",
+	}}
+}
+
 // Passed returns the status of the check
 func (f *Firewall) Passed() bool {
 	return f.passed
@@ -207,6 +260,11 @@ func (f *Firewall) UUID() string {
 	return "2e46c89a-5461-4865-a92e-3b799c12034a"
 }
 
+// Slug returns the stable identifier of the check
+func (f *Firewall) Slug() string {
+	return "linux.firewall"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (f *Firewall) PassedMessage() string {
 	return "Firewall is on"
@@ -222,6 +280,11 @@ func (f *Firewall) RequiresRoot() bool {
 	return true
 }
 
+// Severity returns how important the check is
+func (f *Firewall) Severity() check.Severity {
+	return check.SeverityHigh
+}
+
 // Status returns the status of the check
 func (f *Firewall) Status() string {
 	if f.Passed() {
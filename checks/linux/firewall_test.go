@@ -1,8 +1,13 @@
 package checks
 
 import (
+	"context"
+	"io/fs"
+	"os/exec"
+	"strings"
 	"testing"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/stretchr/testify/assert"
 )
@@ -159,7 +164,7 @@ num  target     prot opt source               destination
 				"iptables -L INPUT --line-numbers": tt.mockOutput,
 			})
 			f := &Firewall{}
-			result := f.checkIptables()
+			result := f.checkIptables(context.Background())
 			assert.Equal(t, tt.expectedResult, result)
 		})
 	}
@@ -179,7 +184,7 @@ func TestCheckIptables_CommandError(t *testing.T) {
 	}
 
 	f := &Firewall{}
-	result := f.checkIptables()
+	result := f.checkIptables(context.Background())
 	assert.False(t, result, "Expected checkIptables to return false when RunCommand fails")
 }
 
@@ -225,7 +230,7 @@ num  target     prot opt source               destination
 				"iptables -L INPUT --line-numbers": tt.mockOutput,
 			})
 			f := &Firewall{}
-			result := f.checkIptables()
+			result := f.checkIptables(context.Background())
 			assert.Equal(t, tt.expectedResult, result)
 		})
 	}
@@ -270,43 +275,88 @@ num  target     prot opt source               destination
 				"iptables -L INPUT --line-numbers": tt.mockOutput,
 			})
 			f := &Firewall{}
-			result := f.checkIptables()
+			result := f.checkIptables(context.Background())
 			assert.Equal(t, tt.expectedPassed, result)
 		})
 	}
 }
 
+// withIPv6Disabled makes the disable_ipv6 sysctl read value, or makes it
+// missing when value is "missing".
+func withIPv6Disabled(t *testing.T, value string) {
+	t.Helper()
+	readFileMock := shared.ReadFileMock
+	t.Cleanup(func() { shared.ReadFileMock = readFileMock })
+	shared.ReadFileMock = func(name string) ([]byte, error) {
+		if name != ipv6DisabledPath || value == "missing" {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		return []byte(value), nil
+	}
+}
+
 func TestCheckNFTables(t *testing.T) {
 	tests := []struct {
 		name           string
 		mockOutput     string
 		mockError      error
+		ipv6Disabled   string
 		expectedResult bool
 	}{
 		{
 			name:           "NFTables configured with chain INPUT",
-			mockOutput:     "table inet filter {\n\tchain INPUT {\n\t\ttype filter hook input priority 0;\n\t\tpolicy drop;\n\t}\n}",
-			mockError:      nil,
+			mockOutput:     nftJSON(`{"chain": {"family": "inet", "table": "filter", "name": "INPUT", "type": "filter", "hook": "input", "prio": 0, "policy": "drop"}}`),
 			expectedResult: true,
 		},
 		{
 			name:           "NFTables configured without chain INPUT",
-			mockOutput:     "table inet filter {\n\tchain OUTPUT {\n\t\ttype filter hook output priority 0;\n\t\tpolicy accept;\n\t}\n}",
-			mockError:      nil,
+			mockOutput:     nftJSON(`{"chain": {"family": "inet", "table": "filter", "name": "OUTPUT", "type": "filter", "hook": "output", "prio": 0, "policy": "accept"}}`),
 			expectedResult: false,
 		},
 		{
-			name:           "nixos NFTables configured with lowercase chain input",
-			mockOutput:     "table inet filter {\n\tchain input {\n\t\ttype filter hook input priority 0;\n\t\tpolicy drop;\n\t}\n}",
-			mockError:      nil,
+			name: "firewalld-style NFTables with filter_INPUT chain",
+			mockOutput: nftJSON(
+				`{"chain": {"family": "inet", "table": "firewalld", "name": "filter_INPUT", "type": "filter", "hook": "input", "prio": 10, "policy": "accept"}}`,
+				`{"chain": {"family": "inet", "table": "firewalld", "name": "filter_INPUT_ZONES"}}`,
+				`{"rule": {"family": "inet", "table": "firewalld", "chain": "filter_INPUT", "handle": 1, "expr": [{"match": {"op": "in", "left": {"ct": {"key": "state"}}, "right": ["established", "related"]}}, {"accept": null}]}}`,
+				`{"rule": {"family": "inet", "table": "firewalld", "chain": "filter_INPUT", "handle": 2, "expr": [{"match": {"op": "==", "left": {"meta": {"key": "iifname"}}, "right": "lo"}}, {"accept": null}]}}`,
+				`{"rule": {"family": "inet", "table": "firewalld", "chain": "filter_INPUT", "handle": 3, "expr": [{"jump": {"target": "filter_INPUT_ZONES"}}]}}`,
+				`{"rule": {"family": "inet", "table": "firewalld", "chain": "filter_INPUT_ZONES", "handle": 4, "expr": [{"match": {"op": "==", "left": {"meta": {"key": "iifname"}}, "right": "eth0"}}, {"goto": {"target": "filter_IN_public"}}]}}`,
+				`{"rule": {"family": "inet", "table": "firewalld", "chain": "filter_INPUT", "handle": 5, "expr": [{"reject": {"type": "icmpx", "expr": "admin-prohibited"}}]}}`,
+			),
 			expectedResult: true,
 		},
 		{
-			name:           "firewalld-style NFTables with filter_INPUT chain",
-			mockOutput:     "table inet filter {\n\tchain filter_INPUT {\n\t\ttype filter hook input priority filter + 10; policy accept;\n\t\tct state { established, related } accept\n\t\tct status dnat accept\n\t\tiifname \"lo\" accept\n\t\tjump filter_INPUT_POLICIES_pre\n\t\tjump filter_INPUT_ZONES\n\t\tjump filter_INPUT_POLICIES_post\n\t\tct state { invalid } drop\n\t\treject with icmpx type admin-prohibited\n\t}\n}",
-			mockError:      nil,
+			name: "NFTables drop policy with an accept-all rule",
+			mockOutput: nftJSON(
+				`{"chain": {"family": "inet", "table": "filter", "name": "input", "type": "filter", "hook": "input", "prio": 0, "policy": "drop"}}`,
+				`{"rule": {"family": "inet", "table": "filter", "chain": "input", "handle": 3, "expr": [{"counter": {"packets": 0, "bytes": 0}}, {"accept": null}]}}`,
+			),
+			expectedResult: false,
+		},
+		{
+			name:           "NFTables filtering IPv4 only",
+			mockOutput:     nftJSON(`{"chain": {"family": "ip", "table": "filter", "name": "INPUT", "type": "filter", "hook": "input", "prio": 0, "policy": "drop"}}`),
+			expectedResult: false,
+		},
+		{
+			name:           "NFTables filtering IPv4 only with IPv6 disabled",
+			mockOutput:     nftJSON(`{"chain": {"family": "ip", "table": "filter", "name": "INPUT", "type": "filter", "hook": "input", "prio": 0, "policy": "drop"}}`),
+			ipv6Disabled:   "1\n",
 			expectedResult: true,
 		},
+		{
+			name:           "NFTables filtering IPv4 only without IPv6 support",
+			mockOutput:     nftJSON(`{"chain": {"family": "ip", "table": "filter", "name": "INPUT", "type": "filter", "hook": "input", "prio": 0, "policy": "drop"}}`),
+			ipv6Disabled:   "missing",
+			expectedResult: true,
+		},
+		{
+			name:           "NFTables filtering IPv6 only with IPv6 disabled",
+			mockOutput:     nftJSON(`{"chain": {"family": "ip6", "table": "filter", "name": "INPUT", "type": "filter", "hook": "input", "prio": 0, "policy": "drop"}}`),
+			ipv6Disabled:   "1\n",
+			expectedResult: false,
+		},
 		{
 			name:           "NFTables command error",
 			mockOutput:     "",
@@ -327,14 +377,76 @@ func TestCheckNFTables(t *testing.T) {
 			shared.RunCommandMocks = []shared.RunCommandMock{
 				{
 					Command: "nft",
-					Args:    []string{"list", "ruleset"},
+					Args:    []string{"-j", "list", "ruleset"},
 					Out:     tt.mockOutput,
 					Err:     tt.mockError,
 				},
 			}
+			if tt.ipv6Disabled == "" {
+				tt.ipv6Disabled = "0\n"
+			}
+			withIPv6Disabled(t, tt.ipv6Disabled)
 			f := &Firewall{}
-			result := f.checkNFTables()
+			result := f.checkNFTables(context.Background())
 			assert.Equal(t, tt.expectedResult, result)
 		})
 	}
 }
+
+func TestCheckNFTables_RecordsDecision(t *testing.T) {
+	shared.RunCommandMocks = []shared.RunCommandMock{{
+		Command: "nft",
+		Args:    []string{"-j", "list", "ruleset"},
+		Out: nftJSON(
+			`{"chain": {"family": "inet", "table": "filter", "name": "input", "type": "filter", "hook": "input", "prio": 0, "policy": "drop"}}`,
+			`{"rule": {"family": "inet", "table": "filter", "chain": "input", "handle": 3, "expr": [{"accept": null}]}}`,
+			`{"chain": {"family": "ip", "table": "nat", "name": "block", "type": "filter", "hook": "input", "prio": -10, "policy": "drop"}}`,
+		),
+	}}
+	withIPv6Disabled(t, "0\n")
+	trail := &check.Trail{}
+
+	assert.False(t, (&Firewall{}).checkNFTables(check.WithTrail(context.Background(), trail)))
+
+	entries := trail.Entries()
+	assert.Equal(t, check.Evidence{Kind: check.EvidenceFile, Subject: ipv6DisabledPath, Detail: "2 bytes"}, entries[1])
+	assert.Equal(t, []check.Evidence{
+		{Kind: check.EvidenceRule, Subject: "nftables", Detail: "rule 3 (accept) in inet filter/input accepts all traffic, so the drop policy of inet filter/input has no effect"},
+		{Kind: check.EvidenceRule, Subject: "nftables", Detail: "incoming IPv4 traffic is dropped by the drop policy of ip nat/block; incoming IPv6 traffic is accepted by rule 3 (accept) in inet filter/input"},
+	}, entries[2:])
+}
+
+// nftJSON wraps objects in the output of `nft -j list ruleset`.
+func nftJSON(objects ...string) string {
+	return `{"nftables": [{"metainfo": {"version": "1.0.9", "json_schema_version": 1}}` + strings.Repeat(",", min(len(objects), 1)) + strings.Join(objects, ",") + `]}`
+}
+
+func TestFirewall_Remediation(t *testing.T) {
+	defer func() { lookPathMock = nil }()
+
+	lookPathMock = func(file string) (string, error) {
+		if file == "firewall-cmd" {
+			return "/usr/bin/firewall-cmd", nil
+		}
+		return "", exec.ErrNotFound
+	}
+	steps := (&Firewall{}).Remediation()
+	assert.Len(t, steps, 1)
+	assert.Equal(t, []string{"systemctl", "enable", "--now", "firewalld"}, steps[0].Command)
+	assert.True(t, steps[0].RequiresRoot)
+
+	lookPathMock = func(file string) (string, error) {
+		return "", exec.ErrNotFound
+	}
+	steps = (&Firewall{}).Remediation()
+	assert.True(t, steps[0].Manual())
+}
+
+func TestFirewall_Replay(t *testing.T) {
+	// iptables-nft refuses to list rules for a normal user, nftables shows the firewall
+	shared.ReplayForTest(t, "testdata/firewall_iptables_denied.json")
+
+	f := &Firewall{}
+	assert.NoError(t, f.RunContext(context.Background()))
+	assert.True(t, f.Passed())
+}
@@ -4,8 +4,6 @@ import (
 	"errors"
 	"io/fs"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 	"testing"
 
@@ -22,7 +20,7 @@ func lookPath(file string) (string, error) {
 	if testing.Testing() && lookPathMock != nil {
 		return lookPathMock(file)
 	}
-	return exec.LookPath(file)
+	return shared.LookPath(file)
 }
 
 var osStatMock func(file string) (os.FileInfo, error)
@@ -30,11 +28,12 @@ var osStatMock func(file string) (os.FileInfo, error)
 // osStat checks if a file exists by attempting to get its file info.
 // During testing, it uses a mock implementation via osStatMock.
 // It returns the file path if the file exists, otherwise returns an empty string and error.
+// The lookup is recorded and replayed, see shared.Stat.
 func osStat(file string) (os.FileInfo, error) {
 	if testing.Testing() && osStatMock != nil {
 		return osStatMock(file)
 	}
-	return os.Stat(file)
+	return shared.Stat(file)
 }
 
 var filepathGlobMock func(pattern string) ([]string, error)
@@ -43,12 +42,12 @@ var filepathGlobMock func(pattern string) ([]string, error)
 //
 // In a testing environment (when testing.Testing() returns true), it delegates
 // the matching to filepathGlobMock to simulate the behavior. Otherwise, it uses
-// the standard library's filepath.Glob to perform glob pattern matching.
+// shared.Glob, which is recorded and replayed.
 func filepathGlob(pattern string) ([]string, error) {
 	if testing.Testing() && filepathGlobMock != nil {
 		return filepathGlobMock(pattern)
 	}
-	return filepath.Glob(pattern)
+	return shared.Glob(pattern)
 }
 
 var osReadFileMock func(file string) ([]byte, error)
@@ -56,24 +55,24 @@ var osReadFileMock func(file string) ([]byte, error)
 // osReadFile reads the contents of the specified file.
 //
 // If the testing mode is enabled, it delegates the file reading to a mock function.
-// Otherwise, it reads the file from disk using the standard os.ReadFile function.
+// Otherwise, it reads the file with shared.ReadSystemFile, which is recorded and replayed.
 func osReadFile(file string) ([]byte, error) {
 	if testing.Testing() && osReadFileMock != nil {
 		return osReadFileMock(file)
 	}
-	return os.ReadFile(file)
+	return shared.ReadSystemFile(file)
 }
 
 var osReadDirMock func(dirname string) ([]os.DirEntry, error)
 
 // osReadDir reads the directory specified by dirname and returns a slice of os.DirEntry.
 // In testing mode, it delegates to osReadDirMock for controlled behavior; otherwise,
-// it uses os.ReadDir from the standard library.
+// it uses shared.ReadDir, which is recorded and replayed.
 func osReadDir(dirname string) ([]os.DirEntry, error) {
 	if testing.Testing() && osReadDirMock != nil {
 		return osReadDirMock(dirname)
 	}
-	return os.ReadDir(dirname)
+	return shared.ReadDir(dirname)
 }
 
 // mockDirEntry is a simple implementation of os.DirEntry for testing.
@@ -0,0 +1,248 @@
+package checks
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Verdicts of nftables rules and chain policies.
+const (
+	nftAccept = "accept"
+	nftDrop   = "drop"
+	nftReject = "reject"
+	nftJump   = "jump"
+	nftGoto   = "goto"
+	nftReturn = "return"
+)
+
+// nftPassive are statements that do not change which packets a rule applies to.
+var nftPassive = []string{"counter", "log"}
+
+// nftStandardPriorities are the named priorities of chains in the ip, ip6 and inet families.
+var nftStandardPriorities = map[string]int{
+	"raw":      -300,
+	"mangle":   -150,
+	"dstnat":   -100,
+	"filter":   0,
+	"security": 50,
+	"srcnat":   100,
+}
+
+// nftRuleset is the output of `nft -j list ruleset`. Only chains and rules
+// are decoded, tables, sets and the other objects are ignored.
+type nftRuleset struct {
+	Nftables []nftObject `json:"nftables"`
+}
+
+// nftObject is an entry of the ruleset, at most one of its fields is set.
+type nftObject struct {
+	Chain *nftChain `json:"chain,omitempty"`
+	Rule  *nftRule  `json:"rule,omitempty"`
+}
+
+// nftChain is a chain. Base chains have a type, a hook, a priority and a policy.
+type nftChain struct {
+	Family string      `json:"family"`
+	Table  string      `json:"table"`
+	Name   string      `json:"name"`
+	Type   string      `json:"type"`
+	Hook   string      `json:"hook"`
+	Prio   nftPriority `json:"prio"`
+	Policy string      `json:"policy"`
+}
+
+// nftRule is a rule of a chain, a list of statements that each hold a single
+// key such as "match", "counter", "accept" or "jump".
+type nftRule struct {
+	Family string                       `json:"family"`
+	Table  string                       `json:"table"`
+	Chain  string                       `json:"chain"`
+	Handle int                          `json:"handle"`
+	Expr   []map[string]json.RawMessage `json:"expr"`
+}
+
+// nftPriority is the priority of a base chain, given as a number or as a
+// standard name by some versions of nft.
+type nftPriority int
+
+func (p *nftPriority) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		*p = nftPriority(number)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	*p = nftPriority(nftStandardPriorities[name])
+	return nil
+}
+
+// nftDecision is where the evaluation of packets that no conditional rule
+// matched ends: a rule with an unconditional verdict or a chain policy.
+type nftDecision struct {
+	Verdict string
+	Family  string
+	Table   string
+	Chain   string
+	// Handle is the handle of the deciding rule, 0 for the policy of the chain
+	Handle int
+}
+
+// drops reports whether the decision discards packets.
+func (d nftDecision) drops() bool {
+	return d.Verdict == nftDrop || d.Verdict == nftReject
+}
+
+func (d nftDecision) String() string {
+	where := fmt.Sprintf("%s %s/%s", d.Family, d.Table, d.Chain)
+	if d.Handle == 0 {
+		return fmt.Sprintf("the %s policy of %s", d.Verdict, where)
+	}
+	return fmt.Sprintf("rule %d (%s) in %s", d.Handle, d.Verdict, where)
+}
+
+// nftAnalysis is the effective default verdict for incoming traffic.
+type nftAnalysis struct {
+	// IPv4 and IPv6 decide incoming packets of each protocol that no
+	// conditional rule matched
+	IPv4 nftDecision
+	IPv6 nftDecision
+	// AcceptAll are unconditional accept rules of input chains whose policy
+	// drops packets, which make that policy meaningless
+	AcceptAll []nftAcceptAll
+}
+
+// nftAcceptAll is an unconditional accept rule and the drop policy it overrides.
+type nftAcceptAll struct {
+	Rule   nftDecision
+	Policy nftDecision
+}
+
+// nftAnalyzer evaluates the chains of a ruleset.
+type nftAnalyzer struct {
+	rules map[string][]nftRule
+}
+
+func nftChainKey(family, table, chain string) string {
+	return family + " " + table + " " + chain
+}
+
+// analyzeNFTables evaluates the input hook across all tables and families.
+// Packets pass through every base chain of the input hook in priority order
+// and are dropped as soon as one of them drops them, so the first chain that
+// drops by default decides. Conditional rules are assumed not to match, and
+// unconditional jumps and gotos are followed into their target chains.
+func analyzeNFTables(ruleset nftRuleset) nftAnalysis {
+	analyzer := nftAnalyzer{rules: map[string][]nftRule{}}
+	inputs := []nftChain{}
+	for _, object := range ruleset.Nftables {
+		if rule := object.Rule; rule != nil {
+			key := nftChainKey(rule.Family, rule.Table, rule.Chain)
+			analyzer.rules[key] = append(analyzer.rules[key], *rule)
+		}
+		if chain := object.Chain; chain != nil && chain.Hook == "input" && chain.Type == "filter" {
+			inputs = append(inputs, *chain)
+		}
+	}
+	slices.SortStableFunc(inputs, func(a, b nftChain) int {
+		return cmp.Compare(a.Prio, b.Prio)
+	})
+
+	analysis := nftAnalysis{}
+	decisions := map[string]nftDecision{}
+	for _, chain := range inputs {
+		decision, decided := analyzer.evaluate(chain.Family, chain.Table, chain.Name, map[string]bool{})
+		if !decided {
+			decision = nftDecision{Verdict: cmp.Or(chain.Policy, nftAccept), Family: chain.Family, Table: chain.Table, Chain: chain.Name}
+		}
+		if chain.Policy == nftDrop && decision.Verdict == nftAccept {
+			policy := nftDecision{Verdict: nftDrop, Family: chain.Family, Table: chain.Table, Chain: chain.Name}
+			analysis.AcceptAll = append(analysis.AcceptAll, nftAcceptAll{Rule: decision, Policy: policy})
+		}
+		decisions[nftChainKey(chain.Family, chain.Table, chain.Name)] = decision
+	}
+
+	decide := func(families ...string) nftDecision {
+		result := nftDecision{Verdict: nftAccept}
+		for _, chain := range inputs {
+			if !slices.Contains(families, chain.Family) {
+				continue
+			}
+			decision := decisions[nftChainKey(chain.Family, chain.Table, chain.Name)]
+			if decision.drops() {
+				return decision
+			}
+			if result.Chain == "" {
+				result = decision
+			}
+		}
+		return result
+	}
+	analysis.IPv4 = decide("ip", "inet")
+	analysis.IPv6 = decide("ip6", "inet")
+	return analysis
+}
+
+// evaluate returns the first unconditional verdict of a chain, following
+// unconditional jumps and gotos. decided is false when packets fall through
+// the chain, or return from it, without a verdict.
+func (a nftAnalyzer) evaluate(family, table, chain string, visited map[string]bool) (decision nftDecision, decided bool) {
+	key := nftChainKey(family, table, chain)
+	if visited[key] {
+		return nftDecision{}, false
+	}
+	visited[key] = true
+	defer delete(visited, key)
+
+	for _, rule := range a.rules[key] {
+		verdict, target, unconditional := nftRuleVerdict(rule)
+		if !unconditional {
+			continue
+		}
+		switch verdict {
+		case nftAccept, nftDrop, nftReject:
+			return nftDecision{Verdict: verdict, Family: family, Table: table, Chain: chain, Handle: rule.Handle}, true
+		case nftJump:
+			if decision, decided := a.evaluate(family, table, target, visited); decided {
+				return decision, true
+			}
+		case nftGoto:
+			// Packets that fall through the target return to the caller of this chain
+			return a.evaluate(family, table, target, visited)
+		case nftReturn:
+			return nftDecision{}, false
+		}
+	}
+	return nftDecision{}, false
+}
+
+// nftRuleVerdict returns the verdict of a rule and the target chain of a jump
+// or goto. unconditional is false when a statement before the verdict can
+// restrict the packets the rule applies to, or when the rule has no verdict.
+func nftRuleVerdict(rule nftRule) (verdict, target string, unconditional bool) {
+	for _, statement := range rule.Expr {
+		for name, value := range statement {
+			switch {
+			case name == nftAccept || name == nftDrop || name == nftReject || name == nftReturn:
+				return name, "", true
+			case name == nftJump || name == nftGoto:
+				var jump struct {
+					Target string `json:"target"`
+				}
+				if err := json.Unmarshal(value, &jump); err != nil {
+					return "", "", false
+				}
+				return name, jump.Target, true
+			case slices.Contains(nftPassive, name):
+				continue
+			default:
+				return "", "", false
+			}
+		}
+	}
+	return "", "", false
+}
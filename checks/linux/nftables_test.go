@@ -0,0 +1,87 @@
+package checks
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// analyzeJSON analyzes a ruleset built from objects with nftJSON.
+func analyzeJSON(t *testing.T, objects ...string) nftAnalysis {
+	t.Helper()
+	var ruleset nftRuleset
+	require.NoError(t, json.Unmarshal([]byte(nftJSON(objects...)), &ruleset))
+	return analyzeNFTables(ruleset)
+}
+
+func TestAnalyzeNFTables_PriorityOrder(t *testing.T) {
+	analysis := analyzeJSON(t,
+		`{"chain": {"family": "inet", "table": "filter", "name": "input", "type": "filter", "hook": "input", "prio": 0, "policy": "drop"}}`,
+		`{"chain": {"family": "inet", "table": "tailscale", "name": "ts-input", "type": "filter", "hook": "input", "prio": "raw", "policy": "accept"}}`,
+		`{"rule": {"family": "inet", "table": "tailscale", "chain": "ts-input", "handle": 7, "expr": [{"accept": null}]}}`,
+	)
+
+	// Accepting in an earlier chain hands the packet to the next chain
+	expected := nftDecision{Verdict: nftDrop, Family: "inet", Table: "filter", Chain: "input"}
+	assert.Equal(t, expected, analysis.IPv4)
+	assert.Equal(t, expected, analysis.IPv6)
+	assert.Empty(t, analysis.AcceptAll)
+}
+
+func TestAnalyzeNFTables_Jumps(t *testing.T) {
+	// The iptables-nft rules of the NixOS firewall
+	analysis := analyzeJSON(t,
+		`{"chain": {"family": "ip", "table": "filter", "name": "INPUT", "type": "filter", "hook": "input", "prio": 0, "policy": "accept"}}`,
+		`{"chain": {"family": "ip", "table": "filter", "name": "nixos-fw"}}`,
+		`{"chain": {"family": "ip", "table": "filter", "name": "nixos-fw-refuse"}}`,
+		`{"rule": {"family": "ip", "table": "filter", "chain": "INPUT", "handle": 1, "expr": [{"jump": {"target": "nixos-fw"}}]}}`,
+		`{"rule": {"family": "ip", "table": "filter", "chain": "nixos-fw", "handle": 2, "expr": [{"match": {"op": "==", "left": {"meta": {"key": "iifname"}}, "right": "lo"}}, {"accept": null}]}}`,
+		`{"rule": {"family": "ip", "table": "filter", "chain": "nixos-fw", "handle": 3, "expr": [{"counter": null}, {"goto": {"target": "nixos-fw-refuse"}}]}}`,
+		`{"rule": {"family": "ip", "table": "filter", "chain": "nixos-fw-refuse", "handle": 4, "expr": [{"drop": null}]}}`,
+		`{"chain": {"family": "ip6", "table": "filter", "name": "INPUT", "type": "filter", "hook": "input", "prio": 0, "policy": "accept"}}`,
+		`{"chain": {"family": "ip6", "table": "filter", "name": "skip"}}`,
+		`{"rule": {"family": "ip6", "table": "filter", "chain": "INPUT", "handle": 1, "expr": [{"jump": {"target": "skip"}}]}}`,
+		`{"rule": {"family": "ip6", "table": "filter", "chain": "skip", "handle": 2, "expr": [{"return": null}]}}`,
+		`{"rule": {"family": "ip6", "table": "filter", "chain": "skip", "handle": 3, "expr": [{"drop": null}]}}`,
+	)
+
+	assert.Equal(t, nftDecision{Verdict: nftDrop, Family: "ip", Table: "filter", Chain: "nixos-fw-refuse", Handle: 4}, analysis.IPv4)
+	// The chain returns before its drop rule
+	assert.Equal(t, nftDecision{Verdict: nftAccept, Family: "ip6", Table: "filter", Chain: "INPUT"}, analysis.IPv6)
+}
+
+func TestAnalyzeNFTables_AcceptAll(t *testing.T) {
+	analysis := analyzeJSON(t,
+		`{"chain": {"family": "inet", "table": "filter", "name": "input", "type": "filter", "hook": "input", "prio": 0, "policy": "drop"}}`,
+		`{"chain": {"family": "inet", "table": "filter", "name": "allow"}}`,
+		`{"rule": {"family": "inet", "table": "filter", "chain": "input", "handle": 2, "expr": [{"jump": {"target": "allow"}}]}}`,
+		`{"rule": {"family": "inet", "table": "filter", "chain": "allow", "handle": 5, "expr": [{"log": {"prefix": "in "}}, {"accept": null}]}}`,
+	)
+
+	accept := nftDecision{Verdict: nftAccept, Family: "inet", Table: "filter", Chain: "allow", Handle: 5}
+	policy := nftDecision{Verdict: nftDrop, Family: "inet", Table: "filter", Chain: "input"}
+	assert.Equal(t, []nftAcceptAll{{Rule: accept, Policy: policy}}, analysis.AcceptAll)
+	assert.Equal(t, accept, analysis.IPv4)
+	assert.Equal(t, "rule 5 (accept) in inet filter/allow", accept.String())
+}
+
+func TestAnalyzeNFTables_Cycle(t *testing.T) {
+	analysis := analyzeJSON(t,
+		`{"chain": {"family": "inet", "table": "filter", "name": "input", "type": "filter", "hook": "input", "prio": 0, "policy": "drop"}}`,
+		`{"chain": {"family": "inet", "table": "filter", "name": "loop"}}`,
+		`{"rule": {"family": "inet", "table": "filter", "chain": "input", "handle": 1, "expr": [{"jump": {"target": "loop"}}]}}`,
+		`{"rule": {"family": "inet", "table": "filter", "chain": "loop", "handle": 2, "expr": [{"jump": {"target": "input"}}]}}`,
+	)
+
+	assert.True(t, analysis.IPv4.drops())
+	assert.Equal(t, "the drop policy of inet filter/input", analysis.IPv4.String())
+}
+
+func TestAnalyzeNFTables_Empty(t *testing.T) {
+	analysis := analyzeJSON(t)
+
+	assert.Equal(t, nftDecision{Verdict: nftAccept}, analysis.IPv4)
+	assert.False(t, analysis.IPv6.drops())
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 )
@@ -144,6 +145,11 @@ func (pmc *PasswordManagerCheck) UUID() string {
 	return "f962c423-fdf5-428a-a57a-827abc9b253e"
 }
 
+// Slug returns the stable identifier of the check
+func (pmc *PasswordManagerCheck) Slug() string {
+	return "linux.password-manager"
+}
+
 func (pmc *PasswordManagerCheck) PassedMessage() string {
 	return "Password manager is present"
 }
@@ -156,6 +162,11 @@ func (pmc *PasswordManagerCheck) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (pmc *PasswordManagerCheck) Severity() check.Severity {
+	return check.SeverityLow
+}
+
 func (pmc *PasswordManagerCheck) Status() string {
 	if pmc.Passed() {
 		return pmc.PassedMessage()
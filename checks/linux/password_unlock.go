@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 )
@@ -113,6 +114,38 @@ func (f *PasswordToUnlock) Run() error {
 	return nil
 }
 
+// Remediation returns the commands that turn on the screen lock for the
+// desktop environments found on the system.
+func (f *PasswordToUnlock) Remediation() []check.FixStep {
+	steps := []check.FixStep{}
+	if _, err := lookPath("gsettings"); err == nil {
+		steps = append(steps, check.FixStep{
+			Description: "Lock the GNOME screen when the screensaver activates",
+			Command:     []string{"gsettings", "set", "org.gnome.desktop.screensaver", "lock-enabled", "true"},
+		})
+	}
+	for _, tool := range []string{"kwriteconfig6", "kwriteconfig5"} {
+		if _, err := lookPath(tool); err == nil {
+			steps = append(steps, check.FixStep{
+				Description: "Lock the KDE screen when resuming from sleep",
+				Command:     []string{tool, "--file", "kscreenlockerrc", "--group", "Daemon", "--key", "LockOnResume", "true"},
+			})
+			break
+		}
+	}
+	if _, err := lookPath("sway"); err == nil {
+		steps = append(steps, check.FixStep{
+			Description: "Run swaylock from swayidle, e.g. `exec swayidle -w before-sleep 'swaylock -f'` in the Sway config",
+		})
+	}
+	if len(steps) == 0 {
+		steps = append(steps, check.FixStep{
+			Description: "Require a password when waking up from sleep or the screensaver in your desktop settings",
+		})
+	}
+	return steps
+}
+
 // Passed returns the status of the check
 func (f *PasswordToUnlock) Passed() bool {
 	return f.passed
@@ -128,6 +161,11 @@ func (f *PasswordToUnlock) UUID() string {
 	return "37dee029-605b-4aab-96b9-5438e5aa44d8"
 }
 
+// Slug returns the stable identifier of the check
+func (f *PasswordToUnlock) Slug() string {
+	return "linux.password-to-unlock"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (f *PasswordToUnlock) PassedMessage() string {
 	return "Password after sleep or screensaver is on"
@@ -143,6 +181,11 @@ func (f *PasswordToUnlock) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (f *PasswordToUnlock) Severity() check.Severity {
+	return check.SeverityHigh
+}
+
 // Status returns the status of the check
 func (f *PasswordToUnlock) Status() string {
 	if f.Passed() {
@@ -332,3 +332,30 @@ func TestPasswordToUnlock_checkSway(t *testing.T) {
 		})
 	}
 }
+
+func TestPasswordToUnlock_Remediation(t *testing.T) {
+	defer func() { lookPathMock = nil }()
+
+	t.Run("GNOME and KDE", func(t *testing.T) {
+		lookPathMock = func(file string) (string, error) {
+			if file == "gsettings" || file == "kwriteconfig5" {
+				return "/usr/bin/" + file, nil
+			}
+			return "", exec.ErrNotFound
+		}
+		steps := (&PasswordToUnlock{}).Remediation()
+		assert.Len(t, steps, 2)
+		assert.Equal(t, "gsettings set org.gnome.desktop.screensaver lock-enabled true", steps[0].String())
+		assert.Equal(t, "kwriteconfig5", steps[1].Command[0])
+		assert.False(t, steps[0].RequiresRoot)
+	})
+
+	t.Run("unknown desktop", func(t *testing.T) {
+		lookPathMock = func(file string) (string, error) {
+			return "", exec.ErrNotFound
+		}
+		steps := (&PasswordToUnlock{}).Remediation()
+		assert.Len(t, steps, 1)
+		assert.True(t, steps[0].Manual())
+	})
+}
@@ -3,13 +3,19 @@ package checks
 import (
 	"fmt"
 
+	"github.com/ParetoSecurity/agent/check"
 	sharedchecks "github.com/ParetoSecurity/agent/checks/shared"
-	"github.com/caarlos0/log"
 )
 
+// printServices are the printer sharing ports
+var printServices = map[int]string{
+	631: "CUPS",
+}
+
 type Printer struct {
 	passed bool
 	ports  map[int]string
+	facts  check.Facts
 }
 
 // Name returns the name of the check
@@ -19,23 +25,19 @@ func (f *Printer) Name() string {
 
 // Run executes the check
 func (f *Printer) Run() error {
-	f.passed = true
-	f.ports = make(map[int]string)
-
-	// Samba, NFS and CUPS ports to check
-	printService := map[int]string{
-		631: "CUPS",
-	}
+	f.ports = sharedchecks.OpenPorts(f.facts, f.UUID(), printServices)
+	f.passed = len(f.ports) == 0
+	return nil
+}
 
-	for port, service := range printService {
-		if sharedchecks.CheckPort(port, "tcp") {
-			log.WithField("check", f.Name()).WithField("port", port).WithField("service", service).Debug("Port open")
-			f.passed = false
-			f.ports[port] = service
-		}
-	}
+// Settings returns the settings of the check
+func (f *Printer) Settings() []check.Setting {
+	return sharedchecks.PortSettings(printServices)
+}
 
-	return nil
+// UseFacts sets the facts shared by the checks of a run
+func (f *Printer) UseFacts(facts check.Facts) {
+	f.facts = facts
 }
 
 // Passed returns the status of the check
@@ -53,6 +55,11 @@ func (f *Printer) UUID() string {
 	return "b96524e0-150b-4bb8-abc7-517051b6c14e"
 }
 
+// Slug returns the stable identifier of the check
+func (f *Printer) Slug() string {
+	return "linux.printer"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (f *Printer) PassedMessage() string {
 	return "Sharing printers is off"
@@ -68,6 +75,11 @@ func (f *Printer) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (f *Printer) Severity() check.Severity {
+	return check.SeverityLow
+}
+
 // Status returns the status of the check
 func (f *Printer) Status() string {
 	if !f.Passed() {
@@ -1,6 +1,10 @@
 package checks
 
-import "os"
+import (
+	"os"
+
+	"github.com/ParetoSecurity/agent/check"
+)
 
 // SecureBoot checks secure boot configuration.
 type SecureBoot struct {
@@ -65,6 +69,11 @@ func (f *SecureBoot) UUID() string {
 	return "c96524f2-850b-4bb9-abc7-517051b6c14e"
 }
 
+// Slug returns the stable identifier of the check
+func (f *SecureBoot) Slug() string {
+	return "linux.secure-boot"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (f *SecureBoot) PassedMessage() string {
 	return "SecureBoot is enabled"
@@ -80,6 +89,11 @@ func (f *SecureBoot) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (f *SecureBoot) Severity() check.Severity {
+	return check.SeverityHigh
+}
+
 // Status returns the status of the check
 func (f *SecureBoot) Status() string {
 	if f.Passed() {
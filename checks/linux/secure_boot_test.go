@@ -2,9 +2,11 @@ package checks
 
 import (
 	"errors"
+	"io/fs"
 	"os"
 	"testing"
 
+	"github.com/ParetoSecurity/agent/shared"
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 )
@@ -77,6 +79,24 @@ func TestSecureBoot_Run(t *testing.T) {
 	}
 }
 
+func TestSecureBoot_Replay(t *testing.T) {
+	stat, glob, read := osStatMock, filepathGlobMock, osReadFileMock
+	osStatMock, filepathGlobMock, osReadFileMock = nil, nil, nil
+	defer func() { osStatMock, filepathGlobMock, osReadFileMock = stat, glob, read }()
+
+	variable := "/sys/firmware/efi/efivars/SecureBoot-8be4df61-93ca-11d2-aa0d-00e098032b8c"
+	recording := &shared.Recording{
+		Stats: []shared.RecordedStat{{Path: "/sys/firmware/efi/efivars/", Mode: fs.ModeDir | 0o755}},
+		Globs: []shared.RecordedGlob{{Pattern: "/sys/firmware/efi/efivars/SecureBoot-*", Matches: []string{variable}}},
+		Files: []shared.RecordedFile{{Path: variable, Content: "\x06\x00\x00\x00\x01"}},
+	}
+	defer shared.StartReplay(recording)()
+
+	sb := &SecureBoot{}
+	assert.NoError(t, sb.Run())
+	assert.True(t, sb.Passed(), "the recorded variable is read instead of the system")
+}
+
 func TestSecureBoot_Name(t *testing.T) {
 	sb := &SecureBoot{}
 	expectedName := "SecureBoot is enabled"
@@ -3,13 +3,24 @@ package checks
 import (
 	"fmt"
 
+	"github.com/ParetoSecurity/agent/check"
 	sharedchecks "github.com/ParetoSecurity/agent/checks/shared"
-	"github.com/caarlos0/log"
 )
 
+// shareServices are the Samba, NFS and media sharing ports
+var shareServices = map[int]string{
+	139:  "NetBIOS",
+	445:  "SMB",
+	2049: "NFS",
+	111:  "RPC",
+	8200: "DLNA",
+	1900: "Ubuntu Media Sharing",
+}
+
 type Sharing struct {
 	passed bool
 	ports  map[int]string
+	facts  check.Facts
 }
 
 // Name returns the name of the check
@@ -19,28 +30,19 @@ func (f *Sharing) Name() string {
 
 // Run executes the check
 func (f *Sharing) Run() error {
-	f.passed = true
-	f.ports = make(map[int]string)
-
-	// Samba and NFS ports to check
-	shareServices := map[int]string{
-		139:  "NetBIOS",
-		445:  "SMB",
-		2049: "NFS",
-		111:  "RPC",
-		8200: "DLNA",
-		1900: "Ubuntu Media Sharing",
-	}
+	f.ports = sharedchecks.OpenPorts(f.facts, f.UUID(), shareServices)
+	f.passed = len(f.ports) == 0
+	return nil
+}
 
-	for port, service := range shareServices {
-		if sharedchecks.CheckPort(port, "tcp") {
-			f.passed = false
-			log.WithField("check", f.Name()).WithField("port:tcp", port).WithField("service", service).Debug("Port open")
-			f.ports[port] = service
-		}
-	}
+// Settings returns the settings of the check
+func (f *Sharing) Settings() []check.Setting {
+	return sharedchecks.PortSettings(shareServices)
+}
 
-	return nil
+// UseFacts sets the facts shared by the checks of a run
+func (f *Sharing) UseFacts(facts check.Facts) {
+	f.facts = facts
 }
 
 // Passed returns the status of the check
@@ -58,6 +60,11 @@ func (f *Sharing) UUID() string {
 	return "b96524e0-850b-4bb8-abc7-517051b6c14e"
 }
 
+// Slug returns the stable identifier of the check
+func (f *Sharing) Slug() string {
+	return "linux.sharing"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (f *Sharing) PassedMessage() string {
 	return "No file sharing services found running"
@@ -73,6 +80,11 @@ func (f *Sharing) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (f *Sharing) Severity() check.Severity {
+	return check.SeverityMedium
+}
+
 // Status returns the status of the check
 func (f *Sharing) Status() string {
 	if !f.Passed() {
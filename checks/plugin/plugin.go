@@ -0,0 +1,310 @@
+// Package plugin runs checks implemented by external executables.
+//
+// A plugin is an executable in one of the plugin directories. It is invoked
+// with a single argument and prints one JSON document to stdout:
+//
+//	plugin metadata
+//	{"version": 1, "uuid": "…", "name": "VPN client is installed",
+//	 "claim": "Corporate", "passedMessage": "VPN client is installed",
+//	 "failedMessage": "VPN client is missing", "severity": "high"}
+//
+//	plugin run
+//	{"version": 1, "passed": false, "details": "vpnc not found in /opt"}
+//
+// A run that sets "error", exits with a non-zero status, prints more than
+// MaxOutputSize bytes or does not finish in time is reported as an error of
+// the check. The PARETOSECURITY_PROTOCOL_VERSION environment variable holds
+// the protocol version spoken by the agent; plugins must answer with the
+// same version. Plugins always run as the user, never through the root helper.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/caarlos0/log"
+)
+
+// ProtocolVersion is the version of the plugin protocol spoken by the agent.
+const ProtocolVersion = 1
+
+// DefaultClaim is the claim of plugins that do not declare one.
+const DefaultClaim = "Custom Checks"
+
+var (
+	// MetadataTimeout bounds the metadata invocation of a plugin
+	MetadataTimeout = 5 * time.Second
+	// RunTimeout bounds a run of a plugin, in addition to the per-check timeout of the runner
+	RunTimeout = 30 * time.Second
+	// MaxOutputSize is the largest output a plugin may print
+	MaxOutputSize = 64 << 10
+)
+
+// maxStderrSize bounds how much of stderr is kept for error messages.
+const maxStderrSize = 4 << 10
+
+// Metadata describes the check implemented by a plugin.
+type Metadata struct {
+	Version       int            `json:"version"`
+	UUID          string         `json:"uuid"`
+	Slug          string         `json:"slug,omitempty"`
+	Name          string         `json:"name"`
+	Claim         string         `json:"claim"`
+	PassedMessage string         `json:"passedMessage"`
+	FailedMessage string         `json:"failedMessage"`
+	Severity      check.Severity `json:"severity,omitempty"`
+	Weight        int            `json:"weight,omitempty"`
+}
+
+// Result is the outcome of a plugin run.
+type Result struct {
+	Version int    `json:"version"`
+	Passed  bool   `json:"passed"`
+	Details string `json:"details,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Plugin is a check implemented by an external executable.
+type Plugin struct {
+	Path     string
+	Metadata Metadata
+	passed   bool
+	details  string
+}
+
+// Load asks the executable at path for its metadata.
+func Load(path string) (*Plugin, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), MetadataTimeout)
+	defer cancel()
+
+	var metadata Metadata
+	if err := invoke(ctx, path, "metadata", &metadata); err != nil {
+		return nil, err
+	}
+	if metadata.Version != ProtocolVersion {
+		return nil, fmt.Errorf("plugin speaks protocol version %d, expected %d", metadata.Version, ProtocolVersion)
+	}
+	if metadata.UUID == "" || metadata.Name == "" {
+		return nil, errors.New("plugin metadata must set uuid and name")
+	}
+	if metadata.Claim == "" {
+		metadata.Claim = DefaultClaim
+	}
+	if metadata.Slug == "" {
+		metadata.Slug = "plugin." + check.Slugify(metadata.Name)
+	}
+	if metadata.PassedMessage == "" {
+		metadata.PassedMessage = metadata.Name
+	}
+	if metadata.FailedMessage == "" {
+		metadata.FailedMessage = metadata.Name
+	}
+	return &Plugin{Path: path, Metadata: metadata}, nil
+}
+
+// Discover loads the plugins in dirs, in directory order and then by file
+// name. Missing directories are skipped; executables that cannot be trusted
+// or do not answer the metadata request are skipped with a warning. Plugins
+// that reuse the UUID of a plugin loaded before them are refused with an
+// error, so a plugin in a later directory cannot replace the result of one in
+// an earlier directory.
+func Discover(dirs []string) []*Plugin {
+	plugins := []*Plugin{}
+	seen := map[string]string{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.WithError(err).WithField("dir", dir).Warn("Cannot read plugin directory")
+			}
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			info, err := entry.Info()
+			if err != nil || !info.Mode().IsRegular() || strings.HasPrefix(entry.Name(), ".") || !isExecutable(info) {
+				continue
+			}
+			logger := log.WithField("plugin", path)
+			if err := verifyOwner(info); err != nil {
+				logger.WithError(err).Warn("Ignoring untrusted plugin")
+				continue
+			}
+			plugin, err := Load(path)
+			if err != nil {
+				logger.WithError(err).Warn("Ignoring plugin")
+				continue
+			}
+			if other, found := seen[plugin.UUID()]; found {
+				logger.WithField("uuid", plugin.UUID()).WithField("other", other).Error("Refusing plugin that reuses the UUID of another plugin")
+				continue
+			}
+			seen[plugin.UUID()] = path
+			plugins = append(plugins, plugin)
+		}
+	}
+	return plugins
+}
+
+// Name returns the name of the check.
+func (p *Plugin) Name() string {
+	return p.Metadata.Name
+}
+
+// Claim returns the title of the claim the check belongs to.
+func (p *Plugin) Claim() string {
+	return p.Metadata.Claim
+}
+
+// Run executes the plugin.
+func (p *Plugin) Run() error {
+	return p.RunContext(context.Background())
+}
+
+// RunContext executes the plugin, killing it when ctx is done or RunTimeout passes.
+func (p *Plugin) RunContext(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, RunTimeout)
+	defer cancel()
+
+	p.passed, p.details = false, ""
+	var result Result
+	err := invoke(ctx, p.Path, "run", &result)
+	evidence := check.Evidence{Kind: check.EvidenceCommand, Subject: p.Path + " run"}
+	if err != nil {
+		evidence.Error = err.Error()
+	}
+	check.Record(ctx, evidence)
+	if err != nil {
+		return err
+	}
+	if result.Version != ProtocolVersion {
+		return fmt.Errorf("plugin speaks protocol version %d, expected %d", result.Version, ProtocolVersion)
+	}
+	if result.Error != "" {
+		return errors.New(result.Error)
+	}
+	p.passed, p.details = result.Passed, result.Details
+	check.Decided(ctx, "plugin", "the plugin reported passed=%t", result.Passed)
+	return nil
+}
+
+// Passed returns the status of the check.
+func (p *Plugin) Passed() bool {
+	return p.passed
+}
+
+// IsRunnable returns whether the check can run.
+func (p *Plugin) IsRunnable() bool {
+	return true
+}
+
+// UUID returns the UUID of the check.
+func (p *Plugin) UUID() string {
+	return p.Metadata.UUID
+}
+
+// Slug returns the stable identifier of the check.
+func (p *Plugin) Slug() string {
+	return p.Metadata.Slug
+}
+
+// PassedMessage returns the message to return if the check passed.
+func (p *Plugin) PassedMessage() string {
+	return p.Metadata.PassedMessage
+}
+
+// FailedMessage returns the message to return if the check failed.
+func (p *Plugin) FailedMessage() string {
+	return p.Metadata.FailedMessage
+}
+
+// RequiresRoot returns whether the check requires root access.
+func (p *Plugin) RequiresRoot() bool {
+	return false
+}
+
+// Severity returns how important the check is.
+func (p *Plugin) Severity() check.Severity {
+	return p.Metadata.Severity
+}
+
+// Weight returns the weight of the check in the device score.
+func (p *Plugin) Weight() int {
+	return p.Metadata.Weight
+}
+
+// Status returns the status of the check.
+func (p *Plugin) Status() string {
+	if p.details != "" {
+		return p.details
+	}
+	if p.passed {
+		return p.PassedMessage()
+	}
+	return p.FailedMessage()
+}
+
+// invoke runs the plugin with command and decodes its output into v.
+func invoke(ctx context.Context, path, command string, v any) error {
+	cmd := exec.CommandContext(ctx, path, command)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("PARETOSECURITY_PROTOCOL_VERSION=%d", ProtocolVersion))
+	stdout := &limitedBuffer{limit: MaxOutputSize}
+	stderr := &limitedBuffer{limit: maxStderrSize}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// Do not wait for children of the plugin that keep its output open
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("plugin %s timed out", command)
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case stdout.overflow:
+		return fmt.Errorf("plugin output exceeds %d bytes", MaxOutputSize)
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("plugin %s failed: %w: %s", command, err, msg)
+		}
+		return fmt.Errorf("plugin %s failed: %w", command, err)
+	}
+	if err := json.Unmarshal(stdout.Bytes(), v); err != nil {
+		return fmt.Errorf("plugin %s printed invalid JSON: %w", command, err)
+	}
+	return nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it and drops the rest,
+// so a plugin printing without end cannot exhaust memory. The buffer is not
+// embedded so that io.Copy cannot bypass Write through ReadFrom.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		b.overflow = true
+		b.buf.Write(p[:max(room, 0)])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,182 @@
+//go:build unix
+
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const vpnMetadata = `{"version": 1, "uuid": "uuid-vpn", "name": "VPN client is installed", "claim": "Corporate", "failedMessage": "VPN client is missing", "severity": "high"}`
+
+// writePlugin writes a shell script plugin that prints metadata and runs body for "run".
+func writePlugin(t *testing.T, dir, name, metadata, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	script := "#!/bin/sh\nif [ \"$1\" = metadata ]; then\n  echo '" + metadata + "'\n  exit 0\nfi\n" + body + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writePlugin(t, t.TempDir(), "vpn", vpnMetadata, "")
+
+	p, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "uuid-vpn", p.UUID())
+	assert.Equal(t, "Corporate", p.Claim())
+	assert.Equal(t, "VPN client is installed", p.PassedMessage(), "defaults to the name")
+	assert.Equal(t, "VPN client is missing", p.FailedMessage())
+	assert.Equal(t, check.SeverityHigh, check.SeverityOf(p))
+	assert.False(t, p.RequiresRoot())
+	assert.True(t, p.IsRunnable())
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata string
+		want     string
+	}{
+		{"other version", `{"version": 2, "uuid": "u", "name": "n"}`, "protocol version 2"},
+		{"missing uuid", `{"version": 1, "name": "n"}`, "must set uuid and name"},
+		{"not json", `VPN ok`, "invalid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writePlugin(t, t.TempDir(), "plugin", tt.metadata, ""))
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestLoad_DefaultClaim(t *testing.T) {
+	p, err := Load(writePlugin(t, t.TempDir(), "plugin", `{"version": 1, "uuid": "u", "name": "n"}`, ""))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultClaim, p.Claim())
+	assert.Equal(t, "plugin.n", p.Slug())
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		passed  bool
+		status  string
+		wantErr string
+	}{
+		{"passed", `echo '{"version": 1, "passed": true}'`, true, "VPN client is installed", ""},
+		{"failed with details", `echo '{"version": 1, "passed": false, "details": "vpnc not found"}'`, false, "vpnc not found", ""},
+		{"error", `echo '{"version": 1, "error": "cannot query EDR"}'`, false, "", "cannot query EDR"},
+		{"exit status", "echo 'no permission' >&2\nexit 3", false, "", "exit status 3: no permission"},
+		{"other version", `echo '{"version": 2, "passed": true}'`, false, "", "protocol version 2"},
+		{"protocol env", `[ "$PARETOSECURITY_PROTOCOL_VERSION" = 1 ] && echo '{"version": 1, "passed": true}'`, true, "VPN client is installed", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Load(writePlugin(t, t.TempDir(), "vpn", vpnMetadata, tt.body))
+			require.NoError(t, err)
+
+			err = p.Run()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.passed, p.Passed())
+			assert.Equal(t, tt.status, p.Status())
+		})
+	}
+}
+
+func TestRun_Evidence(t *testing.T) {
+	path := writePlugin(t, t.TempDir(), "vpn", vpnMetadata, `echo '{"version": 1, "passed": false}'`)
+	p, err := Load(path)
+	require.NoError(t, err)
+
+	trail := &check.Trail{}
+	require.NoError(t, p.RunContext(check.WithTrail(context.Background(), trail)))
+	assert.Equal(t, []check.Evidence{
+		{Kind: check.EvidenceCommand, Subject: path + " run"},
+		{Kind: check.EvidenceRule, Subject: "plugin", Detail: "the plugin reported passed=false"},
+	}, trail.Entries())
+}
+
+func TestRun_Timeout(t *testing.T) {
+	defer func(timeout time.Duration) { RunTimeout = timeout }(RunTimeout)
+	RunTimeout = 100 * time.Millisecond
+	p, err := Load(writePlugin(t, t.TempDir(), "slow", vpnMetadata, "sleep 10"))
+	require.NoError(t, err)
+
+	started := time.Now()
+	assert.ErrorContains(t, p.Run(), "timed out")
+	assert.Less(t, time.Since(started), 5*time.Second)
+}
+
+func TestRun_OutputLimit(t *testing.T) {
+	defer func(size int) { MaxOutputSize = size }(MaxOutputSize)
+	MaxOutputSize = 64
+	p, err := Load(writePlugin(t, t.TempDir(), "chatty", `{"version":1,"uuid":"u","name":"n"}`, "head -c 1000 /dev/zero"))
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, p.Run(), "exceeds 64 bytes")
+}
+
+func TestDiscover(t *testing.T) {
+	userDir, systemDir := t.TempDir(), t.TempDir()
+	writePlugin(t, userDir, "b-vpn", vpnMetadata, "")
+	writePlugin(t, userDir, "a-edr", strings.Replace(vpnMetadata, "uuid-vpn", "uuid-edr", 1), "")
+	writePlugin(t, systemDir, "vpn", vpnMetadata, "")
+	writePlugin(t, systemDir, "broken", "not json", "")
+	require.NoError(t, os.WriteFile(filepath.Join(systemDir, "README"), []byte("docs"), 0o644))
+	shared := writePlugin(t, systemDir, "shared", strings.Replace(vpnMetadata, "uuid-vpn", "uuid-shared", 1), "")
+	require.NoError(t, os.Chmod(shared, 0o777))
+
+	plugins := Discover([]string{systemDir, filepath.Join(t.TempDir(), "missing"), userDir})
+
+	paths := []string{}
+	for _, p := range plugins {
+		paths = append(paths, p.Path)
+	}
+	assert.Equal(t, []string{filepath.Join(systemDir, "vpn"), filepath.Join(userDir, "a-edr")}, paths,
+		"the duplicate, broken, non-executable and world-writable plugins are skipped")
+}
+
+func TestDiscover_UserCannotShadowSystem(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dirs := Dirs()
+	require.Len(t, dirs, 2)
+	assert.Equal(t, filepath.Join(home, ".config", "paretosecurity", "checks.d"), dirs[1], "the user directory is scanned last")
+
+	systemDir, userDir := t.TempDir(), dirs[1]
+	require.NoError(t, os.MkdirAll(userDir, 0o755))
+	writePlugin(t, systemDir, "vpn", vpnMetadata, `echo '{"version": 1, "passed": false}'`)
+	writePlugin(t, userDir, "vpn", vpnMetadata, `echo '{"version": 1, "passed": true}'`)
+
+	plugins := Discover([]string{systemDir, userDir})
+	require.Len(t, plugins, 1)
+	assert.Equal(t, filepath.Join(systemDir, "vpn"), plugins[0].Path)
+	require.NoError(t, plugins[0].Run())
+	assert.False(t, plugins[0].Passed(), "the user plugin cannot report a pass for the system one")
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	buf := &limitedBuffer{limit: 4}
+	n, err := buf.Write([]byte("abc"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	n, err = buf.Write([]byte("defg"))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, "abcd", buf.String())
+	assert.True(t, buf.overflow)
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package plugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Dirs returns the directories plugins are loaded from, the system one
+// first, so a plugin of the user cannot shadow one the administrator installed.
+func Dirs() []string {
+	dirs := []string{"/etc/paretosecurity/checks.d"}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(homeDir, ".config", "paretosecurity", "checks.d"))
+	}
+	return dirs
+}
+
+// isExecutable reports whether any execute bit is set.
+func isExecutable(info os.FileInfo) bool {
+	return info.Mode().Perm()&0o111 != 0
+}
+
+// verifyOwner requires a plugin to be owned by root or the current user and
+// not writable by group or others, so no other user can change what runs.
+func verifyOwner(info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return errors.New("cannot determine the owner of the file")
+	}
+	if stat.Uid != 0 && int(stat.Uid) != os.Getuid() {
+		return errors.New("the file is not owned by root or the current user")
+	}
+	if info.Mode().Perm()&0o022 != 0 {
+		return errors.New("the file is writable by group or others")
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dirs returns the directories plugins are loaded from, the system one
+// first, so a plugin of the user cannot shadow one the administrator installed.
+func Dirs() []string {
+	dirs := []string{filepath.Join(os.Getenv("ProgramData"), "ParetoSecurity", "checks.d")}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(homeDir, ".config", "paretosecurity", "checks.d"))
+	}
+	return dirs
+}
+
+// isExecutable reports whether the file is a Windows executable.
+func isExecutable(info os.FileInfo) bool {
+	return strings.EqualFold(filepath.Ext(info.Name()), ".exe")
+}
+
+// verifyOwner relies on the ACLs of the plugin directories, as with the policy file.
+func verifyOwner(info os.FileInfo) error {
+	return nil
+}
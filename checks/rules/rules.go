@@ -0,0 +1,259 @@
+// Package rules turns the declarative rules of the config and the policy
+// into checks.
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/checks/plugin"
+	sharedchecks "github.com/ParetoSecurity/agent/checks/shared"
+	"github.com/ParetoSecurity/agent/shared"
+)
+
+// Types of rules.
+const (
+	TypeFile    = "file"
+	TypeTOML    = "toml"
+	TypeCommand = "command"
+	TypePort    = "port"
+	TypeSysctl  = "sysctl"
+)
+
+// Rule is a check defined by a shared.Rule.
+type Rule struct {
+	Definition shared.Rule
+	pattern    *regexp.Regexp
+	facts      check.Facts
+	passed     bool
+	details    string
+}
+
+// New validates definition and returns the check it defines.
+func New(definition shared.Rule) (*Rule, error) {
+	if definition.UUID == "" || definition.Name == "" {
+		return nil, errors.New("a rule must set UUID and Name")
+	}
+	rule := &Rule{Definition: definition}
+	switch definition.Type {
+	case TypeFile, TypeCommand:
+		if definition.Type == TypeFile && definition.Path == "" {
+			return nil, errors.New("a file rule must set Path")
+		}
+		if definition.Type == TypeCommand && len(definition.Command) == 0 {
+			return nil, errors.New("a command rule must set Command")
+		}
+		pattern, err := regexp.Compile(definition.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Pattern: %w", err)
+		}
+		rule.pattern = pattern
+	case TypeTOML:
+		if definition.Path == "" || definition.Section == "" || definition.Key == "" {
+			return nil, errors.New("a toml rule must set Path, Section and Key")
+		}
+	case TypePort:
+		if definition.Port < 1 || definition.Port > 65535 {
+			return nil, fmt.Errorf("invalid Port %d", definition.Port)
+		}
+		if definition.Protocol != "" && definition.Protocol != "tcp" && definition.Protocol != "udp" {
+			return nil, fmt.Errorf("invalid Protocol %q, use tcp or udp", definition.Protocol)
+		}
+	case TypeSysctl:
+		if definition.Key == "" {
+			return nil, errors.New("a sysctl rule must set Key")
+		}
+	default:
+		return nil, fmt.Errorf("unknown rule type %q, use one of file, toml, command, port or sysctl", definition.Type)
+	}
+	return rule, nil
+}
+
+// Load returns the checks defined by the rules of the policy and the config,
+// policy rules first, and an error for every rule that was left out. Rules of
+// the config cannot require root and cannot reuse the UUID of another rule.
+func Load() ([]*Rule, []error) {
+	loaded := []*Rule{}
+	var errs []error
+	seen := map[string]bool{}
+	add := func(source string, definition shared.Rule, allowRoot bool) {
+		rule, err := New(definition)
+		switch {
+		case err != nil:
+		case definition.RequiresRoot && !allowRoot:
+			err = errors.New("rules that require root can only be defined in the policy")
+		case seen[definition.UUID]:
+			err = errors.New("another rule has the same UUID")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: rule %s (%s): %w", source, definition.UUID, definition.Name, err))
+			return
+		}
+		seen[definition.UUID] = true
+		loaded = append(loaded, rule)
+	}
+
+	for _, definition := range shared.Policy.Rules {
+		add(shared.PolicyPath, definition, true)
+	}
+	for _, definition := range shared.Config.Rules {
+		add(shared.ConfigPath, definition, false)
+	}
+	return loaded, errs
+}
+
+// Name returns the name of the check.
+func (r *Rule) Name() string {
+	return r.Definition.Name
+}
+
+// Claim returns the title of the claim the check belongs to.
+func (r *Rule) Claim() string {
+	if r.Definition.Claim == "" {
+		return plugin.DefaultClaim
+	}
+	return r.Definition.Claim
+}
+
+// UseFacts sets the facts used to probe ports.
+func (r *Rule) UseFacts(facts check.Facts) {
+	r.facts = facts
+}
+
+// Run executes the check.
+func (r *Rule) Run() error {
+	return r.RunContext(context.Background())
+}
+
+// RunContext executes the check, killing its command when ctx is done.
+func (r *Rule) RunContext(ctx context.Context) error {
+	r.passed, r.details = false, ""
+	holds, err := r.evaluate(ctx)
+	if err != nil {
+		return err
+	}
+	r.passed = holds != r.Definition.Negate
+	decision := "the condition holds"
+	if !holds {
+		decision = "the condition does not hold"
+	}
+	if r.Definition.Negate {
+		decision += ", inverted by Negate"
+	}
+	check.Decided(ctx, "rule "+r.Definition.Type, "%s", decision)
+	return nil
+}
+
+// evaluate reports whether the condition of the rule holds.
+func (r *Rule) evaluate(ctx context.Context) (bool, error) {
+	definition := r.Definition
+	switch definition.Type {
+	case TypeFile:
+		content, err := shared.ReadFileContext(ctx, definition.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			r.details = fmt.Sprintf("%s does not exist", definition.Path)
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return r.pattern.Match(content), nil
+	case TypeTOML:
+		value, found := shared.GetTOMLSectionKey(definition.Path, definition.Section, definition.Key)
+		if !found {
+			return false, nil
+		}
+		return definition.Value == "" || value == definition.Value, nil
+	case TypeCommand:
+		output, err := shared.RunCommandContext(ctx, definition.Command[0], definition.Command[1:]...)
+		if _, exited := shared.ExitCode(err); err != nil && !exited && output == "" {
+			// The command could not be started, as opposed to exiting with a non-zero status
+			return false, err
+		}
+		return r.pattern.MatchString(output), nil
+	case TypePort:
+		protocol := definition.Protocol
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		if sharedchecks.PortOpen(r.facts, definition.Port, protocol) {
+			r.details = fmt.Sprintf("Port %d/%s is listening", definition.Port, protocol)
+			return false, nil
+		}
+		return true, nil
+	case TypeSysctl:
+		output, err := shared.RunCommandContext(ctx, "sysctl", "-n", definition.Key)
+		if err != nil {
+			return false, fmt.Errorf("cannot read %s: %w", definition.Key, err)
+		}
+		value := strings.TrimSpace(output)
+		r.details = fmt.Sprintf("%s is %s", definition.Key, value)
+		return value == definition.Value, nil
+	}
+	return false, fmt.Errorf("unknown rule type %q", definition.Type)
+}
+
+// Passed returns the status of the check.
+func (r *Rule) Passed() bool {
+	return r.passed
+}
+
+// IsRunnable returns whether the check can run.
+func (r *Rule) IsRunnable() bool {
+	return true
+}
+
+// UUID returns the UUID of the check.
+func (r *Rule) UUID() string {
+	return r.Definition.UUID
+}
+
+// Slug returns the stable identifier of the check.
+func (r *Rule) Slug() string {
+	if r.Definition.Slug != "" {
+		return r.Definition.Slug
+	}
+	return "rules." + check.Slugify(r.Definition.Name)
+}
+
+// PassedMessage returns the message to return if the check passed.
+func (r *Rule) PassedMessage() string {
+	if r.Definition.PassedMessage == "" {
+		return r.Definition.Name
+	}
+	return r.Definition.PassedMessage
+}
+
+// FailedMessage returns the message to return if the check failed.
+func (r *Rule) FailedMessage() string {
+	if r.Definition.FailedMessage == "" {
+		return r.Definition.Name
+	}
+	return r.Definition.FailedMessage
+}
+
+// RequiresRoot returns whether the check requires root access.
+func (r *Rule) RequiresRoot() bool {
+	return r.Definition.RequiresRoot
+}
+
+// Severity returns how important the check is.
+func (r *Rule) Severity() check.Severity {
+	return check.Severity(r.Definition.Severity)
+}
+
+// Status returns the status of the check.
+func (r *Rule) Status() string {
+	if r.passed {
+		return r.PassedMessage()
+	}
+	if r.details != "" {
+		return fmt.Sprintf("%s: %s", r.FailedMessage(), r.details)
+	}
+	return r.FailedMessage()
+}
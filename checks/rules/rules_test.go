@@ -0,0 +1,157 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/ParetoSecurity/agent/check"
+	sharedchecks "github.com/ParetoSecurity/agent/checks/shared"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule shared.Rule
+		want string
+	}{
+		{"missing name", shared.Rule{UUID: "u", Type: TypeSysctl, Key: "k"}, "must set UUID and Name"},
+		{"unknown type", shared.Rule{UUID: "u", Name: "n", Type: "registry"}, "unknown rule type \"registry\""},
+		{"file without path", shared.Rule{UUID: "u", Name: "n", Type: TypeFile}, "must set Path"},
+		{"bad pattern", shared.Rule{UUID: "u", Name: "n", Type: TypeCommand, Command: []string{"id"}, Pattern: "("}, "invalid Pattern"},
+		{"toml without key", shared.Rule{UUID: "u", Name: "n", Type: TypeTOML, Path: "/etc/x.toml", Section: "s"}, "must set Path, Section and Key"},
+		{"bad port", shared.Rule{UUID: "u", Name: "n", Type: TypePort, Port: 70000}, "invalid Port 70000"},
+		{"bad protocol", shared.Rule{UUID: "u", Name: "n", Type: TypePort, Port: 22, Protocol: "sctp"}, "invalid Protocol"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.rule)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestRule_Run(t *testing.T) {
+	shared.ReadFileMock = func(name string) ([]byte, error) {
+		if name == "/etc/ssh/sshd_config" {
+			return []byte("PermitRootLogin no\n"), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	shared.GetTOMLSectionKeyMock = func(path, section, key string) (string, bool) {
+		if path == "/etc/greetd/config.toml" && section == "initial_session" && key == "user" {
+			return "alice", true
+		}
+		return "", false
+	}
+	sharedchecks.CheckPortMock = func(port int, proto string) bool { return port == 8080 && proto == "tcp" }
+	shared.RunCommandMocks = []shared.RunCommandMock{
+		{Command: "systemctl", Args: []string{"is-active", "vpn"}, Out: "inactive\n", Err: errors.New("exit status 3")},
+		{Command: "sysctl", Args: []string{"-n", "net.ipv4.ip_forward"}, Out: "1\n"},
+	}
+	defer func() {
+		shared.ReadFileMock = nil
+		shared.GetTOMLSectionKeyMock = nil
+		sharedchecks.CheckPortMock = nil
+		shared.RunCommandMocks = nil
+	}()
+
+	tests := []struct {
+		name   string
+		rule   shared.Rule
+		passed bool
+		status string
+	}{
+		{"file matches", shared.Rule{Type: TypeFile, Path: "/etc/ssh/sshd_config", Pattern: `(?m)^PermitRootLogin no$`}, true, "Rule"},
+		{"file missing", shared.Rule{Type: TypeFile, Path: "/etc/missing", Pattern: "x", FailedMessage: "Not configured"}, false, "Not configured: /etc/missing does not exist"},
+		{"negated file", shared.Rule{Type: TypeFile, Path: "/etc/ssh/sshd_config", Pattern: "PermitRootLogin no", Negate: true}, false, "Rule"},
+		{"toml value", shared.Rule{Type: TypeTOML, Path: "/etc/greetd/config.toml", Section: "initial_session", Key: "user", Value: "alice"}, true, "Rule"},
+		{"toml other value", shared.Rule{Type: TypeTOML, Path: "/etc/greetd/config.toml", Section: "initial_session", Key: "user", Value: "bob"}, false, "Rule"},
+		{"toml key exists", shared.Rule{Type: TypeTOML, Path: "/etc/greetd/config.toml", Section: "initial_session", Key: "user", Negate: true}, false, "Rule"},
+		{"command output", shared.Rule{Type: TypeCommand, Command: []string{"systemctl", "is-active", "vpn"}, Pattern: "^active"}, false, "Rule"},
+		{"port closed", shared.Rule{Type: TypePort, Port: 22}, true, "Rule"},
+		{"port listening", shared.Rule{Type: TypePort, Port: 8080}, false, "Rule: Port 8080/tcp is listening"},
+		{"sysctl", shared.Rule{Type: TypeSysctl, Key: "net.ipv4.ip_forward", Value: "0", FailedMessage: "IP forwarding is enabled"}, false, "IP forwarding is enabled: net.ipv4.ip_forward is 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.UUID, tt.rule.Name = "uuid", "Rule"
+			rule, err := New(tt.rule)
+			require.NoError(t, err)
+
+			assert.NoError(t, rule.Run())
+			assert.Equal(t, tt.passed, rule.Passed())
+			assert.Equal(t, tt.status, rule.Status())
+		})
+	}
+}
+
+func TestRule_RunContextRecordsEvidence(t *testing.T) {
+	shared.ReadFileMock = func(name string) ([]byte, error) { return []byte("PermitRootLogin no\n"), nil }
+	defer func() { shared.ReadFileMock = nil }()
+	rule, err := New(shared.Rule{UUID: "uuid", Name: "Rule", Type: TypeFile, Path: "/etc/ssh/sshd_config", Pattern: "PermitRootLogin no", Negate: true})
+	require.NoError(t, err)
+	trail := &check.Trail{}
+
+	assert.NoError(t, rule.RunContext(check.WithTrail(context.Background(), trail)))
+	assert.Equal(t, []check.Evidence{
+		{Kind: check.EvidenceFile, Subject: "/etc/ssh/sshd_config", Detail: "19 bytes"},
+		{Kind: check.EvidenceRule, Subject: "rule file", Detail: "the condition holds, inverted by Negate"},
+	}, trail.Entries())
+}
+
+func TestRule_RunCommandNotFound(t *testing.T) {
+	shared.RunCommandMocks = nil
+	rule, err := New(shared.Rule{UUID: "uuid", Name: "Rule", Type: TypeCommand, Command: []string{"vpnctl", "status"}})
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, rule.Run(), "fixture not found")
+	assert.False(t, rule.Passed())
+}
+
+func TestRule_Metadata(t *testing.T) {
+	rule, err := New(shared.Rule{UUID: "uuid", Name: "Rule", Type: TypeSysctl, Key: "k", Severity: "high", RequiresRoot: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Custom Checks", rule.Claim())
+	assert.Equal(t, "Rule", rule.PassedMessage())
+	assert.True(t, rule.RequiresRoot())
+	assert.True(t, rule.IsRunnable())
+	assert.Equal(t, "high", string(rule.Severity()))
+	assert.Equal(t, "rules.rule", rule.Slug())
+
+	rule.Definition.Slug = "corp.vpn"
+	assert.Equal(t, "corp.vpn", rule.Slug())
+}
+
+func TestLoad(t *testing.T) {
+	shared.Policy.Rules = []shared.Rule{
+		{UUID: "root-rule", Name: "Root rule", Type: TypeSysctl, Key: "k", RequiresRoot: true},
+	}
+	shared.Config.Rules = []shared.Rule{
+		{UUID: "user-rule", Name: "User rule", Type: TypePort, Port: 22},
+		{UUID: "root-rule", Name: "Shadowing rule", Type: TypePort, Port: 22},
+		{UUID: "user-root-rule", Name: "User root rule", Type: TypeSysctl, Key: "k", RequiresRoot: true},
+		{UUID: "broken-rule", Name: "Broken rule", Type: "registry"},
+	}
+	defer func() {
+		shared.Policy.Rules = nil
+		shared.Config.Rules = nil
+	}()
+
+	loaded, errs := Load()
+
+	uuids := []string{}
+	for _, rule := range loaded {
+		uuids = append(uuids, rule.UUID())
+	}
+	assert.Equal(t, []string{"root-rule", "user-rule"}, uuids)
+	require.Len(t, errs, 3)
+	assert.ErrorContains(t, errs[0], "Shadowing rule): another rule has the same UUID")
+	assert.ErrorContains(t, errs[1], "can only be defined in the policy")
+	assert.ErrorContains(t, errs[2], "unknown rule type")
+}
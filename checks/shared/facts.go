@@ -0,0 +1,68 @@
+package shared
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/shared"
+)
+
+// SystemFacts gathers facts about the system on first use and remembers them,
+// so that checks sharing it probe each port or binary only once per run.
+type SystemFacts struct {
+	mu     sync.Mutex
+	values map[string]*fact
+}
+
+type fact struct {
+	once  sync.Once
+	value any
+	err   error
+}
+
+// NewSystemFacts returns an empty set of facts for a single run.
+func NewSystemFacts() *SystemFacts {
+	return &SystemFacts{values: make(map[string]*fact)}
+}
+
+// gather returns the value stored under key, calling probe the first time the key is requested.
+func (f *SystemFacts) gather(key string, probe func() (any, error)) (any, error) {
+	f.mu.Lock()
+	entry, ok := f.values[key]
+	if !ok {
+		entry = &fact{}
+		f.values[key] = entry
+	}
+	f.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.value, entry.err = probe()
+	})
+	return entry.value, entry.err
+}
+
+// PortOpen reports whether port is reachable on a non-loopback interface.
+func (f *SystemFacts) PortOpen(port int, proto string) bool {
+	value, _ := f.gather(fmt.Sprintf("port/%s/%d", proto, port), func() (any, error) {
+		return CheckPort(port, proto), nil
+	})
+	return value.(bool)
+}
+
+// LookPath searches for an executable named name in the directories of PATH.
+func (f *SystemFacts) LookPath(name string) (string, error) {
+	value, err := f.gather("path/"+name, func() (any, error) {
+		return shared.LookPath(name)
+	})
+	return value.(string), err
+}
+
+// PortOpen reports whether port is open, reading it from facts when the
+// runner provided them and probing the system directly otherwise.
+func PortOpen(facts check.Facts, port int, proto string) bool {
+	if facts != nil {
+		return facts.PortOpen(port, proto)
+	}
+	return CheckPort(port, proto)
+}
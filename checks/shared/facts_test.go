@@ -0,0 +1,50 @@
+package shared
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemFacts_PortOpenProbesOnce(t *testing.T) {
+	var mu sync.Mutex
+	probes := 0
+	CheckPortMock = func(port int, proto string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		probes++
+		return port == 22
+	}
+	defer func() { CheckPortMock = nil }()
+
+	facts := NewSystemFacts()
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.True(t, facts.PortOpen(22, "tcp"))
+		}()
+	}
+	wg.Wait()
+	assert.False(t, facts.PortOpen(631, "tcp"))
+
+	assert.Equal(t, 2, probes)
+}
+
+func TestSystemFacts_LookPath(t *testing.T) {
+	facts := NewSystemFacts()
+	_, err := facts.LookPath("definitely-not-a-real-binary-name")
+	assert.Error(t, err)
+	_, err = facts.LookPath("definitely-not-a-real-binary-name")
+	assert.Error(t, err)
+}
+
+func TestPortOpen(t *testing.T) {
+	CheckPortMock = func(port int, proto string) bool { return port == 5900 }
+	defer func() { CheckPortMock = nil }()
+
+	assert.True(t, PortOpen(nil, 5900, "tcp"))
+	assert.False(t, PortOpen(NewSystemFacts(), 22, "tcp"))
+}
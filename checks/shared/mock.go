@@ -1,8 +1,9 @@
 package shared
 
 import (
-	"os"
 	"testing"
+
+	"github.com/ParetoSecurity/agent/shared"
 )
 
 // checkPortMock is a mock function used for testing purposes. It simulates
@@ -17,10 +18,10 @@ var osReadFileMock func(file string) ([]byte, error)
 // osReadFile reads the contents of the specified file.
 //
 // If the testing mode is enabled, it delegates the file reading to a mock function.
-// Otherwise, it reads the file from disk using the standard os.ReadFile function.
+// Otherwise, it reads the file with shared.ReadSystemFile, which is recorded and replayed.
 func osReadFile(file string) ([]byte, error) {
 	if testing.Testing() {
 		return osReadFileMock(file)
 	}
-	return os.ReadFile(file)
+	return shared.ReadSystemFile(file)
 }
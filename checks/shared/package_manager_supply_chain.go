@@ -1,16 +1,22 @@
 package shared
 
 import (
+	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/shared"
 )
 
-const minReleaseAgeSeconds = 7 * 24 * 60 * 60
-const minReleaseAgeMinutes = 7 * 24 * 60
+// defaultMinReleaseAge is how long package managers wait before installing a new release
+const defaultMinReleaseAge = 7 * 24 * time.Hour
+
+const secondsPerWeek = 7 * 24 * 60 * 60
 
 type packageManagerConfig struct {
 	paths      []string
@@ -18,6 +24,7 @@ type packageManagerConfig struct {
 	validate   func(string, string) []string
 	missing    func([]string) string
 	passDetail func(string) string
+	fix        func(string, string) []check.FixStep
 }
 
 // PackageManagerSupplyChain verifies package managers delay newly published packages.
@@ -33,6 +40,7 @@ type PackageManagerSupplyChain struct {
 	Getenv     func(string) string
 	RunCommand func(string, ...string) ([]byte, error)
 	Versions   map[string]string
+	facts      check.Facts
 }
 
 // Name returns the name of the check.
@@ -54,6 +62,11 @@ func (p *PackageManagerSupplyChain) Run() error {
 	return nil
 }
 
+// UseFacts sets the facts shared by the checks of a run.
+func (p *PackageManagerSupplyChain) UseFacts(facts check.Facts) {
+	p.facts = facts
+}
+
 // Passed returns whether the check passed.
 func (p *PackageManagerSupplyChain) Passed() bool {
 	return p.passed
@@ -72,6 +85,11 @@ func (p *PackageManagerSupplyChain) UUID() string {
 	return "61bf7ef7-a3ee-4d66-a859-49c3ebeb1e7f"
 }
 
+// Slug returns the stable identifier of the check.
+func (p *PackageManagerSupplyChain) Slug() string {
+	return "shared.package-manager-supply-chain"
+}
+
 // PassedMessage returns the message to return if the check passed.
 func (p *PackageManagerSupplyChain) PassedMessage() string {
 	return "Package manager supply-chain protection is configured"
@@ -87,6 +105,11 @@ func (p *PackageManagerSupplyChain) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is.
+func (p *PackageManagerSupplyChain) Severity() check.Severity {
+	return check.SeverityMedium
+}
+
 // Status returns details for the current check state.
 func (p *PackageManagerSupplyChain) Status() string {
 	if p.status != "" {
@@ -101,6 +124,37 @@ func (p *PackageManagerSupplyChain) Status() string {
 	return p.FailedMessage()
 }
 
+// Settings returns the settings of the check.
+func (p *PackageManagerSupplyChain) Settings() []check.Setting {
+	return []check.Setting{
+		{Key: "MinReleaseAge", Description: "Minimum age of a package release before package managers install it", Default: defaultMinReleaseAge},
+	}
+}
+
+// releaseAge is the minimum release age in the units package managers use.
+type releaseAge struct {
+	seconds int
+	minutes int
+	days    int
+}
+
+// String returns the age in days when it is a whole number of days.
+func (a releaseAge) String() string {
+	if a.seconds%(24*60*60) == 0 {
+		return fmt.Sprintf("%d days", a.days)
+	}
+	return (time.Duration(a.seconds) * time.Second).String()
+}
+
+func (p *PackageManagerSupplyChain) minReleaseAge() releaseAge {
+	age := shared.CheckSetting(p.UUID(), "MinReleaseAge", defaultMinReleaseAge)
+	return releaseAge{
+		seconds: int(age / time.Second),
+		minutes: int((age + time.Minute - 1) / time.Minute),
+		days:    int((age + 24*time.Hour - 1) / (24 * time.Hour)),
+	}
+}
+
 func (p *PackageManagerSupplyChain) validationFailures() []string {
 	var failures []string
 
@@ -117,6 +171,95 @@ func (p *PackageManagerSupplyChain) validationFailures() []string {
 	return failures
 }
 
+// Remediation returns the settings to add to the package manager configs that
+// fail the check, or instructions when an existing value has to be changed.
+func (p *PackageManagerSupplyChain) Remediation() []check.FixStep {
+	steps := []check.FixStep{}
+
+	for _, config := range p.configs() {
+		if contents, path, ok := p.activeConfig(config.paths); ok {
+			if len(config.validate(contents, path)) > 0 {
+				steps = append(steps, config.fix(contents, path)...)
+			}
+			continue
+		}
+		if p.anyBinaryInstalled(config.binaries...) {
+			steps = append(steps, config.fix("", config.paths[0])...)
+		}
+	}
+
+	return steps
+}
+
+// settingFix returns the step that adds line to the config at path, or asks
+// the user to change the setting by hand when it is already set.
+func settingFix(path string, present bool, line string) check.FixStep {
+	if present {
+		return check.FixStep{Description: "Change the existing setting to `" + line + "` in " + path}
+	}
+	return check.FixStep{Description: "Add `" + line + "` to " + path, File: path, Append: line + "\n"}
+}
+
+func (p *PackageManagerSupplyChain) fixNpmConfig(contents string, path string) []check.FixStep {
+	values := keyValuePairs(contents)
+	steps := []check.FixStep{}
+	minAge := p.minReleaseAge()
+	if integerValue(values["min-release-age"]) < minAge.days && integerValue(values["minimum-release-age"]) < minAge.minutes {
+		_, age := values["min-release-age"]
+		_, minimumAge := values["minimum-release-age"]
+		steps = append(steps, settingFix(path, age || minimumAge, fmt.Sprintf("min-release-age=%d", minAge.days)))
+	}
+	if strings.ToLower(values["save-exact"]) != "true" {
+		_, present := values["save-exact"]
+		steps = append(steps, settingFix(path, present, "save-exact=true"))
+	}
+	for _, failure := range p.validateNpmConfig(contents, path) {
+		if strings.HasPrefix(failure, "npm ") {
+			steps = append(steps, check.FixStep{Description: "Upgrade npm to 11.14.0 or newer, e.g. `npm install -g npm@latest`"})
+			break
+		}
+	}
+	return steps
+}
+
+func (p *PackageManagerSupplyChain) fixYarnrc(contents string, path string) []check.FixStep {
+	_, present := keyValuePairs(contents)["npmminimalagegate"]
+	return []check.FixStep{settingFix(path, present, fmt.Sprintf("npmMinimalAgeGate: %d", p.minReleaseAge().minutes))}
+}
+
+func (p *PackageManagerSupplyChain) fixPnpmConfig(contents string, path string) []check.FixStep {
+	values := keyValuePairs(contents)
+	_, camel := values["minimumreleaseage"]
+	_, kebab := values["minimum-release-age"]
+	line := fmt.Sprintf("minimum-release-age=%d", p.minReleaseAge().minutes)
+	if strings.HasSuffix(path, ".yaml") {
+		line = fmt.Sprintf("minimumReleaseAge: %d", p.minReleaseAge().minutes)
+	}
+	return []check.FixStep{settingFix(path, camel || kebab, line)}
+}
+
+func (p *PackageManagerSupplyChain) fixBunfig(contents string, path string) []check.FixStep {
+	setting := fmt.Sprintf("minimumReleaseAge = %d", p.minReleaseAge().seconds)
+	_, present := scopedKeyValuePairs(contents)["install.minimumReleaseAge"]
+	if present || strings.Contains(contents, "[install]") {
+		return []check.FixStep{{Description: "Set `" + setting + "` in the [install] section of " + path}}
+	}
+	return []check.FixStep{settingFix(path, false, "[install]\n"+setting)}
+}
+
+func (p *PackageManagerSupplyChain) fixUv(contents string, path string) []check.FixStep {
+	values := scopedKeyValuePairs(contents)
+	_, topLevel := values["exclude-newer"]
+	_, pip := values["pip.exclude-newer"]
+	// Top-level keys must come before any table, so they cannot be appended
+	hasTables := strings.Contains(contents, "[")
+	return []check.FixStep{settingFix(path, topLevel || pip || hasTables, fmt.Sprintf(`exclude-newer = "%d days"`, p.minReleaseAge().days))}
+}
+
+func fixPypirc(_ string, path string) []check.FixStep {
+	return []check.FixStep{{Description: "Remove the plaintext credentials from " + path + " and store them in a keyring instead"}}
+}
+
 func (p *PackageManagerSupplyChain) passingDetails() []string {
 	var details []string
 
@@ -164,40 +307,48 @@ func (p *PackageManagerSupplyChain) configs() []packageManagerConfig {
 			validate:   p.validateNpmConfig,
 			missing:    firstConfigPathMissing,
 			passDetail: func(string) string { return "~/.npmrc delays npm-compatible package releases and pins exact versions" },
+			fix:        p.fixNpmConfig,
 		},
 		{
 			paths:      []string{filepath.Join(home, ".yarnrc.yml")},
 			binaries:   []string{"yarn"},
-			validate:   func(contents string, _ string) []string { return validateYarnrc(contents) },
+			validate:   p.validateYarnrc,
 			missing:    firstConfigPathMissing,
 			passDetail: func(string) string { return "~/.yarnrc.yml delays Yarn package releases" },
+			fix:        p.fixYarnrc,
 		},
 		{
 			paths:      p.pnpmConfigPaths(),
 			binaries:   []string{"pnpm"},
-			validate:   validatePnpmConfig,
+			validate:   p.validatePnpmConfig,
 			missing:    pnpmConfigMissing,
 			passDetail: func(path string) string { return path + " delays pnpm package releases" },
+			fix:        p.fixPnpmConfig,
 		},
 		{
 			paths:      []string{filepath.Join(home, ".bunfig.toml")},
 			binaries:   []string{"bun"},
-			validate:   func(contents string, _ string) []string { return validateBunfig(contents) },
+			validate:   p.validateBunfig,
 			missing:    firstConfigPathMissing,
 			passDetail: func(string) string { return "~/.bunfig.toml delays Bun package releases" },
+			fix:        p.fixBunfig,
 		},
 		{
-			paths:      []string{p.uvConfigPath()},
-			binaries:   []string{"uv"},
-			validate:   func(contents string, _ string) []string { return validateUv(contents) },
-			missing:    firstConfigPathMissing,
-			passDetail: func(path string) string { return path + " excludes Python packages newer than 7 days" },
+			paths:    []string{p.uvConfigPath()},
+			binaries: []string{"uv"},
+			validate: p.validateUv,
+			missing:  firstConfigPathMissing,
+			passDetail: func(path string) string {
+				return path + " excludes Python packages newer than " + p.minReleaseAge().String()
+			},
+			fix: p.fixUv,
 		},
 		{
 			paths:      []string{filepath.Join(home, ".pypirc")},
 			validate:   func(contents string, _ string) []string { return validatePypirc(contents) },
 			missing:    firstConfigPathMissing,
 			passDetail: func(string) string { return "~/.pypirc has no plaintext credentials" },
+			fix:        fixPypirc,
 		},
 	}
 }
@@ -231,14 +382,14 @@ func (p *PackageManagerSupplyChain) readFile(path string) ([]byte, error) {
 	if p.ReadFile != nil {
 		return p.ReadFile(path)
 	}
-	return os.ReadFile(path)
+	return shared.ReadSystemFile(path)
 }
 
 func (p *PackageManagerSupplyChain) fileExists(path string) bool {
 	if p.FileExists != nil {
 		return p.FileExists(path)
 	}
-	_, err := os.Stat(path)
+	_, err := shared.Stat(path)
 	return err == nil
 }
 
@@ -246,7 +397,10 @@ func (p *PackageManagerSupplyChain) lookPath(name string) (string, error) {
 	if p.LookPath != nil {
 		return p.LookPath(name)
 	}
-	return exec.LookPath(name)
+	if p.facts != nil {
+		return p.facts.LookPath(name)
+	}
+	return shared.LookPath(name)
 }
 
 func (p *PackageManagerSupplyChain) packageManagerVersion(name string) string {
@@ -268,7 +422,8 @@ func (p *PackageManagerSupplyChain) runCommand(name string, args ...string) ([]b
 	if p.RunCommand != nil {
 		return p.RunCommand(name, args...)
 	}
-	return exec.Command(name, args...).Output()
+	output, err := shared.RunCommand(name, args...)
+	return []byte(output), err
 }
 
 func (p *PackageManagerSupplyChain) homeDir() string {
@@ -355,7 +510,7 @@ func (p *PackageManagerSupplyChain) goos() string {
 }
 
 func (p *PackageManagerSupplyChain) validateNpmConfig(contents string, _ string) []string {
-	failures := validateNpmrc(contents)
+	failures := validateNpmrc(contents, p.minReleaseAge())
 	if len(failures) != 0 || !p.anyBinaryInstalled("npm") || !npmConfigUsesMinReleaseAge(contents) {
 		return failures
 	}
@@ -375,12 +530,12 @@ func npmConfigUsesMinReleaseAge(contents string) bool {
 	return ok
 }
 
-func validateNpmrc(contents string) []string {
+func validateNpmrc(contents string, minAge releaseAge) []string {
 	values := keyValuePairs(contents)
 	var failures []string
 
-	if integerValue(values["min-release-age"]) < 7 && integerValue(values["minimum-release-age"]) < minReleaseAgeMinutes {
-		failures = append(failures, "~/.npmrc release age is below 7 days; set either min-release-age >= 7 or minimum-release-age >= 10080")
+	if integerValue(values["min-release-age"]) < minAge.days && integerValue(values["minimum-release-age"]) < minAge.minutes {
+		failures = append(failures, fmt.Sprintf("~/.npmrc release age is below %s; set either min-release-age >= %d or minimum-release-age >= %d", minAge, minAge.days, minAge.minutes))
 	}
 	if strings.ToLower(values["save-exact"]) != "true" {
 		failures = append(failures, "~/.npmrc save-exact is not enabled")
@@ -435,35 +590,37 @@ func versionComponents(version string) []int {
 	return components
 }
 
-func validateYarnrc(contents string) []string {
+func (p *PackageManagerSupplyChain) validateYarnrc(contents string, _ string) []string {
 	values := keyValuePairs(contents)
-	if integerValue(values["npmminimalagegate"]) < minReleaseAgeMinutes {
-		return []string{"~/.yarnrc.yml npmMinimalAgeGate is below 10080 minutes"}
+	minutes := p.minReleaseAge().minutes
+	if integerValue(values["npmminimalagegate"]) < minutes {
+		return []string{fmt.Sprintf("~/.yarnrc.yml npmMinimalAgeGate is below %d minutes", minutes)}
 	}
 	return nil
 }
 
-func validatePnpmConfig(contents string, path string) []string {
+func (p *PackageManagerSupplyChain) validatePnpmConfig(contents string, path string) []string {
 	values := keyValuePairs(contents)
 	releaseAge := integerValue(values["minimumreleaseage"])
 	if releaseAge == 0 {
 		releaseAge = integerValue(values["minimum-release-age"])
 	}
-	if releaseAge < minReleaseAgeMinutes {
-		return []string{path + " minimumReleaseAge is below 10080 minutes"}
+	if minutes := p.minReleaseAge().minutes; releaseAge < minutes {
+		return []string{fmt.Sprintf("%s minimumReleaseAge is below %d minutes", path, minutes)}
 	}
 	return nil
 }
 
-func validateBunfig(contents string) []string {
+func (p *PackageManagerSupplyChain) validateBunfig(contents string, _ string) []string {
 	values := scopedKeyValuePairs(contents)
-	if integerValue(values["install.minimumReleaseAge"]) < minReleaseAgeSeconds {
-		return []string{"~/.bunfig.toml minimumReleaseAge is below 604800 seconds"}
+	seconds := p.minReleaseAge().seconds
+	if integerValue(values["install.minimumReleaseAge"]) < seconds {
+		return []string{fmt.Sprintf("~/.bunfig.toml minimumReleaseAge is below %d seconds", seconds)}
 	}
 	return nil
 }
 
-func validateUv(contents string) []string {
+func (p *PackageManagerSupplyChain) validateUv(contents string, _ string) []string {
 	values := scopedKeyValuePairs(contents)
 	var excludeNewer int
 	if value, ok := values["exclude-newer"]; ok {
@@ -471,8 +628,8 @@ func validateUv(contents string) []string {
 	} else {
 		excludeNewer = durationSeconds(values["pip.exclude-newer"])
 	}
-	if excludeNewer < minReleaseAgeSeconds {
-		return []string{"uv exclude-newer is below 7 days"}
+	if minAge := p.minReleaseAge(); excludeNewer < minAge.seconds {
+		return []string{"uv exclude-newer is below " + minAge.String()}
 	}
 	return nil
 }
@@ -571,7 +728,7 @@ func durationSeconds(value string) int {
 	case "d", "day", "days":
 		return amount * 24 * 60 * 60
 	case "w", "week", "weeks":
-		return amount * minReleaseAgeSeconds
+		return amount * secondsPerWeek
 	default:
 		return 0
 	}
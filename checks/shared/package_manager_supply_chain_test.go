@@ -469,3 +469,54 @@ func writeFile(t *testing.T, path string, contents string) {
 func stringsJoin(values ...string) string {
 	return strings.Join(values, "; ")
 }
+
+func TestPackageManagerSupplyChain_Remediation(t *testing.T) {
+	home := t.TempDir()
+	npmrc := filepath.Join(home, ".npmrc")
+	writeFile(t, npmrc, "min-release-age=3\n")
+	writeFile(t, filepath.Join(home, ".bunfig.toml"), "[install]\nexact = true\n")
+	check := testPackageManagerSupplyChain(home, nil, map[string]bool{"npm": true, "yarn": true})
+	check.Versions = map[string]string{"npm": "11.14.0"}
+
+	steps := check.Remediation()
+
+	require.Len(t, steps, 4)
+	assert.True(t, steps[0].Manual())
+	assert.Contains(t, steps[0].Description, "min-release-age=7")
+	assert.Equal(t, npmrc, steps[1].File)
+	assert.Equal(t, "save-exact=true\n", steps[1].Append)
+	assert.Equal(t, filepath.Join(home, ".yarnrc.yml"), steps[2].File)
+	assert.Equal(t, "npmMinimalAgeGate: 10080\n", steps[2].Append)
+	assert.True(t, steps[3].Manual())
+	assert.Contains(t, steps[3].Description, "[install]")
+}
+
+func TestPackageManagerSupplyChain_MinReleaseAgeSetting(t *testing.T) {
+	home := t.TempDir()
+	writeFile(t, filepath.Join(home, ".npmrc"), "min-release-age=3\nsave-exact=true\n")
+	writeFile(t, filepath.Join(home, ".yarnrc.yml"), "npmMinimalAgeGate: 4320\n")
+	writeFile(t, filepath.Join(home, ".bunfig.toml"), "[install]\nminimumReleaseAge = 172800\n")
+	writeFile(t, filepath.Join(home, ".config", "uv", "uv.toml"), "exclude-newer = \"3 days\"\n")
+	check := testPackageManagerSupplyChain(home, nil, nil)
+	withCheckSettings(t, check.UUID(), map[string]interface{}{"MinReleaseAge": "3d"})
+
+	require.NoError(t, check.Run())
+
+	assert.False(t, check.Passed())
+	assert.Equal(t, "~/.bunfig.toml minimumReleaseAge is below 259200 seconds", check.Status())
+	steps := check.Remediation()
+	require.Len(t, steps, 1)
+	assert.Contains(t, steps[0].Description, "minimumReleaseAge = 259200")
+}
+
+func TestPackageManagerSupplyChain_RemediationOldNpm(t *testing.T) {
+	home := t.TempDir()
+	writeFile(t, filepath.Join(home, ".npmrc"), "min-release-age=7\nsave-exact=true\n")
+	check := testPackageManagerSupplyChain(home, nil, map[string]bool{"npm": true})
+	check.Versions = map[string]string{"npm": "10.0.0"}
+
+	steps := check.Remediation()
+
+	require.Len(t, steps, 1)
+	assert.Contains(t, steps[0].Description, "Upgrade npm")
+}
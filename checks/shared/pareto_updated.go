@@ -2,12 +2,14 @@ package shared
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"runtime"
 	"slices"
 	"strings"
 	"time"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 	"github.com/carlmjohnson/requests"
@@ -21,6 +23,9 @@ type ParetoRelease struct {
 	Prerelease  bool      `json:"prerelease,omitempty"`
 }
 
+// updateGracePeriod is how long after a release an older version still passes
+const updateGracePeriod = 10 * 24 * time.Hour
+
 type ParetoUpdated struct {
 	passed  bool
 	details string
@@ -34,6 +39,9 @@ func (f *ParetoUpdated) Name() string {
 // Run executes the check
 func (f *ParetoUpdated) Run() error {
 	f.passed = false
+	if shared.Replaying() {
+		return errors.New("the update server cannot be reached during a replay")
+	}
 	res := []ParetoRelease{}
 	device := shared.CurrentReportingDevice()
 	platform := "linux"
@@ -114,9 +122,9 @@ func (f *ParetoUpdated) checkVersion(res []ParetoRelease) (string, bool) {
 		return "Could not compare versions", false
 	}
 
-	// Only fail if latest release is older than 10 days and current version does not match
-	tenDaysAgo := time.Now().AddDate(0, 0, -10)
-	if latestRelease.PublishedAt.Before(tenDaysAgo) {
+	// Only fail if latest release is older than the grace period and current version does not match
+	gracePeriod := shared.CheckSetting(f.UUID(), "GracePeriod", updateGracePeriod)
+	if latestRelease.PublishedAt.Before(time.Now().Add(-gracePeriod)) {
 		currentVersion := shared.Version
 		if strings.Contains(currentVersion, "-") {
 			// Strip any pre-release suffix for comparison
@@ -127,10 +135,17 @@ func (f *ParetoUpdated) checkVersion(res []ParetoRelease) (string, bool) {
 		}
 	}
 
-	// Within 10 days grace period or version matches
+	// Within the grace period or version matches
 	return latestRelease.Version, true
 }
 
+// Settings returns the settings of the check
+func (f *ParetoUpdated) Settings() []check.Setting {
+	return []check.Setting{
+		{Key: "GracePeriod", Description: "How long an outdated version passes after a new release", Default: updateGracePeriod},
+	}
+}
+
 // Passed returns the status of the check
 func (f *ParetoUpdated) Passed() bool {
 	return f.passed
@@ -146,6 +161,11 @@ func (f *ParetoUpdated) UUID() string {
 	return "44e4754a-0b42-4964-9cc2-b88b2023cb1e"
 }
 
+// Slug returns the stable identifier of the check
+func (f *ParetoUpdated) Slug() string {
+	return "shared.pareto-updated"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (f *ParetoUpdated) PassedMessage() string {
 	return "Pareto Security is up to date"
@@ -161,6 +181,11 @@ func (f *ParetoUpdated) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (f *ParetoUpdated) Severity() check.Severity {
+	return check.SeverityMedium
+}
+
 // Status returns the status of the check
 func (f *ParetoUpdated) Status() string {
 	if f.passed {
@@ -1,6 +1,7 @@
 package shared
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -56,6 +57,15 @@ func TestParetoUpdated_RunPublic(t *testing.T) {
 
 }
 
+func TestParetoUpdated_Replay(t *testing.T) {
+	defer shared.StartReplay(&shared.Recording{})()
+
+	check := &ParetoUpdated{}
+	if err := check.Run(); err == nil || !strings.Contains(err.Error(), "during a replay") {
+		t.Errorf("Expected the update check to fail during a replay, got %v", err)
+	}
+}
+
 func TestParetoUpdated_Name(t *testing.T) {
 	dockerAccess := &ParetoUpdated{}
 	expectedName := "Pareto Security is up to date"
@@ -104,6 +114,26 @@ func TestParetoUpdated_PassedMessage(t *testing.T) {
 	}
 }
 
+func TestParetoUpdated_checkVersion_GracePeriod(t *testing.T) {
+	check := &ParetoUpdated{}
+	withCheckSettings(t, check.UUID(), map[string]interface{}{"GracePeriod": "30d"})
+	shared.Version = "1.2.0"
+
+	_, passed := check.checkVersion([]ParetoRelease{
+		{Version: "1.3.0", PublishedAt: time.Now().AddDate(0, 0, -15)},
+	})
+	if !passed {
+		t.Errorf("Expected a release within the grace period to pass")
+	}
+
+	_, passed = check.checkVersion([]ParetoRelease{
+		{Version: "1.3.0", PublishedAt: time.Now().AddDate(0, 0, -31)},
+	})
+	if passed {
+		t.Errorf("Expected a release past the grace period to fail")
+	}
+}
+
 func TestParetoUpdated_checkVersion(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -3,20 +3,35 @@ package shared
 import (
 	"fmt"
 	"net"
+	"slices"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 )
 
 // checkPort tests if a port is open
+// Probes are recorded and replayed, see shared.StartRecording.
 func CheckPort(port int, proto string) bool {
+	if open, replayed := shared.ReplayPort(port, proto); replayed {
+		return open
+	}
 
+	var open bool
 	if testing.Testing() {
-		return CheckPortMock(port, proto)
+		open = CheckPortMock(port, proto)
+	} else {
+		open = probePort(port, proto)
 	}
+	shared.RecordPort(port, proto, open)
+	return open
+}
 
+// probePort dials port on every non-loopback interface address.
+func probePort(port int, proto string) bool {
 	addrs, err := net.InterfaceAddrs()
 	if err != nil {
 		return false
@@ -64,3 +79,30 @@ func CheckPort(port int, proto string) bool {
 
 	return false
 }
+
+// PortSettings returns the settings of a check that probes the given ports.
+func PortSettings(defaults map[int]string) []check.Setting {
+	return []check.Setting{
+		{Key: "Ports", Description: "TCP ports to probe, mapped to the name of their service", Default: defaults},
+		{Key: "AllowedPorts", Description: "Ports that may be open, for example SSH on a bastion host", Default: []int{}},
+	}
+}
+
+// OpenPorts probes the TCP ports configured for a check and returns the open
+// ones that its settings do not allow, mapped to the name of their service.
+func OpenPorts(facts check.Facts, checkUUID string, defaults map[int]string) map[int]string {
+	ports := shared.CheckSetting(checkUUID, "Ports", defaults)
+	allowed := shared.CheckSetting(checkUUID, "AllowedPorts", []int{})
+
+	open := make(map[int]string)
+	for port, service := range ports {
+		if slices.Contains(allowed, port) {
+			continue
+		}
+		if PortOpen(facts, port, "tcp") {
+			log.WithField("check", checkUUID).WithField("port", port).WithField("service", service).Debug("Port open")
+			open[port] = service
+		}
+	}
+	return open
+}
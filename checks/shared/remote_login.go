@@ -3,12 +3,21 @@ package shared
 import (
 	"fmt"
 
-	"github.com/caarlos0/log"
+	"github.com/ParetoSecurity/agent/check"
 )
 
+// remoteLoginPorts are the common remote access ports
+var remoteLoginPorts = map[int]string{
+	22:   "SSH",
+	3389: "RDP",
+	3390: "RDP",
+	5900: "VNC",
+}
+
 type RemoteLogin struct {
 	passed bool
 	ports  map[int]string
+	facts  check.Facts
 }
 
 // Name returns the name of the check
@@ -18,26 +27,19 @@ func (f *RemoteLogin) Name() string {
 
 // Run executes the check
 func (f *RemoteLogin) Run() error {
-	f.passed = true
-	f.ports = make(map[int]string)
-
-	// Check common remote access ports
-	portsToCheck := map[int]string{
-		22:   "SSH",
-		3389: "RDP",
-		3390: "RDP",
-		5900: "VNC",
-	}
+	f.ports = OpenPorts(f.facts, f.UUID(), remoteLoginPorts)
+	f.passed = len(f.ports) == 0
+	return nil
+}
 
-	for port, service := range portsToCheck {
-		if CheckPort(port, "tcp") {
-			log.WithField("check", f.Name()).WithField("port", port).WithField("service", service).Debug("Remote access service found")
-			f.passed = false
-			f.ports[port] = service
-		}
-	}
+// Settings returns the settings of the check
+func (f *RemoteLogin) Settings() []check.Setting {
+	return PortSettings(remoteLoginPorts)
+}
 
-	return nil
+// UseFacts sets the facts shared by the checks of a run
+func (f *RemoteLogin) UseFacts(facts check.Facts) {
+	f.facts = facts
 }
 
 // Passed returns the status of the check
@@ -55,6 +57,11 @@ func (f *RemoteLogin) UUID() string {
 	return "4ced961d-7cfc-4e7b-8f80-195f6379446e"
 }
 
+// Slug returns the stable identifier of the check
+func (f *RemoteLogin) Slug() string {
+	return "shared.remote-login"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (f *RemoteLogin) PassedMessage() string {
 	return "No remote access services found running"
@@ -70,6 +77,11 @@ func (f *RemoteLogin) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (f *RemoteLogin) Severity() check.Severity {
+	return check.SeverityHigh
+}
+
 // Status returns the status of the check
 func (f *RemoteLogin) Status() string {
 	if !f.Passed() {
@@ -3,9 +3,17 @@ package shared
 import (
 	"testing"
 
+	"github.com/ParetoSecurity/agent/shared"
 	"github.com/stretchr/testify/assert"
 )
 
+// withCheckSettings configures the settings of a check for the duration of a test.
+func withCheckSettings(t *testing.T, uuid string, settings map[string]interface{}) {
+	t.Helper()
+	shared.Config.Checks = map[string]map[string]interface{}{uuid: settings}
+	t.Cleanup(func() { shared.Config.Checks = nil })
+}
+
 func TestRemoteLogin_Run_NoOpenPorts(t *testing.T) {
 	remoteLogin := &RemoteLogin{}
 
@@ -40,6 +48,27 @@ func TestRemoteLogin_Run_OpenPorts(t *testing.T) {
 	assert.False(t, remoteLogin.RequiresRoot())
 }
 
+func TestRemoteLogin_Run_Settings(t *testing.T) {
+	remoteLogin := &RemoteLogin{}
+	withCheckSettings(t, remoteLogin.UUID(), map[string]interface{}{
+		"Ports":        map[string]interface{}{"22": "SSH", "2222": "SSH"},
+		"AllowedPorts": []interface{}{int64(22)},
+	})
+
+	CheckPortMock = func(port int, _ string) bool {
+		return port == 22 || port == 3389
+	}
+	assert.NoError(t, remoteLogin.Run())
+	assert.True(t, remoteLogin.Passed(), "SSH on an allowed port and ports that are not probed pass")
+
+	CheckPortMock = func(port int, _ string) bool {
+		return port == 2222
+	}
+	assert.NoError(t, remoteLogin.Run())
+	assert.False(t, remoteLogin.Passed())
+	assert.Equal(t, map[int]string{2222: "SSH"}, remoteLogin.ports)
+}
+
 func TestRemoteLogin_Name(t *testing.T) {
 	remoteLogin := &RemoteLogin{}
 	expectedName := "Remote login is disabled"
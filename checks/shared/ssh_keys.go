@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
 	sharedG "github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 	"golang.org/x/crypto/ssh"
@@ -40,7 +41,7 @@ func (f *SSHKeys) Run() error {
 	}
 	sshDir := filepath.Join(home, ".ssh")
 
-	files, err := os.ReadDir(sshDir)
+	files, err := sharedG.ReadDir(sshDir)
 	if err != nil {
 		f.passed = true
 		return nil
@@ -50,7 +51,7 @@ func (f *SSHKeys) Run() error {
 	for _, file := range files {
 		if strings.HasSuffix(file.Name(), ".pub") {
 			privateKeyPath := filepath.Join(sshDir, strings.TrimSuffix(file.Name(), ".pub"))
-			if _, err := os.Stat(privateKeyPath); err == nil {
+			if _, err := sharedG.Stat(privateKeyPath); err == nil {
 				if !f.hasPassword(privateKeyPath) {
 					f.passed = false
 					f.failedKeys = append(f.failedKeys, file.Name())
@@ -76,12 +77,12 @@ func (f *SSHKeys) IsRunnable() bool {
 	}
 
 	sshPath := filepath.Join(home, ".ssh")
-	if _, err := os.Stat(sshPath); os.IsNotExist(err) {
+	if _, err := sharedG.Stat(sshPath); os.IsNotExist(err) {
 		return false
 	}
 
 	//check if there are any private keys in the .ssh directory
-	files, err := os.ReadDir(sshPath)
+	files, err := sharedG.ReadDir(sshPath)
 	if err != nil {
 		return false
 	}
@@ -89,7 +90,7 @@ func (f *SSHKeys) IsRunnable() bool {
 	for _, file := range files {
 		if strings.HasSuffix(file.Name(), ".pub") {
 			privateKeyPath := filepath.Join(sshPath, strings.TrimSuffix(file.Name(), ".pub"))
-			if _, err := os.Stat(privateKeyPath); err == nil {
+			if _, err := sharedG.Stat(privateKeyPath); err == nil {
 				f.details = "Found private key: " + file.Name()
 				log.WithField("file", file.Name()).Debug("Found private key")
 				return true
@@ -106,6 +107,11 @@ func (f *SSHKeys) UUID() string {
 	return "b6aaec0f-d76c-429e-aecf-edab7f1ac400"
 }
 
+// Slug returns the stable identifier of the check
+func (f *SSHKeys) Slug() string {
+	return "shared.ssh-keys"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (f *SSHKeys) PassedMessage() string {
 	return "SSH keys are password protected"
@@ -121,6 +127,11 @@ func (f *SSHKeys) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (f *SSHKeys) Severity() check.Severity {
+	return check.SeverityHigh
+}
+
 // Status returns the status of the check
 func (f *SSHKeys) Status() string {
 	if f.Passed() {
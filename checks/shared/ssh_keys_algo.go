@@ -6,12 +6,28 @@ import (
 
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 	"golang.org/x/crypto/ssh" // Import the crypto/ssh package
 )
 
+// minRSABits is the default minimum size of RSA keys
+const minRSABits = 2048
+
+// strongKeyTypes are the key types allowed by default; DSA is considered weak
+var strongKeyTypes = []string{
+	"ssh-rsa",
+	"ecdsa-sha2-nistp256",
+	"ecdsa-sha2-nistp384",
+	"ecdsa-sha2-nistp521",
+	"ssh-ed25519",
+	"sk-ssh-ed25519@openssh.com",
+}
+
 // SSHKeysAlgo runs the SSH keys algorithm.
 type SSHKeysAlgo struct {
 	passed  bool
@@ -37,22 +53,26 @@ func (f *SSHKeysAlgo) isKeyStrong(path string) bool {
 		return false
 	}
 
-	switch key.Type() {
-	case "ssh-rsa":
+	allowed := shared.CheckSetting(f.UUID(), "AllowedKeyTypes", strongKeyTypes)
+	if !slices.Contains(allowed, key.Type()) {
+		log.WithField("keyType", key.Type()).Warn("Key type is not allowed")
+		return false
+	}
+	if key.Type() == ssh.KeyAlgoRSA {
 		rsaKey, ok := key.(ssh.CryptoPublicKey).CryptoPublicKey().(*rsa.PublicKey)
 		if !ok {
 			return false
 		}
-		return rsaKey.N.BitLen() >= 2048
-	case "ssh-dss":
-		return false // DSS is considered weak
-	case "ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384", "ecdsa-sha2-nistp521":
-		return true // ECDSA is considered strong enough
-	case "ssh-ed25519", "sk-ssh-ed25519@openssh.com":
-		return true // Ed25519 is considered strong
-	default:
-		log.WithField("keyType", key.Type()).Warn("Unknown key type")
-		return false
+		return rsaKey.N.BitLen() >= shared.CheckSetting(f.UUID(), "MinRSABits", minRSABits)
+	}
+	return true
+}
+
+// Settings returns the settings of the check
+func (f *SSHKeysAlgo) Settings() []check.Setting {
+	return []check.Setting{
+		{Key: "MinRSABits", Description: "Minimum size of RSA keys in bits", Default: minRSABits},
+		{Key: "AllowedKeyTypes", Description: "SSH key types considered strong", Default: strongKeyTypes},
 	}
 }
 
@@ -64,7 +84,7 @@ func (f *SSHKeysAlgo) Run() error {
 	}
 
 	f.sshPath = filepath.Join(home, ".ssh")
-	entries, err := os.ReadDir(f.sshPath)
+	entries, err := shared.ReadDir(f.sshPath)
 	if err != nil {
 		return err
 	}
@@ -79,7 +99,7 @@ func (f *SSHKeysAlgo) Run() error {
 		pubPath := filepath.Join(f.sshPath, entry.Name())
 		privPath := strings.TrimSuffix(pubPath, ".pub")
 
-		if _, err := os.Stat(privPath); os.IsNotExist(err) {
+		if _, err := shared.Stat(privPath); os.IsNotExist(err) {
 			// Skip if the corresponding private key does not exist
 			continue
 		}
@@ -112,12 +132,12 @@ func (f *SSHKeysAlgo) IsRunnable() bool {
 
 	// Check if the .ssh directory exists
 	sshPath := filepath.Join(home, ".ssh")
-	if _, err := os.Stat(sshPath); os.IsNotExist(err) {
+	if _, err := shared.Stat(sshPath); os.IsNotExist(err) {
 		return false
 	}
 
 	//check if there are any private keys in the .ssh directory
-	files, err := os.ReadDir(sshPath)
+	files, err := shared.ReadDir(sshPath)
 	if err != nil {
 		return false
 	}
@@ -126,7 +146,7 @@ func (f *SSHKeysAlgo) IsRunnable() bool {
 	for _, file := range files {
 		if strings.HasSuffix(file.Name(), ".pub") {
 			privateKeyPath := filepath.Join(sshPath, strings.TrimSuffix(file.Name(), ".pub"))
-			if _, err := os.Stat(privateKeyPath); err == nil {
+			if _, err := shared.Stat(privateKeyPath); err == nil {
 				log.WithField("file", file.Name()).Debug("Found private key")
 				f.details = "Found private key: " + file.Name()
 				return true
@@ -142,6 +162,11 @@ func (f *SSHKeysAlgo) UUID() string {
 	return "ef69f752-0e89-46e2-a644-310429ae5f45"
 }
 
+// Slug returns the stable identifier of the check
+func (f *SSHKeysAlgo) Slug() string {
+	return "shared.ssh-keys-algo"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (f *SSHKeysAlgo) PassedMessage() string {
 	return "SSH keys use strong encryption"
@@ -157,6 +182,11 @@ func (f *SSHKeysAlgo) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (f *SSHKeysAlgo) Severity() check.Severity {
+	return check.SeverityMedium
+}
+
 // Status returns the status of the check
 func (f *SSHKeysAlgo) Status() string {
 	if f.Passed() {
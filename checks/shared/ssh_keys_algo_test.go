@@ -59,6 +59,31 @@ func TestSSHKeysAlgo_PassedMessage(t *testing.T) {
 		t.Errorf("Expected PassedMessage %s, got %s", expectedPassedMessage, dockerAccess.PassedMessage())
 	}
 }
+func TestSSHKeysAlgo_isKeyStrong_Settings(t *testing.T) {
+	sshCheck := &SSHKeysAlgo{}
+	withCheckSettings(t, sshCheck.UUID(), map[string]interface{}{
+		"MinRSABits":      int64(3072),
+		"AllowedKeyTypes": []interface{}{"ssh-rsa", "ssh-ed25519"},
+	})
+
+	keys := map[string]string{
+		"rsa2048": generateRealKey(t, "rsa", 2048),
+		"rsa3072": generateRealKey(t, "rsa", 3072),
+		"ecdsa":   generateRealKey(t, "ecdsa", 256),
+		"ed25519": generateRealKey(t, "ed25519", 0),
+	}
+	osReadFileMock = func(path string) ([]byte, error) {
+		return []byte(keys[path]), nil
+	}
+
+	expected := map[string]bool{"rsa2048": false, "rsa3072": true, "ecdsa": false, "ed25519": true}
+	for key, strong := range expected {
+		if result := sshCheck.isKeyStrong(key); result != strong {
+			t.Errorf("isKeyStrong(%s) = %v, want %v", key, result, strong)
+		}
+	}
+}
+
 func TestSSHKeysAlgo_isKeyStrong(t *testing.T) {
 	// Override osReadFile for testing
 
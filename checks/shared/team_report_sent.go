@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ParetoSecurity/agent/check"
 	sharedG "github.com/ParetoSecurity/agent/shared"
 )
 
@@ -49,6 +50,11 @@ func (t *TeamReportSentCheck) UUID() string {
 	return "e29dfff7-afe3-4800-8919-74be2d74c3be"
 }
 
+// Slug returns the stable identifier of the check.
+func (t *TeamReportSentCheck) Slug() string {
+	return "shared.team-report-sent"
+}
+
 // PassedMessage returns the message to return if the check passed.
 func (t *TeamReportSentCheck) PassedMessage() string {
 	return "Pareto Cloud is receiving reports"
@@ -64,6 +70,11 @@ func (t *TeamReportSentCheck) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is.
+func (t *TeamReportSentCheck) Severity() check.Severity {
+	return check.SeverityLow
+}
+
 // Status returns the status of the check.
 func (t *TeamReportSentCheck) Status() string {
 	if t.passed {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 )
 
@@ -72,6 +73,11 @@ func (a *AutomaticUpdatesCheck) IsRunnable() bool {
 func (a *AutomaticUpdatesCheck) UUID() string {
 	return "28d98536-a93a-4092-845a-92ec081cc82a"
 }
+
+// Slug returns the stable identifier of the check
+func (a *AutomaticUpdatesCheck) Slug() string {
+	return "windows.automatic-updates"
+}
 func (a *AutomaticUpdatesCheck) PassedMessage() string {
 	return "Automatic Updates are on"
 }
@@ -81,6 +87,12 @@ func (a *AutomaticUpdatesCheck) FailedMessage() string {
 func (a *AutomaticUpdatesCheck) RequiresRoot() bool {
 	return false
 }
+
+// Severity returns how important the check is
+func (a *AutomaticUpdatesCheck) Severity() check.Severity {
+	return check.SeverityHigh
+}
+
 func (a *AutomaticUpdatesCheck) Status() string {
 	if a.Passed() {
 		return a.PassedMessage()
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 )
@@ -266,6 +267,11 @@ func (d *WindowsDefender) IsRunnable() bool {
 func (d *WindowsDefender) UUID() string {
 	return "2be03cd7-5cb5-4778-a01a-7ba2fb22750a"
 }
+
+// Slug returns the stable identifier of the check
+func (d *WindowsDefender) Slug() string {
+	return "windows.defender"
+}
 func (d *WindowsDefender) PassedMessage() string {
 	return "Antivirus or EDR software is active"
 }
@@ -275,6 +281,12 @@ func (d *WindowsDefender) FailedMessage() string {
 func (d *WindowsDefender) RequiresRoot() bool {
 	return false
 }
+
+// Severity returns how important the check is
+func (d *WindowsDefender) Severity() check.Severity {
+	return check.SeverityHigh
+}
+
 func (d *WindowsDefender) Status() string {
 	if d.Passed() {
 		return d.PassedMessage()
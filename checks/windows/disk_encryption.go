@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/caarlos0/log"
 )
 
@@ -119,6 +120,11 @@ func (d *DiskEncryption) UUID() string {
 	return "c2cae85c-0335-4708-a428-3a16fd407912"
 }
 
+// Slug returns the stable identifier of the check
+func (d *DiskEncryption) Slug() string {
+	return "windows.disk-encryption"
+}
+
 // PassedMessage returns the message to return if the check passed
 func (d *DiskEncryption) PassedMessage() string {
 	return "Disk encryption is enabled"
@@ -134,6 +140,11 @@ func (d *DiskEncryption) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (d *DiskEncryption) Severity() check.Severity {
+	return check.SeverityCritical
+}
+
 // Status returns the status of the check
 func (d *DiskEncryption) Status() string {
 	if d.Passed() {
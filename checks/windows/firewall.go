@@ -3,6 +3,7 @@ package checks
 import (
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 )
 
@@ -79,6 +80,11 @@ func (f *WindowsFirewall) IsRunnable() bool {
 func (f *WindowsFirewall) UUID() string {
 	return "e632fdd2-b939-4aeb-9a3e-5df2d67d3110"
 }
+
+// Slug returns the stable identifier of the check
+func (f *WindowsFirewall) Slug() string {
+	return "windows.firewall"
+}
 func (f *WindowsFirewall) PassedMessage() string {
 	return "Firewall is active"
 }
@@ -88,6 +94,12 @@ func (f *WindowsFirewall) FailedMessage() string {
 func (f *WindowsFirewall) RequiresRoot() bool {
 	return false
 }
+
+// Severity returns how important the check is
+func (f *WindowsFirewall) Severity() check.Severity {
+	return check.SeverityHigh
+}
+
 func (f *WindowsFirewall) Status() string {
 	if f.Passed() {
 		if f.status != "" {
@@ -3,6 +3,8 @@ package checks
 import (
 	"os"
 	"testing"
+
+	"github.com/ParetoSecurity/agent/shared"
 )
 
 var osStatMock map[string]bool
@@ -17,7 +19,7 @@ func osStat(file string) (string, error) {
 		}
 		return "", os.ErrNotExist
 	}
-	_, err := os.Stat(file)
+	_, err := shared.Stat(file)
 	if err != nil {
 		return "", err
 	}
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 )
 
@@ -82,8 +84,8 @@ func checkForBrowserExtensions() bool {
 	// Check Chromium-based browsers
 	for browser, extPath := range extensionPaths {
 		log.WithField("browser", browser).WithField("path", extPath).Debug("Checking browser extensions path")
-		if _, err := os.Stat(extPath); err == nil {
-			entries, err := os.ReadDir(extPath)
+		if _, err := shared.Stat(extPath); err == nil {
+			entries, err := shared.ReadDir(extPath)
 			if err == nil {
 				log.WithField("browser", browser).WithField("extensionCount", len(entries)).Debug("Found browser extensions directory")
 				for _, entry := range entries {
@@ -113,12 +115,12 @@ func checkFirefoxExtensions(home string) bool {
 	profilesPath := filepath.Join(home, "AppData", "Roaming", "Mozilla", "Firefox", "Profiles")
 
 	log.WithField("path", profilesPath).Debug("Checking Firefox profiles path")
-	if _, err := os.Stat(profilesPath); err != nil {
+	if _, err := shared.Stat(profilesPath); err != nil {
 		log.WithError(err).Debug("Firefox profiles directory not found")
 		return false
 	}
 
-	profiles, err := os.ReadDir(profilesPath)
+	profiles, err := shared.ReadDir(profilesPath)
 	if err != nil {
 		log.WithError(err).Debug("Failed to read Firefox profiles directory")
 		return false
@@ -140,8 +142,8 @@ func checkFirefoxExtensions(home string) bool {
 		if profile.IsDir() {
 			extensionsPath := filepath.Join(profilesPath, profile.Name(), "extensions")
 			log.WithField("profile", profile.Name()).WithField("path", extensionsPath).Debug("Checking Firefox profile extensions")
-			if _, err := os.Stat(extensionsPath); err == nil {
-				extensions, err := os.ReadDir(extensionsPath)
+			if _, err := shared.Stat(extensionsPath); err == nil {
+				extensions, err := shared.ReadDir(extensionsPath)
 				if err == nil {
 					log.WithField("profile", profile.Name()).WithField("extensionCount", len(extensions)).Debug("Found Firefox extensions directory")
 					for _, ext := range extensions {
@@ -175,6 +177,11 @@ func (pmc *PasswordManagerCheck) UUID() string {
 	return "f962c423-fdf5-428a-a57a-827abc9b253e"
 }
 
+// Slug returns the stable identifier of the check
+func (pmc *PasswordManagerCheck) Slug() string {
+	return "windows.password-manager"
+}
+
 func (pmc *PasswordManagerCheck) PassedMessage() string {
 	return "Password manager is present"
 }
@@ -187,6 +194,11 @@ func (pmc *PasswordManagerCheck) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (pmc *PasswordManagerCheck) Severity() check.Severity {
+	return check.SeverityLow
+}
+
 func (pmc *PasswordManagerCheck) Status() string {
 	if pmc.Passed() {
 		return pmc.PassedMessage()
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/shared"
 )
 
@@ -34,6 +35,11 @@ func (s *ScreensaverTimeout) UUID() string {
 	return "13e4dbf1-f87f-4bd9-8a82-f62044f002f4"
 }
 
+// Slug returns the stable identifier of the check
+func (s *ScreensaverTimeout) Slug() string {
+	return "windows.screensaver-timeout"
+}
+
 // Status returns the detailed status message
 func (s *ScreensaverTimeout) Status() string {
 	if s.Passed() {
@@ -53,6 +59,11 @@ func (s *ScreensaverTimeout) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (s *ScreensaverTimeout) Severity() check.Severity {
+	return check.SeverityMedium
+}
+
 // IsRunnable returns whether this check can run on the current platform
 func (s *ScreensaverTimeout) IsRunnable() bool {
 	return true
@@ -214,6 +225,11 @@ func (p *ScreensaverPassword) UUID() string {
 	return "37dee029-605b-4aab-96b9-5438e5aa44d8"
 }
 
+// Slug returns the stable identifier of the check
+func (p *ScreensaverPassword) Slug() string {
+	return "windows.screensaver-password"
+}
+
 // Status returns the detailed status message
 func (p *ScreensaverPassword) Status() string {
 	if p.Passed() {
@@ -233,6 +249,11 @@ func (p *ScreensaverPassword) RequiresRoot() bool {
 	return false
 }
 
+// Severity returns how important the check is
+func (p *ScreensaverPassword) Severity() check.Severity {
+	return check.SeverityHigh
+}
+
 // IsRunnable returns whether this check can run on the current platform
 func (p *ScreensaverPassword) IsRunnable() bool {
 	return true
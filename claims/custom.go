@@ -0,0 +1,75 @@
+package claims
+
+import (
+	"slices"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/checks/plugin"
+	"github.com/ParetoSecurity/agent/checks/rules"
+	"github.com/caarlos0/log"
+)
+
+// Custom is a check that is not built in and declares the claim it belongs to.
+type Custom interface {
+	check.Check
+	Claim() string
+}
+
+// LoadPlugins adds the plugin checks found in plugin.Dirs to All.
+func LoadPlugins() {
+	custom := []Custom{}
+	for _, p := range plugin.Discover(plugin.Dirs()) {
+		custom = append(custom, p)
+	}
+	All = withCustom(All, custom)
+}
+
+// LoadRules adds the checks defined by the rules of the policy and the
+// config to All. Invalid rules are skipped with a warning.
+func LoadRules() {
+	loaded, errs := rules.Load()
+	for _, err := range errs {
+		log.WithError(err).Warn("Ignoring rule")
+	}
+	custom := []Custom{}
+	for _, rule := range loaded {
+		custom = append(custom, rule)
+	}
+	All = withCustom(All, custom)
+}
+
+// withCustom returns a copy of all with each custom check appended to the
+// claim it declares, creating the claim when it does not exist. Checks that
+// reuse the UUID of a check already in all, such as a built-in one, are
+// refused with an error.
+func withCustom(all []Claim, custom []Custom) []Claim {
+	result := make([]Claim, len(all))
+	for i, claim := range all {
+		result[i] = Claim{Title: claim.Title, Checks: slices.Clone(claim.Checks)}
+	}
+
+	for _, chk := range custom {
+		if hasCheck(all, chk.UUID()) {
+			log.WithField("check", chk.Name()).WithField("uuid", chk.UUID()).Error("Refusing custom check that reuses the UUID of an existing check")
+			continue
+		}
+		index := slices.IndexFunc(result, func(claim Claim) bool { return claim.Title == chk.Claim() })
+		if index < 0 {
+			result = append(result, Claim{Title: chk.Claim()})
+			index = len(result) - 1
+		}
+		result[index].Checks = append(result[index].Checks, chk)
+	}
+	return result
+}
+
+func hasCheck(all []Claim, uuid string) bool {
+	for _, claim := range all {
+		for _, chk := range claim.Checks {
+			if chk.UUID() == uuid {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package claims
+
+import (
+	"testing"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/checks/plugin"
+	"github.com/ParetoSecurity/agent/checks/rules"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+type builtinCheck struct {
+	uuid string
+}
+
+func (c *builtinCheck) Name() string          { return c.uuid }
+func (c *builtinCheck) PassedMessage() string { return "" }
+func (c *builtinCheck) FailedMessage() string { return "" }
+func (c *builtinCheck) Run() error            { return nil }
+func (c *builtinCheck) Passed() bool          { return true }
+func (c *builtinCheck) IsRunnable() bool      { return true }
+func (c *builtinCheck) UUID() string          { return c.uuid }
+func (c *builtinCheck) Status() string        { return "" }
+func (c *builtinCheck) RequiresRoot() bool    { return false }
+
+func testPlugin(uuid, claim string) *plugin.Plugin {
+	return &plugin.Plugin{Path: "/plugins/" + uuid, Metadata: plugin.Metadata{UUID: uuid, Name: uuid, Claim: claim}}
+}
+
+func TestWithCustom(t *testing.T) {
+	builtin := &builtinCheck{uuid: "builtin"}
+	all := []Claim{{Title: "Access Security", Checks: []check.Check{builtin}}}
+	rule, err := rules.New(shared.Rule{UUID: "password", Name: "Password", Claim: "Access Security", Type: rules.TypeSysctl, Key: "kernel.x"})
+	assert.NoError(t, err)
+
+	result := withCustom(all, []Custom{
+		testPlugin("edr", "Corporate"),
+		testPlugin("vpn", "Corporate"),
+		rule,
+		testPlugin("builtin", "Corporate"),
+	})
+
+	assert.Len(t, result, 2)
+	assert.Equal(t, "Access Security", result[0].Title)
+	assert.Equal(t, []string{"builtin", "password"}, uuids(result[0]))
+	assert.Equal(t, "Corporate", result[1].Title)
+	assert.Equal(t, []string{"edr", "vpn"}, uuids(result[1]))
+	assert.Len(t, all[0].Checks, 1, "the built-in claims are not modified")
+}
+
+func uuids(claim Claim) []string {
+	result := []string{}
+	for _, chk := range claim.Checks {
+		result = append(result, chk.UUID())
+	}
+	return result
+}
@@ -0,0 +1,109 @@
+package claims
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/ParetoSecurity/agent/check"
+)
+
+// Select returns the UUIDs of the checks in all matched by any of the
+// selectors, in claim order. A selector is a check slug, a UUID, a unique
+// UUID prefix, a claim title or a glob pattern matched against slugs and
+// claim titles, such as "linux.*". Slugs and claim titles match regardless
+// of case. Selectors that match no check, or a UUID prefix that matches
+// several, are reported in the error; the checks matched by the other
+// selectors are still returned.
+func Select(all []Claim, selectors []string) ([]string, error) {
+	matched := map[string]bool{}
+	var errs []error
+	for _, selector := range selectors {
+		uuids, err := selectOne(all, selector)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, uuid := range uuids {
+			matched[uuid] = true
+		}
+	}
+
+	selected := []string{}
+	for _, claim := range all {
+		for _, chk := range claim.Checks {
+			if matched[chk.UUID()] && !slices.Contains(selected, chk.UUID()) {
+				selected = append(selected, chk.UUID())
+			}
+		}
+	}
+	return selected, errors.Join(errs...)
+}
+
+func selectOne(all []Claim, selector string) ([]string, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return nil, errors.New("empty check selector")
+	}
+	// Selectors match regardless of case, UUIDs included
+	key := strings.ToLower(selector)
+	glob := strings.ContainsAny(key, "*?[")
+	if glob {
+		if _, err := path.Match(key, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", selector, err)
+		}
+	}
+	matches := func(value string) bool {
+		if glob {
+			ok, _ := path.Match(key, strings.ToLower(value))
+			return ok
+		}
+		return key == strings.ToLower(value)
+	}
+
+	uuids := []string{}
+	for _, claim := range all {
+		for _, chk := range claim.Checks {
+			if matches(chk.UUID()) || matches(check.SlugOf(chk)) || matches(claim.Title) {
+				uuids = append(uuids, chk.UUID())
+			}
+		}
+	}
+	if len(uuids) > 0 {
+		return uuids, nil
+	}
+	if glob {
+		return nil, fmt.Errorf("no check matches %q", selector)
+	}
+
+	for _, claim := range all {
+		for _, chk := range claim.Checks {
+			if strings.HasPrefix(strings.ToLower(chk.UUID()), key) {
+				uuids = append(uuids, chk.UUID())
+			}
+		}
+	}
+	switch len(uuids) {
+	case 0:
+		return nil, fmt.Errorf("no check matches %q", selector)
+	case 1:
+		return uuids, nil
+	default:
+		return nil, fmt.Errorf("UUID prefix %q is ambiguous, it matches %d checks", selector, len(uuids))
+	}
+}
+
+// Identifiers returns the slug of every check and the title of every claim
+// in all, each followed by a tab and its description, for shell completion.
+func Identifiers(all []Claim) []string {
+	identifiers := []string{}
+	for _, claim := range all {
+		identifiers = append(identifiers, claim.Title+"\tAll checks of the claim")
+		for _, chk := range claim.Checks {
+			identifiers = append(identifiers, check.SlugOf(chk)+"\t"+chk.Name())
+		}
+	}
+	return identifiers
+}
@@ -0,0 +1,81 @@
+package claims
+
+import (
+	"testing"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/checks/rules"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func selectClaims(t *testing.T) []Claim {
+	rule := func(uuid, slug, name string) check.Check {
+		r, err := rules.New(shared.Rule{UUID: uuid, Slug: slug, Name: name, Type: rules.TypeSysctl, Key: "k"})
+		require.NoError(t, err)
+		return r
+	}
+	return []Claim{
+		{"Firewall & Sharing", []check.Check{
+			rule("2e46c89a-5461-4865-a92e-3b799c12034a", "linux.firewall", "Firewall is on"),
+			rule("b96524e0-150b-4bb8-abc7-517051b6c14e", "linux.printer", "Printer sharing is off"),
+		}},
+		{"Access Security", []check.Check{
+			rule("b96524e0-850b-4bb8-abc7-517051b6c14e", "shared.ssh-keys", "SSH keys have a password"),
+			rule("ef69f752-0e89-46e2-a644-310429ae5f45", "", "SSH keys use strong encryption"),
+		}},
+	}
+}
+
+func TestSelect(t *testing.T) {
+	all := selectClaims(t)
+	tests := []struct {
+		name      string
+		selectors []string
+		expected  []string
+	}{
+		{"slug", []string{"linux.printer"}, []string{"b96524e0-150b-4bb8-abc7-517051b6c14e"}},
+		{"slug ignores case", []string{"Linux.Printer"}, []string{"b96524e0-150b-4bb8-abc7-517051b6c14e"}},
+		{"derived slug", []string{"rules.ssh-keys-use-strong-encryption"}, []string{"ef69f752-0e89-46e2-a644-310429ae5f45"}},
+		{"uuid", []string{"2e46c89a-5461-4865-a92e-3b799c12034a"}, []string{"2e46c89a-5461-4865-a92e-3b799c12034a"}},
+		{"uuid ignores case", []string{"2E46C89A-5461-4865-A92E-3B799C12034A"}, []string{"2e46c89a-5461-4865-a92e-3b799c12034a"}},
+		{"uuid prefix", []string{"ef69"}, []string{"ef69f752-0e89-46e2-a644-310429ae5f45"}},
+		{"uuid prefix ignores case", []string{"EF69"}, []string{"ef69f752-0e89-46e2-a644-310429ae5f45"}},
+		{"claim title", []string{"access security"}, []string{"b96524e0-850b-4bb8-abc7-517051b6c14e", "ef69f752-0e89-46e2-a644-310429ae5f45"}},
+		{"glob", []string{"linux.*"}, []string{"2e46c89a-5461-4865-a92e-3b799c12034a", "b96524e0-150b-4bb8-abc7-517051b6c14e"}},
+		{"claim order without duplicates", []string{"shared.ssh-keys", "linux.firewall", "shared.ssh-*"}, []string{"2e46c89a-5461-4865-a92e-3b799c12034a", "b96524e0-850b-4bb8-abc7-517051b6c14e"}},
+		{"none", nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := Select(all, tt.selectors)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, selected)
+		})
+	}
+}
+
+func TestSelect_Errors(t *testing.T) {
+	all := selectClaims(t)
+
+	selected, err := Select(all, []string{"linux.firewall", "linux.firewal"})
+	assert.EqualError(t, err, `no check matches "linux.firewal"`)
+	assert.Equal(t, []string{"2e46c89a-5461-4865-a92e-3b799c12034a"}, selected, "the other selectors still match")
+
+	_, err = Select(all, []string{"b96524e0"})
+	assert.EqualError(t, err, `UUID prefix "b96524e0" is ambiguous, it matches 2 checks`)
+
+	_, err = Select(all, []string{"windows.*"})
+	assert.EqualError(t, err, `no check matches "windows.*"`)
+
+	_, err = Select(all, []string{"linux.[a"})
+	assert.ErrorContains(t, err, `invalid pattern "linux.[a"`)
+}
+
+func TestIdentifiers(t *testing.T) {
+	identifiers := Identifiers(selectClaims(t))
+	assert.Contains(t, identifiers, "Firewall & Sharing\tAll checks of the claim")
+	assert.Contains(t, identifiers, "linux.firewall\tFirewall is on")
+	assert.Len(t, identifiers, 6)
+}
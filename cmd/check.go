@@ -2,29 +2,72 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strings"
 
 	"github.com/ParetoSecurity/agent/claims"
 	"github.com/ParetoSecurity/agent/runner"
 	shared "github.com/ParetoSecurity/agent/shared"
 	team "github.com/ParetoSecurity/agent/team"
 	"github.com/caarlos0/log"
+	"github.com/samber/lo"
 	"github.com/spf13/cobra"
 )
 
 var checkCmd = &cobra.Command{
-	Use:   "check [--skip <uuid>] [--only <uuid>]",
+	Use:   "check [--skip <check>] [--only <check>] [--format json|sarif|junit] [--record <file> | --replay <file>]",
 	Short: "Run checks on your system",
+	Long: `Run checks on your system.
+
+--skip and --only can be repeated and take a check slug such as linux.firewall,
+a UUID, a UUID prefix, a claim title or a glob pattern such as 'linux.*'. See
+` + "`paretosecurity schema --details`" + ` for the slugs of the checks.
+
+--record runs the checks one at a time in this process and saves every command
+they run, every file and directory they read or look up, every executable they
+look up and every port they probe, with the results, to a JSON file. Checks that
+require root only see what the current user may see, run with sudo to record
+them fully. The recording holds command outputs and file contents as they are,
+review it before sharing. --replay runs the checks of a recording against it
+instead of the system and reports the checks whose result changed. The update
+check cannot be replayed and fails. Neither updates the state of the checks or
+notifies anyone.`,
+	PreRunE: func(cc *cobra.Command, args []string) error {
+		format, _ := cc.Flags().GetString("format")
+		if format != "" && !lo.Contains(runner.Formats, format) {
+			return fmt.Errorf("unsupported format %q, use one of: %s", format, strings.Join(runner.Formats, ", "))
+		}
+		return nil
+	},
 	Run: func(cc *cobra.Command, args []string) {
-		skipUUIDs, _ := cc.Flags().GetStringArray("skip")
-		onlyUUID, _ := cc.Flags().GetString("only")
-		checkCommand(skipUUIDs, onlyUUID)
+		skip, _ := cc.Flags().GetStringArray("skip")
+		only, _ := cc.Flags().GetStringArray("only")
+		format, _ := cc.Flags().GetString("format")
+		record, _ := cc.Flags().GetString("record")
+		replay, _ := cc.Flags().GetString("replay")
+		for _, err := range runner.ValidateSettings(claims.All) {
+			log.WithError(err).Warn("Invalid check setting, using the default")
+		}
+		if record != "" || replay != "" {
+			reproduceCommand(skip, only, format, record, replay)
+			return
+		}
+		checkCommand(skip, only, format)
 	},
+	Annotations: usesChecks,
 }
 
 func init() {
 	rootCmd.AddCommand(checkCmd)
-	checkCmd.Flags().StringArray("skip", []string{}, "skip checks by UUID")
-	checkCmd.Flags().String("only", "", "only run checks by UUID")
+	checkCmd.Flags().StringArray("skip", []string{}, "skip checks by slug, UUID, claim title or pattern")
+	checkCmd.Flags().StringArray("only", []string{}, "only run checks by slug, UUID, claim title or pattern")
+	checkCmd.Flags().String("format", "", "print results to stdout as json, sarif or junit")
+	checkCmd.Flags().String("record", "", "record the interactions of the checks with the system to a file")
+	checkCmd.Flags().String("replay", "", "run the checks against a recording instead of the system")
+	checkCmd.MarkFlagsMutuallyExclusive("record", "replay")
+	_ = checkCmd.RegisterFlagCompletionFunc("skip", completeChecks)
+	_ = checkCmd.RegisterFlagCompletionFunc("only", completeChecks)
 }
 
 // CheckConfig holds the configuration for the check command
@@ -34,7 +77,8 @@ type CheckConfig struct {
 	AllChecksPassed func() bool
 	GetFailedChecks func() []shared.LastState
 	ReportToTeam    func(bool) error
-	RunnerCheck     func(context.Context, []claims.Claim, []string, string)
+	RunnerCheck     func(context.Context, []claims.Claim, []string, []string) []runner.CheckResult
+	WriteResults    func(string, []runner.CheckResult) error
 	LogFatal        func(string)
 	LogWarn         func(string)
 	LogErrorf       func(string, ...interface{})
@@ -50,34 +94,61 @@ func DefaultCheckConfig() *CheckConfig {
 		GetFailedChecks: shared.GetFailedChecks,
 		ReportToTeam:    team.ReportToTeam,
 		RunnerCheck:     runner.Check,
-		LogFatal:        log.Fatal,
-		LogWarn:         log.Warn,
-		LogErrorf:       log.Errorf,
-		LogWithError:    log.WithError,
+		WriteResults: func(format string, results []runner.CheckResult) error {
+			return runner.WriteResults(os.Stdout, format, results)
+		},
+		LogFatal:     log.Fatal,
+		LogWarn:      log.Warn,
+		LogErrorf:    log.Errorf,
+		LogWithError: log.WithError,
 	}
 }
 
-func checkCommand(skipUUIDs []string, onlyUUID string) {
+func checkCommand(skip, only []string, format string) {
 	config := DefaultCheckConfig()
-	runCheckCommand(config, skipUUIDs, onlyUUID)
+	if format != "" {
+		// Keep stdout clean for the structured output
+		runner.LogWriter = os.Stderr
+	}
+	runCheckCommand(config, skip, only, format)
 }
 
-func runCheckCommand(config *CheckConfig, skipUUIDs []string, onlyUUID string) {
+// runCheckCommand runs the checks selected by only, all checks when it is
+// empty, except the checks selected by skip. Unknown checks in skip are
+// ignored with a warning, as runbooks often share them across platforms.
+func runCheckCommand(config *CheckConfig, skip, only []string, format string) {
 	if config.IsRoot() {
 		config.LogWarn("Please run this command as a normal user, as it won't report all checks correctly.")
 	}
 
+	skipUUIDs, err := claims.Select(claims.All, skip)
+	if err != nil {
+		config.LogWarn(fmt.Sprintf("Ignoring --skip: %s", err))
+	}
+	onlyUUIDs, err := claims.Select(claims.All, only)
+	if err != nil {
+		config.LogFatal(fmt.Sprintf("Invalid --only: %s", err))
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), shared.CheckTimeout)
 	defer cancel()
 
 	done := make(chan struct{})
+	var results []runner.CheckResult
 	go func() {
-		config.RunnerCheck(ctx, claims.All, skipUUIDs, onlyUUID)
+		results = config.RunnerCheck(ctx, claims.All, skipUUIDs, onlyUUIDs)
 		close(done)
 	}()
 
 	select {
 	case <-done:
+		if format != "" {
+			if err := config.WriteResults(format, results); err != nil {
+				config.LogWithError(err).Warn("failed to write results")
+			}
+		}
+
 		if config.IsLinked() {
 			err := config.ReportToTeam(false)
 			if err != nil {
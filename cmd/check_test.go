@@ -8,7 +8,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ParetoSecurity/agent/check"
+	linuxchecks "github.com/ParetoSecurity/agent/checks/linux"
 	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/runner"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 	"github.com/stretchr/testify/assert"
@@ -44,8 +47,9 @@ func Test_runCheckCommand_Success(t *testing.T) {
 			reportToTeamCalled = true
 			return nil
 		},
-		RunnerCheck: func(ctx context.Context, claims []claims.Claim, skipUUIDs []string, onlyUUID string) {
+		RunnerCheck: func(ctx context.Context, claims []claims.Claim, skipUUIDs, onlyUUIDs []string) []runner.CheckResult {
 			runnerCheckCalled = true
+			return nil
 		},
 		LogFatal: func(msg string) {
 			t.Errorf("LogFatal should not be called, got: %s", msg)
@@ -61,7 +65,7 @@ func Test_runCheckCommand_Success(t *testing.T) {
 		},
 	}
 
-	runCheckCommand(config, []string{}, "")
+	runCheckCommand(config, []string{}, nil, "")
 
 	assert.True(t, runnerCheckCalled)
 	assert.True(t, reportToTeamCalled)
@@ -77,7 +81,9 @@ func Test_runCheckCommand_IsRoot(t *testing.T) {
 		AllChecksPassed: func() bool { return true },
 		GetFailedChecks: func() []shared.LastState { return []shared.LastState{} },
 		ReportToTeam:    func(bool) error { return nil },
-		RunnerCheck:     func(ctx context.Context, claims []claims.Claim, skipUUIDs []string, onlyUUID string) {},
+		RunnerCheck: func(ctx context.Context, claims []claims.Claim, skipUUIDs, onlyUUIDs []string) []runner.CheckResult {
+			return nil
+		},
 		LogFatal: func(msg string) {
 			t.Errorf("LogFatal should not be called")
 		},
@@ -91,7 +97,7 @@ func Test_runCheckCommand_IsRoot(t *testing.T) {
 		},
 	}
 
-	runCheckCommand(config, []string{}, "")
+	runCheckCommand(config, []string{}, nil, "")
 
 	assert.True(t, logWarnCalled)
 }
@@ -115,7 +121,9 @@ func Test_runCheckCommand_ChecksFailed_Verbose(t *testing.T) {
 			}
 		},
 		ReportToTeam: func(bool) error { return nil },
-		RunnerCheck:  func(ctx context.Context, claims []claims.Claim, skipUUIDs []string, onlyUUID string) {},
+		RunnerCheck: func(ctx context.Context, claims []claims.Claim, skipUUIDs, onlyUUIDs []string) []runner.CheckResult {
+			return nil
+		},
 		LogFatal: func(msg string) {
 			logFatalCalled = true
 			assert.Equal(t, "You can use `paretosecurity check --verbose` to get a detailed report.", msg)
@@ -130,7 +138,7 @@ func Test_runCheckCommand_ChecksFailed_Verbose(t *testing.T) {
 		},
 	}
 
-	runCheckCommand(config, []string{}, "")
+	runCheckCommand(config, []string{}, nil, "")
 
 	assert.False(t, logFatalCalled) // verbose is true, so LogFatal should not be called
 	assert.True(t, logErrorfCalled)
@@ -155,7 +163,9 @@ func Test_runCheckCommand_ChecksFailed_NotVerbose(t *testing.T) {
 			}
 		},
 		ReportToTeam: func(bool) error { return nil },
-		RunnerCheck:  func(ctx context.Context, claims []claims.Claim, skipUUIDs []string, onlyUUID string) {},
+		RunnerCheck: func(ctx context.Context, claims []claims.Claim, skipUUIDs, onlyUUIDs []string) []runner.CheckResult {
+			return nil
+		},
 		LogFatal: func(msg string) {
 			logFatalCalled = true
 			assert.Equal(t, "You can use `paretosecurity check --verbose` to get a detailed report.", msg)
@@ -169,7 +179,7 @@ func Test_runCheckCommand_ChecksFailed_NotVerbose(t *testing.T) {
 		},
 	}
 
-	runCheckCommand(config, []string{}, "")
+	runCheckCommand(config, []string{}, nil, "")
 
 	assert.True(t, logFatalCalled)   // suggests using --verbose when not in verbose mode
 	assert.False(t, logErrorfCalled) // failed checks are not logged without verbose
@@ -187,7 +197,9 @@ func Test_runCheckCommand_NotLinked(t *testing.T) {
 			reportToTeamCalled = true
 			return nil
 		},
-		RunnerCheck: func(ctx context.Context, claims []claims.Claim, skipUUIDs []string, onlyUUID string) {},
+		RunnerCheck: func(ctx context.Context, claims []claims.Claim, skipUUIDs, onlyUUIDs []string) []runner.CheckResult {
+			return nil
+		},
 		LogFatal: func(msg string) {
 			t.Errorf("LogFatal should not be called")
 		},
@@ -198,7 +210,7 @@ func Test_runCheckCommand_NotLinked(t *testing.T) {
 		},
 	}
 
-	runCheckCommand(config, []string{}, "")
+	runCheckCommand(config, []string{}, nil, "")
 
 	assert.False(t, reportToTeamCalled) // Not linked, so no report
 }
@@ -217,15 +229,15 @@ func Test_runCheckCommand_Timeout(t *testing.T) {
 		AllChecksPassed: func() bool { return true },
 		GetFailedChecks: func() []shared.LastState { return []shared.LastState{} },
 		ReportToTeam:    func(bool) error { return nil },
-		RunnerCheck: func(ctx context.Context, claims []claims.Claim, skipUUIDs []string, onlyUUID string) {
+		RunnerCheck: func(ctx context.Context, claims []claims.Claim, skipUUIDs, onlyUUIDs []string) []runner.CheckResult {
 			// Simulate long running check that respects context cancellation
 			select {
 			case <-time.After(1 * time.Second): // Longer than the 100ms timeout
 				// This should never be reached due to context timeout
 			case <-ctx.Done():
 				// Context was cancelled due to timeout, return immediately
-				return
 			}
+			return nil
 		},
 		LogFatal: func(msg string) {
 			logFatalCalled = true
@@ -238,7 +250,7 @@ func Test_runCheckCommand_Timeout(t *testing.T) {
 		},
 	}
 
-	runCheckCommand(config, []string{}, "")
+	runCheckCommand(config, []string{}, nil, "")
 
 	assert.True(t, logFatalCalled)
 }
@@ -252,8 +264,81 @@ func Test_DefaultCheckConfig(t *testing.T) {
 	assert.NotNil(t, config.GetFailedChecks)
 	assert.NotNil(t, config.ReportToTeam)
 	assert.NotNil(t, config.RunnerCheck)
+	assert.NotNil(t, config.WriteResults)
 	assert.NotNil(t, config.LogFatal)
 	assert.NotNil(t, config.LogWarn)
 	assert.NotNil(t, config.LogErrorf)
 	assert.NotNil(t, config.LogWithError)
 }
+
+func Test_runCheckCommand_Format(t *testing.T) {
+	var writtenFormat string
+	var writtenResults []runner.CheckResult
+
+	results := []runner.CheckResult{
+		{Claim: "Test Claim", UUID: "test-uuid", Name: "Test Check", State: "pass"},
+	}
+	config := &CheckConfig{
+		IsRoot:          func() bool { return false },
+		IsLinked:        func() bool { return false },
+		AllChecksPassed: func() bool { return true },
+		GetFailedChecks: func() []shared.LastState { return []shared.LastState{} },
+		ReportToTeam:    func(bool) error { return nil },
+		RunnerCheck: func(ctx context.Context, claims []claims.Claim, skipUUIDs, onlyUUIDs []string) []runner.CheckResult {
+			return results
+		},
+		WriteResults: func(format string, results []runner.CheckResult) error {
+			writtenFormat = format
+			writtenResults = results
+			return nil
+		},
+		LogFatal:  func(msg string) {},
+		LogWarn:   func(msg string) {},
+		LogErrorf: func(format string, args ...interface{}) {},
+		LogWithError: func(err error) *log.Entry {
+			return log.WithError(err)
+		},
+	}
+
+	runCheckCommand(config, []string{}, nil, "json")
+
+	assert.Equal(t, "json", writtenFormat)
+	assert.Equal(t, results, writtenResults)
+}
+
+func Test_runCheckCommand_Selectors(t *testing.T) {
+	var skipped, selected []string
+	var warnings, fatals []string
+
+	all := claims.All
+	defer func() { claims.All = all }()
+	claims.All = []claims.Claim{{Title: "Firewall & Sharing", Checks: []check.Check{&linuxchecks.Firewall{}, &linuxchecks.Printer{}}}}
+
+	config := &CheckConfig{
+		IsRoot:          func() bool { return false },
+		IsLinked:        func() bool { return false },
+		AllChecksPassed: func() bool { return true },
+		GetFailedChecks: func() []shared.LastState { return []shared.LastState{} },
+		ReportToTeam:    func(bool) error { return nil },
+		RunnerCheck: func(ctx context.Context, claims []claims.Claim, skipUUIDs, onlyUUIDs []string) []runner.CheckResult {
+			skipped, selected = skipUUIDs, onlyUUIDs
+			return nil
+		},
+		LogFatal:  func(msg string) { fatals = append(fatals, msg) },
+		LogWarn:   func(msg string) { warnings = append(warnings, msg) },
+		LogErrorf: func(format string, args ...interface{}) {},
+		LogWithError: func(err error) *log.Entry {
+			return log.WithError(err)
+		},
+	}
+
+	runCheckCommand(config, []string{"linux.printer", "windows.firewall"}, []string{"firewall & sharing"}, "")
+	assert.Equal(t, []string{(&linuxchecks.Printer{}).UUID()}, skipped)
+	assert.Equal(t, []string{(&linuxchecks.Firewall{}).UUID(), (&linuxchecks.Printer{}).UUID()}, selected)
+	assert.Equal(t, []string{`Ignoring --skip: no check matches "windows.firewall"`}, warnings)
+
+	selected = nil
+	runCheckCommand(config, nil, []string{"linux.*", "linux.firewal"}, "")
+	assert.Equal(t, []string{`Invalid --only: no check matches "linux.firewal"`}, fatals)
+	assert.Nil(t, selected, "nothing runs when --only is invalid")
+}
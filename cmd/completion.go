@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/spf13/cobra"
+)
+
+// completeChecks offers the slugs of the checks and the titles of the claims
+// that start with toComplete.
+func completeChecks(cc *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	completions := []string{}
+	for _, identifier := range claims.Identifiers(claims.All) {
+		if strings.HasPrefix(strings.ToLower(identifier), strings.ToLower(toComplete)) {
+			completions = append(completions, identifier)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeCheckArg completes the single check argument of a command.
+func completeCheckArg(cc *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeChecks(cc, args, toComplete)
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ParetoSecurity/agent/check"
+	linuxchecks "github.com/ParetoSecurity/agent/checks/linux"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleteChecks(t *testing.T) {
+	all := claims.All
+	defer func() { claims.All = all }()
+	claims.All = []claims.Claim{{Title: "Firewall & Sharing", Checks: []check.Check{&linuxchecks.Firewall{}, &linuxchecks.Printer{}}}}
+
+	completions, directive := completeChecks(checkCmd, nil, "linux.f")
+	assert.Equal(t, []string{"linux.firewall\tFirewall is configured"}, completions)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+
+	completions, _ = completeChecks(checkCmd, nil, "fire")
+	assert.Equal(t, []string{"Firewall & Sharing\tAll checks of the claim"}, completions)
+
+	completions, _ = completeCheckArg(enableCmd, []string{"linux.firewall"}, "")
+	assert.Empty(t, completions, "config commands take a single check")
+}
@@ -1,6 +1,17 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ParetoSecurity/agent/checks/rules"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/runner"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 	"github.com/spf13/cobra"
@@ -23,35 +34,138 @@ var resetCmd = &cobra.Command{
 }
 
 var enableCmd = &cobra.Command{
-	Use:   "enable [check UUID]",
-	Short: "Enable a specific check",
-	Long:  "Enable a specific check by providing its id.",
-	Args:  cobra.ExactArgs(1),
+	Use:               "enable [check]",
+	Short:             "Enable a specific check",
+	Long:              "Enable a specific check by providing its slug, UUID or UUID prefix, or several checks by a claim title or a glob pattern such as 'linux.*'.",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCheckArg,
 	Run: func(cmd *cobra.Command, args []string) {
-		check := args[0]
-		err := shared.EnableCheck(check)
-		if err != nil {
-			log.WithError(err).Fatalf("Failed to enable check: %s", check)
-		} else {
-			log.WithField("check", check).Info("Check enabled successfully.")
-		}
+		forEachCheck(args[0], "enable", shared.Config.DisableChecks, shared.EnableCheck, func(entry *log.Entry) {
+			entry.Info("Check enabled successfully.")
+		})
 	},
+	Annotations: usesChecks,
 }
 
 var disableCmd = &cobra.Command{
-	Use:   "disable [check UUID]",
-	Short: "Disable a specific check",
-	Long:  "Disable a specific check by providing its id.",
-	Args:  cobra.ExactArgs(1),
+	Use:               "disable [check]",
+	Short:             "Disable a specific check",
+	Long:              "Disable a specific check by providing its slug, UUID or UUID prefix, or several checks by a claim title or a glob pattern such as 'linux.*'.",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCheckArg,
+	Run: func(cmd *cobra.Command, args []string) {
+		forEachCheck(args[0], "disable", nil, shared.DisableCheck, func(entry *log.Entry) {
+			entry.Info("Check disabled successfully.")
+		})
+	},
+	Annotations: usesChecks,
+}
+
+var snoozeCmd = &cobra.Command{
+	Use:   "snooze [check] --for <duration> [--reason <reason>]",
+	Short: "Snooze a specific check for a while",
+	Long: `Snooze a specific check for a while, for example --for 72h. The check is
+selected like for config enable.
+
+A snoozed check still runs and records its real state, it is shown as snoozed
+and does not raise notifications. When the snooze expires, the check alerts
+again if it still fails. Snoozes are included in the team report.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCheckArg,
+	Run: func(cmd *cobra.Command, args []string) {
+		duration, _ := cmd.Flags().GetDuration("for")
+		reason, _ := cmd.Flags().GetString("reason")
+		snooze := func(uuid string) error {
+			return shared.SnoozeCheck(uuid, duration, reason)
+		}
+		forEachCheck(args[0], "snooze", nil, snooze, func(entry *log.Entry) {
+			entry.WithField("until", time.Now().Add(duration).Format(time.DateTime)).
+				Info("Check snoozed successfully.")
+		})
+	},
+	Annotations: usesChecks,
+}
+
+var unsnoozeCmd = &cobra.Command{
+	Use:               "unsnooze [check]",
+	Short:             "Remove the snooze of a specific check",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCheckArg,
+	Run: func(cmd *cobra.Command, args []string) {
+		forEachCheck(args[0], "unsnooze", snoozedChecks(), shared.UnsnoozeCheck, func(entry *log.Entry) {
+			entry.Info("Check unsnoozed successfully.")
+		})
+	},
+	Annotations: usesChecks,
+}
+
+// forEachCheck applies change to every check matched by selector and logs
+// the outcome. It exits with an error when the selector matches no check or
+// the change failed for any of them. configured are the UUIDs the config
+// already names for the action to undo, see selectConfigured.
+func forEachCheck(selector, action string, configured []string, change func(uuid string) error, done func(*log.Entry)) {
+	uuids, err := selectConfigured(selector, configured)
+	if err != nil {
+		log.WithError(err).Fatalf("Failed to %s check: %s", action, selector)
+	}
+	failed := false
+	for _, uuid := range uuids {
+		entry := log.WithField("check", uuid)
+		if err := change(uuid); err != nil {
+			entry.WithError(err).Errorf("Failed to %s check: %s", action, uuid)
+			failed = true
+			continue
+		}
+		done(entry)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// selectConfigured returns the checks matched by selector. A selector that
+// matches no known check but is the full UUID of one of configured, such as
+// a check of a removed plugin, selects that UUID, so it can still be removed
+// from the config.
+func selectConfigured(selector string, configured []string) ([]string, error) {
+	uuids, err := claims.Select(claims.All, []string{selector})
+	if err != nil {
+		if index := slices.IndexFunc(configured, func(uuid string) bool { return strings.EqualFold(uuid, selector) }); index >= 0 {
+			return []string{configured[index]}, nil
+		}
+	}
+	return uuids, err
+}
+
+// snoozedChecks returns the UUIDs of the checks snoozed in the config.
+func snoozedChecks() []string {
+	uuids := []string{}
+	for _, snooze := range shared.Config.Snoozes {
+		uuids = append(uuids, snooze.Check)
+	}
+	return uuids
+}
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Print the policy applied to the checks",
+	Long:  "Print the required checks, exceptions and parameters set by the policy file and the checks disabled or snoozed in the config file.",
 	Run: func(cmd *cobra.Command, args []string) {
-		check := args[0]
-		err := shared.DisableCheck(check)
-		if err != nil {
-			log.WithError(err).Fatalf("Failed to disable check: %s", check)
-		} else {
-			log.WithField("check", check).Info("Check disabled successfully.")
+		printPolicy(os.Stdout, claims.All)
+	},
+	Annotations: usesChecks,
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the check settings and rules",
+	Long:  "Validate the check settings in the config file and the policy file against the settings the checks declare, see `paretosecurity schema --settings`, and the rules they define.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if !validateSettings(os.Stdout, claims.All) {
+			os.Exit(1)
 		}
 	},
+	Annotations: usesChecks,
 }
 
 func init() {
@@ -59,4 +173,79 @@ func init() {
 	configCmd.AddCommand(resetCmd)
 	configCmd.AddCommand(enableCmd)
 	configCmd.AddCommand(disableCmd)
+	configCmd.AddCommand(snoozeCmd)
+	configCmd.AddCommand(unsnoozeCmd)
+	configCmd.AddCommand(policyCmd)
+	configCmd.AddCommand(validateCmd)
+	snoozeCmd.Flags().Duration("for", 0, "how long to snooze the check, at most 720h")
+	snoozeCmd.Flags().String("reason", "", "why the check is snoozed")
+	_ = snoozeCmd.MarkFlagRequired("for")
+}
+
+// validateSettings prints the invalid check settings and rules and reports whether all are valid.
+func validateSettings(w io.Writer, all []claims.Claim) bool {
+	_, ruleErrs := rules.Load()
+	errs := append(ruleErrs, runner.ValidateSettings(all)...)
+	for _, err := range errs {
+		fmt.Fprintln(w, err)
+	}
+	if len(errs) > 0 {
+		return false
+	}
+	fmt.Fprintln(w, "Check settings and rules are valid.")
+	return true
+}
+
+func printPolicy(w io.Writer, all []claims.Claim) {
+	fmt.Fprintf(w, "Policy: %s\nConfig: %s\n\n", shared.PolicyPath, shared.ConfigPath)
+	name := func(uuid string) string {
+		if chk, found := findCheck(all, uuid); found {
+			return chk.Name()
+		}
+		return uuid
+	}
+
+	data := [][]string{}
+	for _, claim := range all {
+		for _, chk := range claim.Checks {
+			policy := shared.CheckPolicyFor(chk.UUID())
+			if policy.Source == shared.SourceDefault {
+				continue
+			}
+			state := "required"
+			if policy.Disabled {
+				state = "disabled"
+			} else if policy.Snooze != nil {
+				state = "snoozed"
+			}
+			data = append(data, []string{chk.UUID(), chk.Name(), state, string(policy.Source), policy.Describe()})
+		}
+	}
+	for _, exception := range shared.Policy.Exceptions {
+		if !exception.Active() {
+			data = append(data, []string{exception.Check, name(exception.Check), "expired", string(shared.SourcePolicy), fmt.Sprintf("Exception expired %s: %s", exception.Expires, exception.Justification)})
+		}
+	}
+	if len(data) == 0 {
+		fmt.Fprintln(w, "All checks run with their defaults.")
+	} else {
+		table := newHistoryTable(w, []string{"UUID", "Name", "State", "Source", "Details"})
+		table.Bulk(data)
+		table.Render()
+	}
+
+	if len(shared.Policy.Parameters) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	data = [][]string{}
+	for _, uuid := range slices.Sorted(maps.Keys(shared.Policy.Parameters)) {
+		params := shared.Policy.Parameters[uuid]
+		for _, key := range slices.Sorted(maps.Keys(params)) {
+			data = append(data, []string{uuid, name(uuid), key, fmt.Sprintf("%v", params[key])})
+		}
+	}
+	table := newHistoryTable(w, []string{"UUID", "Name", "Parameter", "Value"})
+	table.Bulk(data)
+	table.Render()
 }
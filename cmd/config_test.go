@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_printPolicy(t *testing.T) {
+	all := []claims.Claim{{Title: "Test", Checks: []check.Check{
+		&fixableCheck{uuid: "required-uuid"},
+		&fixableCheck{uuid: "waived-uuid"},
+		&fixableCheck{uuid: "local-uuid"},
+		&fixableCheck{uuid: "default-uuid"},
+		&fixableCheck{uuid: "snoozed-uuid"},
+	}}}
+	shared.Policy = shared.ParetoPolicy{
+		Required: []string{"required-uuid"},
+		Exceptions: []shared.PolicyException{
+			{Check: "waived-uuid", Expires: "2999-01-01", Justification: "Kiosk"},
+			{Check: "local-uuid", Expires: "2000-01-01", Justification: "Old laptop"},
+		},
+		Parameters: map[string]map[string]interface{}{"required-uuid": {"MinReleaseAge": "168h"}},
+	}
+	shared.Config.DisableChecks = []string{"local-uuid"}
+	shared.Config.Snoozes = []shared.CheckSnooze{{Check: "snoozed-uuid", Until: time.Now().Add(time.Hour), Reason: "Vendor fix"}}
+	defer func() {
+		shared.Policy = shared.ParetoPolicy{}
+		shared.Config.DisableChecks = nil
+		shared.Config.Snoozes = nil
+	}()
+
+	var buf bytes.Buffer
+	printPolicy(&buf, all)
+	out := buf.String()
+	assert.Regexp(t, `required-uuid \|\s+Fixable\s+\| required \| policy \|\s+Required by policy`, out)
+	assert.Regexp(t, `waived-uuid\s+\|\s+Fixable\s+\| disabled \| policy \| Disabled by policy exception until 2999-01-01: Kiosk`, out)
+	assert.Regexp(t, `local-uuid\s+\|\s+Fixable\s+\| disabled \| config \|\s+Disabled by the config file`, out)
+	assert.Regexp(t, `local-uuid\s+\|\s+Fixable\s+\| expired\s+\| policy \|\s+Exception expired 2000-01-01: Old laptop`, out)
+	assert.Regexp(t, `required-uuid \|\s+Fixable\s+\| MinReleaseAge \| 168h`, out)
+	assert.Regexp(t, `snoozed-uuid\s+\|\s+Fixable\s+\| snoozed\s+\| config \|\s+Snoozed until .+: Vendor fix`, out)
+	assert.NotContains(t, out, "default-uuid")
+}
+
+func Test_printPolicy_Empty(t *testing.T) {
+	shared.Policy = shared.ParetoPolicy{}
+	shared.Config.DisableChecks = nil
+
+	var buf bytes.Buffer
+	printPolicy(&buf, []claims.Claim{{Title: "Test", Checks: []check.Check{&fixableCheck{uuid: "uuid"}}}})
+	assert.Contains(t, buf.String(), "All checks run with their defaults.")
+}
+
+func Test_validateSettings(t *testing.T) {
+	shared.Config.Checks = map[string]map[string]interface{}{"unknown-uuid": {"Key": "value"}}
+	defer func() { shared.Config.Checks = nil }()
+
+	var buf bytes.Buffer
+	assert.False(t, validateSettings(&buf, []claims.Claim{}))
+	assert.Contains(t, buf.String(), "unknown check unknown-uuid")
+
+	shared.Config.Checks = nil
+	buf.Reset()
+	assert.True(t, validateSettings(&buf, []claims.Claim{}))
+	assert.Contains(t, buf.String(), "Check settings and rules are valid.")
+}
+
+func Test_validateSettings_Rules(t *testing.T) {
+	shared.Config.Rules = []shared.Rule{{UUID: "rule-uuid", Name: "Root rule", Type: "sysctl", Key: "kernel.x", RequiresRoot: true}}
+	defer func() { shared.Config.Rules = nil }()
+
+	var buf bytes.Buffer
+	assert.False(t, validateSettings(&buf, []claims.Claim{}))
+	assert.Contains(t, buf.String(), "rule rule-uuid (Root rule): rules that require root can only be defined in the policy")
+}
+
+func Test_selectConfigured(t *testing.T) {
+	claims.All = []claims.Claim{{Title: "Test", Checks: []check.Check{&fixableCheck{uuid: "known-uuid"}}}}
+	shared.Config = shared.ParetoConfig{
+		DisableChecks: []string{"removed-plugin-uuid"},
+		Snoozes:       []shared.CheckSnooze{{Check: "removed-snoozed-uuid", Until: time.Now().Add(time.Hour)}},
+	}
+	defer func() { shared.Config = shared.ParetoConfig{} }()
+
+	uuids, err := selectConfigured("known-uuid", shared.Config.DisableChecks)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"known-uuid"}, uuids)
+
+	uuids, err = selectConfigured("REMOVED-PLUGIN-UUID", shared.Config.DisableChecks)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"removed-plugin-uuid"}, uuids, "disabled checks that no longer exist can be enabled")
+
+	uuids, err = selectConfigured("removed-snoozed-uuid", snoozedChecks())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"removed-snoozed-uuid"}, uuids, "snoozed checks that no longer exist can be unsnoozed")
+
+	_, err = selectConfigured("removed-plugin", shared.Config.DisableChecks)
+	assert.Error(t, err, "only full UUIDs fall back to the config")
+	_, err = selectConfigured("removed-plugin-uuid", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/debug"
+	"github.com/ParetoSecurity/agent/runner"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/caarlos0/log"
+	"github.com/spf13/cobra"
+)
+
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Troubleshoot the agent",
+}
+
+var debugBundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Write an archive to attach to bug reports",
+	Long: `Write a zip archive with what is needed to troubleshoot the agent: the info
+output, the config without secrets, the state file, the schema, the status of
+the systemd units, whether the root helper is reachable, the desktop
+environment and the output of every command the checks run.
+
+The checks run again to collect fresh outputs, without updating their state.
+Hostnames, user names, serial numbers and MAC addresses are replaced with
+placeholders such as <hostname-1>. Review the archive before sharing it.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			output = fmt.Sprintf("paretosecurity-debug-%s.zip", time.Now().Format("20060102-150405"))
+		}
+		file, err := os.Create(output)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to create debug bundle")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), shared.CheckTimeout)
+		defer cancel()
+		log.Info("Running the checks to collect their outputs, this can take a minute")
+		err = writeDebugBundle(ctx, file, DefaultDebugBundleConfig())
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(output)
+			log.WithError(err).Fatal("Failed to write debug bundle")
+		}
+		log.Infof("Wrote debug bundle to %s", output)
+	},
+	Annotations: usesChecks,
+}
+
+// DebugBundleConfig holds the sources of the debug bundle
+type DebugBundleConfig struct {
+	Claims         []claims.Claim
+	Masker         *debug.Masker
+	Info           func() []string
+	Services       func() string
+	Helper         func(context.Context, []claims.Claim) string
+	CommandOutputs func(context.Context, []claims.Claim) map[string]string
+}
+
+// DefaultDebugBundleConfig returns the default configuration
+func DefaultDebugBundleConfig() *DebugBundleConfig {
+	return &DebugBundleConfig{
+		Claims:         claims.All,
+		Masker:         debug.NewSystemMasker(),
+		Info:           systemInfo,
+		Services:       debug.Services,
+		Helper:         debug.Helper,
+		CommandOutputs: debug.CommandOutputs,
+	}
+}
+
+// writeDebugBundle writes the debug bundle to w.
+func writeDebugBundle(ctx context.Context, w io.Writer, config *DebugBundleConfig) error {
+	bundle := debug.NewBundle(w, config.Masker)
+	add := func(name string, content []byte) error {
+		log.Debugf("Adding %s to the debug bundle", name)
+		return bundle.Add(name, content)
+	}
+
+	if err := add("info.txt", []byte(strings.Join(config.Info(), "\n")+"\n")); err != nil {
+		return err
+	}
+
+	settings, err := debug.RedactedConfig(shared.Config)
+	if err != nil {
+		settings = []byte(fmt.Sprintf("Failed to encode config: %v\n", err))
+	}
+	if err := add("config.toml", settings); err != nil {
+		return err
+	}
+
+	state, err := os.ReadFile(shared.StatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		state = []byte("The checks have not run yet.\n")
+	} else if err != nil {
+		state = []byte(fmt.Sprintf("Failed to read %s: %v\n", shared.StatePath, err))
+	}
+	if err := add("state.toml", state); err != nil {
+		return err
+	}
+
+	var schema bytes.Buffer
+	if err := runner.WriteSchemaDetailsJSON(&schema, config.Claims); err != nil {
+		return err
+	}
+	if err := add("schema.json", schema.Bytes()); err != nil {
+		return err
+	}
+
+	if err := add("systemd.txt", []byte(config.Services())); err != nil {
+		return err
+	}
+	if err := add("helper.txt", []byte(config.Helper(ctx, config.Claims))); err != nil {
+		return err
+	}
+	if err := add("desktop.txt", []byte(debug.Desktop())); err != nil {
+		return err
+	}
+
+	outputs := config.CommandOutputs(ctx, config.Claims)
+	for _, slug := range slices.Sorted(maps.Keys(outputs)) {
+		if err := add("commands/"+slug+".txt", []byte(outputs[slug])); err != nil {
+			return err
+		}
+	}
+	return bundle.Close()
+}
+
+func init() {
+	debugBundleCmd.Flags().StringP("output", "o", "", "path of the archive (default paretosecurity-debug-<time>.zip)")
+	debugCmd.AddCommand(debugBundleCmd)
+	rootCmd.AddCommand(debugCmd)
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ParetoSecurity/agent/check"
+	linuxchecks "github.com/ParetoSecurity/agent/checks/linux"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/debug"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_writeDebugBundle(t *testing.T) {
+	statePath := shared.StatePath
+	config := shared.Config
+	defer func() {
+		shared.StatePath = statePath
+		shared.Config = config
+	}()
+	shared.StatePath = filepath.Join(t.TempDir(), "state")
+	require.NoError(t, os.WriteFile(shared.StatePath, []byte("[uuid]\nDetails = \"jane-laptop is exposed\"\n"), 0o600))
+	shared.Config = shared.ParetoConfig{TeamID: "team-id", AuthToken: "secret-token"}
+
+	masker := debug.NewMasker()
+	masker.Add(debug.KindHostname, "jane-laptop")
+	firewall := &linuxchecks.Firewall{}
+	bundleConfig := &DebugBundleConfig{
+		Claims:   []claims.Claim{{Title: "Firewall & Sharing", Checks: []check.Check{firewall}}},
+		Masker:   masker,
+		Info:     func() []string { return []string{"Team: team-id", `"machineName": "jane-laptop"`} },
+		Services: func() string { return "paretosecurity-user.timer active\n" },
+		Helper: func(context.Context, []claims.Claim) string {
+			return "Socket /run/paretosecurity.sock: reachable\n"
+		},
+		CommandOutputs: func(context.Context, []claims.Claim) map[string]string {
+			return map[string]string{"linux.firewall": "$ hostname\njane-laptop\n"}
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeDebugBundle(context.Background(), &buf, bundleConfig))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := map[string]string{}
+	names := []string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		require.NoError(t, err)
+		files[file.Name] = string(content)
+		names = append(names, file.Name)
+	}
+
+	assert.Equal(t, []string{
+		"info.txt", "config.toml", "state.toml", "schema.json",
+		"systemd.txt", "helper.txt", "desktop.txt", "commands/linux.firewall.txt",
+	}, names)
+	assert.Equal(t, "Team: team-id\n\"machineName\": \"<hostname-1>\"\n", files["info.txt"])
+	assert.Contains(t, files["config.toml"], "team-id")
+	assert.NotContains(t, files["config.toml"], "secret-token")
+	assert.Contains(t, files["state.toml"], "<hostname-1> is exposed")
+	assert.Contains(t, files["schema.json"], `"slug": "linux.firewall"`)
+	assert.Equal(t, "$ hostname\n<hostname-1>\n", files["commands/linux.firewall.txt"])
+}
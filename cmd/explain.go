@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/caarlos0/log"
+	"github.com/spf13/cobra"
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain [check]",
+	Short: "Explain the result of a check in the last run",
+	Long: `Print the evidence a check relied on in the last run: the commands it ran
+with their exit codes, the files it read and the rule that decided its result.
+Home directories, the hostname and values that look like secrets are redacted.
+
+Evidence is recorded by the checks that can be canceled while running: on
+Linux the firewall, disk encryption, Docker and application update checks,
+and on every platform custom rules and plugins. Other checks, like most checks
+on Windows and macOS, only show their result.
+
+The check is selected like for config enable, by slug, UUID or UUID prefix.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCheckArg,
+	Run: func(cmd *cobra.Command, args []string) {
+		uuids, err := claims.Select(claims.All, args)
+		if err != nil {
+			log.WithError(err).Fatal("Cannot explain check")
+		}
+		evidence, err := shared.LoadEvidence()
+		if err != nil {
+			log.WithError(err).Warn("Failed to load evidence")
+		}
+		printExplanation(os.Stdout, claims.All, uuids, shared.GetLastStates(), evidence)
+	},
+	Annotations: usesChecks,
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+}
+
+// printExplanation prints the last result and the redacted evidence of each check in uuids.
+func printExplanation(w io.Writer, all []claims.Claim, uuids []string, states map[string]shared.LastState, evidence map[string]shared.CheckEvidence) {
+	for i, uuid := range uuids {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		chk, found := findCheck(all, uuid)
+		if !found {
+			continue
+		}
+		fmt.Fprintf(w, "%s (%s, %s)\n", chk.Name(), check.SlugOf(chk), uuid)
+
+		state, ran := states[uuid]
+		if !ran {
+			fmt.Fprintln(w, "The check has not run yet.")
+			continue
+		}
+		result := string(state.State())
+		if state.Details != "" {
+			result += ": " + state.Details
+		}
+		fmt.Fprintf(w, "Result: %s\n", shared.RedactEvidence(check.Evidence{Detail: result}).Detail)
+
+		if _, records := chk.(check.ContextRunner); !records {
+			fmt.Fprintln(w, "The check does not record evidence.")
+			continue
+		}
+		recorded, found := evidence[uuid]
+		if !found || len(recorded.Evidence) == 0 {
+			fmt.Fprintln(w, "No evidence was recorded in the last run.")
+			continue
+		}
+		fmt.Fprintf(w, "Evidence from %s:\n", recorded.Time.Local().Format(time.DateTime))
+		for n, entry := range recorded.Evidence {
+			fmt.Fprintf(w, "%3d. %s\n", n+1, describeEvidence(shared.RedactEvidence(entry)))
+		}
+	}
+}
+
+// describeEvidence renders evidence on one line.
+func describeEvidence(evidence check.Evidence) string {
+	line := evidence.Kind + ": " + evidence.Subject
+	notes := []string{}
+	if evidence.Kind == check.EvidenceCommand && evidence.Error == "" {
+		notes = append(notes, fmt.Sprintf("exit %d", evidence.ExitCode))
+	}
+	if evidence.Error != "" {
+		notes = append(notes, "error: "+evidence.Error)
+	}
+	if evidence.Detail != "" {
+		if evidence.Kind == check.EvidenceRule {
+			return line + ": " + evidence.Detail
+		}
+		notes = append(notes, evidence.Detail)
+	}
+	if len(notes) > 0 {
+		line += " (" + strings.Join(notes, ", ") + ")"
+	}
+	return line
+}
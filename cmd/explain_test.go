@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	linuxchecks "github.com/ParetoSecurity/agent/checks/linux"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_printExplanation(t *testing.T) {
+	firewall, printer := &linuxchecks.Firewall{}, &linuxchecks.Printer{}
+	all := []claims.Claim{{Title: "Firewall & Sharing", Checks: []check.Check{firewall, printer}}}
+	states := map[string]shared.LastState{
+		firewall.UUID(): {UUID: firewall.UUID(), Name: firewall.Name(), Passed: false},
+	}
+	when := time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local)
+	evidence := map[string]shared.CheckEvidence{
+		firewall.UUID(): {Time: when, Evidence: []check.Evidence{
+			{Kind: check.EvidenceCommand, Subject: "iptables -L INPUT --line-numbers", Detail: "120 bytes of output"},
+			{Kind: check.EvidenceCommand, Subject: "nft list ruleset", ExitCode: 1, Detail: "0 bytes of output"},
+			{Kind: check.EvidenceCommand, Subject: "curl --token abc", Error: "executable file not found"},
+			{Kind: check.EvidenceFile, Subject: "/etc/crypttab", Error: "does not exist"},
+			{Kind: check.EvidenceRule, Subject: "iptables", Detail: "the INPUT chain policy is ACCEPT and no rule filters traffic"},
+		}},
+	}
+
+	var buf bytes.Buffer
+	printExplanation(&buf, all, []string{firewall.UUID(), printer.UUID()}, states, evidence)
+
+	assert.Equal(t, `Firewall is configured (linux.firewall, 2e46c89a-5461-4865-a92e-3b799c12034a)
+Result: fail
+Evidence from 2026-10-18 09:30:00:
+  1. command: iptables -L INPUT --line-numbers (exit 0, 120 bytes of output)
+  2. command: nft list ruleset (exit 1, 0 bytes of output)
+  3. command: curl --token <redacted> (error: executable file not found)
+  4. file: /etc/crypttab (error: does not exist)
+  5. rule: iptables: the INPUT chain policy is ACCEPT and no rule filters traffic
+
+Sharing printers is off (linux.printer, b96524e0-150b-4bb8-abc7-517051b6c14e)
+The check has not run yet.
+`, buf.String())
+
+	buf.Reset()
+	states[printer.UUID()] = shared.LastState{UUID: printer.UUID(), Passed: true}
+	printExplanation(&buf, all, []string{printer.UUID()}, states, evidence)
+	assert.Contains(t, buf.String(), "Result: pass\nThe check does not record evidence.\n")
+
+	buf.Reset()
+	delete(evidence, firewall.UUID())
+	printExplanation(&buf, all, []string{firewall.UUID()}, states, evidence)
+	assert.Contains(t, buf.String(), "Result: fail\nNo evidence was recorded in the last run.\n")
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/runner"
+	shared "github.com/ParetoSecurity/agent/shared"
+	"github.com/caarlos0/log"
+	"github.com/spf13/cobra"
+)
+
+var fixCmd = &cobra.Command{
+	Use:   "fix [check UUID] [--dry-run] [--yes]",
+	Short: "Fix a failing check",
+	Long:  "Print the steps that make a failing check pass and, after confirmation, apply the ones that can be automated.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		yes, _ := cmd.Flags().GetBool("yes")
+		if err := runFixCommand(DefaultFixConfig(), args[0], dryRun, yes); err != nil {
+			log.WithError(err).Fatal("Failed to fix check")
+		}
+	},
+	Annotations: usesChecks,
+}
+
+func init() {
+	rootCmd.AddCommand(fixCmd)
+	fixCmd.Flags().Bool("dry-run", false, "only print the steps, do not apply them")
+	fixCmd.Flags().BoolP("yes", "y", false, "apply the steps without asking for confirmation")
+}
+
+// FixConfig holds the configuration for the fix command
+type FixConfig struct {
+	Claims     []claims.Claim
+	In         io.Reader
+	Out        io.Writer
+	IsRoot     func() bool
+	RunCheck   func(context.Context, check.Check) (bool, error)
+	ApplyStep  func(context.Context, check.FixStep) error
+	FixViaRoot func(context.Context, string) (*runner.CheckStatus, error)
+}
+
+// DefaultFixConfig returns the default configuration
+func DefaultFixConfig() *FixConfig {
+	return &FixConfig{
+		Claims:     claims.All,
+		In:         os.Stdin,
+		Out:        os.Stdout,
+		IsRoot:     shared.IsRoot,
+		RunCheck:   runFixedCheck,
+		ApplyStep:  runner.ApplyFixStep,
+		FixViaRoot: runner.FixCheckViaRoot,
+	}
+}
+
+// runFixedCheck runs a check, through the root helper if it needs root, and
+// returns whether it passed.
+func runFixedCheck(ctx context.Context, chk check.Check) (bool, error) {
+	if chk.RequiresRoot() && !shared.IsRoot() {
+		status, err := runner.RunCheckViaRootContext(ctx, chk.UUID())
+		return status.Passed, err
+	}
+	if err := runner.RunCheck(ctx, chk); err != nil {
+		return false, err
+	}
+	return chk.Passed(), nil
+}
+
+// findCheck returns the check with the given UUID.
+func findCheck(all []claims.Claim, uuid string) (check.Check, bool) {
+	for _, claim := range all {
+		for _, chk := range claim.Checks {
+			if chk.UUID() == uuid {
+				return chk, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func runFixCommand(config *FixConfig, uuid string, dryRun, yes bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shared.CheckTimeout)
+	defer cancel()
+
+	chk, found := findCheck(config.Claims, uuid)
+	if !found {
+		return fmt.Errorf("check %s not found", uuid)
+	}
+	if !chk.IsRunnable() {
+		return fmt.Errorf("%s does not apply to this system: %s", chk.Name(), chk.Status())
+	}
+
+	passed, err := config.RunCheck(ctx, chk)
+	if err == nil && passed {
+		fmt.Fprintf(config.Out, "%s: already passing, nothing to fix\n", chk.Name())
+		return nil
+	}
+
+	remediator, ok := chk.(check.Remediator)
+	if !ok {
+		arch := "check-linux"
+		if runtime.GOOS == "windows" {
+			arch = "check-windows"
+		}
+		fmt.Fprintf(config.Out, "%s: %s\nNo automatic fix is known for this check, see https://paretosecurity.com/%s/%s\n", chk.Name(), chk.FailedMessage(), arch, chk.UUID())
+		return nil
+	}
+	steps := remediator.Remediation()
+	printFixSteps(config.Out, chk, steps)
+
+	automatic := 0
+	for _, step := range steps {
+		if !step.Manual() {
+			automatic++
+		}
+	}
+	if dryRun || automatic == 0 {
+		return nil
+	}
+	if !yes && !confirm(config.In, config.Out, "Apply the automatic steps?") {
+		fmt.Fprintln(config.Out, "Nothing was changed.")
+		return nil
+	}
+
+	needsHelper := false
+	for _, step := range steps {
+		if step.Manual() {
+			continue
+		}
+		if step.RequiresRoot && !config.IsRoot() {
+			needsHelper = true
+			continue
+		}
+		if err := config.ApplyStep(ctx, step); err != nil {
+			return err
+		}
+	}
+	if needsHelper {
+		if _, err := config.FixViaRoot(ctx, chk.UUID()); err != nil {
+			return fmt.Errorf("root helper: %w", err)
+		}
+	}
+
+	passed, err = config.RunCheck(ctx, chk)
+	switch {
+	case err != nil:
+		return fmt.Errorf("fix applied, but the check failed to run: %w", err)
+	case passed:
+		fmt.Fprintf(config.Out, "%s: fixed\n", chk.Name())
+	default:
+		fmt.Fprintf(config.Out, "%s: still failing, follow the manual steps above\n", chk.Name())
+	}
+	return nil
+}
+
+func printFixSteps(w io.Writer, chk check.Check, steps []check.FixStep) {
+	fmt.Fprintf(w, "%s: %s\n\nTo fix it:\n", chk.Name(), chk.FailedMessage())
+	for i, step := range steps {
+		tag := ""
+		switch {
+		case step.Manual():
+			tag = "[manual] "
+		case step.RequiresRoot:
+			tag = "[root] "
+		}
+		fmt.Fprintf(w, "  %d. %s%s\n", i+1, tag, step.Description)
+		if !step.Manual() {
+			for _, line := range strings.Split(strings.TrimSpace(step.String()), "\n") {
+				fmt.Fprintf(w, "     %s\n", line)
+			}
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// confirm asks a yes/no question and defaults to no.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
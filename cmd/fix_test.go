@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+type fixableCheck struct {
+	uuid  string
+	steps []check.FixStep
+}
+
+func (f *fixableCheck) Name() string                 { return "Fixable" }
+func (f *fixableCheck) PassedMessage() string        { return "Fixed" }
+func (f *fixableCheck) FailedMessage() string        { return "Broken" }
+func (f *fixableCheck) Run() error                   { return nil }
+func (f *fixableCheck) Passed() bool                 { return false }
+func (f *fixableCheck) IsRunnable() bool             { return true }
+func (f *fixableCheck) UUID() string                 { return f.uuid }
+func (f *fixableCheck) Status() string               { return "Broken" }
+func (f *fixableCheck) RequiresRoot() bool           { return false }
+func (f *fixableCheck) Remediation() []check.FixStep { return f.steps }
+
+type fixRecorder struct {
+	applied  []string
+	viaRoot  int
+	runCount int
+}
+
+func newFixConfig(chk check.Check, answer string, rec *fixRecorder) (*FixConfig, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &FixConfig{
+		Claims: []claims.Claim{{Title: "Test", Checks: []check.Check{chk}}},
+		In:     strings.NewReader(answer),
+		Out:    out,
+		IsRoot: func() bool { return false },
+		RunCheck: func(context.Context, check.Check) (bool, error) {
+			rec.runCount++
+			// Passes once the fix was applied
+			return rec.runCount > 1, nil
+		},
+		ApplyStep: func(_ context.Context, step check.FixStep) error {
+			rec.applied = append(rec.applied, step.String())
+			return nil
+		},
+		FixViaRoot: func(context.Context, string) (*runner.CheckStatus, error) {
+			rec.viaRoot++
+			return &runner.CheckStatus{Passed: true}, nil
+		},
+	}, out
+}
+
+var fixSteps = []check.FixStep{
+	{Description: "user", Command: []string{"gsettings", "set", "a", "b"}},
+	{Description: "root", Command: []string{"ufw", "enable"}, RequiresRoot: true},
+	{Description: "by hand"},
+}
+
+func Test_runFixCommand_Apply(t *testing.T) {
+	rec := &fixRecorder{}
+	config, out := newFixConfig(&fixableCheck{uuid: "fix", steps: fixSteps}, "y\n", rec)
+
+	assert.NoError(t, runFixCommand(config, "fix", false, false))
+	assert.Equal(t, []string{"gsettings set a b"}, rec.applied)
+	assert.Equal(t, 1, rec.viaRoot)
+	assert.Contains(t, out.String(), "[root] root")
+	assert.Contains(t, out.String(), "[manual] by hand")
+	assert.Contains(t, out.String(), "Fixable: fixed")
+}
+
+func Test_runFixCommand_DryRun(t *testing.T) {
+	rec := &fixRecorder{}
+	config, out := newFixConfig(&fixableCheck{uuid: "fix", steps: fixSteps}, "", rec)
+
+	assert.NoError(t, runFixCommand(config, "fix", true, false))
+	assert.Empty(t, rec.applied)
+	assert.Zero(t, rec.viaRoot)
+	assert.Contains(t, out.String(), "ufw enable")
+}
+
+func Test_runFixCommand_Declined(t *testing.T) {
+	rec := &fixRecorder{}
+	config, out := newFixConfig(&fixableCheck{uuid: "fix", steps: fixSteps}, "n\n", rec)
+
+	assert.NoError(t, runFixCommand(config, "fix", false, false))
+	assert.Empty(t, rec.applied)
+	assert.Contains(t, out.String(), "Nothing was changed.")
+}
+
+func Test_runFixCommand_HelperFailure(t *testing.T) {
+	rec := &fixRecorder{}
+	config, _ := newFixConfig(&fixableCheck{uuid: "fix", steps: fixSteps}, "", rec)
+	config.FixViaRoot = func(context.Context, string) (*runner.CheckStatus, error) {
+		return &runner.CheckStatus{State: check.CheckStateError, Error: "failed to apply fix: boom"}, errors.New("failed to apply fix: boom")
+	}
+	assert.ErrorContains(t, runFixCommand(config, "fix", false, true), "boom")
+
+	config, _ = newFixConfig(&fixableCheck{uuid: "fix", steps: fixSteps}, "", &fixRecorder{})
+	config.FixViaRoot = func(context.Context, string) (*runner.CheckStatus, error) {
+		return &runner.CheckStatus{}, errors.New("failed to connect to root helper")
+	}
+	assert.Error(t, runFixCommand(config, "fix", false, true))
+}
+
+func Test_runFixCommand_NotFound(t *testing.T) {
+	config, _ := newFixConfig(&fixableCheck{uuid: "fix"}, "", &fixRecorder{})
+	assert.Error(t, runFixCommand(config, "missing", false, false))
+}
+
+func Test_runFixCommand_AlreadyPassing(t *testing.T) {
+	config, out := newFixConfig(&fixableCheck{uuid: "fix", steps: fixSteps}, "", &fixRecorder{})
+	config.RunCheck = func(context.Context, check.Check) (bool, error) { return true, nil }
+
+	assert.NoError(t, runFixCommand(config, "fix", false, false))
+	assert.Contains(t, out.String(), "already passing")
+}
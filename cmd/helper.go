@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"net"
 	"os"
+	"time"
 
 	"github.com/ParetoSecurity/agent/runner"
 	shared "github.com/ParetoSecurity/agent/shared"
@@ -11,9 +13,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// helperIdleTimeout is how long the helper waits for another connection before it exits.
+var helperIdleTimeout = 10 * time.Second
+
 // runHelperServer listens on a socket (passed via file descriptor 0) and handles incoming connections.
 // It's designed to be run in a systemd context where systemd provides the socket.
-// The server accepts a single connection, handles it using runner.HandleConnection, and then exits.
 // It logs the socket path and version information upon startup and logs any errors encountered during socket creation or connection acceptance.
 func runHelperServer() {
 	// Get the socket from file descriptor 0
@@ -25,15 +29,30 @@ func runHelperServer() {
 	defer listener.Close()
 	log.WithField("socket", runner.SocketPath).WithField("version", shared.Version).Info("Listening on socket")
 
+	serveHelper(listener, helperIdleTimeout)
+}
+
+// serveHelper handles connections one at a time using runner.HandleConnection
+// until no new connection arrives for idle, so the checks and fixes requested
+// by a run share a single activation of the helper.
+func serveHelper(listener net.Listener, idle time.Duration) {
 	for {
+		if deadline, ok := listener.(interface{ SetDeadline(time.Time) error }); ok {
+			if err := deadline.SetDeadline(time.Now().Add(idle)); err != nil {
+				log.WithError(err).Warn("Failed to set idle timeout")
+			}
+		}
 		conn, err := listener.Accept()
+		if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, net.ErrClosed) {
+			log.Info("No more connections, exiting")
+			return
+		}
 		if err != nil {
 			log.WithError(err).Warn("Failed to accept connection")
 			continue
 		}
 
 		runner.HandleConnection(conn)
-		break
 	}
 }
 
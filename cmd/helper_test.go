@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_serveHelper(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "helper.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	done := make(chan struct{})
+	go func() {
+		serveHelper(listener, 200*time.Millisecond)
+		close(done)
+	}()
+
+	// Several connections are served by the same helper
+	for range 2 {
+		conn, err := net.Dial("unix", socket)
+		assert.NoError(t, err)
+		assert.NoError(t, json.NewEncoder(conn).Encode(runner.HelperRequest{Version: 0, UUIDs: []string{"uuid"}}))
+		var status runner.CheckStatus
+		assert.NoError(t, json.NewDecoder(conn).Decode(&status))
+		assert.Equal(t, runner.ProtocolVersion, status.Version)
+		conn.Close()
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("helper did not exit after being idle")
+	}
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/caarlos0/log"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/renderer"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Print the history of check runs",
+	Long:  "Print the recorded check runs, the timeline of a single check or the difference between two runs.",
+	Run: func(cmd *cobra.Command, args []string) {
+		runs := loadHistory()
+		printRuns(os.Stdout, runs)
+	},
+}
+
+var historyCheckCmd = &cobra.Command{
+	Use:   "check [check UUID]",
+	Short: "Print the timeline of a specific check",
+	Long:  "Print every recorded state of a check along with when it first failed, last passed and since when it is failing.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runs := loadHistory()
+		printCheckTimeline(os.Stdout, runs, args[0])
+	},
+	Annotations: usesChecks,
+}
+
+var historyDiffCmd = &cobra.Command{
+	Use:   "diff [from run] [to run]",
+	Short: "Print the checks that changed between two runs",
+	Long:  "Print the checks that changed between two runs, numbered as in `history`. Defaults to the last two runs.",
+	Args:  cobra.RangeArgs(0, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runs := loadHistory()
+		from, to, err := diffRange(runs, args)
+		if err != nil {
+			log.WithError(err).Fatal("Cannot diff runs")
+		}
+		printRunDiff(os.Stdout, runs[from-1], runs[to-1])
+	},
+	Annotations: usesChecks,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyCheckCmd)
+	historyCmd.AddCommand(historyDiffCmd)
+}
+
+func loadHistory() []shared.HistoryRun {
+	runs, err := shared.LoadHistory()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load history")
+	}
+	return runs
+}
+
+// diffRange resolves the 1-based run numbers to compare.
+func diffRange(runs []shared.HistoryRun, args []string) (int, int, error) {
+	if len(args) == 1 {
+		return 0, 0, fmt.Errorf("both runs must be given")
+	}
+	if len(args) == 0 {
+		if len(runs) < 2 {
+			return 0, 0, fmt.Errorf("at least two runs are needed, found %d", len(runs))
+		}
+		return len(runs) - 1, len(runs), nil
+	}
+
+	numbers := [2]int{}
+	for i, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > len(runs) {
+			return 0, 0, fmt.Errorf("run %q does not exist, pick one between 1 and %d", arg, len(runs))
+		}
+		numbers[i] = n
+	}
+	return numbers[0], numbers[1], nil
+}
+
+// checkName returns the name of the check with the given UUID, or the UUID itself.
+func checkName(uuid string) string {
+	if chk, found := findCheck(claims.All, uuid); found {
+		return chk.Name()
+	}
+	return uuid
+}
+
+func formatHistoryTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func newHistoryTable(w io.Writer, header []string) *tablewriter.Table {
+	table := tablewriter.NewTable(w,
+		tablewriter.WithRenderer(renderer.NewMarkdown()),
+	)
+	table.Header(header)
+	return table
+}
+
+func printRuns(w io.Writer, runs []shared.HistoryRun) {
+	fmt.Fprintf(w, "Loaded %d runs from %s\n\n", len(runs), shared.HistoryPath)
+	if len(runs) == 0 {
+		return
+	}
+
+	data := [][]string{}
+	for i, run := range runs {
+		counts := map[check.CheckState]int{}
+		for _, state := range run.Checks {
+			counts[state]++
+		}
+		data = append(data, []string{
+			strconv.Itoa(i + 1),
+			formatHistoryTime(run.Time),
+			run.Version,
+			strconv.Itoa(counts[check.CheckStatePassed]),
+			strconv.Itoa(counts[check.CheckStateFailed]),
+			strconv.Itoa(counts[check.CheckStateError]),
+			strconv.Itoa(counts[check.CheckStateDisabled]),
+		})
+	}
+	table := newHistoryTable(w, []string{"Run", "Time", "Version", "Passed", "Failed", "Error", "Off"})
+	table.Bulk(data)
+	table.Render()
+}
+
+func printCheckTimeline(w io.Writer, runs []shared.HistoryRun, uuid string) {
+	timeline := shared.CheckTimeline(runs, uuid)
+	trend := shared.Trend(runs, uuid)
+
+	fmt.Fprintf(w, "%s (%s)\n", checkName(uuid), uuid)
+	fmt.Fprintf(w, "First failed: %s\n", formatHistoryTime(trend.FirstFailed))
+	fmt.Fprintf(w, "Last passed: %s\n", formatHistoryTime(trend.LastPassed))
+	if !trend.FailingSince.IsZero() {
+		fmt.Fprintf(w, "Failing since: %s\n", formatHistoryTime(trend.FailingSince))
+	}
+	fmt.Fprintln(w)
+	if len(timeline) == 0 {
+		fmt.Fprintln(w, "No recorded runs for this check.")
+		return
+	}
+
+	data := [][]string{}
+	for _, entry := range timeline {
+		data = append(data, []string{formatHistoryTime(entry.Time), entry.Version, string(entry.State)})
+	}
+	table := newHistoryTable(w, []string{"Time", "Version", "State"})
+	table.Bulk(data)
+	table.Render()
+}
+
+func printRunDiff(w io.Writer, from, to shared.HistoryRun) {
+	fmt.Fprintf(w, "Changes from %s to %s\n\n", formatHistoryTime(from.Time), formatHistoryTime(to.Time))
+	changes := shared.DiffRuns(from, to)
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes.")
+		return
+	}
+
+	data := [][]string{}
+	for _, change := range changes {
+		data = append(data, []string{change.UUID, checkName(change.UUID), historyState(change.From), historyState(change.To)})
+	}
+	table := newHistoryTable(w, []string{"UUID", "Name", "From", "To"})
+	table.Bulk(data)
+	table.Render()
+}
+
+// historyState prints a state of a diff, where an empty state means the check did not run.
+func historyState(state check.CheckState) string {
+	if state == "" {
+		return "-"
+	}
+	return string(state)
+}
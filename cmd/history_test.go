@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+var historyRuns = []shared.HistoryRun{
+	{Time: time.Now().Add(-2 * time.Hour), Version: "1.0.0", Checks: map[string]check.CheckState{
+		"2e46c89a-5461-4865-a92e-3b799c12034a": check.CheckStatePassed,
+		"other":                                check.CheckStatePassed,
+	}},
+	{Time: time.Now().Add(-time.Hour), Version: "1.0.1", Checks: map[string]check.CheckState{
+		"2e46c89a-5461-4865-a92e-3b799c12034a": check.CheckStateFailed,
+		"other":                                check.CheckStatePassed,
+	}},
+}
+
+func Test_diffRange(t *testing.T) {
+	from, to, err := diffRange(historyRuns, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, []int{from, to})
+
+	from, to, err = diffRange(historyRuns, []string{"2", "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 1}, []int{from, to})
+
+	_, _, err = diffRange(historyRuns, []string{"1"})
+	assert.Error(t, err)
+	_, _, err = diffRange(historyRuns, []string{"1", "3"})
+	assert.Error(t, err)
+	_, _, err = diffRange(historyRuns[:1], []string{})
+	assert.Error(t, err)
+}
+
+func Test_printRuns(t *testing.T) {
+	var buf bytes.Buffer
+	printRuns(&buf, historyRuns)
+	assert.Contains(t, buf.String(), "Loaded 2 runs")
+	assert.Contains(t, buf.String(), "1.0.1")
+}
+
+func Test_printCheckTimeline(t *testing.T) {
+	var buf bytes.Buffer
+	printCheckTimeline(&buf, historyRuns, "2e46c89a-5461-4865-a92e-3b799c12034a")
+	assert.Contains(t, buf.String(), "Failing since: "+formatHistoryTime(historyRuns[1].Time))
+	assert.Contains(t, buf.String(), "Last passed: "+formatHistoryTime(historyRuns[0].Time))
+
+	buf.Reset()
+	printCheckTimeline(&buf, historyRuns, "missing")
+	assert.Contains(t, buf.String(), "First failed: never")
+	assert.Contains(t, buf.String(), "No recorded runs")
+}
+
+func Test_printRunDiff(t *testing.T) {
+	var buf bytes.Buffer
+	printRunDiff(&buf, historyRuns[0], historyRuns[1])
+	assert.Contains(t, buf.String(), "2e46c89a-5461-4865-a92e-3b799c12034a")
+	assert.NotContains(t, buf.String(), "other")
+
+	buf.Reset()
+	printRunDiff(&buf, historyRuns[1], historyRuns[1])
+	assert.Contains(t, buf.String(), "No changes.")
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"encoding/json"
+	"fmt"
 	"runtime"
 
 	"github.com/ParetoSecurity/agent/shared"
@@ -14,31 +15,40 @@ var infoCmd = &cobra.Command{
 	Use:   "info",
 	Short: "Print the system information",
 	Run: func(cmd *cobra.Command, args []string) {
+		for _, line := range systemInfo() {
+			log.Info(line)
+		}
+	},
+}
 
-		log.Infof("%s@%s %s", shared.Version, shared.Commit, shared.Date)
-		log.Infof("Built with %s", runtime.Version())
-		log.Infof("Team: %s\n", shared.Config.TeamID)
+// systemInfo returns the lines printed by the info command.
+func systemInfo() []string {
+	lines := []string{
+		fmt.Sprintf("%s@%s %s", shared.Version, shared.Commit, shared.Date),
+		fmt.Sprintf("Built with %s", runtime.Version()),
+		fmt.Sprintf("Team: %s", shared.Config.TeamID),
+	}
 
-		device := shared.CurrentReportingDevice()
-		jsonOutput, err := json.MarshalIndent(device, "", "  ")
-		if err != nil {
-			log.Warn("Failed to marshal host info")
-		}
-		log.Infof("Device Info: %s\n", string(jsonOutput))
+	device := shared.CurrentReportingDevice()
+	jsonOutput, err := json.MarshalIndent(device, "", "  ")
+	if err != nil {
+		log.Warn("Failed to marshal host info")
+	}
+	lines = append(lines, fmt.Sprintf("Device Info: %s", string(jsonOutput)))
 
-		hostInfo, err := sysinfo.Host()
-		if err != nil {
-			log.Warn("Failed to get process information")
-		}
-		envInfo := hostInfo.Info()
-		envInfo.IPs = []string{}  // Exclude IPs for privacy
-		envInfo.MACs = []string{} // Exclude MACs for privacy
-		jsonOutput, err = json.MarshalIndent(envInfo, "", "  ")
-		if err != nil {
-			log.Warn("Failed to marshal host info")
-		}
-		log.Infof("Host Info: %s\n", string(jsonOutput))
-	},
+	hostInfo, err := sysinfo.Host()
+	if err != nil {
+		log.Warn("Failed to get process information")
+		return lines
+	}
+	envInfo := hostInfo.Info()
+	envInfo.IPs = []string{}  // Exclude IPs for privacy
+	envInfo.MACs = []string{} // Exclude MACs for privacy
+	jsonOutput, err = json.MarshalIndent(envInfo, "", "  ")
+	if err != nil {
+		log.Warn("Failed to marshal host info")
+	}
+	return append(lines, fmt.Sprintf("Host Info: %s", string(jsonOutput)))
 }
 
 func init() {
@@ -55,6 +55,7 @@ func runLinkCommand(teamURL string) error {
 		shared.Config.TeamID = ""
 		shared.Config.AuthToken = ""
 		shared.Config.TeamAPI = ""
+		shared.Config.TeamKeys = ""
 		log.Info("Device unlinked, proceeding with new team linking")
 	}
 
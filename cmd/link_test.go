@@ -1,14 +1,28 @@
 package cmd
 
 import (
+	"bytes"
 	"os"
 	"testing"
 
 	"github.com/ParetoSecurity/agent/shared"
+	"github.com/ParetoSecurity/agent/team"
+	"github.com/ParetoSecurity/agent/team/teamtest"
 	"github.com/h2non/gock"
 	"github.com/stretchr/testify/assert"
 )
 
+// linkIssuer signs the auth tokens returned by the mocked enrollment endpoint.
+var linkIssuer = teamtest.NewIssuer("link-key")
+
+// mockTeamKeys serves the key set of linkIssuer on host.
+func mockTeamKeys(host string) {
+	gock.New(host).
+		Get(team.KeySetPath).
+		Reply(200).
+		Body(bytes.NewReader(linkIssuer.KeySet()))
+}
+
 func TestParseEnrollmentURL(t *testing.T) {
 	t.Run("valid URL with invite_id", func(t *testing.T) {
 		inviteID, host, err := parseEnrollmentURL("paretosecurity://linkDevice?invite_id=test-invite-123")
@@ -44,8 +58,11 @@ func TestRunLinkCommand_Success(t *testing.T) {
 	tempDir := t.TempDir()
 	originalConfigPath := shared.ConfigPath
 	shared.ConfigPath = tempDir + "/config.toml"
+	originalOutboxPath := team.OutboxPath
+	team.OutboxPath = tempDir + "/outbox"
 	defer func() {
 		shared.ConfigPath = originalConfigPath
+		team.OutboxPath = originalOutboxPath
 	}()
 
 	// Mock the enrollment endpoint
@@ -53,8 +70,9 @@ func TestRunLinkCommand_Success(t *testing.T) {
 		Post("/api/v1/team/enroll").
 		Reply(200).
 		JSON(map[string]string{
-			"auth": "eyJhbGciOiJIUzUxMiIsInR5cCI6IkpXVCJ9.eyJ0ZWFtX2lkIjoiMjQyOWM0OWUtMzdiYi00MWJiLTkwNzctNmJiNjIwMmUyNTViIiwic3ViIjoianRAZXhhbXBsZS5jb20iLCJpYXQiOjE3MzY0MTc0MTB9.test",
+			"auth": linkIssuer.Token("2429c49e-37bb-41bb-9077-6bb6202e255b"),
 		})
+	mockTeamKeys("https://cloud.paretosecurity.com")
 
 	// Mock the device report endpoint
 	gock.New("https://cloud.paretosecurity.com").
@@ -71,6 +89,7 @@ func TestRunLinkCommand_Success(t *testing.T) {
 		shared.Config.TeamID = ""
 		shared.Config.AuthToken = ""
 		shared.Config.TeamAPI = ""
+		shared.Config.TeamKeys = ""
 	}()
 
 	// Construct the URL with an invite_id
@@ -92,8 +111,11 @@ func TestRunLinkCommand_HostOverrideIgnoredByDefault(t *testing.T) {
 	tempDir := t.TempDir()
 	originalConfigPath := shared.ConfigPath
 	shared.ConfigPath = tempDir + "/config.toml"
+	originalOutboxPath := team.OutboxPath
+	team.OutboxPath = tempDir + "/outbox"
 	defer func() {
 		shared.ConfigPath = originalConfigPath
+		team.OutboxPath = originalOutboxPath
 	}()
 
 	// Only the production host should ever be hit, even though the URL asks for an attacker host.
@@ -101,8 +123,9 @@ func TestRunLinkCommand_HostOverrideIgnoredByDefault(t *testing.T) {
 		Post("/api/v1/team/enroll").
 		Reply(200).
 		JSON(map[string]string{
-			"auth": "eyJhbGciOiJIUzUxMiIsInR5cCI6IkpXVCJ9.eyJ0ZWFtX2lkIjoiMjQyOWM0OWUtMzdiYi00MWJiLTkwNzctNmJiNjIwMmUyNTViIiwic3ViIjoianRAZXhhbXBsZS5jb20iLCJpYXQiOjE3MzY0MTc0MTB9.test",
+			"auth": linkIssuer.Token("2429c49e-37bb-41bb-9077-6bb6202e255b"),
 		})
+	mockTeamKeys("https://cloud.paretosecurity.com")
 	gock.New("https://cloud.paretosecurity.com").
 		Patch("/api/v1/team/2429c49e-37bb-41bb-9077-6bb6202e255b/device").
 		Reply(200).
@@ -115,6 +138,7 @@ func TestRunLinkCommand_HostOverrideIgnoredByDefault(t *testing.T) {
 		shared.Config.TeamID = ""
 		shared.Config.AuthToken = ""
 		shared.Config.TeamAPI = ""
+		shared.Config.TeamKeys = ""
 	}()
 
 	os.Unsetenv(allowHostOverrideEnv)
@@ -131,8 +155,11 @@ func TestRunLinkCommand_HostOverrideAllowedWhenOptedIn(t *testing.T) {
 	tempDir := t.TempDir()
 	originalConfigPath := shared.ConfigPath
 	shared.ConfigPath = tempDir + "/config.toml"
+	originalOutboxPath := team.OutboxPath
+	team.OutboxPath = tempDir + "/outbox"
 	defer func() {
 		shared.ConfigPath = originalConfigPath
+		team.OutboxPath = originalOutboxPath
 	}()
 
 	os.Setenv(allowHostOverrideEnv, "1")
@@ -142,8 +169,9 @@ func TestRunLinkCommand_HostOverrideAllowedWhenOptedIn(t *testing.T) {
 		Post("/api/v1/team/enroll").
 		Reply(200).
 		JSON(map[string]string{
-			"auth": "eyJhbGciOiJIUzUxMiIsInR5cCI6IkpXVCJ9.eyJ0ZWFtX2lkIjoiMjQyOWM0OWUtMzdiYi00MWJiLTkwNzctNmJiNjIwMmUyNTViIiwic3ViIjoianRAZXhhbXBsZS5jb20iLCJpYXQiOjE3MzY0MTc0MTB9.test",
+			"auth": linkIssuer.Token("2429c49e-37bb-41bb-9077-6bb6202e255b"),
 		})
+	mockTeamKeys("https://staging.example.com")
 	gock.New("https://staging.example.com").
 		Patch("/api/v1/team/2429c49e-37bb-41bb-9077-6bb6202e255b/device").
 		Reply(200).
@@ -156,6 +184,7 @@ func TestRunLinkCommand_HostOverrideAllowedWhenOptedIn(t *testing.T) {
 		shared.Config.TeamID = ""
 		shared.Config.AuthToken = ""
 		shared.Config.TeamAPI = ""
+		shared.Config.TeamKeys = ""
 	}()
 
 	err := runLinkCommand("paretosecurity://linkDevice?invite_id=test-invite-123&host=https://staging.example.com")
@@ -171,8 +200,11 @@ func TestRunLinkCommand_RelinkSuccess(t *testing.T) {
 	tempDir := t.TempDir()
 	originalConfigPath := shared.ConfigPath
 	shared.ConfigPath = tempDir + "/config.toml"
+	originalOutboxPath := team.OutboxPath
+	team.OutboxPath = tempDir + "/outbox"
 	defer func() {
 		shared.ConfigPath = originalConfigPath
+		team.OutboxPath = originalOutboxPath
 	}()
 
 	// Mock the enrollment endpoint for the new team
@@ -180,8 +212,9 @@ func TestRunLinkCommand_RelinkSuccess(t *testing.T) {
 		Post("/api/v1/team/enroll").
 		Reply(200).
 		JSON(map[string]string{
-			"auth": "eyJhbGciOiJIUzUxMiIsInR5cCI6IkpXVCJ9.eyJ0ZWFtX2lkIjoibmV3LXRlYW0taWQiLCJzdWIiOiJqc0BleGFtcGxlLmNvbSIsImlhdCI6MTczNjQxNzQxMH0.test",
+			"auth": linkIssuer.Token("new-team-id"),
 		})
+	mockTeamKeys("https://cloud.paretosecurity.com")
 
 	// Mock the device report endpoint for the new team
 	gock.New("https://cloud.paretosecurity.com").
@@ -198,6 +231,7 @@ func TestRunLinkCommand_RelinkSuccess(t *testing.T) {
 		shared.Config.TeamID = ""
 		shared.Config.AuthToken = ""
 		shared.Config.TeamAPI = ""
+		shared.Config.TeamKeys = ""
 	}()
 
 	// Construct the URL with a new invite_id
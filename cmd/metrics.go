@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/runner"
+	"github.com/caarlos0/log"
+	"github.com/spf13/cobra"
+)
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics [--textfile <dir>] [--listen <addr>]",
+	Short: "Export the check states as OpenMetrics",
+	Long: `Export the states of the last check run in the OpenMetrics text format.
+
+By default the metrics are printed to stdout. With --textfile they are written
+atomically to a node_exporter textfile collector directory; set MetricsDir in
+the config to rewrite them after every check run. With --listen they are served
+over HTTP on /metrics.`,
+	Run: func(cc *cobra.Command, args []string) {
+		textfile, _ := cc.Flags().GetString("textfile")
+		listen, _ := cc.Flags().GetString("listen")
+		if err := runMetricsCommand(DefaultMetricsConfig(), textfile, listen); err != nil {
+			log.WithError(err).Fatal("Failed to export metrics")
+		}
+	},
+	Annotations: usesChecks,
+}
+
+func init() {
+	rootCmd.AddCommand(metricsCmd)
+	metricsCmd.Flags().String("textfile", "", "write the metrics to a textfile collector directory")
+	metricsCmd.Flags().String("listen", "", "serve the metrics on this address, for example 127.0.0.1:9184")
+}
+
+// MetricsConfig holds the configuration for the metrics command
+type MetricsConfig struct {
+	Stdout           io.Writer
+	WriteMetrics     func(io.Writer, []claims.Claim) error
+	WriteMetricsFile func(string, []claims.Claim) error
+	ListenAndServe   func(*http.Server) error
+}
+
+// DefaultMetricsConfig returns the default configuration
+func DefaultMetricsConfig() *MetricsConfig {
+	return &MetricsConfig{
+		Stdout:           os.Stdout,
+		WriteMetrics:     runner.WriteMetrics,
+		WriteMetricsFile: runner.WriteMetricsFile,
+		ListenAndServe: func(server *http.Server) error {
+			return server.ListenAndServe()
+		},
+	}
+}
+
+func runMetricsCommand(config *MetricsConfig, textfile, listen string) error {
+	if textfile != "" {
+		if err := config.WriteMetricsFile(textfile, claims.All); err != nil {
+			return err
+		}
+		log.WithField("dir", textfile).Info("Metrics written")
+	}
+	if listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", runner.MetricsHandler(claims.All))
+		server := &http.Server{
+			Addr:              listen,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		log.WithField("addr", listen).Info("Serving metrics on /metrics")
+		return config.ListenAndServe(server)
+	}
+	if textfile == "" {
+		return config.WriteMetrics(config.Stdout, claims.All)
+	}
+	return nil
+}
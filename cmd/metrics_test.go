@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/stretchr/testify/assert"
+)
+
+func testMetricsConfig(calls *[]string) *MetricsConfig {
+	return &MetricsConfig{
+		Stdout: &bytes.Buffer{},
+		WriteMetrics: func(w io.Writer, _ []claims.Claim) error {
+			*calls = append(*calls, "stdout")
+			return nil
+		},
+		WriteMetricsFile: func(dir string, _ []claims.Claim) error {
+			*calls = append(*calls, "textfile:"+dir)
+			return nil
+		},
+		ListenAndServe: func(server *http.Server) error {
+			*calls = append(*calls, "listen:"+server.Addr)
+			return http.ErrServerClosed
+		},
+	}
+}
+
+func Test_runMetricsCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		textfile string
+		listen   string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "stdout", want: []string{"stdout"}},
+		{name: "textfile", textfile: "/var/lib/node_exporter", want: []string{"textfile:/var/lib/node_exporter"}},
+		{name: "listen", listen: "127.0.0.1:9184", want: []string{"listen:127.0.0.1:9184"}, wantErr: true},
+		{name: "textfile and listen", textfile: "/tmp", listen: ":9184", want: []string{"textfile:/tmp", "listen::9184"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			err := runMetricsCommand(testMetricsConfig(&calls), tt.textfile, tt.listen)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, calls)
+		})
+	}
+}
+
+func Test_runMetricsCommand_TextfileError(t *testing.T) {
+	var calls []string
+	config := testMetricsConfig(&calls)
+	config.WriteMetricsFile = func(string, []claims.Claim) error { return errors.New("read-only") }
+
+	assert.EqualError(t, runMetricsCommand(config, "/tmp", ":9184"), "read-only")
+	assert.Empty(t, calls, "the listener does not start when the textfile cannot be written")
+}
+
+func Test_DefaultMetricsConfig(t *testing.T) {
+	config := DefaultMetricsConfig()
+	assert.NotNil(t, config.WriteMetrics)
+	assert.NotNil(t, config.WriteMetricsFile)
+	assert.NotNil(t, config.ListenAndServe)
+}
@@ -4,12 +4,14 @@
 package main
 
 import (
+	"context"
 	"math/rand"
 	"os"
 	"time"
 
 	"fyne.io/systray"
 	"github.com/ParetoSecurity/agent/shared"
+	"github.com/ParetoSecurity/agent/team"
 	"github.com/ParetoSecurity/agent/trayapp"
 	"github.com/caarlos0/log"
 )
@@ -24,6 +26,7 @@ type TrayAppConfig struct {
 	Exit            func(code int)
 	Sleep           func(duration time.Duration)
 	Rand            func(n int) int
+	ReplayReports   func(ctx context.Context)
 }
 
 // DefaultTrayAppConfig returns the default configuration
@@ -37,6 +40,7 @@ func DefaultTrayAppConfig() *TrayAppConfig {
 		Exit:            os.Exit,
 		Sleep:           time.Sleep,
 		Rand:            rand.Intn,
+		ReplayReports:   team.ReplayOnReconnect,
 	}
 }
 
@@ -65,6 +69,11 @@ func (t *TrayApp) Run() {
 	// Scheduled check command
 	go t.checkScheduler()
 
+	// Deliver reports queued while offline once the network is back
+	if t.config.ReplayReports != nil {
+		go t.config.ReplayReports(context.Background())
+	}
+
 	// Initialize the state file
 	if t.config.GetModifiedTime().IsZero() || time.Since(t.config.GetModifiedTime()) > time.Hour {
 		log.Info("Initializing state file...")
@@ -43,6 +43,7 @@ func (l *LinkService) LinkDevice(inviteID string, host string) error {
 		shared.Config.TeamID = ""
 		shared.Config.AuthToken = ""
 		shared.Config.TeamAPI = ""
+		shared.Config.TeamKeys = ""
 		log.Info("Device unlinked, proceeding with new team linking")
 	}
 
@@ -101,9 +102,7 @@ func (l *LinkService) LinkDevice(inviteID string, host string) error {
 				return
 			}
 
-			// Get the lock file path
-			lockDir, _ := shared.UserHomeDir()
-			lockPath := filepath.Join(lockDir, ".paretosecurity-tray.lock")
+			lockPath := shared.TrayLockPath
 
 			// Try to read PID from lock file
 			if data, err := os.ReadFile(lockPath); err == nil {
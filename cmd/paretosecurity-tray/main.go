@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"net/url"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/ParetoSecurity/agent/shared"
@@ -31,8 +30,7 @@ func main() {
 	}
 
 	// Normal tray app execution
-	lockDir, _ := shared.UserHomeDir()
-	if err := shared.OnlyInstance(filepath.Join(lockDir, ".paretosecurity-tray.lock")); err != nil {
+	if err := shared.OnlyInstance(shared.TrayLockPath); err != nil {
 		log.WithError(err).Fatal("An instance of ParetoSecurity tray application is already running.")
 		return
 	}
@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 
+	"github.com/ParetoSecurity/agent/claims"
 	"github.com/ParetoSecurity/agent/cmd"
 	shared "github.com/ParetoSecurity/agent/shared"
 	"github.com/ParetoSecurity/agent/tui"
@@ -11,17 +12,21 @@ import (
 
 // AppConfig holds the configuration for the application
 type AppConfig struct {
-	LoadConfig func() error
-	IsRoot     func() bool
-	Execute    func()
+	LoadConfig  func() error
+	LoadRules   func()
+	LoadPlugins func()
+	IsRoot      func() bool
+	Execute     func()
 }
 
 // DefaultAppConfig returns the default configuration
 func DefaultAppConfig() *AppConfig {
 	return &AppConfig{
-		LoadConfig: shared.LoadConfig,
-		IsRoot:     shared.IsRoot,
-		Execute:    cmd.Execute,
+		LoadConfig:  shared.LoadConfig,
+		LoadRules:   claims.LoadRules,
+		LoadPlugins: claims.LoadPlugins,
+		IsRoot:      shared.IsRoot,
+		Execute:     cmd.Execute,
 	}
 }
 
@@ -45,7 +50,14 @@ func (a *App) Run() error {
 			log.WithError(err).Warn("failed to load config")
 		}
 	}
+	// The root helper needs the rules of the policy that require root
+	a.config.LoadRules()
+	// Plugins run as the user, the root helper never loads them
+	if !a.config.IsRoot() {
+		cmd.LoadPlugins = a.config.LoadPlugins
+	}
 	if len(os.Args) == 1 && os.Getenv("TERM") != "" && os.Getenv("TERM") != "dumb" {
+		cmd.LoadPlugins()
 		tui.Run()
 		return nil
 	}
@@ -3,6 +3,8 @@ package main
 import (
 	"errors"
 	"testing"
+
+	"github.com/ParetoSecurity/agent/cmd"
 )
 
 func TestApp_Run(t *testing.T) {
@@ -12,12 +14,14 @@ func TestApp_Run(t *testing.T) {
 		isRoot        bool
 		expectLogWarn bool
 		expectExecute bool
+		expectPlugins bool
 	}{
 		{
 			name:          "successful run with config loaded",
 			loadConfigErr: nil,
 			isRoot:        false,
 			expectExecute: true,
+			expectPlugins: true,
 		},
 		{
 			name:          "config load error, not root",
@@ -25,6 +29,7 @@ func TestApp_Run(t *testing.T) {
 			isRoot:        false,
 			expectLogWarn: true,
 			expectExecute: true,
+			expectPlugins: true,
 		},
 		{
 			name:          "config load error, is root",
@@ -37,12 +42,20 @@ func TestApp_Run(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var executeCalled bool
+			var executeCalled, rulesLoaded, pluginsLoaded bool
+			cmd.LoadPlugins = func() {}
+			t.Cleanup(func() { cmd.LoadPlugins = func() {} })
 
 			config := &AppConfig{
 				LoadConfig: func() error {
 					return tt.loadConfigErr
 				},
+				LoadRules: func() {
+					rulesLoaded = true
+				},
+				LoadPlugins: func() {
+					pluginsLoaded = true
+				},
 				IsRoot: func() bool {
 					return tt.isRoot
 				},
@@ -61,6 +74,19 @@ func TestApp_Run(t *testing.T) {
 			if executeCalled != tt.expectExecute {
 				t.Errorf("Execute called = %v, expected = %v", executeCalled, tt.expectExecute)
 			}
+
+			if !rulesLoaded {
+				t.Error("expected LoadRules to be called")
+			}
+
+			if pluginsLoaded {
+				t.Error("expected LoadPlugins to be left to the commands that use checks")
+			}
+			cmd.LoadPlugins()
+
+			if pluginsLoaded != tt.expectPlugins {
+				t.Errorf("LoadPlugins called = %v, expected = %v", pluginsLoaded, tt.expectPlugins)
+			}
 		})
 	}
 }
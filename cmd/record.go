@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/runner"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/caarlos0/log"
+)
+
+// reproduceCommand records the run of the selected checks to record, or
+// replays the recording at replay, instead of a regular check run.
+func reproduceCommand(skip, only []string, format, record, replay string) {
+	if format != "" {
+		// Keep stdout clean for the structured output
+		runner.LogWriter = os.Stderr
+	}
+	skipUUIDs, err := claims.Select(claims.All, skip)
+	if err != nil {
+		log.Warnf("Ignoring --skip: %s", err)
+	}
+	onlyUUIDs, err := claims.Select(claims.All, only)
+	if err != nil {
+		log.Fatalf("Invalid --only: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shared.CheckTimeout)
+	defer cancel()
+
+	var results []runner.CheckResult
+	var differences []string
+	if record != "" {
+		results, err = recordChecks(ctx, claims.All, skipUUIDs, onlyUUIDs, record)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to save the recording")
+		}
+		log.Infof("Recorded the run to %s, it holds command outputs and file contents as they are", record)
+	} else {
+		results, differences, err = replayChecks(ctx, claims.All, skipUUIDs, onlyUUIDs, replay)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to replay")
+		}
+	}
+
+	if format != "" {
+		if err := runner.WriteResults(os.Stdout, format, results); err != nil {
+			log.WithError(err).Warn("failed to write results")
+		}
+	}
+	for _, difference := range differences {
+		log.Warn(difference)
+	}
+	if len(differences) > 0 {
+		log.Fatalf("%d checks differ from the recording", len(differences))
+	}
+}
+
+// recordChecks runs the selected checks in this process while recording
+// their interactions with the system, and saves the recording to path.
+func recordChecks(ctx context.Context, all []claims.Claim, skipUUIDs, onlyUUIDs []string, path string) ([]runner.CheckResult, error) {
+	recording := shared.NewRecording()
+	stop := shared.StartRecording(recording)
+	results := runner.RunInProcess(ctx, all, skipUUIDs, onlyUUIDs)
+	stop()
+
+	recorded := make([]shared.RecordedResult, len(results))
+	for i, result := range results {
+		recorded[i] = shared.RecordedResult{UUID: result.UUID, State: result.State, Details: result.Details}
+	}
+	recording.SetResults(recorded)
+	return results, recording.Save(path)
+}
+
+// replayChecks runs the checks against the recording at path and describes
+// the checks whose result differs from the recorded one. Without onlyUUIDs
+// the checks of the recorded run are replayed.
+func replayChecks(ctx context.Context, all []claims.Claim, skipUUIDs, onlyUUIDs []string, path string) ([]runner.CheckResult, []string, error) {
+	recording, err := shared.LoadRecording(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	recorded := map[string]shared.RecordedResult{}
+	replayed := []string{}
+	for _, result := range recording.Results {
+		recorded[result.UUID] = result
+		replayed = append(replayed, result.UUID)
+	}
+	if len(onlyUUIDs) == 0 {
+		onlyUUIDs = replayed
+	}
+
+	stop := shared.StartReplay(recording)
+	results := runner.RunInProcess(ctx, all, skipUUIDs, onlyUUIDs)
+	stop()
+
+	differences := []string{}
+	for _, result := range results {
+		before, found := recorded[result.UUID]
+		if !found || (before.State == result.State && before.Details == result.Details) {
+			continue
+		}
+		differences = append(differences, fmt.Sprintf("%s: recorded %s (%s), replayed %s (%s)",
+			describeCheck(all, result.UUID), before.State, before.Details, result.State, result.Details))
+	}
+	return results, differences, nil
+}
+
+// describeCheck returns the slug of the check with uuid, or uuid if it is unknown.
+func describeCheck(all []claims.Claim, uuid string) string {
+	if chk, found := findCheck(all, uuid); found {
+		return check.SlugOf(chk)
+	}
+	return uuid
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ParetoSecurity/agent/check"
+	linuxchecks "github.com/ParetoSecurity/agent/checks/linux"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_recordAndReplayChecks(t *testing.T) {
+	mocks := shared.RunCommandMocks
+	defer func() { shared.RunCommandMocks = mocks }()
+	shared.RunCommandMocks = []shared.RunCommandMock{
+		{Command: "iptables", Args: []string{"-L", "INPUT", "--line-numbers"}, Out: "Chain INPUT (policy DROP)\nnum  target     prot opt source               destination\n"},
+	}
+	firewall := &linuxchecks.Firewall{}
+	all := []claims.Claim{{Title: "Firewall & Sharing", Checks: []check.Check{firewall, &linuxchecks.Printer{}}}}
+	path := filepath.Join(t.TempDir(), "recording.json")
+
+	results, err := recordChecks(context.Background(), all, nil, []string{firewall.UUID()}, path)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, check.CheckStatePassed, results[0].State)
+
+	recording, err := shared.LoadRecording(path)
+	require.NoError(t, err)
+	assert.Len(t, recording.Commands, 1)
+	assert.Equal(t, []shared.RecordedResult{{UUID: firewall.UUID(), State: check.CheckStatePassed, Details: firewall.Status()}}, recording.Results)
+
+	// The replay does not need the mocks and runs the recorded checks only
+	shared.RunCommandMocks = nil
+	results, differences, err := replayChecks(context.Background(), all, nil, nil, path)
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Empty(t, differences)
+
+	// A different outcome is reported
+	recording.Commands[0].Output = "Chain INPUT (policy ACCEPT)\n"
+	require.NoError(t, recording.Save(path))
+	_, differences, err = replayChecks(context.Background(), all, nil, nil, path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"linux.firewall: recorded pass (Firewall is on), replayed fail (Firewall is off)",
+	}, differences)
+}
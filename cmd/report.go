@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	shared "github.com/ParetoSecurity/agent/shared"
+	team "github.com/ParetoSecurity/agent/team"
+	"github.com/caarlos0/log"
+	"github.com/spf13/cobra"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report [--flush] [--dry-run [--initial] [--json]]",
+	Short: "Show the reports waiting for delivery to the team",
+	Long: `Show the reports waiting for delivery to the team.
+
+Reports that cannot be delivered, for example while the device is offline,
+are queued and retried with an exponential backoff on the next check run and
+when the network changes. Only the newest report is kept. With --flush the
+queued reports are delivered right away, ignoring the backoff.
+
+With --dry-run nothing is sent. Instead the report that would be sent now is
+printed with its target URL, headers and an explanation of every field. The
+report is built from the last check run; --initial shows the device details
+sent when the device is linked. Set RedactHostname or RedactSerial in the
+config to keep the hostname or serial number out of reports.`,
+	Run: func(cc *cobra.Command, args []string) {
+		flush, _ := cc.Flags().GetBool("flush")
+		dryRun, _ := cc.Flags().GetBool("dry-run")
+		if dryRun {
+			initial, _ := cc.Flags().GetBool("initial")
+			asJSON, _ := cc.Flags().GetBool("json")
+			if err := runReportPreview(DefaultReportConfig(), initial, asJSON); err != nil {
+				log.WithError(err).Fatal("Failed to build the report")
+			}
+			return
+		}
+		if err := runReportCommand(DefaultReportConfig(), flush); err != nil {
+			log.WithError(err).Fatal("Failed to deliver the queued reports")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().Bool("flush", false, "deliver the queued reports now")
+	reportCmd.Flags().Bool("dry-run", false, "print the report that would be sent, without sending it")
+	reportCmd.Flags().Bool("initial", false, "with --dry-run, print the report sent when the device is linked")
+	reportCmd.Flags().Bool("json", false, "with --dry-run, print the report as JSON")
+	reportCmd.MarkFlagsMutuallyExclusive("flush", "dry-run")
+}
+
+// ReportConfig holds the configuration for the report command
+type ReportConfig struct {
+	Stdout      io.Writer
+	IsLinked    func() bool
+	LoadOutbox  func() (team.Outbox, error)
+	FlushOutbox func(bool) (team.Outbox, error)
+	LastSuccess func() int64
+	Preview     func(bool) (team.ReportPreview, error)
+}
+
+// DefaultReportConfig returns the default configuration
+func DefaultReportConfig() *ReportConfig {
+	return &ReportConfig{
+		Stdout:      os.Stdout,
+		IsLinked:    shared.IsLinked,
+		LoadOutbox:  team.LoadOutbox,
+		FlushOutbox: team.FlushOutbox,
+		LastSuccess: func() int64 { return shared.Config.LastTeamReportSuccess },
+		Preview:     team.PreviewReport,
+	}
+}
+
+func runReportCommand(config *ReportConfig, flush bool) error {
+	if !config.IsLinked() {
+		return errors.New("this device is not linked to a team")
+	}
+
+	var outbox team.Outbox
+	var err error
+	if flush {
+		// Show what is left in the outbox even when delivery fails
+		outbox, err = config.FlushOutbox(true)
+	} else if outbox, err = config.LoadOutbox(); err != nil {
+		return err
+	}
+	printOutbox(config.Stdout, outbox, config.LastSuccess())
+	return err
+}
+
+// printOutbox writes the queue status in a human readable form.
+func printOutbox(w io.Writer, outbox team.Outbox, lastSuccess int64) {
+	if lastSuccess > 0 {
+		fmt.Fprintf(w, "Last delivered report: %s\n", time.UnixMilli(lastSuccess).Format(time.DateTime))
+	} else {
+		fmt.Fprintln(w, "Last delivered report: never")
+	}
+	if len(outbox.Reports) == 0 {
+		fmt.Fprintln(w, "No reports are waiting for delivery.")
+		return
+	}
+	fmt.Fprintf(w, "%d report(s) waiting for delivery:\n", len(outbox.Reports))
+	for _, report := range outbox.Reports {
+		fmt.Fprintf(w, "  %s queued %s, %d failed attempt(s)", report.Method, report.Queued.Format(time.DateTime), report.Attempts)
+		if !report.NextAttempt.IsZero() {
+			fmt.Fprintf(w, ", next attempt %s", report.NextAttempt.Format(time.DateTime))
+		}
+		fmt.Fprintln(w)
+		if report.LastError != "" {
+			fmt.Fprintf(w, "    last error: %s\n", report.LastError)
+		}
+	}
+}
+
+// runReportPreview prints the report that would be sent, without sending it.
+// It works on unlinked devices too, so the report can be reviewed before
+// linking.
+func runReportPreview(config *ReportConfig, initial, asJSON bool) error {
+	preview, err := config.Preview(initial)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		encoder := json.NewEncoder(config.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(preview)
+	}
+
+	fmt.Fprintf(config.Stdout, "%s %s\n", preview.Method, preview.URL)
+	for _, name := range slices.Sorted(maps.Keys(preview.Headers)) {
+		fmt.Fprintf(config.Stdout, "%s: %s\n", name, preview.Headers[name])
+	}
+	var payload bytes.Buffer
+	if err := json.Indent(&payload, preview.Payload, "", "  "); err != nil {
+		return err
+	}
+	fmt.Fprintf(config.Stdout, "\n%s\n\nFields:\n", payload.String())
+
+	table := newHistoryTable(config.Stdout, []string{"Field", "Description"})
+	for _, field := range preview.Fields {
+		table.Append([]string{field.Path, field.Description})
+	}
+	return table.Render()
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/team"
+	"github.com/stretchr/testify/assert"
+)
+
+func testReportConfig(outbox team.Outbox, flushErr error) (*ReportConfig, *bytes.Buffer, *[]bool) {
+	stdout := &bytes.Buffer{}
+	flushes := &[]bool{}
+	return &ReportConfig{
+		Stdout:     stdout,
+		IsLinked:   func() bool { return true },
+		LoadOutbox: func() (team.Outbox, error) { return outbox, nil },
+		FlushOutbox: func(force bool) (team.Outbox, error) {
+			*flushes = append(*flushes, force)
+			return outbox, flushErr
+		},
+		LastSuccess: func() int64 { return 0 },
+		Preview: func(initial bool) (team.ReportPreview, error) {
+			method := http.MethodPatch
+			if initial {
+				method = http.MethodPut
+			}
+			return team.ReportPreview{
+				Method:  method,
+				URL:     "https://cloud.example.com/api/v1/team/t/device",
+				Headers: map[string]string{"X-Device-Auth": "<redacted>", "Content-Type": "application/json"},
+				Payload: []byte(`{"score":80}`),
+				Fields:  []team.ReportField{{Path: "score", Value: []byte("80"), Description: "Device score"}},
+			}, nil
+		},
+	}, stdout, flushes
+}
+
+func Test_runReportCommand_Status(t *testing.T) {
+	queued := time.Date(2026, 10, 18, 9, 0, 0, 0, time.Local)
+	config, stdout, flushes := testReportConfig(team.Outbox{Reports: []team.QueuedReport{{
+		Method:      http.MethodPatch,
+		Queued:      queued,
+		Attempts:    2,
+		NextAttempt: queued.Add(2 * time.Minute),
+		LastError:   "network is unreachable",
+	}}}, nil)
+
+	assert.NoError(t, runReportCommand(config, false))
+	assert.Empty(t, *flushes)
+	assert.Equal(t, `Last delivered report: never
+1 report(s) waiting for delivery:
+  PATCH queued 2026-10-18 09:00:00, 2 failed attempt(s), next attempt 2026-10-18 09:02:00
+    last error: network is unreachable
+`, stdout.String())
+}
+
+func Test_runReportCommand_Flush(t *testing.T) {
+	config, stdout, flushes := testReportConfig(team.Outbox{}, nil)
+	config.LastSuccess = func() int64 { return time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local).UnixMilli() }
+
+	assert.NoError(t, runReportCommand(config, true))
+	assert.Equal(t, []bool{true}, *flushes, "flushing ignores the backoff")
+	assert.Equal(t, "Last delivered report: 2026-10-18 09:30:00\nNo reports are waiting for delivery.\n", stdout.String())
+}
+
+func Test_runReportCommand_FlushError(t *testing.T) {
+	config, stdout, _ := testReportConfig(team.Outbox{Reports: []team.QueuedReport{{Method: http.MethodPatch}}}, errors.New("offline"))
+
+	assert.EqualError(t, runReportCommand(config, true), "offline")
+	assert.Contains(t, stdout.String(), "1 report(s) waiting for delivery")
+}
+
+func Test_runReportCommand_NotLinked(t *testing.T) {
+	config, _, _ := testReportConfig(team.Outbox{}, nil)
+	config.IsLinked = func() bool { return false }
+
+	assert.ErrorContains(t, runReportCommand(config, true), "not linked")
+}
+
+func Test_runReportPreview(t *testing.T) {
+	config, stdout, flushes := testReportConfig(team.Outbox{}, nil)
+	config.IsLinked = func() bool { return false }
+
+	assert.NoError(t, runReportPreview(config, false, false))
+	assert.Empty(t, *flushes, "nothing is sent")
+	output := stdout.String()
+	assert.Contains(t, output, "PATCH https://cloud.example.com/api/v1/team/t/device\n")
+	assert.Contains(t, output, "Content-Type: application/json\nX-Device-Auth: <redacted>\n")
+	assert.Contains(t, output, "{\n  \"score\": 80\n}")
+	assert.Contains(t, output, "| score | Device score |")
+}
+
+func Test_runReportPreview_JSON(t *testing.T) {
+	config, stdout, _ := testReportConfig(team.Outbox{}, nil)
+
+	assert.NoError(t, runReportPreview(config, true, true))
+	var preview team.ReportPreview
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &preview))
+	assert.Equal(t, http.MethodPut, preview.Method)
+	assert.JSONEq(t, `{"score":80}`, string(preview.Payload))
+}
@@ -8,6 +8,14 @@ import (
 
 var verbose bool
 
+// LoadPlugins adds the checks of plugins to claims.All. Each plugin is
+// started to describe itself, so only commands annotated with usesChecks
+// load them, right before they run.
+var LoadPlugins = func() {}
+
+// usesChecks annotates the commands that run, list or select checks.
+var usesChecks = map[string]string{"checks": "true"}
+
 var rootCmd = &cobra.Command{
 	Use:     "paretosecurity --help --version [command]",
 	Short:   "Pareto Security CLI",
@@ -17,6 +25,9 @@ var rootCmd = &cobra.Command{
 		if verbose {
 			log.SetLevel(log.DebugLevel)
 		}
+		if cmd.Annotations["checks"] == "true" {
+			LoadPlugins()
+		}
 	},
 }
 
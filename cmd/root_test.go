@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadPluginsOnlyForCommandsUsingChecks(t *testing.T) {
+	loaded := 0
+	LoadPlugins = func() { loaded++ }
+	t.Cleanup(func() { LoadPlugins = func() {} })
+
+	for _, cc := range []*cobra.Command{schemaCmd, helperCmd, resetCmd, statusCmd} {
+		rootCmd.PersistentPreRun(cc, nil)
+	}
+	assert.Zero(t, loaded, "commands that do not use checks do not start plugins")
+
+	for _, cc := range []*cobra.Command{checkCmd, explainCmd, enableCmd, unsnoozeCmd} {
+		rootCmd.PersistentPreRun(cc, nil)
+	}
+	assert.Equal(t, 4, loaded)
+}
@@ -11,10 +11,22 @@ var schemaCmd = &cobra.Command{
 	Short: "Output schema for all checks",
 	Long:  "Output schema for all checks in JSON format.",
 	Run: func(cc *cobra.Command, args []string) {
+		details, _ := cc.Flags().GetBool("details")
+		settings, _ := cc.Flags().GetBool("settings")
+		if settings {
+			runner.PrintSettingsSchemaJSON(claims.All)
+			return
+		}
+		if details {
+			runner.PrintSchemaDetailsJSON(claims.All)
+			return
+		}
 		runner.PrintSchemaJSON(claims.All)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(schemaCmd)
+	schemaCmd.Flags().Bool("details", false, "include the slug, severity, weight and other metadata for each check")
+	schemaCmd.Flags().Bool("settings", false, "output the JSON Schema of the check settings in the config")
 }
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/runner"
+	shared "github.com/ParetoSecurity/agent/shared"
+	team "github.com/ParetoSecurity/agent/team"
+	"github.com/caarlos0/log"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve [--socket <path>]",
+	Short: "Serve a local JSON API on a per-user socket",
+	Long: `Serve a local JSON API over HTTP on a Unix socket that only the current user
+can connect to. It lists claims and checks, returns the latest results, runs
+checks and streams result events:
+
+  curl --unix-socket ~/.paretosecurity/api.sock http://localhost/v1/results
+  curl --unix-socket ~/.paretosecurity/api.sock -X POST http://localhost/v1/run
+  curl --unix-socket ~/.paretosecurity/api.sock http://localhost/v1/events
+
+The socket is created in XDG_RUNTIME_DIR/paretosecurity when XDG_RUNTIME_DIR
+is set. A socket given with --socket must be in a directory only the current
+user can enter.`,
+	Run: func(cc *cobra.Command, args []string) {
+		socket, _ := cc.Flags().GetString("socket")
+		if socket == "" {
+			socket = runner.UserSocketPath()
+		}
+		if shared.IsRoot() {
+			log.Warn("Please run this command as a normal user, as it won't report all checks correctly.")
+		}
+		if err := serveCommand(socket); err != nil {
+			log.WithError(err).Fatal("Failed to serve the API")
+		}
+	},
+	Annotations: usesChecks,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().String("socket", "", "socket path, defaults to a socket in a private directory in XDG_RUNTIME_DIR or the home directory")
+}
+
+func serveCommand(socket string) error {
+	listener, err := runner.ListenUserSocket(socket)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := runner.NewAPIServer(claims.All)
+	server.AfterRun = reportAfterRun
+	go team.ReplayOnReconnect(ctx)
+	log.WithField("socket", socket).WithField("version", shared.Version).Info("Serving the API")
+	return server.Serve(ctx, listener)
+}
+
+// reportAfterRun reports a run requested over the API to the team, like `check` does.
+func reportAfterRun() {
+	if !shared.IsLinked() {
+		return
+	}
+	if err := team.ReportToTeam(false); err != nil {
+		log.WithError(err).Warn("failed to report to team")
+	}
+}
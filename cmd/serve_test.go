@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_serveCommand_InvalidSocket(t *testing.T) {
+	// Other users can enter the directory of the socket
+	dir := filepath.Join(t.TempDir(), "public")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	require.NoError(t, os.Chmod(dir, 0o755))
+
+	err := serveCommand(filepath.Join(dir, "api.sock"))
+	assert.ErrorContains(t, err, "private directory")
+}
@@ -3,14 +3,15 @@
 package cmd
 
 import (
+	"context"
 	"os"
-	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
 
 	"fyne.io/systray"
 	"github.com/ParetoSecurity/agent/shared"
+	team "github.com/ParetoSecurity/agent/team"
 	"github.com/ParetoSecurity/agent/trayapp"
 	"github.com/caarlos0/log"
 	"github.com/godbus/dbus/v5"
@@ -21,8 +22,7 @@ var trayiconCmd = &cobra.Command{
 	Use:   "trayicon",
 	Short: "Display the status of the checks in the system tray",
 	Run: func(cc *cobra.Command, args []string) {
-		lockDir, _ := shared.UserHomeDir()
-		if err := shared.OnlyInstance(filepath.Join(lockDir, ".paretosecurity-tray.lock")); err != nil {
+		if err := shared.OnlyInstance(shared.TrayLockPath); err != nil {
 			log.WithError(err).Fatal("An instance of ParetoSecurity tray application is already running.")
 			return
 		}
@@ -32,6 +32,8 @@ var trayiconCmd = &cobra.Command{
 		}
 
 		trayApp := trayapp.NewTrayApp()
+		// Deliver reports queued while offline once the network is back
+		go team.ReplayOnReconnect(context.Background())
 
 		// On Linux, handle potential systray registration failure
 		if runtime.GOOS == "linux" {
@@ -58,6 +60,7 @@ var trayiconCmd = &cobra.Command{
 
 		systray.Run(trayApp.OnReady, onExit)
 	},
+	Annotations: usesChecks,
 }
 
 func checkStatusNotifierSupport() bool {
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"github.com/ParetoSecurity/agent/shared"
+	team "github.com/ParetoSecurity/agent/team"
 	"github.com/caarlos0/log"
 	"github.com/spf13/cobra"
 )
@@ -14,9 +15,13 @@ var unlinkCmd = &cobra.Command{
 		shared.Config.TeamID = ""
 		shared.Config.AuthToken = ""
 		shared.Config.TeamAPI = ""
+		shared.Config.TeamKeys = ""
 		if err := shared.SaveConfig(); err != nil {
 			log.WithError(err).Fatal("failed to save config")
 		}
+		if err := team.ClearOutbox(); err != nil {
+			log.WithError(err).Warn("failed to drop queued reports")
+		}
 	},
 }
 
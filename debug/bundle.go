@@ -0,0 +1,37 @@
+package debug
+
+import (
+	"archive/zip"
+	"io"
+	"time"
+)
+
+// Bundle is a zip archive whose files are masked as they are added.
+type Bundle struct {
+	zip    *zip.Writer
+	masker *Masker
+}
+
+// NewBundle returns a Bundle writing to w and masking with masker.
+func NewBundle(w io.Writer, masker *Masker) *Bundle {
+	return &Bundle{zip: zip.NewWriter(w), masker: masker}
+}
+
+// Add masks content and stores it in the archive as name.
+func (b *Bundle) Add(name string, content []byte) error {
+	file, err := b.zip.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(file, b.masker.Mask(string(content)))
+	return err
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (b *Bundle) Close() error {
+	return b.zip.Close()
+}
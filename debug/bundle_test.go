@@ -0,0 +1,35 @@
+package debug
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundle(t *testing.T) {
+	masker := NewMasker()
+	masker.Add(KindHostname, "jane-laptop")
+
+	var buf bytes.Buffer
+	bundle := NewBundle(&buf, masker)
+	require.NoError(t, bundle.Add("info.txt", []byte("Hostname: jane-laptop\n")))
+	require.NoError(t, bundle.Add("commands/linux.firewall.txt", []byte("$ hostname\njane-laptop\n")))
+	require.NoError(t, bundle.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, archive.File, 2)
+	assert.Equal(t, "info.txt", archive.File[0].Name)
+	assert.Equal(t, "commands/linux.firewall.txt", archive.File[1].Name)
+
+	file, err := archive.File[1].Open()
+	require.NoError(t, err)
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, "$ hostname\n<hostname-1>\n", string(content))
+}
@@ -0,0 +1,113 @@
+// Package debug writes the bundle users attach to bug reports: what the
+// agent knows about the device, its configuration and the raw outputs the
+// checks work with, with personal data masked.
+package debug
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Kinds of masked values.
+const (
+	KindHostname = "hostname"
+	KindUser     = "user"
+	KindSerial   = "serial"
+	KindMAC      = "mac"
+)
+
+var macPattern = regexp.MustCompile(`(?i)\b[0-9a-f]{2}(?:[:-][0-9a-f]{2}){5}\b`)
+
+// systemUsers are not personal and appear in too many places to be masked.
+var systemUsers = []string{"root", "nobody"}
+
+// Masker replaces personal values with placeholders such as <hostname-1>.
+// The same value always gets the same placeholder, so masked files can still
+// be correlated with each other. Values are matched case-insensitively.
+type Masker struct {
+	mutex        sync.Mutex
+	placeholders map[string]string
+	counts       map[string]int
+	pattern      *regexp.Regexp
+}
+
+// NewMasker returns a Masker that masks MAC addresses and nothing else until
+// values are added.
+func NewMasker() *Masker {
+	return &Masker{placeholders: map[string]string{}, counts: map[string]int{}}
+}
+
+// Add registers value to be masked as kind. Values shorter than three
+// characters and well-known system users are ignored.
+func (m *Masker) Add(kind, value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < 3 || (kind == KindUser && slices.Contains(systemUsers, value)) {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.placeholder(kind, value)
+	m.pattern = nil
+}
+
+// placeholder returns the placeholder of value, assigning the next one of
+// kind if value is new.
+func (m *Masker) placeholder(kind, value string) string {
+	key := strings.ToLower(value)
+	if placeholder, found := m.placeholders[key]; found {
+		return placeholder
+	}
+	m.counts[kind]++
+	placeholder := fmt.Sprintf("<%s-%d>", kind, m.counts[kind])
+	m.placeholders[key] = placeholder
+	return placeholder
+}
+
+// Mask replaces every registered value and every MAC address in s.
+func (m *Masker) Mask(s string) string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	s = macPattern.ReplaceAllStringFunc(s, func(mac string) string {
+		return m.placeholder(KindMAC, strings.ReplaceAll(strings.ToLower(mac), "-", ":"))
+	})
+	if pattern := m.compile(); pattern != nil {
+		s = pattern.ReplaceAllStringFunc(s, func(value string) string {
+			return m.placeholders[strings.ToLower(value)]
+		})
+	}
+	return s
+}
+
+// compile builds the pattern matching the registered values, longest first
+// so that a hostname wins over its short form. Values only match as whole
+// words, so a user named "sam" does not mask "sample".
+func (m *Masker) compile() *regexp.Regexp {
+	if m.pattern != nil {
+		return m.pattern
+	}
+	values := []string{}
+	for value, placeholder := range m.placeholders {
+		if !strings.HasPrefix(placeholder, "<"+KindMAC+"-") {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	slices.SortFunc(values, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+	alternatives := make([]string, len(values))
+	for i, value := range values {
+		alternatives[i] = regexp.QuoteMeta(value)
+	}
+	m.pattern = regexp.MustCompile(`(?i)\b(?:` + strings.Join(alternatives, "|") + `)\b`)
+	return m.pattern
+}
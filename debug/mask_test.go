@@ -0,0 +1,40 @@
+package debug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMasker(t *testing.T) {
+	masker := NewMasker()
+	masker.Add(KindHostname, "jane-laptop")
+	masker.Add(KindHostname, "jane-laptop.example.com")
+	masker.Add(KindUser, "jane\n")
+	masker.Add(KindUser, "root")
+	masker.Add(KindSerial, "C0")
+	masker.Add(KindSerial, "PF2ABCDE")
+
+	assert.Equal(t,
+		"<hostname-2> (<hostname-1>) /home/<user-1> janet root <serial-1> C0",
+		masker.Mask("jane-laptop.example.com (JANE-LAPTOP) /home/jane janet root pf2abcde C0"),
+	)
+}
+
+func TestMasker_MACs(t *testing.T) {
+	masker := NewMasker()
+
+	first := masker.Mask("link/ether 3c:22:fb:0a:1b:2c brd ff:ff:ff:ff:ff:ff")
+	second := masker.Mask("Physical Address: 3C-22-FB-0A-1B-2C")
+
+	assert.Equal(t, "link/ether <mac-1> brd <mac-2>", first)
+	assert.Equal(t, "Physical Address: <mac-1>", second)
+}
+
+func TestMasker_AddAfterMask(t *testing.T) {
+	masker := NewMasker()
+	assert.Equal(t, "jane", masker.Mask("jane"))
+
+	masker.Add(KindUser, "jane")
+	assert.Equal(t, "<user-1>", masker.Mask("jane"))
+}
@@ -0,0 +1,220 @@
+package debug
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/runner"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/pelletier/go-toml"
+)
+
+// Redacted replaces secrets in the redacted config.
+const Redacted = "<redacted>"
+
+// DesktopVariables are the environment variables that describe the desktop session.
+var DesktopVariables = []string{
+	"XDG_CURRENT_DESKTOP",
+	"XDG_SESSION_DESKTOP",
+	"DESKTOP_SESSION",
+	"XDG_SESSION_TYPE",
+	"WAYLAND_DISPLAY",
+	"DISPLAY",
+}
+
+// NewSystemMasker returns a Masker for the hostname, the current user, the
+// user that ran sudo and the serial number of this device.
+func NewSystemMasker() *Masker {
+	masker := NewMasker()
+	if hostname, err := os.Hostname(); err == nil {
+		masker.Add(KindHostname, hostname)
+		short, _, _ := strings.Cut(hostname, ".")
+		masker.Add(KindHostname, short)
+	}
+	if current, err := user.Current(); err == nil {
+		// Windows user names are prefixed with the domain
+		_, name, found := strings.Cut(current.Username, `\`)
+		if !found {
+			name = current.Username
+		}
+		masker.Add(KindUser, name)
+	}
+	masker.Add(KindUser, os.Getenv("USER"))
+	masker.Add(KindUser, os.Getenv("SUDO_USER"))
+	if serial, err := shared.SystemSerial(); err == nil {
+		masker.Add(KindSerial, serial)
+	}
+	return masker
+}
+
+// RedactedConfig encodes config without the auth token, the team keys and
+// the webhook secrets. Webhook URLs keep only their host, since the path
+// often carries a token.
+func RedactedConfig(config shared.ParetoConfig) ([]byte, error) {
+	if config.AuthToken != "" {
+		config.AuthToken = Redacted
+	}
+	if config.TeamKeys != "" {
+		config.TeamKeys = Redacted
+	}
+	webhooks := make([]shared.Webhook, len(config.Webhooks))
+	for i, hook := range config.Webhooks {
+		hook.URL = redactURL(hook.URL)
+		if hook.Secret != "" {
+			hook.Secret = Redacted
+		}
+		webhooks[i] = hook
+	}
+	config.Webhooks = webhooks
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(config); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// redactURL keeps the scheme and host of rawURL.
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return Redacted
+	}
+	return parsed.Scheme + "://" + parsed.Host + "/" + Redacted
+}
+
+// Desktop describes the platform and the desktop session.
+func Desktop() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "GOOS=%s\nGOARCH=%s\n", runtime.GOOS, runtime.GOARCH)
+	for _, name := range DesktopVariables {
+		fmt.Fprintf(&buf, "%s=%s\n", name, os.Getenv(name))
+	}
+	return buf.String()
+}
+
+// Services returns the status of the systemd units of the agent: the user
+// timer and tray icon, and the socket of the root helper.
+func Services() string {
+	if runtime.GOOS != "linux" {
+		return "The agent uses systemd units only on Linux.\n"
+	}
+	var buf strings.Builder
+	for _, args := range [][]string{
+		{"--user", "status", "--no-pager", "paretosecurity-user.timer", "paretosecurity-user.service", "paretosecurity-trayicon.service"},
+		{"status", "--no-pager", "paretosecurity.socket", "paretosecurity.service"},
+	} {
+		// systemctl status exits non-zero for inactive units, the output says why
+		output, err := shared.RunCommand("systemctl", args...)
+		writeCommand(&buf, shared.CommandRun{Name: "systemctl", Args: args, Output: output, Err: err})
+	}
+	return buf.String()
+}
+
+// Helper reports whether the root helper is installed and reachable, and
+// whether it runs a check that requires root.
+func Helper(ctx context.Context, all []claims.Claim) string {
+	if runtime.GOOS != "linux" {
+		return "The root helper is used only on Linux.\n"
+	}
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "Running as root: %t\n", shared.IsRoot())
+	fmt.Fprintf(&buf, "Socket unit enabled: %t\n", runner.IsSocketServicePresent())
+
+	conn, err := net.DialTimeout("unix", runner.SocketPath, time.Second)
+	if err != nil {
+		fmt.Fprintf(&buf, "Socket %s: %v\n", runner.SocketPath, err)
+		return buf.String()
+	}
+	conn.Close()
+	fmt.Fprintf(&buf, "Socket %s: reachable\n", runner.SocketPath)
+
+	for _, claim := range all {
+		for _, chk := range claim.Checks {
+			if !chk.RequiresRoot() {
+				continue
+			}
+			status, err := runner.RunCheckViaRootContext(ctx, chk.UUID())
+			if err != nil {
+				fmt.Fprintf(&buf, "Root check %s: %v\n", check.SlugOf(chk), err)
+			} else {
+				fmt.Fprintf(&buf, "Root check %s: passed=%t %s\n", check.SlugOf(chk), status.Passed, status.Details)
+			}
+			return buf.String()
+		}
+	}
+	fmt.Fprintln(&buf, "No check requires root.")
+	return buf.String()
+}
+
+// CommandOutputs runs every check in this process and returns, keyed by
+// check slug, the result and the full output of every command it ran. The
+// checks run one at a time so their commands are not mixed up, and their
+// results are not stored. Checks that require root only see what the
+// current user may see.
+func CommandOutputs(ctx context.Context, all []claims.Claim) map[string]string {
+	outputs := map[string]string{}
+	for _, claim := range all {
+		for _, chk := range claim.Checks {
+			if err := ctx.Err(); err != nil {
+				return outputs
+			}
+			var runs []shared.CommandRun
+			stop := shared.ObserveCommands(func(run shared.CommandRun) { runs = append(runs, run) })
+			result := "not runnable"
+			if chk.IsRunnable() {
+				if err := runner.RunCheck(ctx, chk); err != nil {
+					result = "error: " + err.Error()
+				} else if chk.Passed() {
+					result = "pass: " + chk.Status()
+				} else {
+					result = "fail: " + chk.Status()
+				}
+			}
+			stop()
+
+			var buf strings.Builder
+			fmt.Fprintf(&buf, "# %s (%s)\n# Result: %s\n", chk.Name(), chk.UUID(), result)
+			if chk.RequiresRoot() && !shared.IsRoot() {
+				fmt.Fprintln(&buf, "# Requires root, ran as the current user")
+			}
+			if len(runs) == 0 {
+				fmt.Fprintln(&buf, "# No commands were run")
+			}
+			for _, run := range runs {
+				fmt.Fprintln(&buf)
+				writeCommand(&buf, run)
+			}
+			outputs[check.SlugOf(chk)] = buf.String()
+		}
+	}
+	return outputs
+}
+
+// writeCommand writes a command, how it exited and its output.
+func writeCommand(buf *strings.Builder, run shared.CommandRun) {
+	fmt.Fprintf(buf, "$ %s\n", strings.TrimSpace(run.Name+" "+strings.Join(run.Args, " ")))
+	code, exited := shared.ExitCode(run.Err)
+	switch {
+	case exited:
+		fmt.Fprintf(buf, "exit %d\n", code)
+	case run.Err != nil:
+		fmt.Fprintf(buf, "error: %v\n", run.Err)
+	default:
+		fmt.Fprintln(buf, "exit 0")
+	}
+	buf.WriteString(run.Output)
+	if run.Output != "" && !strings.HasSuffix(run.Output, "\n") {
+		fmt.Fprintln(buf)
+	}
+}
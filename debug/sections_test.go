@@ -0,0 +1,106 @@
+package debug
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commandCheck runs its commands through shared.RunCommand.
+type commandCheck struct {
+	name     string
+	commands [][]string
+	runnable bool
+	passed   bool
+}
+
+func (c *commandCheck) Name() string          { return c.name }
+func (c *commandCheck) PassedMessage() string { return c.name + " passed" }
+func (c *commandCheck) FailedMessage() string { return c.name + " failed" }
+func (c *commandCheck) Passed() bool          { return c.passed }
+func (c *commandCheck) IsRunnable() bool      { return c.runnable }
+func (c *commandCheck) UUID() string          { return "uuid-" + c.name }
+func (c *commandCheck) RequiresRoot() bool    { return false }
+func (c *commandCheck) Status() string {
+	if c.passed {
+		return c.PassedMessage()
+	}
+	return c.FailedMessage()
+}
+func (c *commandCheck) Run() error {
+	c.passed = true
+	for _, command := range c.commands {
+		if _, err := shared.RunCommand(command[0], command[1:]...); err != nil {
+			c.passed = false
+		}
+	}
+	return nil
+}
+
+func TestCommandOutputs(t *testing.T) {
+	mocks := shared.RunCommandMocks
+	defer func() { shared.RunCommandMocks = mocks }()
+	shared.RunCommandMocks = []shared.RunCommandMock{
+		{Command: "ufw", Args: []string{"status"}, Out: "Status: active"},
+		{Command: "nft", Args: []string{"list", "ruleset"}, Err: errors.New("permission denied")},
+	}
+	firewall := &commandCheck{name: "Firewall", runnable: true, commands: [][]string{{"ufw", "status"}, {"nft", "list", "ruleset"}}}
+	printer := &commandCheck{name: "Printer"}
+	all := []claims.Claim{{Title: "Firewall & Sharing", Checks: []check.Check{firewall, printer}}}
+
+	outputs := CommandOutputs(context.Background(), all)
+
+	assert.Equal(t, `# Firewall (uuid-Firewall)
+# Result: fail: Firewall failed
+
+$ ufw status
+exit 0
+Status: active
+
+$ nft list ruleset
+error: permission denied
+`, outputs["firewall"])
+	assert.Equal(t, "# Printer (uuid-Printer)\n# Result: not runnable\n# No commands were run\n", outputs["printer"])
+}
+
+func TestRedactedConfig(t *testing.T) {
+	config := shared.ParetoConfig{
+		TeamID:    "team-id",
+		AuthToken: "secret-token",
+		TeamKeys:  `{"keys":[]}`,
+		Webhooks: []shared.Webhook{
+			{URL: "https://hooks.slack.com/services/T000/B000/XXXX", Format: "slack", Secret: "hmac-secret"},
+			{URL: "not a url"},
+		},
+	}
+
+	content, err := RedactedConfig(config)
+	require.NoError(t, err)
+
+	redacted := string(content)
+	assert.Contains(t, redacted, "team-id")
+	assert.Contains(t, redacted, "https://hooks.slack.com/<redacted>")
+	for _, secret := range []string{"secret-token", `"keys"`, "T000", "hmac-secret", "not a url"} {
+		assert.NotContains(t, redacted, secret)
+	}
+	// The config itself is left untouched
+	assert.Equal(t, "hmac-secret", config.Webhooks[0].Secret)
+}
+
+func TestDesktop(t *testing.T) {
+	t.Setenv("XDG_CURRENT_DESKTOP", "GNOME")
+	t.Setenv("XDG_SESSION_TYPE", "wayland")
+
+	desktop := Desktop()
+
+	assert.Contains(t, desktop, "XDG_CURRENT_DESKTOP=GNOME\n")
+	assert.Contains(t, desktop, "XDG_SESSION_TYPE=wayland\n")
+	assert.True(t, strings.HasPrefix(desktop, "GOOS="))
+}
@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ParetoSecurity/agent/shared"
+)
+
+// ActionPrefix marks the action keys of the agent's notifications, so that
+// actions of other applications are ignored.
+const ActionPrefix = "paretosecurity."
+
+// Kinds of actions offered by the notification about failing checks.
+const (
+	ActionDetails = "details"
+	ActionSnooze  = "snooze"
+	ActionDisable = "disable"
+)
+
+// Action is a button of a notification. Key is reported back by Serve when
+// the button is clicked.
+type Action struct {
+	Key   string
+	Label string
+}
+
+// ActionKey returns the key of an action of kind on the checks with the given UUIDs.
+func ActionKey(kind string, uuids ...string) string {
+	return ActionPrefix + kind + ":" + strings.Join(uuids, ",")
+}
+
+// ParseActionKey returns the kind and the check UUIDs of an action key made
+// by ActionKey. It reports false for keys of other applications.
+func ParseActionKey(key string) (kind string, uuids []string, ok bool) {
+	rest, found := strings.CutPrefix(key, ActionPrefix)
+	if !found {
+		return "", nil, false
+	}
+	kind, list, _ := strings.Cut(rest, ":")
+	if kind == "" {
+		return "", nil, false
+	}
+	uuids = []string{}
+	for _, uuid := range strings.Split(list, ",") {
+		if uuid != "" {
+			uuids = append(uuids, uuid)
+		}
+	}
+	return kind, uuids, true
+}
+
+// Notification is a notification raised by Serve.
+type Notification struct {
+	Title   string
+	Body    string
+	Actions []Action
+}
+
+// Regressions describes the checks that started failing. A single check can
+// be shown, snoozed or disabled from the notification; several checks can be
+// shown in the console or snoozed together. Checks required by the policy can
+// be neither snoozed nor disabled.
+func Regressions(checks []shared.LastState) Notification {
+	names := []string{}
+	optional := []string{}
+	for _, state := range checks {
+		names = append(names, "• "+state.Name)
+		if !shared.IsCheckRequired(state.UUID) {
+			optional = append(optional, state.UUID)
+		}
+	}
+	notification := Notification{Body: strings.Join(names, "\n")}
+	if len(checks) == 1 {
+		notification.Title = "A check started failing"
+		notification.Actions = []Action{{Key: ActionKey(ActionDetails, checks[0].UUID), Label: "Show details"}}
+	} else {
+		notification.Title = fmt.Sprintf("%d checks started failing", len(checks))
+		notification.Actions = []Action{{Key: ActionKey(ActionDetails), Label: "Show details"}}
+	}
+	if len(optional) > 0 {
+		notification.Actions = append(notification.Actions, Action{Key: ActionKey(ActionSnooze, optional...), Label: "Snooze 1 day"})
+	}
+	if len(checks) == 1 && len(optional) == 1 {
+		notification.Actions = append(notification.Actions, Action{Key: ActionKey(ActionDisable, optional...), Label: "Disable check"})
+	}
+	return notification
+}
+
+// raised are the action keys of the notifications raised by Serve, by
+// notification ID. Any application on the session bus can emit an
+// ActionInvoked signal, so only the actions offered by these notifications
+// are handled.
+type raised map[uint32][]string
+
+func (r raised) add(id uint32, notification Notification) {
+	keys := []string{}
+	for _, action := range notification.Actions {
+		keys = append(keys, action.Key)
+	}
+	r[id] = keys
+}
+
+// offers reports whether notification id was raised with the action key.
+func (r raised) offers(id uint32, key string) bool {
+	return slices.Contains(r[id], key)
+}
@@ -1,8 +1,11 @@
 package notify
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
+	"strings"
+	"testing"
 )
 
 // Toast displays a system notification on macOS using AppleScript.
@@ -10,3 +13,26 @@ func Toast(message string) {
 	cmd := exec.Command("osascript", "-e", fmt.Sprintf(`display notification "%s" with title "Pareto Security"`, message))
 	cmd.Run()
 }
+
+// Notify displays a notification with the given title and body.
+func Notify(title, body string) {
+	if testing.Testing() {
+		return
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace
+	cmd := exec.Command("osascript", "-e", fmt.Sprintf(`display notification "%s" with title "%s"`, escape(body), escape(title)))
+	cmd.Run()
+}
+
+// Serve raises the notifications received on notifications until ctx is
+// done. Their actions are left out on macOS, where notifications have no actions.
+func Serve(ctx context.Context, notifications <-chan Notification, _ func(key string)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-notifications:
+			Notify(notification.Title, notification.Body)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package notify
 
 import (
+	"context"
+	"errors"
+	"testing"
+
 	"github.com/caarlos0/log"
 	"github.com/godbus/dbus/v5"
 )
@@ -34,3 +38,118 @@ func Toast(body string) {
 		log.WithError(call.Err).Error("failed to send notification")
 	}
 }
+
+// Notify sends a desktop notification with the given title and body.
+// Desktops without a session bus, like servers, are skipped quietly.
+func Notify(title, body string) {
+	if testing.Testing() {
+		return
+	}
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		log.WithError(err).Debug("no session bus, skipping notification")
+		return
+	}
+	defer conn.Close()
+	if _, err := raise(conn, Notification{Title: title, Body: body}); err != nil {
+		log.WithError(err).Warn("failed to send notification")
+	}
+}
+
+// Serve raises the notifications received on notifications, until ctx is
+// done, and calls handle with the key of every action clicked in them. The
+// notifications are raised from the connection that listens for clicks, as
+// notification servers may send the ActionInvoked signal only to the
+// connection that raised the notification. Signals not sent by the
+// notification server, or for notifications or actions Serve did not raise,
+// are ignored.
+func Serve(ctx context.Context, notifications <-chan Notification, handle func(key string)) error {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, member := range []string{"ActionInvoked", "NotificationClosed"} {
+		if err := conn.AddMatchSignal(
+			dbus.WithMatchObjectPath("/org/freedesktop/Notifications"),
+			dbus.WithMatchInterface("org.freedesktop.Notifications"),
+			dbus.WithMatchMember(member),
+		); err != nil {
+			return err
+		}
+	}
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	// server is the unique bus name of the notification server that
+	// assigned the IDs in sent
+	server := ""
+	sent := raised{}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-notifications:
+			id, err := raise(conn, notification)
+			if err != nil {
+				log.WithError(err).Warn("failed to send notification")
+				continue
+			}
+			owner := ""
+			if err := conn.BusObject().Call("org.freedesktop.DBus.GetNameOwner", 0, "org.freedesktop.Notifications").Store(&owner); err != nil {
+				log.WithError(err).Warn("failed to look up the notification server")
+				continue
+			}
+			if owner != server {
+				// A restarted server reuses IDs of the notifications it forgot
+				server, sent = owner, raised{}
+			}
+			sent.add(id, notification)
+		case signal, ok := <-signals:
+			if !ok {
+				return errors.New("session bus connection closed")
+			}
+			if server == "" || signal.Sender != server || len(signal.Body) == 0 {
+				continue
+			}
+			id, ok := signal.Body[0].(uint32)
+			if !ok {
+				continue
+			}
+			switch signal.Name {
+			case "org.freedesktop.Notifications.ActionInvoked":
+				// ActionInvoked carries the notification ID and the action key
+				if len(signal.Body) != 2 {
+					continue
+				}
+				if key, ok := signal.Body[1].(string); ok && sent.offers(id, key) {
+					handle(key)
+				}
+			case "org.freedesktop.Notifications.NotificationClosed":
+				delete(sent, id)
+			}
+		}
+	}
+}
+
+// raise sends the notification on conn and returns its ID.
+func raise(conn *dbus.Conn, notification Notification) (uint32, error) {
+	pairs := []string{}
+	for _, action := range notification.Actions {
+		pairs = append(pairs, action.Key, action.Label)
+	}
+	var id uint32
+	err := conn.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications").Call(
+		"org.freedesktop.Notifications.Notify", 0,
+		"ParetoSecurity",          // app_name
+		uint32(0),                 // replaces_id
+		"dialog-warning",          // app_icon
+		notification.Title,        // summary
+		notification.Body,         // body
+		pairs,                     // actions, as key and label pairs
+		map[string]dbus.Variant{}, // hints
+		int32(-1),                 // expire_timeout, as the notification server prefers
+	).Store(&id)
+	return id, err
+}
@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ParetoSecurity/agent/shared"
+)
+
+func TestParseActionKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		kind  string
+		uuids []string
+		ok    bool
+	}{
+		{ActionKey(ActionSnooze, "a", "b"), ActionSnooze, []string{"a", "b"}, true},
+		{ActionKey(ActionDetails), ActionDetails, []string{}, true},
+		{"default", "", nil, false},
+		{ActionPrefix, "", nil, false},
+	}
+	for _, tt := range tests {
+		kind, uuids, ok := ParseActionKey(tt.key)
+		if kind != tt.kind || ok != tt.ok || !reflect.DeepEqual(uuids, tt.uuids) {
+			t.Errorf("ParseActionKey(%q) = %q, %v, %v, want %q, %v, %v", tt.key, kind, uuids, ok, tt.kind, tt.uuids, tt.ok)
+		}
+	}
+}
+
+func TestRegressions(t *testing.T) {
+	a := shared.LastState{UUID: "a", Name: "Check A"}
+	b := shared.LastState{UUID: "b", Name: "Check B", HasError: true}
+
+	got := Regressions([]shared.LastState{a})
+	want := Notification{
+		Title: "A check started failing",
+		Body:  "• Check A",
+		Actions: []Action{
+			{Key: "paretosecurity.details:a", Label: "Show details"},
+			{Key: "paretosecurity.snooze:a", Label: "Snooze 1 day"},
+			{Key: "paretosecurity.disable:a", Label: "Disable check"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Regressions(a) = %+v, want %+v", got, want)
+	}
+
+	got = Regressions([]shared.LastState{a, b})
+	want = Notification{
+		Title: "2 checks started failing",
+		Body:  "• Check A\n• Check B",
+		Actions: []Action{
+			{Key: "paretosecurity.details:", Label: "Show details"},
+			{Key: "paretosecurity.snooze:a,b", Label: "Snooze 1 day"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Regressions(a, b) = %+v, want %+v", got, want)
+	}
+
+	// Checks required by the policy can be neither snoozed nor disabled
+	policy := shared.Policy
+	defer func() { shared.Policy = policy }()
+	shared.Policy.Required = []string{"a"}
+	if actions := Regressions([]shared.LastState{a}).Actions; len(actions) != 1 {
+		t.Errorf("Regressions(a) offers %v for a required check", actions)
+	}
+	if key := Regressions([]shared.LastState{a, b}).Actions[1].Key; key != "paretosecurity.snooze:b" {
+		t.Errorf("Regressions(a, b) snoozes with %q, want only b", key)
+	}
+}
+
+func TestRaisedOffers(t *testing.T) {
+	sent := raised{}
+	sent.add(7, Regressions([]shared.LastState{{UUID: "a", Name: "Check A"}}))
+
+	tests := []struct {
+		id   uint32
+		key  string
+		want bool
+	}{
+		{7, ActionKey(ActionSnooze, "a"), true},
+		{7, ActionKey(ActionDisable, "a"), true},
+		// Keys the notification did not offer, like a forged glob
+		{7, ActionKey(ActionDisable, "*"), false},
+		// Notifications raised by someone else
+		{8, ActionKey(ActionSnooze, "a"), false},
+	}
+	for _, tt := range tests {
+		if got := sent.offers(tt.id, tt.key); got != tt.want {
+			t.Errorf("offers(%d, %q) = %v, want %v", tt.id, tt.key, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package notify
 
 import (
+	"context"
+	"testing"
+
 	"github.com/caarlos0/log"
 	"github.com/kolide/toast"
 )
@@ -19,3 +22,31 @@ func Toast(message string) {
 		return
 	}
 }
+
+// Notify displays a notification with the given title and body.
+func Notify(title, body string) {
+	if testing.Testing() {
+		return
+	}
+	notification := toast.Notification{
+		AppID:   "Pareto Security",
+		Title:   title,
+		Message: body,
+	}
+	if err := notification.Push(); err != nil {
+		log.WithError(err).Warn("failed to send notification")
+	}
+}
+
+// Serve raises the notifications received on notifications until ctx is
+// done. Their actions are left out on Windows, where toast actions can only launch URLs.
+func Serve(ctx context.Context, notifications <-chan Notification, _ func(key string)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-notifications:
+			Notify(notification.Title, notification.Body)
+		}
+	}
+}
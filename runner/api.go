@@ -0,0 +1,349 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/caarlos0/log"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Types of APIEvent.
+const (
+	EventRunStarted   = "run_started"
+	EventResult       = "result"
+	EventRunFinished  = "run_finished"
+	EventStateChanged = "state_changed"
+)
+
+// eventBuffer is how many events a slow subscriber may lag behind before
+// events are dropped for it.
+const eventBuffer = 64
+
+// APICheck describes a check in the claims listing of the local API.
+type APICheck struct {
+	UUID string `json:"uuid"`
+	SchemaCheck
+	Disabled bool `json:"disabled"`
+	Required bool `json:"required"`
+}
+
+// APIClaim is a claim and its checks in the claims listing of the local API.
+type APIClaim struct {
+	Title  string     `json:"title"`
+	Checks []APICheck `json:"checks"`
+}
+
+// APIResults are the latest results of the checks.
+type APIResults struct {
+	Time    time.Time     `json:"time"`
+	Score   int           `json:"score"`
+	Results []CheckResult `json:"results"`
+}
+
+// APIEvent is one line of the event stream. Result is set for EventResult
+// and Results for EventRunFinished.
+type APIEvent struct {
+	Type    string        `json:"type"`
+	Time    time.Time     `json:"time"`
+	Result  *CheckResult  `json:"result,omitempty"`
+	Results []CheckResult `json:"results,omitempty"`
+}
+
+// apiError is the body of an error response.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// APIServer serves the local JSON API:
+//
+//	GET  /v1/claims          claims and their checks
+//	GET  /v1/results         latest result of every check
+//	GET  /v1/results/{uuid}  latest result of one check
+//	POST /v1/run[?uuid=]     run all checks or one check and return the results
+//	GET  /v1/events          newline-delimited JSON stream of APIEvent
+//
+// Runs are serialized; a run requested while another one is in progress
+// starts once the first one finishes.
+type APIServer struct {
+	Claims []claims.Claim
+	// RunChecks runs the checks, it is Check unless overridden for testing
+	RunChecks func(context.Context, []claims.Claim, []string, []string) []CheckResult
+	// LoadConfig reloads the config before every run, so the server picks up
+	// checks enabled or disabled since it started
+	LoadConfig func() error
+	// AfterRun is called after every run requested over the API
+	AfterRun func()
+
+	runMutex    sync.Mutex
+	subMutex    sync.Mutex
+	subscribers map[chan APIEvent]struct{}
+}
+
+// NewAPIServer returns a server for the checks of all.
+func NewAPIServer(all []claims.Claim) *APIServer {
+	return &APIServer{
+		Claims:      all,
+		RunChecks:   Check,
+		LoadConfig:  shared.LoadConfig,
+		subscribers: make(map[chan APIEvent]struct{}),
+	}
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *APIServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/claims", s.handleClaims)
+	mux.HandleFunc("GET /v1/results", s.handleResults)
+	mux.HandleFunc("GET /v1/results/{uuid}", s.handleResult)
+	mux.HandleFunc("POST /v1/run", s.handleRun)
+	mux.HandleFunc("GET /v1/events", s.handleEvents)
+	return mux
+}
+
+// Serve serves the API on listener until ctx is done. Changes to the state
+// file, for example by a scheduled run, are published as events.
+func (s *APIServer) Serve(ctx context.Context, listener net.Listener) error {
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go s.watchState(ctx, shared.StatePath)
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	err := server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Run runs all checks, or only the check with onlyUUID, and publishes the
+// results as events.
+func (s *APIServer) Run(onlyUUID string) []CheckResult {
+	s.runMutex.Lock()
+	defer s.runMutex.Unlock()
+
+	if err := s.LoadConfig(); err != nil {
+		log.WithError(err).Warn("failed to reload config")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shared.CheckTimeout)
+	defer cancel()
+	ctx = WithResultHandler(ctx, func(result CheckResult) {
+		s.publish(APIEvent{Type: EventResult, Result: &result})
+	})
+
+	var onlyUUIDs []string
+	if onlyUUID != "" {
+		onlyUUIDs = []string{onlyUUID}
+	}
+	s.publish(APIEvent{Type: EventRunStarted})
+	results := s.RunChecks(ctx, s.Claims, []string{}, onlyUUIDs)
+	s.publish(APIEvent{Type: EventRunFinished, Results: results})
+	if s.AfterRun != nil {
+		s.AfterRun()
+	}
+	return results
+}
+
+// LatestResults returns the results recorded in the state file.
+func (s *APIServer) LatestResults() APIResults {
+	states := shared.GetLastStates()
+	latest := APIResults{
+		Time:    shared.GetModifiedTime(),
+		Score:   shared.DeviceScore(states),
+		Results: []CheckResult{},
+	}
+	for _, claim := range s.Claims {
+		for _, chk := range claim.Checks {
+			if state, found := states[chk.UUID()]; found {
+				latest.Results = append(latest.Results, stateResult(claim, chk, state))
+			}
+		}
+	}
+	return latest
+}
+
+// stateResult converts the recorded state of a check to a result.
+func stateResult(claim claims.Claim, chk check.Check, state shared.LastState) CheckResult {
+	severity := state.Severity
+	if severity == "" {
+		severity = check.SeverityOf(chk)
+	}
+	return CheckResult{
+		Claim:    claim.Title,
+		UUID:     chk.UUID(),
+		Name:     chk.Name(),
+		State:    state.State(),
+		Details:  state.Details,
+		Severity: severity,
+		Root:     chk.RequiresRoot(),
+	}
+}
+
+func (s *APIServer) handleClaims(w http.ResponseWriter, r *http.Request) {
+	all := []APIClaim{}
+	for _, claim := range s.Claims {
+		apiClaim := APIClaim{Title: claim.Title, Checks: []APICheck{}}
+		for _, chk := range claim.Checks {
+			policy := shared.CheckPolicyFor(chk.UUID())
+			apiClaim.Checks = append(apiClaim.Checks, APICheck{
+				UUID: chk.UUID(),
+				SchemaCheck: SchemaCheck{
+					Slug:          check.SlugOf(chk),
+					Name:          chk.Name(),
+					PassedMessage: chk.PassedMessage(),
+					FailedMessage: chk.FailedMessage(),
+					Severity:      check.SeverityOf(chk),
+					Weight:        check.WeightOf(chk),
+					RequiresRoot:  chk.RequiresRoot(),
+					Settings:      schemaSettings(chk),
+				},
+				Disabled: policy.Disabled,
+				Required: policy.Required,
+			})
+		}
+		all = append(all, apiClaim)
+	}
+	writeAPIJSON(w, http.StatusOK, all)
+}
+
+func (s *APIServer) handleResults(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, s.LatestResults())
+}
+
+func (s *APIServer) handleResult(w http.ResponseWriter, r *http.Request) {
+	uuid := r.PathValue("uuid")
+	if _, found := findCheck(s.Claims, uuid); !found {
+		writeAPIJSON(w, http.StatusNotFound, apiError{Error: "unknown check " + uuid})
+		return
+	}
+	latest := s.LatestResults()
+	index := slices.IndexFunc(latest.Results, func(result CheckResult) bool { return result.UUID == uuid })
+	if index < 0 {
+		writeAPIJSON(w, http.StatusNotFound, apiError{Error: "check " + uuid + " has not run yet"})
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, latest.Results[index])
+}
+
+func (s *APIServer) handleRun(w http.ResponseWriter, r *http.Request) {
+	uuid := r.URL.Query().Get("uuid")
+	if uuid != "" {
+		if _, found := findCheck(s.Claims, uuid); !found {
+			writeAPIJSON(w, http.StatusNotFound, apiError{Error: "unknown check " + uuid})
+			return
+		}
+	}
+	writeAPIJSON(w, http.StatusOK, s.Run(uuid))
+}
+
+func (s *APIServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIJSON(w, http.StatusInternalServerError, apiError{Error: "streaming is not supported"})
+		return
+	}
+	events := s.subscribe()
+	defer s.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			if err := encoder.Encode(event); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *APIServer) subscribe() chan APIEvent {
+	s.subMutex.Lock()
+	defer s.subMutex.Unlock()
+	events := make(chan APIEvent, eventBuffer)
+	s.subscribers[events] = struct{}{}
+	return events
+}
+
+func (s *APIServer) unsubscribe(events chan APIEvent) {
+	s.subMutex.Lock()
+	defer s.subMutex.Unlock()
+	delete(s.subscribers, events)
+}
+
+// publish sends event to every subscriber, skipping subscribers that are too slow.
+func (s *APIServer) publish(event APIEvent) {
+	event.Time = time.Now()
+	s.subMutex.Lock()
+	defer s.subMutex.Unlock()
+	for events := range s.subscribers {
+		select {
+		case events <- event:
+		default:
+			log.WithField("type", event.Type).Debug("Dropping event for slow subscriber")
+		}
+	}
+}
+
+// watchState publishes EventStateChanged whenever the state file is written.
+// The directory is watched because the state file may not exist yet.
+func (s *APIServer) watchState(ctx context.Context, statePath string) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.WithError(err).Warn("Failed to create state file watcher")
+		return
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(statePath)); err != nil {
+		log.WithError(err).WithField("path", statePath).Warn("Failed to watch state file")
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) == filepath.Clean(statePath) && event.Has(fsnotify.Write) {
+				s.publish(APIEvent{Type: EventStateChanged})
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.WithError(err).Warn("State file watcher error")
+		}
+	}
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.WithError(err).Debug("Failed to write response")
+	}
+}
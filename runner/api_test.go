@@ -0,0 +1,267 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func apiClaims() []claims.Claim {
+	return []claims.Claim{{Title: "Test Case", Checks: []check.Check{
+		&DummyCheck{name: "Pass", runnable: true, passedVal: true, statusMsg: "ok", uuid: "uuid-api-pass"},
+		&DummyCheck{name: "Fail", runnable: true, passedVal: false, statusMsg: "bad", uuid: "uuid-api-fail"},
+	}}}
+}
+
+// newTestAPIServer returns a server for apiClaims that runs checks without
+// touching the user's config, state or history.
+func newTestAPIServer(t *testing.T) (*APIServer, *httptest.Server) {
+	t.Helper()
+	withMetricsPaths(t)
+	server := NewAPIServer(apiClaims())
+	server.LoadConfig = func() error { return nil }
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+	return server, httpServer
+}
+
+func getJSON(t *testing.T, url string, body any) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(body))
+	return resp.StatusCode
+}
+
+func TestAPIClaims(t *testing.T) {
+	_, httpServer := newTestAPIServer(t)
+
+	var all []APIClaim
+	assert.Equal(t, http.StatusOK, getJSON(t, httpServer.URL+"/v1/claims", &all))
+	require.Len(t, all, 1)
+	assert.Equal(t, "Test Case", all[0].Title)
+	assert.Equal(t, "uuid-api-pass", all[0].Checks[0].UUID)
+	assert.Equal(t, "Pass", all[0].Checks[0].Name)
+	assert.Equal(t, check.DefaultSeverity, all[0].Checks[0].Severity)
+}
+
+func TestAPIRunAndResults(t *testing.T) {
+	server, httpServer := newTestAPIServer(t)
+	afterRun := 0
+	server.AfterRun = func() { afterRun++ }
+
+	resp, err := http.Post(httpServer.URL+"/v1/run", "application/json", nil)
+	require.NoError(t, err)
+	var results []CheckResult
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, results, 2)
+	assert.Equal(t, 1, afterRun)
+
+	var latest APIResults
+	assert.Equal(t, http.StatusOK, getJSON(t, httpServer.URL+"/v1/results", &latest))
+	assert.Len(t, latest.Results, 2)
+	assert.Equal(t, check.CheckStateFailed, latest.Results[1].State)
+
+	var one CheckResult
+	assert.Equal(t, http.StatusOK, getJSON(t, httpServer.URL+"/v1/results/uuid-api-pass", &one))
+	assert.Equal(t, check.CheckStatePassed, one.State)
+	assert.Equal(t, "Test Case", one.Claim)
+}
+
+func TestAPIRunOne(t *testing.T) {
+	_, httpServer := newTestAPIServer(t)
+
+	resp, err := http.Post(httpServer.URL+"/v1/run?uuid=uuid-api-fail", "application/json", nil)
+	require.NoError(t, err)
+	var results []CheckResult
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+	resp.Body.Close()
+	require.Len(t, results, 1)
+	assert.Equal(t, "uuid-api-fail", results[0].UUID)
+}
+
+func TestAPIUnknownCheck(t *testing.T) {
+	_, httpServer := newTestAPIServer(t)
+
+	var apiErr apiError
+	assert.Equal(t, http.StatusNotFound, getJSON(t, httpServer.URL+"/v1/results/uuid-missing", &apiErr))
+	assert.Equal(t, "unknown check uuid-missing", apiErr.Error)
+
+	resp, err := http.Post(httpServer.URL+"/v1/run?uuid=uuid-missing", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(httpServer.URL + "/v1/run")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestAPIEvents(t *testing.T) {
+	server, httpServer := newTestAPIServer(t)
+
+	resp, err := http.Get(httpServer.URL + "/v1/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	go server.Run("")
+
+	scanner := bufio.NewScanner(resp.Body)
+	var types []string
+	for scanner.Scan() {
+		var event APIEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		types = append(types, event.Type)
+		if event.Type == EventResult {
+			assert.NotNil(t, event.Result)
+		}
+		if event.Type == EventRunFinished {
+			assert.Len(t, event.Results, 2)
+			break
+		}
+	}
+	assert.Equal(t, []string{EventRunStarted, EventResult, EventResult, EventRunFinished}, types)
+}
+
+func TestAPIPublishSkipsSlowSubscribers(t *testing.T) {
+	server := NewAPIServer(nil)
+	events := server.subscribe()
+	for range eventBuffer + 1 {
+		server.publish(APIEvent{Type: EventStateChanged})
+	}
+	assert.Len(t, events, eventBuffer)
+
+	server.unsubscribe(events)
+	server.publish(APIEvent{Type: EventStateChanged})
+	assert.Len(t, events, eventBuffer)
+}
+
+func TestAPIServeUserSocket(t *testing.T) {
+	withMetricsPaths(t)
+	dir, err := os.MkdirTemp("", "pareto")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "api.sock")
+
+	listener, err := ListenUserSocket(socket)
+	require.NoError(t, err)
+	info, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	_, err = ListenUserSocket(socket)
+	assert.ErrorContains(t, err, "already listening")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- NewAPIServer(apiClaims()).Serve(ctx, listener) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Get("http://localhost/v1/claims")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
+
+func TestListenUserSocket_Stale(t *testing.T) {
+	dir, err := os.MkdirTemp("", "pareto")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "api.sock")
+	require.NoError(t, os.WriteFile(socket, nil, 0o600))
+
+	listener, err := ListenUserSocket(socket)
+	require.NoError(t, err)
+	listener.Close()
+}
+
+func TestUserSocketPath(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	assert.Equal(t, "/run/user/1000/paretosecurity/api.sock", UserSocketPath())
+
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	assert.Equal(t, filepath.Join(".paretosecurity", "api.sock"), filepath.Join(filepath.Base(filepath.Dir(UserSocketPath())), filepath.Base(UserSocketPath())))
+}
+
+func TestListenUserSocket_PrivateDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	// A missing directory is created for the current user only
+	socket := filepath.Join(dir, "private", "api.sock")
+	listener, err := ListenUserSocket(socket)
+	require.NoError(t, err)
+	listener.Close()
+	info, err := os.Stat(filepath.Dir(socket))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+	public := filepath.Join(dir, "public")
+	require.NoError(t, os.Mkdir(public, 0o755))
+	require.NoError(t, os.Chmod(public, 0o755))
+	_, err = ListenUserSocket(filepath.Join(public, "api.sock"))
+	assert.ErrorContains(t, err, "private directory")
+}
+
+func TestCheckResultHandler(t *testing.T) {
+	withMetricsPaths(t)
+	var handled []string
+	ctx := WithResultHandler(context.Background(), func(result CheckResult) {
+		handled = append(handled, result.UUID)
+	})
+
+	Check(ctx, apiClaims(), []string{}, nil)
+
+	assert.Equal(t, []string{"uuid-api-pass", "uuid-api-fail"}, handled)
+}
+
+func TestAPIWatchState(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state")
+	server := NewAPIServer(nil)
+	events := server.subscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.watchState(ctx, statePath)
+
+	// The watcher starts asynchronously, so keep writing until it reports
+	deadline := time.After(5 * time.Second)
+	for {
+		require.NoError(t, os.WriteFile(statePath, []byte("updated"), 0o600))
+		select {
+		case event := <-events:
+			assert.Equal(t, EventStateChanged, event.Type)
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("no event for the state file")
+		}
+	}
+}
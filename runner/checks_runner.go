@@ -3,13 +3,18 @@ package runner
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/fatih/color"
 
 	"github.com/ParetoSecurity/agent/check"
+	sharedchecks "github.com/ParetoSecurity/agent/checks/shared"
 	"github.com/ParetoSecurity/agent/claims"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
@@ -44,16 +49,55 @@ func wrapStatus(chk check.Check, err error) string {
 	return fmt.Sprintf("%s %s", color.RedString("[FAIL]"), chk.Status())
 }
 
-// Check runs a series of checks concurrently for a list of claims.
+type resultHandlerKey struct{}
+
+// WithResultHandler returns a context that makes Check pass every result to
+// handle as soon as it is available, in the order the checks are declared.
+func WithResultHandler(ctx context.Context, handle func(CheckResult)) context.Context {
+	return context.WithValue(ctx, resultHandlerKey{}, handle)
+}
+
+// resultHandler returns the handler set with WithResultHandler, or a no-op.
+func resultHandler(ctx context.Context) func(CheckResult) {
+	if handle, ok := ctx.Value(resultHandlerKey{}).(func(CheckResult)); ok {
+		return handle
+	}
+	return func(CheckResult) {}
+}
+
+// Concurrency is the number of checks the runner executes at the same time.
+var Concurrency = 4
+
+// Check runs a series of checks for a list of claims on a bounded worker pool.
 //
 // It iterates over each claim provided in claimsTorun and, for each claim,
-// over its associated checks. Each check is executed in its own goroutine.
-func Check(ctx context.Context, claimsTorun []claims.Claim, skipUUIDs []string, onlyUUID string) {
+// over its associated checks. Checks run after the checks they depend on, share
+// one set of facts for the run, and their log lines are printed in claim order.
+// Checks that require root are sent to the root helper in a single batch.
+// Once the checks ran, the run is stored, see storeRun, and announced, see
+// announceRun. The outcome of every check that was considered is returned in
+// claim order.
+func Check(ctx context.Context, claimsTorun []claims.Claim, skipUUIDs, onlyUUIDs []string) []CheckResult {
 
-	var checkLogger = log.New(os.Stdout)
-	var wg sync.WaitGroup
+	var checkLogger = log.New(LogWriter)
 	checkLogger.Info("Starting checks...")
+	started := time.Now()
+	// The states are updated as checks finish, keep the previous ones to
+	// tell what changed for the webhooks and notifications
+	previous := maps.Clone(shared.GetLastStates())
+
+	jobs := selectJobs(checkLogger, claimsTorun, skipUUIDs, onlyUUIDs)
+	results := runJobs(ctx, checkLogger, jobs)
+	storeRun(claimsTorun, jobs, results, time.Since(started))
+	announceRun(ctx, previous, results)
+
+	checkLogger.Info("Checks completed.")
+	return results
+}
 
+// selectJobs schedules the checks of claimsTorun that are not skipped.
+func selectJobs(checkLogger *log.Logger, claimsTorun []claims.Claim, skipUUIDs, onlyUUIDs []string) []*job {
+	jobs := []*job{}
 	for _, claim := range claimsTorun {
 		for _, chk := range claim.Checks {
 			// Skip checks that are skipped
@@ -61,82 +105,237 @@ func Check(ctx context.Context, claimsTorun []claims.Claim, skipUUIDs []string,
 				checkLogger.Warn(fmt.Sprintf("%s: %s > %s", claim.Title, chk.Name(), fmt.Sprintf("%s Skipped by the command rule", color.YellowString("[SKIP]"))))
 				continue
 			}
-			wg.Add(1)
-			go func(claim claims.Claim, chk check.Check) {
-				defer wg.Done()
-				select {
-				case <-ctx.Done():
-					return
-				default:
-
-					// Skip checks that are not in the onlyUUID list
-					if onlyUUID != "" && onlyUUID != chk.UUID() {
-						checkLogger.Debug(fmt.Sprintf("%s: %s > %s", claim.Title, chk.Name(), fmt.Sprintf("%s Skipped by the command rule", color.YellowString("[SKIP]"))))
-						return
-					}
-
-					// Skip checks that are not runnable or are disabled
-					if !chk.IsRunnable() || shared.IsCheckDisabled(chk.UUID()) {
-						reason := chk.Status()
-						if shared.IsCheckDisabled(chk.UUID()) {
-							reason = "Disabled by the config file"
-						}
-						checkLogger.Warn(fmt.Sprintf("%s: %s > %s %s", claim.Title, chk.Name(), color.YellowString("[DISABLED]"), reason))
-						return
-					}
-					hasError := false
-					if chk.RequiresRoot() {
-						log.Debug("Running check via root helper")
-						// Run as root
-						status, err := RunCheckViaRoot(chk.UUID())
-						if err != nil {
-							hasError = true
-							checkLogger.Info(fmt.Sprintf("[root] %s: %s > %s", claim.Title, chk.Name(), wrapStatusRoot(status, chk, err)))
-						} else {
-							if status.Passed {
-								checkLogger.Info(fmt.Sprintf("[root] %s: %s > %s", claim.Title, chk.Name(), wrapStatusRoot(status, chk, err)))
-							} else {
-								checkLogger.Warn(fmt.Sprintf("[root] %s: %s > %s", claim.Title, chk.Name(), wrapStatusRoot(status, chk, err)))
-							}
-
-						}
-						shared.UpdateLastState(shared.LastState{
-							UUID:     chk.UUID(),
-							Name:     chk.Name(),
-							Passed:   status.Passed,
-							HasError: hasError,
-							Details:  status.Details,
-						})
-					} else {
-						if err := chk.Run(); err != nil {
-							hasError = true
-							checkLogger.Info(fmt.Sprintf("%s: %s > %s", claim.Title, chk.Name(), wrapStatus(chk, err)))
-						} else {
-							if chk.Passed() {
-								checkLogger.Info(fmt.Sprintf("%s: %s > %s", claim.Title, chk.Name(), wrapStatus(chk, err)))
-							} else {
-								checkLogger.Warn(fmt.Sprintf("%s: %s > %s", claim.Title, chk.Name(), wrapStatus(chk, err)))
-							}
-						}
-						shared.UpdateLastState(shared.LastState{
-							UUID:     chk.UUID(),
-							Name:     chk.Name(),
-							Passed:   chk.Passed(),
-							HasError: hasError,
-							Details:  chk.Status(),
-						})
-					}
-
-				}
-			}(claim, chk)
+			// Skip checks that are not in the onlyUUIDs list
+			if len(onlyUUIDs) > 0 && !lo.Contains(onlyUUIDs, chk.UUID()) {
+				checkLogger.Debug(fmt.Sprintf("%s: %s > %s", claim.Title, chk.Name(), fmt.Sprintf("%s Skipped by the command rule", color.YellowString("[SKIP]"))))
+				continue
+			}
+			jobs = append(jobs, newJob(claim, chk))
+		}
+	}
+	return jobs
+}
+
+// runJobs runs the jobs on the worker pool and prints their log lines in the
+// order the checks are declared. It returns once every job finished.
+func runJobs(ctx context.Context, checkLogger *log.Logger, jobs []*job) []CheckResult {
+	facts := sharedchecks.NewSystemFacts()
+	for _, j := range jobs {
+		useFacts(j.chk, facts)
+	}
+
+	ordered, cyclic := orderJobs(jobs)
+	batch := startRootBatch(ctx, ordered)
+	for _, j := range cyclic {
+		err := errors.New("dependency cycle detected")
+		j.logf(log.InfoLevel, "%s: %s > %s", j.claim.Title, j.chk.Name(), wrapStatus(j.chk, err))
+		j.record(check.CheckStateError, err.Error())
+		close(j.done)
+	}
+
+	queue := make(chan *job)
+	var wg sync.WaitGroup
+	for range max(Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				runJob(ctx, j, batch)
+				close(j.done)
+			}
+		}()
+	}
+	go func() {
+		for _, j := range ordered {
+			queue <- j
+		}
+		close(queue)
+	}()
+
+	// Print log lines in the order checks are declared, as soon as they are available
+	results := []CheckResult{}
+	handleResult := resultHandler(ctx)
+	for _, j := range jobs {
+		<-j.done
+		j.flush(checkLogger)
+		if j.result != nil {
+			results = append(results, *j.result)
+			handleResult(*j.result)
 		}
 	}
 	wg.Wait()
+
+	// Checks that timed out reset their facts once their run returns
+	for _, j := range jobs {
+		useFacts(j.chk, nil)
+	}
+	return results
+}
+
+// storeRun saves the states, evidence and history of a run, and the metrics
+// when they are enabled.
+func storeRun(claimsTorun []claims.Claim, jobs []*job, results []CheckResult, duration time.Duration) {
 	if err := shared.CommitLastState(); err != nil {
 		log.WithError(err).Warn("failed to commit last state")
 	}
+	if err := shared.SaveEvidence(runEvidence(jobs, time.Now())); err != nil {
+		log.WithError(err).Warn("failed to save evidence")
+	}
+	if len(results) > 0 {
+		if err := shared.AppendHistory(historyRun(results, duration)); err != nil {
+			log.WithError(err).Warn("failed to append run history")
+		}
+	}
+	if shared.Config.MetricsDir != "" {
+		if err := WriteMetricsFile(shared.Config.MetricsDir, claimsTorun); err != nil {
+			log.WithError(err).Warn("failed to write metrics")
+		}
+	}
+}
 
-	checkLogger.Info("Checks completed.")
+// announceRun notifies the configured webhooks when the set of failing checks
+// changed, and names the checks that started failing in a desktop notification.
+func announceRun(ctx context.Context, previous map[string]shared.LastState, results []CheckResult) {
+	change := newPostureChange(previous, results)
+	notifyWebhooks(ctx, change)
+	notifyRegressions(change, time.Now())
+}
+
+// runEvidence collects the evidence of the checks that ran.
+func runEvidence(jobs []*job, now time.Time) map[string]shared.CheckEvidence {
+	evidence := map[string]shared.CheckEvidence{}
+	for _, j := range jobs {
+		if j.result == nil || j.result.State == check.CheckStateDisabled {
+			continue
+		}
+		entries := j.evidence
+		if entries == nil {
+			entries = []check.Evidence{}
+		}
+		evidence[j.chk.UUID()] = shared.CheckEvidence{Time: now, Evidence: entries}
+	}
+	return evidence
+}
+
+// historyRun converts the results of a run into a history record.
+func historyRun(results []CheckResult, duration time.Duration) shared.HistoryRun {
+	run := shared.HistoryRun{
+		Time:     time.Now(),
+		Version:  shared.Version,
+		Duration: duration,
+		Checks:   make(map[string]check.CheckState, len(results)),
+	}
+	for _, result := range results {
+		run.Checks[result.UUID] = result.State
+	}
+	return run
+}
+
+// runJob executes a single scheduled check once its dependencies have finished.
+// Root checks are not run here but collected from the batch sent to the helper.
+func runJob(ctx context.Context, j *job, batch *rootBatch) {
+	for _, dep := range j.deps {
+		select {
+		case <-dep.done:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return
+	default:
+	}
+
+	claim, chk := j.claim, j.chk
+
+	// A run of the check that timed out earlier may still change its state
+	if !chk.RequiresRoot() && !whenIdle(chk, func() {}) {
+		j.logf(log.InfoLevel, "%s: %s > %s", claim.Title, chk.Name(), wrapStatus(chk, ErrCheckBusy))
+		j.record(check.CheckStateError, ErrCheckBusy.Error())
+		return
+	}
+
+	// Skip checks that are not runnable or are disabled
+	if policy := shared.CheckPolicyFor(chk.UUID()); !chk.IsRunnable() || policy.Disabled {
+		reason := chk.Status()
+		if policy.Disabled {
+			reason = policy.Describe()
+		}
+		j.logf(log.WarnLevel, "%s: %s > %s %s", claim.Title, chk.Name(), color.YellowString("[DISABLED]"), reason)
+		j.record(check.CheckStateDisabled, reason)
+		return
+	}
+
+	hasError := false
+	started := time.Now()
+	if chk.RequiresRoot() {
+		log.Debug("Waiting for check from root helper")
+		// Run as root
+		status, err := batch.wait(ctx, chk.UUID())
+		if err != nil {
+			hasError = true
+			j.logf(log.InfoLevel, "[root] %s: %s > %s", claim.Title, chk.Name(), wrapStatusRoot(status, chk, err))
+		} else {
+			if status.Passed {
+				j.logf(log.InfoLevel, "[root] %s: %s > %s", claim.Title, chk.Name(), wrapStatusRoot(status, chk, err))
+			} else {
+				j.logf(log.WarnLevel, "[root] %s: %s > %s", claim.Title, chk.Name(), wrapStatusRoot(status, chk, err))
+			}
+
+		}
+		shared.UpdateLastState(shared.LastState{
+			UUID:     chk.UUID(),
+			Name:     chk.Name(),
+			Passed:   status.Passed,
+			HasError: hasError,
+			Details:  status.Details,
+			Severity: check.SeverityOf(chk),
+			Weight:   check.WeightOf(chk),
+		})
+		details := status.Details
+		if err != nil {
+			details = err.Error()
+		}
+		j.evidence = status.Evidence
+		j.record(resultState(status.Passed, hasError), details)
+	} else {
+		trail := &check.Trail{}
+		err := RunCheck(check.WithTrail(ctx, trail), chk)
+		j.evidence = trail.Entries()
+		if err != nil {
+			hasError = true
+			j.logf(log.InfoLevel, "%s: %s > %s", claim.Title, chk.Name(), wrapStatus(chk, err))
+		} else {
+			if chk.Passed() {
+				j.logf(log.InfoLevel, "%s: %s > %s", claim.Title, chk.Name(), wrapStatus(chk, err))
+			} else {
+				j.logf(log.WarnLevel, "%s: %s > %s", claim.Title, chk.Name(), wrapStatus(chk, err))
+			}
+		}
+		passed, details := false, ""
+		if isInterrupted(err) {
+			// The check may still be running, so its state cannot be read
+			details = err.Error()
+		} else {
+			passed, details = chk.Passed(), chk.Status()
+		}
+		shared.UpdateLastState(shared.LastState{
+			UUID:     chk.UUID(),
+			Name:     chk.Name(),
+			Passed:   passed,
+			HasError: hasError,
+			Details:  details,
+			Severity: check.SeverityOf(chk),
+			Weight:   check.WeightOf(chk),
+		})
+		if err != nil {
+			details = err.Error()
+		}
+		j.record(resultState(passed, hasError), details)
+	}
+	j.result.Duration = time.Since(started)
 }
 
 // PrintSchemaJSON constructs and prints a JSON schema generated from a slice of claims.
@@ -160,3 +359,50 @@ func PrintSchemaJSON(claimsTorun []claims.Claim) {
 	}
 	fmt.Println(string(out))
 }
+
+// SchemaCheck describes a single check in the detailed schema.
+type SchemaCheck struct {
+	Slug          string          `json:"slug"`
+	Name          string          `json:"name"`
+	PassedMessage string          `json:"passedMessage"`
+	FailedMessage string          `json:"failedMessage"`
+	Severity      check.Severity  `json:"severity"`
+	Weight        int             `json:"weight"`
+	RequiresRoot  bool            `json:"requiresRoot"`
+	Settings      []SchemaSetting `json:"settings,omitempty"`
+}
+
+// PrintSchemaDetailsJSON prints a JSON schema keyed by claim title and check UUID,
+// like PrintSchemaJSON, but with the full metadata of each check.
+func PrintSchemaDetailsJSON(claimsTorun []claims.Claim) {
+	if err := WriteSchemaDetailsJSON(os.Stdout, claimsTorun); err != nil {
+		log.WithError(err).Warn("cannot marshal schema")
+	}
+}
+
+// WriteSchemaDetailsJSON writes the schema printed by PrintSchemaDetailsJSON to w.
+func WriteSchemaDetailsJSON(w io.Writer, claimsTorun []claims.Claim) error {
+	schema := make(map[string]map[string]SchemaCheck)
+	for _, claim := range claimsTorun {
+		checks := make(map[string]SchemaCheck)
+		for _, chk := range claim.Checks {
+			checks[chk.UUID()] = SchemaCheck{
+				Slug:          check.SlugOf(chk),
+				Name:          chk.Name(),
+				PassedMessage: chk.PassedMessage(),
+				FailedMessage: chk.FailedMessage(),
+				Severity:      check.SeverityOf(chk),
+				Weight:        check.WeightOf(chk),
+				RequiresRoot:  chk.RequiresRoot(),
+				Settings:      schemaSettings(chk),
+			}
+		}
+		schema[claim.Title] = checks
+	}
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"strings"
 	"sync/atomic"
 	"testing"
@@ -75,7 +76,7 @@ func TestCheckSuccess(t *testing.T) {
 		}},
 	}
 	ctx := context.Background()
-	Check(ctx, dummyClaims, []string{}, "")
+	Check(ctx, dummyClaims, []string{}, nil)
 
 	if atomic.LoadInt32(&dc.runCalled) != 1 {
 		t.Errorf("Expected Run to be called on DummyCheck, but it wasn't")
@@ -98,12 +99,29 @@ func TestCheckNotRunnable(t *testing.T) {
 		}},
 	}
 	ctx := context.Background()
-	Check(ctx, dummyClaims, []string{}, "")
+	Check(ctx, dummyClaims, []string{}, nil)
 	if atomic.LoadInt32(&dc.runCalled) != 0 {
 		t.Errorf("Expected Run NOT to be called on non-runnable DummyCheck, but it was")
 	}
 }
 
+func TestCheckSkipAndOnly(t *testing.T) {
+	a := &DummyCheck{name: "A", runnable: true, passedVal: true, statusMsg: "ok", uuid: "uuid-a"}
+	b := &DummyCheck{name: "B", runnable: true, passedVal: true, statusMsg: "ok", uuid: "uuid-b"}
+	c := &DummyCheck{name: "C", runnable: true, passedVal: true, statusMsg: "ok", uuid: "uuid-c"}
+	dummyClaims := []claims.Claim{
+		{Title: "Test Case", Checks: []check.Check{a, b, c}},
+	}
+	results := Check(context.Background(), dummyClaims, []string{"uuid-b"}, []string{"uuid-a", "uuid-b"})
+
+	if len(results) != 1 || results[0].UUID != "uuid-a" {
+		t.Errorf("Expected only uuid-a to run, got %+v", results)
+	}
+	if atomic.LoadInt32(&b.runCalled) != 0 || atomic.LoadInt32(&c.runCalled) != 0 {
+		t.Errorf("Expected skipped checks and checks not selected by only NOT to run")
+	}
+}
+
 func TestCheckContextCanceled(t *testing.T) {
 
 	// Create a dummy check that is runnable.
@@ -124,7 +142,7 @@ func TestCheckContextCanceled(t *testing.T) {
 	cancel()
 	// Allow a short time for the goroutine to select context.Done.
 	time.Sleep(10 * time.Millisecond)
-	Check(ctx, dummyClaims, []string{}, "")
+	Check(ctx, dummyClaims, []string{}, nil)
 
 	if atomic.LoadInt32(&dc.runCalled) != 0 {
 		t.Errorf("Expected Run NOT to be called when context is canceled, but it was")
@@ -283,3 +301,29 @@ func TestWrapStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestPrintSchemaDetailsJSON(t *testing.T) {
+	dc := &DummyCheck{name: "DummySchema", uuid: "uuid-schema"}
+	claimsTorun := []claims.Claim{{Title: "Test Claim", Checks: []check.Check{dc}}}
+
+	output := captureOutput(func() {
+		PrintSchemaDetailsJSON(claimsTorun)
+	})
+
+	var schema map[string]map[string]SchemaCheck
+	if err := json.Unmarshal([]byte(output), &schema); err != nil {
+		t.Fatalf("failed to unmarshal schema: %v", err)
+	}
+	got := schema["Test Claim"]["uuid-schema"]
+	expected := SchemaCheck{
+		Slug:          "dummyschema",
+		Name:          "DummySchema",
+		PassedMessage: "passed",
+		FailedMessage: "failed",
+		Severity:      check.DefaultSeverity,
+		Weight:        3,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("PrintSchemaDetailsJSON mismatch.\nExpected: %+v\nGot: %+v", expected, got)
+	}
+}
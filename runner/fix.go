@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/caarlos0/log"
+)
+
+// ApplyFixStep performs a single automatic remediation step as the current user.
+func ApplyFixStep(ctx context.Context, step check.FixStep) error {
+	switch {
+	case len(step.Command) > 0:
+		output, err := shared.RunCommandContext(ctx, step.Command[0], step.Command[1:]...)
+		if err != nil {
+			return fmt.Errorf("%s: %w: %s", step, err, output)
+		}
+		return nil
+	case step.File != "":
+		return appendToFile(step.File, step.Append)
+	}
+	return errors.New("step has to be done manually")
+}
+
+// appendToFile appends text to path on a line of its own, creating the file if needed.
+func appendToFile(path, text string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		text = "\n" + text
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// applyRootFix applies the root steps of the remediation of chk. It runs in
+// the root helper, so only steps declared by the built-in check are executed;
+// the request merely names the check.
+func applyRootFix(ctx context.Context, chk check.Check) error {
+	remediator, ok := chk.(check.Remediator)
+	if !ok {
+		return errors.New("check has no remediation")
+	}
+	for _, step := range remediator.Remediation() {
+		if !step.RequiresRoot || step.Manual() {
+			continue
+		}
+		log.WithField("step", step.String()).Info("Applying fix")
+		if err := ApplyFixStep(ctx, step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FixCheckViaRoot asks the root helper to apply the root steps of the
+// remediation of a check. The helper re-runs the check afterwards and
+// returns its new status; a fix that could not be applied is an error.
+func FixCheckViaRoot(ctx context.Context, uuid string) (*CheckStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, shared.PerCheckTimeout)
+	defer cancel()
+
+	log.WithField("uuid", uuid).Debug("Applying fix via root helper")
+	return callHelperOne(ctx, ActionFix, uuid)
+}
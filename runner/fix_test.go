@@ -0,0 +1,159 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+type remediableCheck struct {
+	MockCheck
+	steps []check.FixStep
+	fixed bool
+}
+
+func (r *remediableCheck) Remediation() []check.FixStep { return r.steps }
+func (r *remediableCheck) Run() error {
+	r.PassedValue = r.fixed
+	return nil
+}
+
+func TestApplyFixStepAppendsToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "npmrc")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NoError(t, os.WriteFile(path, []byte("registry=https://example.com"), 0o600))
+
+	err := ApplyFixStep(context.Background(), check.FixStep{File: path, Append: "save-exact=true\n"})
+	assert.NoError(t, err)
+
+	contents, _ := os.ReadFile(path)
+	assert.Equal(t, "registry=https://example.com\nsave-exact=true\n", string(contents))
+}
+
+func TestApplyFixStepCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "new", ".yarnrc.yml")
+
+	assert.NoError(t, ApplyFixStep(context.Background(), check.FixStep{File: path, Append: "npmMinimalAgeGate: 10080\n"}))
+
+	contents, _ := os.ReadFile(path)
+	assert.Equal(t, "npmMinimalAgeGate: 10080\n", string(contents))
+}
+
+func TestApplyFixStepCommand(t *testing.T) {
+	shared.RunCommandMocks = []shared.RunCommandMock{
+		{Command: "ufw", Args: []string{"enable"}, Out: "Firewall is active"},
+	}
+	defer func() { shared.RunCommandMocks = nil }()
+
+	assert.NoError(t, ApplyFixStep(context.Background(), check.FixStep{Command: []string{"ufw", "enable"}}))
+	assert.Error(t, ApplyFixStep(context.Background(), check.FixStep{Command: []string{"ufw", "disable"}}))
+	assert.Error(t, ApplyFixStep(context.Background(), check.FixStep{Description: "do it by hand"}))
+}
+
+func TestHandleConnectionFix(t *testing.T) {
+	allowAllPeers(t)
+	shared.RunCommandMocks = []shared.RunCommandMock{
+		{Command: "ufw", Args: []string{"enable"}, Out: "Firewall is active"},
+	}
+	defer func() { shared.RunCommandMocks = nil }()
+
+	chk := &remediableCheck{
+		MockCheck: MockCheck{UUIDValue: "fix-uuid", RequiresRootValue: true, StatusValue: "Firewall is on"},
+		steps: []check.FixStep{
+			{Description: "user step", Command: []string{"gsettings", "set"}},
+			{Description: "root step", Command: []string{"ufw", "enable"}, RequiresRoot: true},
+		},
+		fixed: true,
+	}
+	claims.All = []claims.Claim{{Checks: []check.Check{chk}}}
+
+	conn := &mockConn{readData: helperRequest(ActionFix, "fix-uuid")}
+	HandleConnection(conn)
+
+	statuses := helperResponses(t, conn)
+	assert.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Passed)
+	assert.Equal(t, "Firewall is on", statuses[0].Details)
+}
+
+func TestHandleConnectionFixFailure(t *testing.T) {
+	allowAllPeers(t)
+	shared.RunCommandMocks = nil
+	chk := &remediableCheck{
+		MockCheck: MockCheck{UUIDValue: "fix-uuid", RequiresRootValue: true},
+		steps:     []check.FixStep{{Description: "root step", Command: []string{"ufw", "enable"}, RequiresRoot: true}},
+	}
+	claims.All = []claims.Claim{{Checks: []check.Check{chk}}}
+
+	conn := &mockConn{readData: helperRequest(ActionFix, "fix-uuid")}
+	HandleConnection(conn)
+
+	statuses := helperResponses(t, conn)
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, check.CheckStateError, statuses[0].State)
+	assert.Contains(t, statuses[0].Error, "failed to apply fix")
+}
+
+func TestHandleConnectionFixRequiresAdmin(t *testing.T) {
+	groups := peerGroups
+	defer func() { peerGroups = groups }()
+	peerGroups = func(peer *PeerCredentials) ([]string, error) {
+		if peer.UID == 1001 {
+			return []string{"alice", "wheel"}, nil
+		}
+		return []string{"bob", "users"}, nil
+	}
+	shared.RunCommandMocks = []shared.RunCommandMock{
+		{Command: "ufw", Args: []string{"enable"}, Out: "Firewall is active"},
+	}
+	defer func() { shared.RunCommandMocks = nil }()
+	chk := &remediableCheck{
+		MockCheck: MockCheck{UUIDValue: "fix-uuid", RequiresRootValue: true},
+		steps:     []check.FixStep{{Description: "root step", Command: []string{"ufw", "enable"}, RequiresRoot: true}},
+		fixed:     true,
+	}
+	claims.All = []claims.Claim{{Checks: []check.Check{chk}}}
+
+	allowPeer(t, &PeerCredentials{PID: 10, UID: 1000, GID: 1000})
+	conn := &mockConn{readData: helperRequest(ActionFix, "fix-uuid")}
+	HandleConnection(conn)
+	statuses := helperResponses(t, conn)
+	assert.Len(t, statuses, 1)
+	assert.Empty(t, statuses[0].UUID)
+	assert.Equal(t, CodeUnauthorized, statuses[0].Code)
+	assert.Contains(t, statuses[0].Error, "sudo paretosecurity fix")
+
+	// Running checks stays open to every user
+	conn = &mockConn{readData: helperRequest(ActionRun, "fix-uuid")}
+	HandleConnection(conn)
+	assert.Equal(t, "fix-uuid", helperResponses(t, conn)[0].UUID)
+
+	allowPeer(t, &PeerCredentials{PID: 11, UID: 1001, GID: 1001})
+	conn = &mockConn{readData: helperRequest(ActionFix, "fix-uuid")}
+	HandleConnection(conn)
+	statuses = helperResponses(t, conn)
+	assert.Len(t, statuses, 1)
+	assert.True(t, statuses[0].Passed)
+}
+
+func TestHandleConnectionFixRejectsUserChecks(t *testing.T) {
+	allowAllPeers(t)
+	chk := &remediableCheck{
+		MockCheck: MockCheck{UUIDValue: "user-fix-uuid"},
+		steps:     []check.FixStep{{Description: "root step", Command: []string{"ufw", "enable"}, RequiresRoot: true}},
+	}
+	claims.All = []claims.Claim{{Checks: []check.Check{chk}}}
+
+	conn := &mockConn{readData: helperRequest(ActionFix, "user-fix-uuid")}
+	HandleConnection(conn)
+
+	statuses := helperResponses(t, conn)
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, CodeNotFound, statuses[0].Code)
+}
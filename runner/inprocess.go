@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fatih/color"
+
+	"github.com/ParetoSecurity/agent/check"
+	sharedchecks "github.com/ParetoSecurity/agent/checks/shared"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/caarlos0/log"
+	"github.com/samber/lo"
+)
+
+// RunInProcess runs the checks one at a time in this process, including the
+// checks that require root, which then only see what the current user may
+// see. Unlike Check it does not store states, evidence or history and does
+// not notify anyone, so it can record or replay a run without affecting the
+// device. Running one check at a time keeps the order of the interactions
+// with the system stable.
+func RunInProcess(ctx context.Context, claimsTorun []claims.Claim, skipUUIDs, onlyUUIDs []string) []CheckResult {
+	var checkLogger = log.New(LogWriter)
+
+	jobs := []*job{}
+	for _, claim := range claimsTorun {
+		for _, chk := range claim.Checks {
+			if lo.Contains(skipUUIDs, chk.UUID()) || (len(onlyUUIDs) > 0 && !lo.Contains(onlyUUIDs, chk.UUID())) {
+				continue
+			}
+			jobs = append(jobs, newJob(claim, chk))
+		}
+	}
+
+	facts := sharedchecks.NewSystemFacts()
+	for _, j := range jobs {
+		useFacts(j.chk, facts)
+	}
+	defer func() {
+		for _, j := range jobs {
+			useFacts(j.chk, nil)
+		}
+	}()
+
+	ordered, cyclic := orderJobs(jobs)
+	for _, j := range cyclic {
+		err := errors.New("dependency cycle detected")
+		j.logf(log.InfoLevel, "%s: %s > %s", j.claim.Title, j.chk.Name(), wrapStatus(j.chk, err))
+		j.record(check.CheckStateError, err.Error())
+	}
+	for _, j := range ordered {
+		if ctx.Err() != nil {
+			break
+		}
+		runInProcess(ctx, j)
+	}
+
+	results := []CheckResult{}
+	for _, j := range jobs {
+		j.flush(checkLogger)
+		if j.result != nil {
+			results = append(results, *j.result)
+		}
+	}
+	return results
+}
+
+// runInProcess runs the check of j and records its outcome.
+func runInProcess(ctx context.Context, j *job) {
+	claim, chk := j.claim, j.chk
+	if !whenIdle(chk, func() {}) {
+		j.logf(log.InfoLevel, "%s: %s > %s", claim.Title, chk.Name(), wrapStatus(chk, ErrCheckBusy))
+		j.record(check.CheckStateError, ErrCheckBusy.Error())
+		return
+	}
+	if policy := shared.CheckPolicyFor(chk.UUID()); !chk.IsRunnable() || policy.Disabled {
+		reason := chk.Status()
+		if policy.Disabled {
+			reason = policy.Describe()
+		}
+		j.logf(log.WarnLevel, "%s: %s > %s %s", claim.Title, chk.Name(), color.YellowString("[DISABLED]"), reason)
+		j.record(check.CheckStateDisabled, reason)
+		return
+	}
+
+	started := time.Now()
+	err := RunCheck(ctx, chk)
+	level := log.InfoLevel
+	if err == nil && !chk.Passed() {
+		level = log.WarnLevel
+	}
+	j.logf(level, "%s: %s > %s", claim.Title, chk.Name(), wrapStatus(chk, err))
+
+	if err != nil {
+		j.record(check.CheckStateError, err.Error())
+	} else {
+		j.record(resultState(chk.Passed(), false), chk.Status())
+	}
+	j.result.Duration = time.Since(started)
+}
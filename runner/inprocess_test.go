@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunInProcess(t *testing.T) {
+	withMetricsPaths(t)
+	passing := &DummyCheck{name: "Passing", runnable: true, passedVal: true, statusMsg: "ok", uuid: "uuid-inprocess-pass"}
+	root := &MockCheck{UUIDValue: "uuid-inprocess-root", RequiresRootValue: true, StatusValue: "off"}
+	broken := &DummyCheck{name: "Broken", runnable: true, runErr: errors.New("boom"), uuid: "uuid-inprocess-error"}
+	skipped := &DummyCheck{name: "Skipped", runnable: true, uuid: "uuid-inprocess-skip"}
+	dummyClaims := []claims.Claim{
+		{Title: "Test Case", Checks: []check.Check{passing, root, broken, skipped}},
+	}
+
+	results := RunInProcess(context.Background(), dummyClaims, []string{"uuid-inprocess-skip"}, nil)
+
+	states := []check.CheckState{}
+	for _, result := range results {
+		states = append(states, result.State)
+	}
+	assert.Equal(t, []check.CheckState{check.CheckStatePassed, check.CheckStateFailed, check.CheckStateError}, states)
+	assert.Equal(t, "boom", results[2].Details)
+	assert.True(t, results[1].Root, "root checks run in this process")
+
+	// Nothing is stored
+	_, found := shared.GetLastStates()["uuid-inprocess-pass"]
+	assert.False(t, found)
+	runs, _ := shared.LoadHistory()
+	assert.Empty(t, runs)
+}
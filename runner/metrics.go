@@ -0,0 +1,187 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/caarlos0/log"
+)
+
+// MetricsFile is the name of the file written to the textfile collector directory.
+const MetricsFile = "paretosecurity.prom"
+
+// MetricsContentType is the content type of the metrics served over HTTP.
+const MetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// metricStates are the states a check can be in, exported as one series each.
+var metricStates = []check.CheckState{check.CheckStatePassed, check.CheckStateFailed, check.CheckStateError}
+
+// metricsSnapshot is the data exported as metrics.
+type metricsSnapshot struct {
+	States     map[string]shared.LastState
+	LastRun    shared.HistoryRun
+	LastReport time.Time
+	Version    string
+}
+
+// currentMetrics collects the last check states, the last recorded run and
+// the last successful team report.
+func currentMetrics() metricsSnapshot {
+	snapshot := metricsSnapshot{
+		States:  shared.GetLastStates(),
+		Version: shared.Version,
+	}
+	if run, found := shared.LastRun(); found {
+		snapshot.LastRun = run
+	} else {
+		snapshot.LastRun.Time = shared.GetModifiedTime()
+	}
+	if shared.Config.LastTeamReportSuccess > 0 {
+		snapshot.LastReport = time.UnixMilli(shared.Config.LastTeamReportSuccess)
+	}
+	return snapshot
+}
+
+// WriteMetrics writes the last check states in the OpenMetrics text format.
+func WriteMetrics(w io.Writer, all []claims.Claim) error {
+	return renderMetrics(w, all, currentMetrics())
+}
+
+// WriteMetricsFile writes the metrics to MetricsFile in dir. The file is
+// replaced atomically so a collector never reads a partial file.
+func WriteMetricsFile(dir string, all []claims.Claim) error {
+	var buf bytes.Buffer
+	if err := WriteMetrics(&buf, all); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+MetricsFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, MetricsFile))
+}
+
+// MetricsHandler serves the metrics on every request.
+func MetricsHandler(all []claims.Claim) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		if err := WriteMetrics(&buf, all); err != nil {
+			log.WithError(err).Warn("failed to render metrics")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", MetricsContentType)
+		_, _ = w.Write(buf.Bytes())
+	})
+}
+
+func renderMetrics(w io.Writer, all []claims.Claim, snapshot metricsSnapshot) error {
+	claimTitles := map[string]string{}
+	for _, claim := range all {
+		for _, chk := range claim.Checks {
+			claimTitles[chk.UUID()] = claim.Title
+		}
+	}
+
+	var buf bytes.Buffer
+	metricFamily(&buf, "paretosecurity_check_state", "State of each check in the last run, 1 for the current state")
+	for _, uuid := range slices.Sorted(maps.Keys(snapshot.States)) {
+		state := snapshot.States[uuid]
+		severity := state.Severity
+		if severity == "" {
+			severity = check.DefaultSeverity
+		}
+		for _, metricState := range metricStates {
+			value := 0.0
+			if state.State() == metricState {
+				value = 1
+			}
+			metricSample(&buf, "paretosecurity_check_state", value,
+				"uuid", uuid,
+				"name", state.Name,
+				"claim", claimTitles[uuid],
+				"severity", string(severity),
+				"state", string(metricState),
+			)
+		}
+	}
+
+	metricFamily(&buf, "paretosecurity_score", "Weighted security score of the device from 0 to 100")
+	metricSample(&buf, "paretosecurity_score", float64(shared.DeviceScore(snapshot.States)))
+
+	if !snapshot.LastRun.Time.IsZero() {
+		metricFamily(&buf, "paretosecurity_last_run_timestamp_seconds", "Time of the last check run")
+		metricSample(&buf, "paretosecurity_last_run_timestamp_seconds", unixSeconds(snapshot.LastRun.Time))
+	}
+	if snapshot.LastRun.Duration > 0 {
+		metricFamily(&buf, "paretosecurity_run_duration_seconds", "Duration of the last check run")
+		metricSample(&buf, "paretosecurity_run_duration_seconds", snapshot.LastRun.Duration.Seconds())
+	}
+	if !snapshot.LastReport.IsZero() {
+		metricFamily(&buf, "paretosecurity_team_report_last_success_timestamp_seconds", "Time of the last successful team report")
+		metricSample(&buf, "paretosecurity_team_report_last_success_timestamp_seconds", unixSeconds(snapshot.LastReport))
+	}
+
+	metricFamily(&buf, "paretosecurity_agent_version", "Version of the agent, always 1")
+	metricSample(&buf, "paretosecurity_agent_version", 1, "version", snapshot.Version)
+
+	buf.WriteString("# EOF\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// metricFamily writes the metadata of a gauge.
+func metricFamily(buf *bytes.Buffer, name, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, escapeMetricText(help))
+	fmt.Fprintf(buf, "# TYPE %s gauge\n", name)
+}
+
+// metricSample writes a sample with the given label names and values.
+func metricSample(buf *bytes.Buffer, name string, value float64, labels ...string) {
+	buf.WriteString(name)
+	if len(labels) > 0 {
+		pairs := make([]string, 0, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labels[i], escapeMetricLabel(labels[i+1])))
+		}
+		buf.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	buf.WriteString(" " + strconv.FormatFloat(value, 'f', -1, 64) + "\n")
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixMilli()) / 1000
+}
+
+func escapeMetricText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeMetricLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+// withMetricsPaths points the state and history files to a temporary directory.
+func withMetricsPaths(t *testing.T) {
+	t.Helper()
+	statePath, historyPath, evidencePath := shared.StatePath, shared.HistoryPath, shared.EvidencePath
+	shared.StatePath = filepath.Join(t.TempDir(), "state")
+	shared.HistoryPath = filepath.Join(t.TempDir(), "history")
+	shared.EvidencePath = filepath.Join(t.TempDir(), "evidence")
+	t.Cleanup(func() {
+		shared.StatePath, shared.HistoryPath, shared.EvidencePath = statePath, historyPath, evidencePath
+	})
+}
+
+func TestRenderMetrics(t *testing.T) {
+	all := []claims.Claim{{Title: "Access Security", Checks: []check.Check{&DummyCheck{uuid: "uuid-pass"}}}}
+	snapshot := metricsSnapshot{
+		States: map[string]shared.LastState{
+			"uuid-pass":  {Name: "Passing", UUID: "uuid-pass", Passed: true, Severity: check.SeverityHigh},
+			"uuid-error": {Name: `Quoted "name"`, UUID: "uuid-error", HasError: true},
+		},
+		LastRun: shared.HistoryRun{
+			Time:     time.UnixMilli(1735689600500),
+			Duration: 1500 * time.Millisecond,
+		},
+		LastReport: time.UnixMilli(1735689000000),
+		Version:    "1.2.3",
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, renderMetrics(&buf, all, snapshot))
+
+	assert.Equal(t, `# HELP paretosecurity_check_state State of each check in the last run, 1 for the current state
+# TYPE paretosecurity_check_state gauge
+paretosecurity_check_state{uuid="uuid-error",name="Quoted \"name\"",claim="",severity="medium",state="pass"} 0
+paretosecurity_check_state{uuid="uuid-error",name="Quoted \"name\"",claim="",severity="medium",state="fail"} 0
+paretosecurity_check_state{uuid="uuid-error",name="Quoted \"name\"",claim="",severity="medium",state="error"} 1
+paretosecurity_check_state{uuid="uuid-pass",name="Passing",claim="Access Security",severity="high",state="pass"} 1
+paretosecurity_check_state{uuid="uuid-pass",name="Passing",claim="Access Security",severity="high",state="fail"} 0
+paretosecurity_check_state{uuid="uuid-pass",name="Passing",claim="Access Security",severity="high",state="error"} 0
+# HELP paretosecurity_score Weighted security score of the device from 0 to 100
+# TYPE paretosecurity_score gauge
+paretosecurity_score 63
+# HELP paretosecurity_last_run_timestamp_seconds Time of the last check run
+# TYPE paretosecurity_last_run_timestamp_seconds gauge
+paretosecurity_last_run_timestamp_seconds 1735689600.5
+# HELP paretosecurity_run_duration_seconds Duration of the last check run
+# TYPE paretosecurity_run_duration_seconds gauge
+paretosecurity_run_duration_seconds 1.5
+# HELP paretosecurity_team_report_last_success_timestamp_seconds Time of the last successful team report
+# TYPE paretosecurity_team_report_last_success_timestamp_seconds gauge
+paretosecurity_team_report_last_success_timestamp_seconds 1735689000
+# HELP paretosecurity_agent_version Version of the agent, always 1
+# TYPE paretosecurity_agent_version gauge
+paretosecurity_agent_version{version="1.2.3"} 1
+# EOF
+`, buf.String())
+}
+
+func TestRenderMetrics_NeverRun(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, renderMetrics(&buf, nil, metricsSnapshot{Version: "dev"}))
+
+	assert.NotContains(t, buf.String(), "paretosecurity_last_run_timestamp_seconds")
+	assert.NotContains(t, buf.String(), "paretosecurity_team_report_last_success_timestamp_seconds")
+	assert.Contains(t, buf.String(), "paretosecurity_agent_version{version=\"dev\"} 1\n# EOF\n")
+}
+
+func TestWriteMetricsFile(t *testing.T) {
+	withMetricsPaths(t)
+	dir := t.TempDir()
+
+	assert.NoError(t, WriteMetricsFile(dir, nil))
+	assert.NoError(t, WriteMetricsFile(dir, nil))
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are renamed or removed")
+	content, err := os.ReadFile(filepath.Join(dir, MetricsFile))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "# EOF\n")
+}
+
+func TestWriteMetricsFile_MissingDir(t *testing.T) {
+	assert.Error(t, WriteMetricsFile(filepath.Join(t.TempDir(), "missing"), nil))
+}
+
+func TestMetricsHandler(t *testing.T) {
+	withMetricsPaths(t)
+
+	rec := httptest.NewRecorder()
+	MetricsHandler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, MetricsContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "# TYPE paretosecurity_score gauge")
+}
+
+func TestCheckWritesMetrics(t *testing.T) {
+	withMetricsPaths(t)
+	shared.Config.MetricsDir = t.TempDir()
+	defer func() { shared.Config.MetricsDir = "" }()
+	pass := &DummyCheck{name: "Pass", runnable: true, passedVal: true, statusMsg: "ok", uuid: "uuid-metrics-pass"}
+
+	Check(context.Background(), []claims.Claim{{Title: "Test Case", Checks: []check.Check{pass}}}, []string{}, nil)
+
+	content, err := os.ReadFile(filepath.Join(shared.Config.MetricsDir, MetricsFile))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `paretosecurity_check_state{uuid="uuid-metrics-pass",name="Pass",claim="Test Case",severity="medium",state="pass"} 1`)
+	assert.Contains(t, string(content), "paretosecurity_run_duration_seconds ")
+}
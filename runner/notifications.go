@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/notify"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/caarlos0/log"
+)
+
+// NotificationInterval is the minimum time between two notifications about
+// failing checks. Checks that start failing in between are named in the next
+// notification if they still fail by then.
+var NotificationInterval = 4 * time.Hour
+
+// RenotifyInterval is the minimum time before a check is named in a
+// notification again, so that flapping checks stay quiet.
+var RenotifyInterval = 24 * time.Hour
+
+// showNotification displays the notification
+// Can be overridden for testing
+var showNotification = notify.Notify
+
+// trayRunning reports whether the tray application is running. It raises
+// the notifications then, so that their buttons can be acted on; a check run
+// exits before anyone could click them.
+// Can be overridden for testing
+var trayRunning = func() bool {
+	return shared.InstanceRunning(shared.TrayLockPath)
+}
+
+// notifyRegressions raises a desktop notification naming the checks that
+// started failing, unless notifications are rate limited or disabled. Checks
+// whose snooze expired alert again if they still fail. When the tray
+// application runs, the checks are queued for it to raise the notification.
+func notifyRegressions(change PostureChange, now time.Time) {
+	expired, err := shared.ExpireSnoozes(now)
+	if err != nil {
+		log.WithError(err).Warn("failed to remove expired snoozes")
+	}
+	if shared.Config.DisableNotifications {
+		return
+	}
+	regressed := []string{}
+	for _, result := range change.Regressed {
+		regressed = append(regressed, result.UUID)
+	}
+	if len(regressed) == 0 && len(expired) == 0 {
+		// Nothing new, only write the state when earlier checks are pending
+		state, err := shared.LoadNotificationState()
+		if err == nil && len(state.Pending) == 0 {
+			return
+		}
+	}
+	states := shared.GetLastStates()
+	tray := trayRunning()
+	var checks []shared.LastState
+	err = shared.UpdateNotificationState(func(state *shared.NotificationState) {
+		checks = regressionsToNotify(state, regressed, expired, states, now)
+		if tray {
+			for _, last := range checks {
+				if !slices.Contains(state.Queued, last.UUID) {
+					state.Queued = append(state.Queued, last.UUID)
+				}
+			}
+		}
+	})
+	if err != nil {
+		log.WithError(err).Warn("failed to update the notification state")
+		return
+	}
+	if len(checks) == 0 || tray {
+		return
+	}
+	notification := notify.Regressions(checks)
+	showNotification(notification.Title, notification.Body)
+}
+
+// regressionsToNotify adds the regressed checks and the checks whose snooze
+// expired to the pending ones and returns the checks to name in a
+// notification now, if any. Pending checks that no longer fail, snoozed
+// checks and checks named within RenotifyInterval are dropped. Nothing is
+// returned within NotificationInterval of the last notification.
+func regressionsToNotify(state *shared.NotificationState, regressed, expired []string, states map[string]shared.LastState, now time.Time) []shared.LastState {
+	maps.DeleteFunc(state.Notified, func(uuid string, at time.Time) bool {
+		return now.Sub(at) >= RenotifyInterval || slices.Contains(expired, uuid)
+	})
+	candidates := state.Pending
+	for _, uuid := range slices.Concat(regressed, expired) {
+		if !slices.Contains(candidates, uuid) {
+			candidates = append(candidates, uuid)
+		}
+	}
+
+	state.Pending = nil
+	for _, uuid := range candidates {
+		last, found := states[uuid]
+		if !found || last.State() == check.CheckStatePassed {
+			continue
+		}
+		if shared.ActiveSnooze(uuid, now) != nil {
+			continue
+		}
+		if _, notified := state.Notified[uuid]; notified {
+			continue
+		}
+		state.Pending = append(state.Pending, uuid)
+	}
+	if len(state.Pending) == 0 || now.Sub(state.Last) < NotificationInterval {
+		return nil
+	}
+
+	if state.Notified == nil {
+		state.Notified = map[string]time.Time{}
+	}
+	checks := []shared.LastState{}
+	for _, uuid := range state.Pending {
+		checks = append(checks, states[uuid])
+		state.Notified[uuid] = now
+	}
+	state.Last = now
+	state.Pending = nil
+	return checks
+}
@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type shownNotification struct {
+	title, body string
+}
+
+// withNotifications records the notifications raised during the test, with
+// the tray application not running.
+func withNotifications(t *testing.T) *[]shownNotification {
+	t.Helper()
+	show, tray, path := showNotification, trayRunning, shared.NotificationStatePath
+	shared.NotificationStatePath = filepath.Join(t.TempDir(), "notifications")
+	shown := []shownNotification{}
+	showNotification = func(title, body string) {
+		shown = append(shown, shownNotification{title, body})
+	}
+	trayRunning = func() bool { return false }
+	t.Cleanup(func() {
+		showNotification, trayRunning, shared.NotificationStatePath = show, tray, path
+	})
+	return &shown
+}
+
+var notificationStates = map[string]shared.LastState{
+	"a": {UUID: "a", Name: "Check A"},
+	"b": {UUID: "b", Name: "Check B", HasError: true},
+	"c": {UUID: "c", Name: "Check C", Passed: true},
+}
+
+func TestRegressionsToNotify(t *testing.T) {
+	snoozes := shared.Config.Snoozes
+	defer func() { shared.Config.Snoozes = snoozes }()
+	now := time.Now()
+	state := &shared.NotificationState{}
+
+	checks := regressionsToNotify(state, []string{"a"}, nil, notificationStates, now)
+	require.Len(t, checks, 1)
+	assert.Equal(t, "Check A", checks[0].Name)
+	assert.Equal(t, now, state.Notified["a"])
+
+	// Rate limited, b waits for the next notification
+	assert.Empty(t, regressionsToNotify(state, []string{"b"}, nil, notificationStates, now.Add(time.Hour)))
+	assert.Equal(t, []string{"b"}, state.Pending)
+
+	// a was named recently, c passes again
+	checks = regressionsToNotify(state, []string{"a", "c"}, nil, notificationStates, now.Add(NotificationInterval))
+	require.Len(t, checks, 1)
+	assert.Equal(t, "b", checks[0].UUID)
+	assert.Empty(t, state.Pending)
+
+	// Once RenotifyInterval has passed, a can be named again unless it is snoozed
+	later := now.Add(RenotifyInterval + NotificationInterval)
+	shared.Config.Snoozes = []shared.CheckSnooze{{Check: "a", Until: later.Add(time.Hour)}}
+	assert.Empty(t, regressionsToNotify(state, []string{"a"}, nil, notificationStates, later))
+	assert.Len(t, regressionsToNotify(state, []string{"a"}, nil, notificationStates, later.Add(time.Hour)), 1)
+
+	// A check whose snooze expired alerts again, even if it was named recently
+	checks = regressionsToNotify(state, nil, []string{"a"}, notificationStates, later.Add(time.Hour+NotificationInterval))
+	require.Len(t, checks, 1)
+	assert.Equal(t, "a", checks[0].UUID)
+}
+
+func TestCheckNotifiesRegressions(t *testing.T) {
+	withMetricsPaths(t)
+	shown := withNotifications(t)
+	dc := &DummyCheck{name: "Notified", runnable: true, passedVal: true, statusMsg: "ok", uuid: "uuid-notified"}
+	run := func() {
+		Check(context.Background(), []claims.Claim{{Title: "Test Case", Checks: []check.Check{dc}}}, []string{}, nil)
+	}
+
+	run()
+	dc.passedVal = false
+	run()
+	require.Len(t, *shown, 1)
+	assert.Equal(t, "• Notified", (*shown)[0].body)
+
+	dc.passedVal = true
+	run()
+	dc.passedVal = false
+	run()
+	assert.Len(t, *shown, 1, "a flapping check is not named again right away")
+
+	shared.Config.DisableNotifications = true
+	defer func() { shared.Config.DisableNotifications = false }()
+	assert.NoError(t, shared.UpdateNotificationState(func(state *shared.NotificationState) {
+		*state = shared.NotificationState{}
+	}))
+	dc.passedVal = true
+	run()
+	dc.passedVal = false
+	run()
+	assert.Len(t, *shown, 1)
+}
+
+func TestCheckQueuesRegressionsForTray(t *testing.T) {
+	withMetricsPaths(t)
+	shown := withNotifications(t)
+	trayRunning = func() bool { return true }
+	dc := &DummyCheck{name: "Queued", runnable: true, passedVal: true, statusMsg: "ok", uuid: "uuid-queued"}
+	run := func() {
+		Check(context.Background(), []claims.Claim{{Title: "Test Case", Checks: []check.Check{dc}}}, []string{}, nil)
+	}
+
+	run()
+	dc.passedVal = false
+	run()
+	assert.Empty(t, *shown, "the tray raises the notification")
+	state, err := shared.LoadNotificationState()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"uuid-queued"}, state.Queued)
+}
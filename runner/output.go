@@ -0,0 +1,275 @@
+package runner
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/shared"
+)
+
+// LogWriter is where the runner writes its human readable progress log.
+// Commands that emit machine-readable output on stdout point it at stderr.
+var LogWriter io.Writer = os.Stdout
+
+// Supported machine-readable output formats for check results.
+const (
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+	FormatJUnit = "junit"
+)
+
+// Formats lists all supported output formats.
+var Formats = []string{FormatJSON, FormatSARIF, FormatJUnit}
+
+// CheckResult is the outcome of a single check in a run.
+type CheckResult struct {
+	Claim    string           `json:"claim"`
+	UUID     string           `json:"uuid"`
+	Name     string           `json:"name"`
+	State    check.CheckState `json:"state"`
+	Details  string           `json:"details"`
+	Severity check.Severity   `json:"severity"`
+	Duration time.Duration    `json:"-"`
+	Root     bool             `json:"root"`
+}
+
+// MarshalJSON encodes the result with its duration in milliseconds.
+func (r CheckResult) MarshalJSON() ([]byte, error) {
+	type alias CheckResult
+	return json.Marshal(struct {
+		alias
+		DurationMs int64 `json:"durationMs"`
+	}{alias(r), r.Duration.Milliseconds()})
+}
+
+// resultState maps the outcome of a check run to its reported state.
+func resultState(passed, hasError bool) check.CheckState {
+	if hasError {
+		return check.CheckStateError
+	}
+	if passed {
+		return check.CheckStatePassed
+	}
+	return check.CheckStateFailed
+}
+
+// WriteResults writes the check results to w in the requested format.
+func WriteResults(w io.Writer, format string, results []CheckResult) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, results)
+	case FormatSARIF:
+		return writeSARIF(w, results)
+	case FormatJUnit:
+		return writeJUnit(w, results)
+	}
+	return fmt.Errorf("unsupported output format: %s", format)
+}
+
+func writeJSON(w io.Writer, results []CheckResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string         `json:"ruleId"`
+	Kind       string         `json:"kind"`
+	Level      string         `json:"level"`
+	Message    sarifMessage   `json:"message"`
+	Properties map[string]any `json:"properties"`
+}
+
+// writeSARIF writes the results as a SARIF 2.1.0 log with one rule per check.
+func writeSARIF(w io.Writer, results []CheckResult) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "Pareto Security",
+			Version:        shared.Version,
+			InformationURI: "https://paretosecurity.com",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	for _, result := range results {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               result.UUID,
+			Name:             result.Name,
+			ShortDescription: sarifMessage{Text: result.Name},
+		})
+
+		kind, level := "pass", "none"
+		switch result.State {
+		case check.CheckStateFailed:
+			kind, level = "fail", sarifLevel(result.Severity)
+		case check.CheckStateError:
+			kind, level = "review", "warning"
+		case check.CheckStateDisabled:
+			kind, level = "notApplicable", "none"
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  result.UUID,
+			Kind:    kind,
+			Level:   level,
+			Message: sarifMessage{Text: result.Details},
+			Properties: map[string]any{
+				"claim":      result.Claim,
+				"state":      result.State,
+				"severity":   result.Severity,
+				"durationMs": result.Duration.Milliseconds(),
+				"root":       result.Root,
+			},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// sarifLevel maps the severity of a failed check to a SARIF result level.
+func sarifLevel(severity check.Severity) string {
+	switch severity {
+	case check.SeverityCritical, check.SeverityHigh:
+		return "error"
+	case check.SeverityLow:
+		return "note"
+	}
+	return "warning"
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	Classname  string          `xml:"classname,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Failure    *junitMessage   `xml:"failure,omitempty"`
+	Error      *junitMessage   `xml:"error,omitempty"`
+	Skipped    *junitMessage   `xml:"skipped,omitempty"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnit writes the results as a JUnit XML report with one test suite per claim.
+func writeJUnit(w io.Writer, results []CheckResult) error {
+	report := junitTestSuites{Name: "Pareto Security"}
+	suites := map[string]int{}
+	for _, result := range results {
+		idx, ok := suites[result.Claim]
+		if !ok {
+			idx = len(report.Suites)
+			suites[result.Claim] = idx
+			report.Suites = append(report.Suites, junitTestSuite{Name: result.Claim})
+		}
+		suite := &report.Suites[idx]
+
+		testCase := junitTestCase{
+			Name:      result.Name,
+			Classname: result.Claim,
+			Time:      fmt.Sprintf("%.3f", result.Duration.Seconds()),
+			Properties: []junitProperty{
+				{Name: "uuid", Value: result.UUID},
+				{Name: "state", Value: string(result.State)},
+				{Name: "severity", Value: string(result.Severity)},
+				{Name: "root", Value: fmt.Sprintf("%t", result.Root)},
+			},
+			SystemOut: result.Details,
+		}
+		switch result.State {
+		case check.CheckStateFailed:
+			testCase.Failure = &junitMessage{Message: result.Details}
+			suite.Failures++
+			report.Failures++
+		case check.CheckStateError:
+			testCase.Error = &junitMessage{Message: result.Details}
+			suite.Errors++
+			report.Errors++
+		case check.CheckStateDisabled:
+			testCase.Skipped = &junitMessage{Message: result.Details}
+			suite.Skipped++
+			report.Skipped++
+		}
+		suite.Tests++
+		report.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+var testResults = []CheckResult{
+	{Claim: "Access Security", UUID: "uuid-pass", Name: "Passing", State: check.CheckStatePassed, Details: "ok", Duration: 1500 * time.Millisecond},
+	{Claim: "Access Security", UUID: "uuid-fail", Name: "Failing", State: check.CheckStateFailed, Details: "not ok", Severity: check.SeverityHigh, Root: true},
+	{Claim: "System Integrity", UUID: "uuid-error", Name: "Broken", State: check.CheckStateError, Details: "boom"},
+	{Claim: "System Integrity", UUID: "uuid-off", Name: "Disabled", State: check.CheckStateDisabled, Details: "Disabled by the config file"},
+}
+
+func TestWriteResultsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteResults(&buf, FormatJSON, testResults))
+
+	var decoded []map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Len(t, decoded, 4)
+	assert.Equal(t, "uuid-pass", decoded[0]["uuid"])
+	assert.Equal(t, "pass", decoded[0]["state"])
+	assert.Equal(t, float64(1500), decoded[0]["durationMs"])
+	assert.Equal(t, true, decoded[1]["root"])
+	assert.Equal(t, "Access Security", decoded[1]["claim"])
+	assert.Equal(t, "high", decoded[1]["severity"])
+}
+
+func TestWriteResultsSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteResults(&buf, FormatSARIF, testResults))
+
+	var decoded sarifLog
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "2.1.0", decoded.Version)
+	assert.Len(t, decoded.Runs, 1)
+	assert.Len(t, decoded.Runs[0].Tool.Driver.Rules, 4)
+
+	kinds := []string{}
+	for _, result := range decoded.Runs[0].Results {
+		kinds = append(kinds, result.Kind+"/"+result.Level)
+	}
+	assert.Equal(t, []string{"pass/none", "fail/error", "review/warning", "notApplicable/none"}, kinds)
+}
+
+func TestSarifLevel(t *testing.T) {
+	assert.Equal(t, "error", sarifLevel(check.SeverityCritical))
+	assert.Equal(t, "error", sarifLevel(check.SeverityHigh))
+	assert.Equal(t, "warning", sarifLevel(check.SeverityMedium))
+	assert.Equal(t, "note", sarifLevel(check.SeverityLow))
+}
+
+func TestWriteResultsJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteResults(&buf, FormatJUnit, testResults))
+
+	var decoded junitTestSuites
+	assert.NoError(t, xml.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, 4, decoded.Tests)
+	assert.Equal(t, 1, decoded.Failures)
+	assert.Equal(t, 1, decoded.Errors)
+	assert.Equal(t, 1, decoded.Skipped)
+	assert.Len(t, decoded.Suites, 2)
+	assert.Equal(t, "Access Security", decoded.Suites[0].Name)
+	assert.Equal(t, "1.500", decoded.Suites[0].TestCases[0].Time)
+	assert.NotNil(t, decoded.Suites[0].TestCases[1].Failure)
+}
+
+func TestWriteResultsUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, WriteResults(&buf, "yaml", testResults))
+}
+
+func TestCheckReturnsResults(t *testing.T) {
+	shared.HistoryPath = filepath.Join(t.TempDir(), "history")
+	pass := &DummyCheck{name: "Pass", runnable: true, passedVal: true, statusMsg: "ok", uuid: "uuid-result-pass"}
+	fail := &DummyCheck{name: "Fail", runnable: true, passedVal: false, statusMsg: "bad", uuid: "uuid-result-fail"}
+	broken := &DummyCheck{name: "Broken", runnable: true, runErr: errors.New("boom"), uuid: "uuid-result-error"}
+	off := &DummyCheck{name: "Off", runnable: false, statusMsg: "not here", uuid: "uuid-result-off"}
+	dummyClaims := []claims.Claim{
+		{Title: "Test Case", Checks: []check.Check{pass, fail, broken, off}},
+	}
+
+	results := Check(context.Background(), dummyClaims, []string{}, nil)
+
+	assert.Len(t, results, 4)
+	assert.Equal(t, []check.CheckState{
+		check.CheckStatePassed,
+		check.CheckStateFailed,
+		check.CheckStateError,
+		check.CheckStateDisabled,
+	}, []check.CheckState{results[0].State, results[1].State, results[2].State, results[3].State})
+	assert.Equal(t, "boom", results[2].Details)
+	assert.Equal(t, "Test Case", results[0].Claim)
+}
+
+func TestCheckAppendsHistory(t *testing.T) {
+	shared.HistoryPath = filepath.Join(t.TempDir(), "history")
+	pass := &DummyCheck{name: "Pass", runnable: true, passedVal: true, statusMsg: "ok", uuid: "uuid-history-pass"}
+	fail := &DummyCheck{name: "Fail", runnable: true, passedVal: false, statusMsg: "bad", uuid: "uuid-history-fail"}
+	dummyClaims := []claims.Claim{
+		{Title: "Test Case", Checks: []check.Check{pass, fail}},
+	}
+
+	Check(context.Background(), dummyClaims, []string{}, nil)
+
+	runs, err := shared.LoadHistory()
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, shared.Version, runs[0].Version)
+	assert.Equal(t, map[string]check.CheckState{
+		"uuid-history-pass": check.CheckStatePassed,
+		"uuid-history-fail": check.CheckStateFailed,
+	}, runs[0].Checks)
+}
@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/caarlos0/log"
+)
+
+// PeerCredentials identify the process on the other end of a helper connection.
+type PeerCredentials struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// identifyPeerMock replaces peer verification in tests that use fake connections.
+var identifyPeerMock func(conn net.Conn) (*PeerCredentials, error)
+
+// adminGroups are the groups whose members may change the system through the helper.
+var adminGroups = []string{"sudo", "wheel", "admin"}
+
+// peerGroups returns the names of the groups of a peer, overridable in tests.
+var peerGroups = groupNames
+
+// identifyPeer returns the credentials of the process on the other end of a
+// helper connection, as reported by the kernel. The socket is world-writable
+// so every user's agent can reach it and run the read-only checks; actions
+// that change the system are authorized on these credentials, see
+// authorizeAdmin. The executable of the peer is not considered, since any
+// user can start the Pareto Security executable.
+func identifyPeer(conn net.Conn) (*PeerCredentials, error) {
+	if testing.Testing() && identifyPeerMock != nil {
+		return identifyPeerMock(conn)
+	}
+
+	peer, err := peerCredentials(conn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot verify peer: %w", err)
+	}
+	log.WithField("pid", peer.PID).WithField("uid", peer.UID).WithField("gid", peer.GID).Info("Peer connected")
+	return peer, nil
+}
+
+// authorizeAdmin allows root and members of adminGroups, who could make the
+// same changes with sudo. It guards the actions that change the system.
+func authorizeAdmin(peer *PeerCredentials) error {
+	if peer.UID == 0 {
+		return nil
+	}
+	groups, err := peerGroups(peer)
+	if err != nil {
+		return fmt.Errorf("cannot look up the groups of user %d: %w", peer.UID, err)
+	}
+	for _, group := range groups {
+		if slices.Contains(adminGroups, group) {
+			return nil
+		}
+	}
+	return fmt.Errorf("user %d is not an administrator, run `sudo paretosecurity fix` instead", peer.UID)
+}
+
+// groupNames returns the names of the group the peer runs as and of the
+// groups of its user.
+func groupNames(peer *PeerCredentials) ([]string, error) {
+	account, err := user.LookupId(fmt.Sprint(peer.UID))
+	if err != nil {
+		return nil, err
+	}
+	ids, err := account.GroupIds()
+	if err != nil {
+		return nil, err
+	}
+	ids = append(ids, fmt.Sprint(peer.GID))
+	names := []string{}
+	for _, id := range ids {
+		if group, err := user.LookupGroupId(id); err == nil {
+			names = append(names, group.Name)
+		}
+	}
+	return names, nil
+}
+
+// authorizeUser allows a connection only from processes of the current user.
+// Peer credentials are only available on Linux; elsewhere the permissions of
+// the socket file restrict access to its owner.
+func authorizeUser(conn net.Conn) error {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	peer, err := peerCredentials(conn)
+	if err != nil {
+		return fmt.Errorf("cannot verify peer: %w", err)
+	}
+	if peer.UID != uint32(os.Getuid()) {
+		return fmt.Errorf("peer %d runs as user %d", peer.PID, peer.UID)
+	}
+	return nil
+}
+
+// userListener accepts only connections that pass authorizeUser.
+type userListener struct {
+	net.Listener
+}
+
+// Accept waits for the next connection from the current user.
+func (l *userListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if err := authorizeUser(conn); err != nil {
+			log.WithError(err).Warn("Rejecting connection from another user")
+			conn.Close()
+			continue
+		}
+		return conn, nil
+	}
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentifyPeer(t *testing.T) {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "peer.sock"))
+	require.NoError(t, err)
+	defer listener.Close()
+
+	client, err := net.Dial("unix", listener.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	peer, err := identifyPeer(conn)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(os.Getuid()), peer.UID)
+	assert.Equal(t, uint32(os.Getgid()), peer.GID)
+	assert.Equal(t, int32(os.Getpid()), peer.PID)
+}
+
+func TestAuthorizeAdmin(t *testing.T) {
+	groups := peerGroups
+	defer func() { peerGroups = groups }()
+	peerGroups = func(peer *PeerCredentials) ([]string, error) {
+		if peer.GID == 10 {
+			return []string{"users", "wheel"}, nil
+		}
+		return []string{"users"}, nil
+	}
+
+	assert.NoError(t, authorizeAdmin(&PeerCredentials{UID: 0, GID: 100}))
+	assert.NoError(t, authorizeAdmin(&PeerCredentials{UID: 1000, GID: 10}))
+	assert.ErrorContains(t, authorizeAdmin(&PeerCredentials{UID: 1000, GID: 100}), "not an administrator")
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package runner
+
+import (
+	"errors"
+	"net"
+)
+
+// peerCredentials is only supported on Linux, where the root helper runs.
+func peerCredentials(_ net.Conn) (*PeerCredentials, error) {
+	return nil, errors.New("peer credentials are not supported on this platform")
+}
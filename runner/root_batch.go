@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/shared"
+)
+
+// rootResult is the outcome of a check run by the root helper. done is closed
+// once status and err are set.
+type rootResult struct {
+	status *CheckStatus
+	err    error
+	done   chan struct{}
+}
+
+// rootBatch runs all root checks of a run in a single helper round-trip and
+// hands each streamed status to the jobs waiting for it.
+type rootBatch struct {
+	results map[string]*rootResult
+}
+
+// startRootBatch sends the root checks among jobs that will run to the helper.
+// It returns immediately; jobs collect their results with wait.
+func startRootBatch(ctx context.Context, jobs []*job) *rootBatch {
+	batch := &rootBatch{results: map[string]*rootResult{}}
+	uuids := []string{}
+	for _, j := range jobs {
+		uuid := j.chk.UUID()
+		if !j.chk.RequiresRoot() || !j.chk.IsRunnable() || shared.IsCheckDisabled(uuid) {
+			continue
+		}
+		if _, ok := batch.results[uuid]; ok {
+			continue
+		}
+		batch.results[uuid] = &rootResult{done: make(chan struct{})}
+		uuids = append(uuids, uuid)
+	}
+	if len(uuids) == 0 {
+		return batch
+	}
+
+	go func() {
+		err := RunChecksViaRoot(ctx, uuids, func(status *CheckStatus) {
+			result := batch.results[status.UUID]
+			result.status = status
+			if status.State == check.CheckStateError {
+				result.err = errors.New(status.Error)
+			}
+			close(result.done)
+		})
+		if err == nil {
+			err = errors.New("root helper did not return a result")
+		}
+		for _, uuid := range uuids {
+			result := batch.results[uuid]
+			if result.status == nil {
+				result.status, result.err = &CheckStatus{}, err
+				close(result.done)
+			}
+		}
+	}()
+	return batch
+}
+
+// wait returns the status of a root check from the batch. Checks that are not
+// part of the batch are run by the helper on their own.
+func (b *rootBatch) wait(ctx context.Context, uuid string) (*CheckStatus, error) {
+	result, ok := b.results[uuid]
+	if !ok {
+		return RunCheckViaRootContext(ctx, uuid)
+	}
+	select {
+	case <-result.done:
+		return result.status, result.err
+	case <-ctx.Done():
+		return &CheckStatus{}, contextError(ctx)
+	}
+}
@@ -1,111 +1,373 @@
 package runner
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net"
+	"os"
+	"time"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 )
 
+// ProtocolVersion is the version of the root helper protocol spoken by this build.
+// The helper rejects requests with any other version, so a user process and
+// a helper from different releases detect each other instead of misbehaving.
+const ProtocolVersion = 2
+
+// Actions the root helper can perform on the requested checks.
+const (
+	ActionRun = "run"
+	// ActionFix applies the root steps of a check's remediation and re-runs it.
+	ActionFix = "fix"
+)
+
+// maxBatchSize bounds the number of checks in a single helper request.
+const maxBatchSize = 128
+
+// maxRequestSize bounds the size of a single helper request in bytes.
+const maxRequestSize = 64 << 10
+
+// helperReadTimeout is how long the helper waits for a peer to send its
+// request, so a peer that connects and stays silent cannot stall the helper.
+var helperReadTimeout = 5 * time.Second
+
+// helperWriteTimeout is how long the helper waits for a peer to accept a
+// status before giving up on the connection.
+var helperWriteTimeout = 5 * time.Second
+
+// helperGrace is how much longer than shared.PerCheckTimeout the client waits
+// for a check, so the helper's own timeout is reported first.
+const helperGrace = 5 * time.Second
+
+// Codes of CheckStatus.Code. Statuses without a UUID reject the whole request.
+const (
+	CodeUnsupportedVersion = "unsupported_version"
+	CodeUnauthorized       = "unauthorized"
+	CodeBadRequest         = "bad_request"
+	CodeNotFound           = "not_found"
+	CodeCheckError         = "check_error"
+)
+
+var (
+	// ErrHelperVersion is returned when the root helper speaks another protocol version.
+	ErrHelperVersion = errors.New("root helper protocol version mismatch, restart it with `sudo systemctl restart paretosecurity.socket` after upgrading")
+	// ErrHelperRejected is returned when the root helper refuses a request.
+	ErrHelperRejected = errors.New("root helper rejected the request")
+)
+
+// HelperRequest asks the root helper to perform an action on a batch of checks.
+type HelperRequest struct {
+	Version int      `json:"version"`
+	Action  string   `json:"action"`
+	UUIDs   []string `json:"uuids"`
+}
+
+// CheckStatus is one line of the root helper response, the outcome of a
+// single check. A check that could not be run has State set to
+// check.CheckStateError and the reason in Error, which is distinct from a
+// check that ran and failed. Evidence is what the check relied on.
 type CheckStatus struct {
-	UUID    string `json:"uuid"`
-	Passed  bool   `json:"passed"`
-	Details string `json:"details"`
+	Version  int              `json:"version"`
+	UUID     string           `json:"uuid"`
+	State    check.CheckState `json:"state"`
+	Passed   bool             `json:"passed"`
+	Details  string           `json:"details"`
+	Code     string           `json:"code,omitempty"`
+	Error    string           `json:"error,omitempty"`
+	Evidence []check.Evidence `json:"evidence,omitempty"`
 }
 
-// handleConnection handles an incoming network connection.
-// It reads input from the connection, processes the input to run checks,
-// and sends back the status of the checks as a JSON response.
+// errorStatus returns a status in the error state. Details repeats the error so
+// helpers and clients predating the error fields still show a reason.
+func errorStatus(uuid, code, msg string) *CheckStatus {
+	return &CheckStatus{
+		Version: ProtocolVersion,
+		UUID:    uuid,
+		State:   check.CheckStateError,
+		Details: msg,
+		Code:    code,
+		Error:   msg,
+	}
+}
+
+// HandleConnection serves a single root helper connection.
 //
-// The input is expected to be a JSON object containing a "uuid" key.
-// The function will look for checks that are runnable, require root,
-// and match the provided UUID. It will run those checks and collect their status.
+// It verifies the peer, reads one HelperRequest of at most maxRequestSize
+// bytes within helperReadTimeout and writes one CheckStatus per requested
+// UUID, in request order, as newline-delimited JSON as soon as each check
+// finishes. Requests with another protocol version, an unknown
+// action or from an unauthorized peer get a single status without a UUID.
+// Fixes change the system, so only administrators may request them.
 func HandleConnection(conn net.Conn) {
 	defer conn.Close()
 	log.Info("Connection received")
 
-	// Read input from connection
-	decoder := json.NewDecoder(conn)
-	var input map[string]string
-	if err := decoder.Decode(&input); err != nil {
+	encoder := json.NewEncoder(conn)
+	reply := func(status *CheckStatus) bool {
+		if err := conn.SetWriteDeadline(time.Now().Add(helperWriteTimeout)); err != nil {
+			log.Debugf("Failed to set write deadline: %v\n", err)
+			return false
+		}
+		if err := encoder.Encode(status); err != nil {
+			log.Debugf("Failed to write to connection: %v\n", err)
+			return false
+		}
+		return true
+	}
+
+	peer, err := identifyPeer(conn)
+	if err != nil {
+		log.WithError(err).Warn("Rejecting unauthorized peer")
+		reply(errorStatus("", CodeUnauthorized, err.Error()))
+		return
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(helperReadTimeout)); err != nil {
+		log.Debugf("Failed to set read deadline: %v\n", err)
+		return
+	}
+	var request HelperRequest
+	if err := json.NewDecoder(io.LimitReader(conn, maxRequestSize)).Decode(&request); err != nil {
 		log.Debugf("Failed to decode input: %v\n", err)
+		reply(errorStatus("", CodeBadRequest, "malformed request"))
+		return
+	}
+	// Nothing else is read from the peer, and checks may take longer than the read timeout
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		log.Debugf("Failed to clear read deadline: %v\n", err)
+		return
+	}
+	if request.Version != ProtocolVersion {
+		log.WithField("version", request.Version).Warn("Rejecting request with unsupported protocol version")
+		reply(errorStatus("", CodeUnsupportedVersion, fmt.Sprintf("unsupported protocol version %d, helper %s speaks version %d", request.Version, shared.Version, ProtocolVersion)))
+		return
+	}
+	if request.Action == "" {
+		request.Action = ActionRun
+	}
+	if request.Action != ActionRun && request.Action != ActionFix {
+		reply(errorStatus("", CodeBadRequest, fmt.Sprintf("unknown action %q", request.Action)))
 		return
 	}
-	uuid, ok := input["uuid"]
-	if !ok {
-		log.Debugf("UUID not found in input")
+	if request.Action == ActionFix {
+		if err := authorizeAdmin(peer); err != nil {
+			log.WithError(err).Warn("Rejecting fix from a user who is not an administrator")
+			reply(errorStatus("", CodeUnauthorized, err.Error()))
+			return
+		}
+	}
+	if len(request.UUIDs) == 0 || len(request.UUIDs) > maxBatchSize {
+		reply(errorStatus("", CodeBadRequest, fmt.Sprintf("a request must name between 1 and %d checks", maxBatchSize)))
 		return
 	}
-	log.Debugf("Received UUID: %s", uuid)
+	log.WithField("action", request.Action).WithField("uuids", request.UUIDs).Debug("Received request")
 
-	status := &CheckStatus{
-		UUID:    uuid,
-		Passed:  false,
-		Details: "Check not found",
+	for _, uuid := range request.UUIDs {
+		var status *CheckStatus
+		if request.Action == ActionFix {
+			status = fixCheck(uuid)
+		} else {
+			status = runRootCheck(uuid)
+		}
+		if !reply(status) {
+			return
+		}
 	}
-	for _, claim := range claims.All {
+}
+
+// findRootCheck returns the built-in check with the given UUID.
+func findRootCheck(uuid string) (check.Check, bool) {
+	return findCheck(claims.All, uuid)
+}
+
+// findCheck returns the check with the given UUID.
+func findCheck(all []claims.Claim, uuid string) (check.Check, bool) {
+	for _, claim := range all {
 		for _, chk := range claim.Checks {
-			if chk.RequiresRoot() && uuid == chk.UUID() {
-				log.Infof("Running check %s\n", chk.UUID())
-				if chk.Run() != nil {
-					log.Warnf("Failed to run check %s\n", chk.UUID())
-					continue
-				}
-				log.Infof("Check %s completed\n", chk.UUID())
-				status.Passed = chk.Passed()
-				status.Details = chk.Status()
-				log.Infof("Check %s status: %v\n", chk.UUID(), status.Passed)
+			if chk.UUID() == uuid {
+				return chk, true
 			}
 		}
 	}
+	return nil, false
+}
 
-	// Handle the request
-	response, err := json.Marshal(status)
-	if err != nil {
-		log.Debugf("Failed to marshal response: %v\n", err)
-		return
+// runRootCheck runs a check that requires root and returns its status.
+func runRootCheck(uuid string) *CheckStatus {
+	chk, found := findRootCheck(uuid)
+	if !found || !chk.RequiresRoot() {
+		return errorStatus(uuid, CodeNotFound, "check not found")
+	}
+
+	log.Infof("Running check %s\n", chk.UUID())
+	trail := &check.Trail{}
+	if err := RunCheck(check.WithTrail(context.Background(), trail), chk); err != nil {
+		log.WithError(err).Warnf("Failed to run check %s\n", chk.UUID())
+		status := errorStatus(uuid, CodeCheckError, err.Error())
+		status.Evidence = trail.Entries()
+		return status
+	}
+	log.Infof("Check %s status: %v\n", chk.UUID(), chk.Passed())
+	status := checkStatus(chk)
+	status.Evidence = trail.Entries()
+	return status
+}
+
+// checkStatus returns the status of a check that ran successfully.
+func checkStatus(chk check.Check) *CheckStatus {
+	return &CheckStatus{
+		Version: ProtocolVersion,
+		UUID:    chk.UUID(),
+		State:   resultState(chk.Passed(), false),
+		Passed:  chk.Passed(),
+		Details: chk.Status(),
+	}
+}
+
+// fixCheck applies the root steps of the remediation of the check with the
+// given UUID and returns the status of the check after the fix. Like
+// runRootCheck, it only serves checks that require root.
+func fixCheck(uuid string) *CheckStatus {
+	chk, found := findRootCheck(uuid)
+	if !found || !chk.RequiresRoot() || !chk.IsRunnable() {
+		return errorStatus(uuid, CodeNotFound, "check not found")
+	}
+
+	log.Infof("Fixing check %s\n", chk.UUID())
+	if err := applyRootFix(context.Background(), chk); err != nil {
+		log.WithError(err).Warnf("Failed to fix check %s\n", chk.UUID())
+		return errorStatus(uuid, CodeCheckError, fmt.Sprintf("failed to apply fix: %s", err))
 	}
-	if _, err = conn.Write(response); err != nil {
-		log.Debugf("Failed to write to connection: %v\n", err)
+	if err := RunCheck(context.Background(), chk); err != nil {
+		return errorStatus(uuid, CodeCheckError, fmt.Sprintf("fix applied, but the check failed to run: %s", err))
 	}
+	return checkStatus(chk)
 }
 
-// RunCheckViaRoot connects to a Unix socket, sends a UUID, and receives a boolean status.
+// RunCheckViaRoot runs a single check in the root helper and returns its status.
 // It is used to execute a check with root privileges via a helper process.
-// The function establishes a connection to the socket specified by SocketPath,
-// sends the UUID as a JSON-encoded string, and then decodes the JSON response
-// to determine the status of the check. It returns the boolean status associated
-// with the UUID and any error encountered during the process.
+// A check the helper could not run is returned with an error.
 func RunCheckViaRoot(uuid string) (*CheckStatus, error) {
+	return RunCheckViaRootContext(context.Background(), uuid)
+}
+
+// RunCheckViaRootContext is like RunCheckViaRoot but gives up waiting for the
+// helper once ctx is done or shared.PerCheckTimeout has passed.
+func RunCheckViaRootContext(ctx context.Context, uuid string) (*CheckStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, shared.PerCheckTimeout)
+	defer cancel()
 
-	rateLimitCall.Take()
-	log.WithField("uuid", uuid).Debug("Running check via root helper")
+	return callHelperOne(ctx, ActionRun, uuid)
+}
+
+// RunChecksViaRoot runs a batch of root checks in a single helper round-trip.
+// onStatus is called for every check as soon as the helper streams its
+// status back, in the order of uuids. The helper is given
+// shared.PerCheckTimeout for each check.
+func RunChecksViaRoot(ctx context.Context, uuids []string, onStatus func(*CheckStatus)) error {
+	for start := 0; start < len(uuids); start += maxBatchSize {
+		batch := uuids[start:min(start+maxBatchSize, len(uuids))]
+		if err := callHelper(ctx, HelperRequest{Action: ActionRun, UUIDs: batch}, onStatus); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	conn, err := net.Dial("unix", SocketPath)
+// callHelperOne performs action on a single check in the root helper.
+func callHelperOne(ctx context.Context, action, uuid string) (*CheckStatus, error) {
+	var status *CheckStatus
+	err := callHelper(ctx, HelperRequest{Action: action, UUIDs: []string{uuid}}, func(s *CheckStatus) {
+		status = s
+	})
+	if err != nil {
+		return &CheckStatus{}, err
+	}
+	if status.State == check.CheckStateError {
+		return status, errors.New(status.Error)
+	}
+	return status, nil
+}
+
+// callHelper sends request to the root helper and calls onStatus for every
+// status in the response as it arrives. It returns an error if the helper
+// cannot be reached, rejects the request, speaks another protocol version or
+// does not answer for every requested check.
+func callHelper(ctx context.Context, request HelperRequest, onStatus func(*CheckStatus)) error {
+	request.Version = ProtocolVersion
+	log.WithField("request", request).Debug("Sending request to root helper")
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", SocketPath)
 	if err != nil {
 		log.WithError(err).Warn("Failed to connect to root helper")
-		return &CheckStatus{}, errors.New("failed to connect to root helper")
+		return errors.New("failed to connect to root helper")
 	}
 	defer conn.Close()
+	// Unblock reads and writes as soon as ctx is done
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
 
-	// Send UUID
-	input := map[string]string{"uuid": uuid}
-	encoder := json.NewEncoder(conn)
-	log.WithField("input", input).Debug("Sending input to helper")
-	if err := encoder.Encode(input); err != nil {
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
 		log.WithError(err).Warn("Failed to encode JSON")
-		return &CheckStatus{}, errors.New("failed to encode JSON")
+		return errors.New("failed to encode JSON")
 	}
 
-	// Read response
 	decoder := json.NewDecoder(conn)
-	var status = &CheckStatus{}
-	if err := decoder.Decode(status); err != nil {
-		log.WithError(err).Warn("Failed to decode JSON")
-		return &CheckStatus{}, errors.New("failed to decode JSON")
+	for received := 0; received < len(request.UUIDs); received++ {
+		// Every check has its own time budget, however long the batch is, but
+		// never past the deadline of ctx
+		deadline := time.Now().Add(shared.PerCheckTimeout + helperGrace)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			log.WithError(err).Debug("Failed to set helper connection deadline")
+		}
+		// The deadline above replaces the one set when ctx is done, so check
+		// ctx only after setting it
+		if ctx.Err() != nil {
+			return contextError(ctx)
+		}
+		status := &CheckStatus{}
+		if err := decoder.Decode(status); err != nil {
+			if ctx.Err() != nil {
+				return contextError(ctx)
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return fmt.Errorf("%w after %s waiting for the root helper", ErrCheckTimeout, shared.PerCheckTimeout)
+			}
+			if errors.Is(err, io.EOF) && received == 0 {
+				// Helpers predating the versioned protocol drop requests they cannot parse
+				return fmt.Errorf("%w: the helper closed the connection without replying", ErrHelperVersion)
+			}
+			log.WithError(err).Warn("Failed to decode JSON")
+			return errors.New("failed to decode JSON")
+		}
+		log.WithField("status", status).Debug("Received status from helper")
+
+		if status.Version != ProtocolVersion {
+			return fmt.Errorf("%w: helper speaks version %d, expected %d", ErrHelperVersion, status.Version, ProtocolVersion)
+		}
+		if status.UUID == "" {
+			if status.Code == CodeUnsupportedVersion {
+				return fmt.Errorf("%w: %s", ErrHelperVersion, status.Error)
+			}
+			return fmt.Errorf("%w: %s", ErrHelperRejected, status.Error)
+		}
+		if status.UUID != request.UUIDs[received] {
+			return fmt.Errorf("root helper answered for %s, expected %s", status.UUID, request.UUIDs[received])
+		}
+		onStatus(status)
 	}
-	log.WithField("status", status).Debug("Received status from helper")
-	return status, nil
+	return nil
 }
@@ -1,15 +1,21 @@
 package runner
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type MockCheck struct {
@@ -30,79 +36,270 @@ func (m *MockCheck) PassedMessage() string { return "Mock check passed" }
 func (m *MockCheck) IsRunnable() bool      { return true }
 func (m *MockCheck) Name() string          { return "MockCheck" }
 
-func TestHandleConnection(t *testing.T) {
-	// Setup
-	uuid := "test-uuid"
-	input := map[string]string{"uuid": uuid}
-	inputJSON, _ := json.Marshal(input)
-
-	// Mock connection
-	conn := &mockConn{
-		readData: string(inputJSON),
-	}
+// helperRequest encodes a request of the current protocol version.
+func helperRequest(action string, uuids ...string) string {
+	request, _ := json.Marshal(HelperRequest{Version: ProtocolVersion, Action: action, UUIDs: uuids})
+	return string(request)
+}
 
-	// Mock check
-	mockCheck := &MockCheck{
-		UUIDValue:         uuid,
-		RequiresRootValue: true,
-		RunError:          nil,
-		PassedValue:       true,
-		StatusValue:       "Check passed",
+// helperResponses decodes all statuses written to a mock connection.
+func helperResponses(t *testing.T, conn *mockConn) []CheckStatus {
+	statuses := []CheckStatus{}
+	decoder := json.NewDecoder(strings.NewReader(conn.writtenData))
+	for decoder.More() {
+		var status CheckStatus
+		assert.NoError(t, decoder.Decode(&status))
+		statuses = append(statuses, status)
 	}
+	return statuses
+}
+
+func allowAllPeers(t *testing.T) {
+	allowPeer(t, &PeerCredentials{PID: 1, UID: 0, GID: 0})
+}
+
+// allowPeer makes every connection come from peer.
+func allowPeer(t *testing.T, peer *PeerCredentials) {
+	identifyPeerMock = func(net.Conn) (*PeerCredentials, error) { return peer, nil }
+	t.Cleanup(func() { identifyPeerMock = nil })
+}
 
-	// Set claims.All to use the mock check
+func TestHandleConnection(t *testing.T) {
+	allowAllPeers(t)
 	claims.All = []claims.Claim{
 		{
-			Checks: []check.Check{mockCheck},
+			Checks: []check.Check{
+				&MockCheck{UUIDValue: "pass-uuid", RequiresRootValue: true, PassedValue: true, StatusValue: "Check passed"},
+				&MockCheck{UUIDValue: "fail-uuid", RequiresRootValue: true, PassedValue: false, StatusValue: "Check failed"},
+				&MockCheck{UUIDValue: "error-uuid", RequiresRootValue: true, RunError: errors.New("boom")},
+				&MockCheck{UUIDValue: "user-uuid", RequiresRootValue: false, PassedValue: true},
+			},
 		},
 	}
 
-	// Execute
+	conn := &mockConn{readData: helperRequest(ActionRun, "pass-uuid", "fail-uuid", "error-uuid", "user-uuid", "missing-uuid")}
 	HandleConnection(conn)
 
-	// Assertions
 	assert.True(t, conn.closed, "Connection should be closed")
+	statuses := helperResponses(t, conn)
+	assert.Len(t, statuses, 5)
+	for _, status := range statuses {
+		assert.Equal(t, ProtocolVersion, status.Version)
+	}
+	assert.Equal(t, CheckStatus{Version: ProtocolVersion, UUID: "pass-uuid", State: check.CheckStatePassed, Passed: true, Details: "Check passed"}, statuses[0])
+	assert.Equal(t, check.CheckStateFailed, statuses[1].State)
+	assert.Equal(t, "Check failed", statuses[1].Details)
+	assert.Equal(t, check.CheckStateError, statuses[2].State)
+	assert.Equal(t, CodeCheckError, statuses[2].Code)
+	assert.Equal(t, "boom", statuses[2].Error)
+	// Checks that do not need root are not run by the helper
+	assert.Equal(t, CodeNotFound, statuses[3].Code)
+	assert.Equal(t, CodeNotFound, statuses[4].Code)
+}
+
+func TestHandleConnectionRejects(t *testing.T) {
+	allowAllPeers(t)
+	claims.All = []claims.Claim{{Checks: []check.Check{&MockCheck{UUIDValue: "test-uuid", RequiresRootValue: true}}}}
 
-	var response CheckStatus
-	err := json.Unmarshal([]byte(conn.writtenData), &response)
-	assert.NoError(t, err, "Should not error when unmarshaling response")
+	tests := []struct {
+		name  string
+		input string
+		code  string
+	}{
+		{name: "legacy request", input: `{"uuid": "test-uuid"}`, code: CodeUnsupportedVersion},
+		{name: "future version", input: `{"version": 99, "uuids": ["test-uuid"]}`, code: CodeUnsupportedVersion},
+		{name: "unknown action", input: helperRequest("delete", "test-uuid"), code: CodeBadRequest},
+		{name: "no checks", input: helperRequest(ActionRun), code: CodeBadRequest},
+		{name: "malformed", input: `not json`, code: CodeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &mockConn{readData: tt.input}
+			HandleConnection(conn)
 
-	assert.Equal(t, uuid, response.UUID, "UUID should match")
-	assert.True(t, response.Passed, "Check should pass")
-	assert.Equal(t, "Check passed", response.Details, "Details should match")
+			statuses := helperResponses(t, conn)
+			assert.Len(t, statuses, 1)
+			assert.Empty(t, statuses[0].UUID)
+			assert.Equal(t, tt.code, statuses[0].Code)
+			assert.Equal(t, check.CheckStateError, statuses[0].State)
+			assert.False(t, statuses[0].Passed)
+		})
+	}
 }
 
-func TestRunCheckViaRoot(t *testing.T) {
-	// Setup
+func TestHandleConnectionSilentPeer(t *testing.T) {
+	allowAllPeers(t)
+	helperReadTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { helperReadTimeout = 5 * time.Second })
+
+	server, client := net.Pipe()
+	defer client.Close()
+	done := make(chan struct{})
+	go func() {
+		HandleConnection(server)
+		close(done)
+	}()
 
-	expectedStatus := &CheckStatus{
-		UUID:    "",
-		Passed:  false,
-		Details: "",
+	// The peer never sends a request and is answered once the read times out
+	var status CheckStatus
+	require.NoError(t, json.NewDecoder(client).Decode(&status))
+	assert.Equal(t, CodeBadRequest, status.Code)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("helper kept waiting for a silent peer")
 	}
+}
+
+func TestHandleConnectionOversizedRequest(t *testing.T) {
+	allowAllPeers(t)
+	claims.All = []claims.Claim{{Checks: []check.Check{&MockCheck{UUIDValue: "test-uuid", RequiresRootValue: true}}}}
+
+	padding := strings.Repeat("a", maxRequestSize)
+	conn := &mockConn{readData: `{"version": 2, "action": "run", "uuids": ["test-uuid"], "padding": "` + padding + `"}`}
+	HandleConnection(conn)
 
-	// Execute
-	status, _ := RunCheckViaRoot("test-uuid")
-	assert.Equal(t, expectedStatus, status, "Status should match")
+	statuses := helperResponses(t, conn)
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, CodeBadRequest, statuses[0].Code)
+}
+
+func TestHandleConnectionUnauthorized(t *testing.T) {
+	// A fake connection carries no peer credentials
+	conn := &mockConn{readData: helperRequest(ActionRun, "test-uuid")}
+	HandleConnection(conn)
 
+	statuses := helperResponses(t, conn)
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, CodeUnauthorized, statuses[0].Code)
+}
+
+func TestRunCheckViaRoot(t *testing.T) {
+	SocketPath = filepath.Join(t.TempDir(), "missing.sock")
+
+	status, err := RunCheckViaRoot("test-uuid")
+	assert.Error(t, err)
+	assert.Equal(t, &CheckStatus{}, status, "Status should match")
+}
+
+// serveHelper answers every connection on a temporary SocketPath with handler.
+func serveHelper(t *testing.T, handler func(net.Conn)) {
+	SocketPath = filepath.Join(t.TempDir(), "helper.sock")
+	listener, err := net.Listen("unix", SocketPath)
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			handler(conn)
+		}
+	}()
+}
+
+func TestRunCheckViaRootOverSocket(t *testing.T) {
+	claims.All = []claims.Claim{{Checks: []check.Check{
+		&MockCheck{UUIDValue: "pass-uuid", RequiresRootValue: true, PassedValue: true, StatusValue: "Check passed"},
+		&MockCheck{UUIDValue: "error-uuid", RequiresRootValue: true, RunError: errors.New("boom")},
+	}}}
+	// Peer verification runs for real: the test process connects to itself
+	serveHelper(t, HandleConnection)
+
+	status, err := RunCheckViaRoot("pass-uuid")
+	assert.NoError(t, err)
+	assert.True(t, status.Passed)
+	assert.Equal(t, "Check passed", status.Details)
+
+	status, err = RunCheckViaRoot("error-uuid")
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, check.CheckStateError, status.State)
+}
+
+func TestRunCheckViaRootVersionMismatch(t *testing.T) {
+	t.Run("newer helper", func(t *testing.T) {
+		serveHelper(t, func(conn net.Conn) {
+			defer conn.Close()
+			_ = json.NewEncoder(conn).Encode(CheckStatus{Version: ProtocolVersion + 1, UUID: "test-uuid", Passed: true})
+		})
+		_, err := RunCheckViaRoot("test-uuid")
+		assert.ErrorIs(t, err, ErrHelperVersion)
+	})
+
+	t.Run("helper rejects version", func(t *testing.T) {
+		serveHelper(t, func(conn net.Conn) {
+			defer conn.Close()
+			_ = json.NewEncoder(conn).Encode(errorStatus("", CodeUnsupportedVersion, "unsupported protocol version 2"))
+		})
+		_, err := RunCheckViaRoot("test-uuid")
+		assert.ErrorIs(t, err, ErrHelperVersion)
+	})
+
+	t.Run("legacy helper", func(t *testing.T) {
+		serveHelper(t, func(conn net.Conn) {
+			defer conn.Close()
+			// Old helpers cannot decode the request and hang up
+			var input map[string]string
+			assert.Error(t, json.NewDecoder(conn).Decode(&input))
+		})
+		_, err := RunCheckViaRoot("test-uuid")
+		assert.ErrorIs(t, err, ErrHelperVersion)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		serveHelper(t, func(conn net.Conn) {
+			defer conn.Close()
+			_ = json.NewEncoder(conn).Encode(errorStatus("", CodeUnauthorized, "cannot verify peer"))
+		})
+		_, err := RunCheckViaRoot("test-uuid")
+		assert.ErrorIs(t, err, ErrHelperRejected)
+	})
+}
+
+func TestRunChecksViaRootCanceled(t *testing.T) {
+	serveHelper(t, func(conn net.Conn) {
+		defer conn.Close()
+		decoder := json.NewDecoder(conn)
+		var request HelperRequest
+		assert.NoError(t, decoder.Decode(&request))
+		_ = json.NewEncoder(conn).Encode(CheckStatus{Version: ProtocolVersion, UUID: "first-uuid", Passed: true})
+		// Never answer for the second check, until the client hangs up
+		_ = decoder.Decode(&request)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := time.Now()
+	err := RunChecksViaRoot(ctx, []string{"first-uuid", "second-uuid"}, func(*CheckStatus) {
+		// Canceled between two reads, and the connection deadline is set
+		// on cancellation before the next read deadline is
+		cancel()
+		time.Sleep(50 * time.Millisecond)
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(started), shared.PerCheckTimeout)
 }
 
 // mockConn is a mock implementation of the net.Conn interface.
 type mockConn struct {
 	readData    string
+	reader      *strings.Reader
 	writtenData string
 	closed      bool
 }
 
 // Read mocks the Read method of the net.Conn interface.
 func (m *mockConn) Read(b []byte) (n int, err error) {
-	r := strings.NewReader(m.readData)
-	return r.Read(b)
+	if m.reader == nil {
+		m.reader = strings.NewReader(m.readData)
+	}
+	return m.reader.Read(b)
 }
 
 // Write mocks the Write method of the net.Conn interface.
 func (m *mockConn) Write(b []byte) (n int, err error) {
-	m.writtenData = string(b)
+	m.writtenData += string(b)
 	return len(b), nil
 }
 
@@ -136,3 +333,83 @@ func (m *mockConn) SetReadDeadline(t time.Time) error {
 func (m *mockConn) SetWriteDeadline(t time.Time) error {
 	return nil
 }
+
+func TestCheckBatchesRootChecks(t *testing.T) {
+	shared.HistoryPath = filepath.Join(t.TempDir(), "history")
+	rootClaims := []claims.Claim{{Title: "Root", Checks: []check.Check{
+		&MockCheck{UUIDValue: "root-pass", RequiresRootValue: true, PassedValue: true, StatusValue: "ok"},
+		&MockCheck{UUIDValue: "root-fail", RequiresRootValue: true, PassedValue: false, StatusValue: "bad"},
+		&MockCheck{UUIDValue: "root-error", RequiresRootValue: true, RunError: errors.New("boom")},
+		&DummyCheck{name: "User", runnable: true, passedVal: true, statusMsg: "ok", uuid: "user-pass"},
+	}}}
+	claims.All = rootClaims
+
+	allowAllPeers(t)
+	var connections atomic.Int32
+	serveHelper(t, func(conn net.Conn) {
+		connections.Add(1)
+		HandleConnection(conn)
+	})
+
+	results := Check(context.Background(), rootClaims, []string{}, nil)
+
+	assert.Equal(t, int32(1), connections.Load())
+	assert.Len(t, results, 4)
+	assert.Equal(t, []check.CheckState{
+		check.CheckStatePassed,
+		check.CheckStateFailed,
+		check.CheckStateError,
+		check.CheckStatePassed,
+	}, []check.CheckState{results[0].State, results[1].State, results[2].State, results[3].State})
+	assert.Equal(t, "boom", results[2].Details)
+}
+
+func TestCheckRootHelperUnavailable(t *testing.T) {
+	shared.HistoryPath = filepath.Join(t.TempDir(), "history")
+	SocketPath = filepath.Join(t.TempDir(), "missing.sock")
+	rootClaims := []claims.Claim{{Title: "Root", Checks: []check.Check{
+		&MockCheck{UUIDValue: "root-a", RequiresRootValue: true},
+		&MockCheck{UUIDValue: "root-b", RequiresRootValue: true},
+	}}}
+
+	results := Check(context.Background(), rootClaims, []string{}, nil)
+
+	assert.Len(t, results, 2)
+	for _, result := range results {
+		assert.Equal(t, check.CheckStateError, result.State)
+		assert.Equal(t, "failed to connect to root helper", result.Details)
+	}
+}
+
+// EvidenceCheck records the rule that decided its result.
+type EvidenceCheck struct {
+	DummyCheck
+	root bool
+}
+
+func (e *EvidenceCheck) RequiresRoot() bool { return e.root }
+func (e *EvidenceCheck) RunContext(ctx context.Context) error {
+	check.Decided(ctx, e.uuid, "decided as %s", map[bool]string{true: "root", false: "user"}[e.root])
+	return nil
+}
+
+func TestCheckSavesEvidence(t *testing.T) {
+	withMetricsPaths(t)
+	rootClaims := []claims.Claim{{Title: "Root", Checks: []check.Check{
+		&EvidenceCheck{DummyCheck: DummyCheck{name: "Root", runnable: true, passedVal: true, uuid: "root-evidence"}, root: true},
+		&EvidenceCheck{DummyCheck: DummyCheck{name: "User", runnable: true, uuid: "user-evidence"}},
+		&DummyCheck{name: "Silent", runnable: true, uuid: "silent"},
+	}}}
+	claims.All = rootClaims
+	allowAllPeers(t)
+	serveHelper(t, HandleConnection)
+
+	Check(context.Background(), rootClaims, []string{}, nil)
+
+	evidence, err := shared.LoadEvidence()
+	require.NoError(t, err)
+	assert.Equal(t, []check.Evidence{{Kind: check.EvidenceRule, Subject: "root-evidence", Detail: "decided as root"}}, evidence["root-evidence"].Evidence)
+	assert.Equal(t, []check.Evidence{{Kind: check.EvidenceRule, Subject: "user-evidence", Detail: "decided as user"}}, evidence["user-evidence"].Evidence)
+	assert.Empty(t, evidence["silent"].Evidence)
+	assert.False(t, evidence["silent"].Time.IsZero(), "checks that record nothing still have an entry for the run")
+}
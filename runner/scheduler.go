@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/caarlos0/log"
+)
+
+// logLine is a log message buffered until the check's turn to print.
+type logLine struct {
+	level log.Level
+	msg   string
+}
+
+// job is a check scheduled in a run.
+type job struct {
+	claim    claims.Claim
+	chk      check.Check
+	deps     []*job
+	done     chan struct{}
+	result   *CheckResult
+	logs     []logLine
+	evidence []check.Evidence
+}
+
+func newJob(claim claims.Claim, chk check.Check) *job {
+	return &job{claim: claim, chk: chk, done: make(chan struct{})}
+}
+
+// logf buffers a log line for the job.
+func (j *job) logf(level log.Level, format string, args ...any) {
+	j.logs = append(j.logs, logLine{level: level, msg: fmt.Sprintf(format, args...)})
+}
+
+// record stores the outcome of the job.
+func (j *job) record(state check.CheckState, details string) {
+	j.result = &CheckResult{
+		Claim:    j.claim.Title,
+		UUID:     j.chk.UUID(),
+		Name:     j.chk.Name(),
+		State:    state,
+		Details:  details,
+		Severity: check.SeverityOf(j.chk),
+		Root:     j.chk.RequiresRoot(),
+	}
+}
+
+// flush writes the buffered log lines of the job to logger.
+func (j *job) flush(logger *log.Logger) {
+	for _, line := range j.logs {
+		switch line.level {
+		case log.WarnLevel:
+			logger.Warn(line.msg)
+		case log.DebugLevel:
+			logger.Debug(line.msg)
+		default:
+			logger.Info(line.msg)
+		}
+	}
+}
+
+// orderJobs sorts jobs so that every job comes after the jobs it depends on,
+// keeping the declaration order otherwise. Dependencies on checks that are not
+// part of the run are ignored. Jobs that are part of a dependency cycle cannot
+// be ordered and are returned separately.
+func orderJobs(jobs []*job) (ordered []*job, cyclic []*job) {
+	byUUID := make(map[string]*job, len(jobs))
+	for _, j := range jobs {
+		byUUID[j.chk.UUID()] = j
+	}
+
+	pending := make(map[*job]int, len(jobs))
+	dependents := make(map[*job][]*job, len(jobs))
+	for _, j := range jobs {
+		j.deps = nil
+		if dependent, ok := j.chk.(check.Dependent); ok {
+			for _, uuid := range dependent.DependsOn() {
+				if dep, found := byUUID[uuid]; found && dep != j {
+					j.deps = append(j.deps, dep)
+					dependents[dep] = append(dependents[dep], j)
+				}
+			}
+		}
+		pending[j] = len(j.deps)
+	}
+
+	placed := make(map[*job]bool, len(jobs))
+	for len(ordered) < len(jobs) {
+		progressed := false
+		for _, j := range jobs {
+			if placed[j] || pending[j] > 0 {
+				continue
+			}
+			placed[j] = true
+			progressed = true
+			ordered = append(ordered, j)
+			for _, dependent := range dependents[j] {
+				pending[dependent]--
+			}
+		}
+		if !progressed {
+			break
+		}
+	}
+
+	for _, j := range jobs {
+		if !placed[j] {
+			cyclic = append(cyclic, j)
+		}
+	}
+	return ordered, cyclic
+}
@@ -0,0 +1,190 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/stretchr/testify/assert"
+)
+
+// DependentCheck is a DummyCheck that declares prerequisites and records when it ran.
+type DependentCheck struct {
+	DummyCheck
+	deps  []string
+	order *[]string
+	mu    *sync.Mutex
+	delay time.Duration
+}
+
+func (d *DependentCheck) DependsOn() []string { return d.deps }
+func (d *DependentCheck) Run() error {
+	time.Sleep(d.delay)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	*d.order = append(*d.order, d.uuid)
+	return nil
+}
+
+func TestOrderJobs(t *testing.T) {
+	var mu sync.Mutex
+	order := []string{}
+	a := &DependentCheck{DummyCheck: DummyCheck{uuid: "a"}, deps: []string{"b"}, order: &order, mu: &mu}
+	b := &DependentCheck{DummyCheck: DummyCheck{uuid: "b"}, deps: []string{"missing"}, order: &order, mu: &mu}
+	c := &DummyCheck{uuid: "c"}
+	claim := claims.Claim{Title: "Test"}
+
+	ordered, cyclic := orderJobs([]*job{newJob(claim, a), newJob(claim, b), newJob(claim, c)})
+
+	uuids := []string{}
+	for _, j := range ordered {
+		uuids = append(uuids, j.chk.UUID())
+	}
+	assert.Equal(t, []string{"b", "c", "a"}, uuids)
+	assert.Empty(t, cyclic)
+}
+
+func TestOrderJobs_Cycle(t *testing.T) {
+	var mu sync.Mutex
+	order := []string{}
+	a := &DependentCheck{DummyCheck: DummyCheck{uuid: "a"}, deps: []string{"b"}, order: &order, mu: &mu}
+	b := &DependentCheck{DummyCheck: DummyCheck{uuid: "b"}, deps: []string{"a"}, order: &order, mu: &mu}
+	c := &DummyCheck{uuid: "c"}
+	claim := claims.Claim{Title: "Test"}
+
+	ordered, cyclic := orderJobs([]*job{newJob(claim, a), newJob(claim, b), newJob(claim, c)})
+
+	assert.Len(t, ordered, 1)
+	assert.Len(t, cyclic, 2)
+}
+
+func TestCheck_RunsDependenciesFirst(t *testing.T) {
+	var mu sync.Mutex
+	order := []string{}
+	first := &DependentCheck{
+		DummyCheck: DummyCheck{name: "First", runnable: true, uuid: "uuid-first"},
+		order:      &order, mu: &mu, delay: 20 * time.Millisecond,
+	}
+	second := &DependentCheck{
+		DummyCheck: DummyCheck{name: "Second", runnable: true, uuid: "uuid-second"},
+		deps:       []string{"uuid-first"},
+		order:      &order, mu: &mu,
+	}
+	dummyClaims := []claims.Claim{
+		{Title: "Test Case", Checks: []check.Check{second, first}},
+	}
+
+	results := Check(context.Background(), dummyClaims, []string{}, nil)
+
+	assert.Equal(t, []string{"uuid-first", "uuid-second"}, order)
+	// Results and logs keep the declaration order
+	assert.Equal(t, "uuid-second", results[0].UUID)
+	assert.Equal(t, "uuid-first", results[1].UUID)
+}
+
+func TestCheck_DependencyCycleIsError(t *testing.T) {
+	var mu sync.Mutex
+	order := []string{}
+	a := &DependentCheck{DummyCheck: DummyCheck{name: "A", runnable: true, uuid: "uuid-cycle-a"}, deps: []string{"uuid-cycle-b"}, order: &order, mu: &mu}
+	b := &DependentCheck{DummyCheck: DummyCheck{name: "B", runnable: true, uuid: "uuid-cycle-b"}, deps: []string{"uuid-cycle-a"}, order: &order, mu: &mu}
+	dummyClaims := []claims.Claim{
+		{Title: "Test Case", Checks: []check.Check{a, b}},
+	}
+
+	results := Check(context.Background(), dummyClaims, []string{}, nil)
+
+	assert.Empty(t, order)
+	assert.Len(t, results, 2)
+	assert.Equal(t, check.CheckStateError, results[0].State)
+	assert.Equal(t, "dependency cycle detected", results[0].Details)
+}
+
+// CountingCheck tracks how many instances run at the same time.
+type CountingCheck struct {
+	DummyCheck
+	running *int32
+	peak    *int32
+}
+
+func (c *CountingCheck) Run() error {
+	now := atomic.AddInt32(c.running, 1)
+	for {
+		peak := atomic.LoadInt32(c.peak)
+		if now <= peak || atomic.CompareAndSwapInt32(c.peak, peak, now) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	atomic.AddInt32(c.running, -1)
+	return nil
+}
+
+func TestCheck_BoundedConcurrency(t *testing.T) {
+	original := Concurrency
+	Concurrency = 2
+	defer func() { Concurrency = original }()
+
+	var running, peak int32
+	checks := []check.Check{}
+	for _, uuid := range []string{"uuid-c1", "uuid-c2", "uuid-c3", "uuid-c4", "uuid-c5"} {
+		checks = append(checks, &CountingCheck{
+			DummyCheck: DummyCheck{name: uuid, runnable: true, uuid: uuid},
+			running:    &running,
+			peak:       &peak,
+		})
+	}
+
+	results := Check(context.Background(), []claims.Claim{{Title: "Test Case", Checks: checks}}, []string{}, nil)
+
+	assert.Len(t, results, 5)
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2))
+}
+
+func TestCheck_LogOrderFollowsDeclaration(t *testing.T) {
+	var buf bytes.Buffer
+	original := LogWriter
+	LogWriter = &buf
+	defer func() { LogWriter = original }()
+
+	var mu sync.Mutex
+	order := []string{}
+	slow := &DependentCheck{DummyCheck: DummyCheck{name: "Slow", runnable: true, uuid: "uuid-log-slow"}, order: &order, mu: &mu, delay: 30 * time.Millisecond}
+	fast := &DependentCheck{DummyCheck: DummyCheck{name: "Fast", runnable: true, uuid: "uuid-log-fast"}, order: &order, mu: &mu}
+
+	Check(context.Background(), []claims.Claim{{Title: "Test Case", Checks: []check.Check{slow, fast}}}, []string{}, nil)
+
+	out := buf.String()
+	assert.Less(t, strings.Index(out, "Slow"), strings.Index(out, "Fast"))
+}
+
+// FactsCheck records the facts injected by the runner.
+type FactsCheck struct {
+	DummyCheck
+	seen  check.Facts
+	calls int
+}
+
+func (f *FactsCheck) UseFacts(facts check.Facts) {
+	f.calls++
+	if facts != nil {
+		f.seen = facts
+	}
+}
+
+func TestCheck_InjectsSharedFacts(t *testing.T) {
+	a := &FactsCheck{DummyCheck: DummyCheck{name: "A", runnable: true, uuid: "uuid-facts-a"}}
+	b := &FactsCheck{DummyCheck: DummyCheck{name: "B", runnable: true, uuid: "uuid-facts-b"}}
+
+	Check(context.Background(), []claims.Claim{{Title: "Test Case", Checks: []check.Check{a, b}}}, []string{}, nil)
+
+	assert.NotNil(t, a.seen)
+	assert.Same(t, a.seen, b.seen)
+	// Facts are injected before the run and reset after it
+	assert.Equal(t, 2, a.calls)
+}
@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/caarlos0/log"
+)
+
+// SchemaSetting describes a check setting in the detailed schema.
+type SchemaSetting struct {
+	Key         string `json:"key"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Default     any    `json:"default"`
+}
+
+// schemaSettings returns the settings of chk with JSON-friendly defaults.
+func schemaSettings(chk check.Check) []SchemaSetting {
+	settings := []SchemaSetting{}
+	for _, setting := range check.SettingsOf(chk) {
+		settings = append(settings, SchemaSetting{
+			Key:         setting.Key,
+			Type:        check.SettingType(setting.Default),
+			Description: setting.Description,
+			Default:     jsonDefault(setting.Default),
+		})
+	}
+	return settings
+}
+
+// jsonDefault returns a default value the way it is written in the config.
+func jsonDefault(def any) any {
+	if duration, ok := def.(time.Duration); ok {
+		return duration.String()
+	}
+	return def
+}
+
+// ValidateSettings validates the check settings in the config and the policy
+// parameters against the settings the checks declare. It returns an error for
+// every unknown check, unknown setting or value of the wrong type.
+func ValidateSettings(all []claims.Claim) []error {
+	errs := validateSettingsSource(all, shared.ConfigPath, shared.Config.Checks)
+	return append(errs, validateSettingsSource(all, shared.PolicyPath, shared.Policy.Parameters)...)
+}
+
+func validateSettingsSource(all []claims.Claim, source string, values map[string]map[string]interface{}) []error {
+	var errs []error
+	for _, uuid := range slices.Sorted(maps.Keys(values)) {
+		chk, found := findCheck(all, uuid)
+		if !found {
+			errs = append(errs, fmt.Errorf("%s: unknown check %s", source, uuid))
+			continue
+		}
+		declared := map[string]check.Setting{}
+		for _, setting := range check.SettingsOf(chk) {
+			declared[setting.Key] = setting
+		}
+		for _, key := range slices.Sorted(maps.Keys(values[uuid])) {
+			setting, ok := declared[key]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: check %s (%s) has no setting %q", source, uuid, chk.Name(), key))
+				continue
+			}
+			if _, err := check.ConvertSetting(values[uuid][key], setting.Default); err != nil {
+				errs = append(errs, fmt.Errorf("%s: setting %q of check %s (%s): %w", source, key, uuid, chk.Name(), err))
+			}
+		}
+	}
+	return errs
+}
+
+// settingJSONSchema returns the JSON Schema of a setting value.
+func settingJSONSchema(setting check.Setting) map[string]any {
+	schema := map[string]any{"description": setting.Description, "default": jsonDefault(setting.Default)}
+	switch setting.Default.(type) {
+	case int:
+		schema["type"] = "integer"
+	case bool:
+		schema["type"] = "boolean"
+	case string:
+		schema["type"] = "string"
+	case time.Duration:
+		schema["oneOf"] = []map[string]any{
+			{"type": "string", "pattern": `^([0-9]+d|([0-9.]+(ns|us|µs|ms|s|m|h))+)$`},
+			{"type": "integer", "description": "seconds"},
+		}
+	case []int:
+		schema["type"] = "array"
+		schema["items"] = map[string]any{"type": "integer"}
+	case []string:
+		schema["type"] = "array"
+		schema["items"] = map[string]any{"type": "string"}
+	case map[int]string:
+		schema["type"] = "object"
+		schema["propertyNames"] = map[string]any{"pattern": "^[0-9]+$"}
+		schema["additionalProperties"] = map[string]any{"type": "string"}
+	}
+	return schema
+}
+
+// SettingsSchema returns a JSON Schema of the Checks section of the config,
+// with one object per configurable check keyed by its UUID.
+func SettingsSchema(all []claims.Claim) map[string]any {
+	properties := map[string]any{}
+	for _, claim := range all {
+		for _, chk := range claim.Checks {
+			settings := check.SettingsOf(chk)
+			if len(settings) == 0 {
+				continue
+			}
+			settingProperties := map[string]any{}
+			for _, setting := range settings {
+				settingProperties[setting.Key] = settingJSONSchema(setting)
+			}
+			properties[chk.UUID()] = map[string]any{
+				"title":                chk.Name(),
+				"type":                 "object",
+				"properties":           settingProperties,
+				"additionalProperties": false,
+			}
+		}
+	}
+	return map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "Pareto Security check settings",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// PrintSettingsSchemaJSON prints the JSON Schema of the check settings.
+func PrintSettingsSchemaJSON(all []claims.Claim) {
+	out, err := json.MarshalIndent(SettingsSchema(all), "", "  ")
+	if err != nil {
+		log.WithError(err).Warn("cannot marshal settings schema")
+	}
+	fmt.Println(string(out))
+}
@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+type configurableCheck struct {
+	DummyCheck
+}
+
+func (c *configurableCheck) Settings() []check.Setting {
+	return []check.Setting{
+		{Key: "GracePeriod", Description: "Grace period", Default: 10 * 24 * time.Hour},
+		{Key: "AllowedPorts", Description: "Allowed ports", Default: []int{}},
+	}
+}
+
+func settingsClaims() []claims.Claim {
+	return []claims.Claim{{Title: "Test", Checks: []check.Check{
+		&configurableCheck{DummyCheck{name: "Configurable", uuid: "uuid-settings"}},
+		&DummyCheck{name: "Plain", uuid: "uuid-plain"},
+	}}}
+}
+
+func TestValidateSettings(t *testing.T) {
+	shared.ConfigPath = "pareto.toml"
+	shared.PolicyPath = "policy.toml"
+	shared.Config.Checks = map[string]map[string]interface{}{
+		"uuid-settings": {"GracePeriod": "3d", "AllowedPorts": "22", "Unknown": true},
+		"uuid-plain":    {"Anything": int64(1)},
+		"uuid-missing":  {"GracePeriod": "3d"},
+	}
+	shared.Policy.Parameters = map[string]map[string]interface{}{
+		"uuid-settings": {"GracePeriod": "soon"},
+	}
+	defer func() {
+		shared.Config.Checks = nil
+		shared.Policy = shared.ParetoPolicy{}
+	}()
+
+	errs := ValidateSettings(settingsClaims())
+
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	assert.Equal(t, []string{
+		`pareto.toml: unknown check uuid-missing`,
+		`pareto.toml: check uuid-plain (Plain) has no setting "Anything"`,
+		`pareto.toml: setting "AllowedPorts" of check uuid-settings (Configurable): expected list of integers, got string`,
+		`pareto.toml: check uuid-settings (Configurable) has no setting "Unknown"`,
+		`policy.toml: setting "GracePeriod" of check uuid-settings (Configurable): invalid duration "soon", use for example "36h" or "7d"`,
+	}, messages)
+}
+
+func TestValidateSettings_Valid(t *testing.T) {
+	shared.Config.Checks = map[string]map[string]interface{}{
+		"uuid-settings": {"GracePeriod": "36h", "AllowedPorts": []interface{}{int64(22)}},
+	}
+	defer func() { shared.Config.Checks = nil }()
+
+	assert.Empty(t, ValidateSettings(settingsClaims()))
+}
+
+func TestSettingsSchema(t *testing.T) {
+	schema := SettingsSchema(settingsClaims())
+
+	properties := schema["properties"].(map[string]any)
+	assert.Len(t, properties, 1, "checks without settings are not configurable")
+	checkSchema := properties["uuid-settings"].(map[string]any)
+	assert.Equal(t, "Configurable", checkSchema["title"])
+	settings := checkSchema["properties"].(map[string]any)
+	assert.Equal(t, "240h0m0s", settings["GracePeriod"].(map[string]any)["default"])
+	assert.Equal(t, "array", settings["AllowedPorts"].(map[string]any)["type"])
+}
+
+func TestSchemaSettings(t *testing.T) {
+	assert.Equal(t, []SchemaSetting{
+		{Key: "GracePeriod", Type: "duration", Description: "Grace period", Default: "240h0m0s"},
+		{Key: "AllowedPorts", Type: "list of integers", Description: "Allowed ports", Default: []int{}},
+	}, schemaSettings(&configurableCheck{}))
+	assert.Empty(t, schemaSettings(&DummyCheck{}))
+}
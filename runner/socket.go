@@ -1,14 +1,85 @@
 package runner
 
 import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
 	"github.com/ParetoSecurity/agent/shared"
-	"go.uber.org/ratelimit"
 )
 
 var SocketPath = "/run/paretosecurity.sock"
-var rateLimitCall = ratelimit.New(1)
 
 func IsSocketServicePresent() bool {
 	_, err := shared.RunCommand("systemctl", "is-enabled", "--quiet", "paretosecurity.socket")
 	return err == nil
 }
+
+// UserSocketPath returns the per-user socket of the local API, in a private
+// directory in XDG_RUNTIME_DIR when it is set and in the home directory otherwise.
+func UserSocketPath() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "paretosecurity", "api.sock")
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	return filepath.Join(homeDir, ".paretosecurity", "api.sock")
+}
+
+// ListenUserSocket listens on a Unix socket only the current user can connect
+// to. A socket left behind by a server that is no longer running is replaced.
+// The socket has to be in a directory only the current user can enter, which
+// is created if needed, so that nobody else can connect between creating the
+// socket and restricting its permissions.
+func ListenUserSocket(path string) (net.Listener, error) {
+	if err := privateDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		conn, err := net.DialTimeout("unix", path, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another server is already listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return &userListener{Listener: listener}, nil
+}
+
+// privateDir creates dir if needed and checks that other users cannot enter
+// it. On Windows the directories of a user profile are private by their ACL.
+func privateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("%s can be entered by other users, the socket must be in a private directory", dir)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/shared"
+)
+
+// ErrCheckTimeout is returned when a check does not finish within shared.PerCheckTimeout.
+var ErrCheckTimeout = errors.New("check timed out")
+
+// ErrCheckBusy is returned for a check whose run timed out earlier and is
+// still going in the background.
+var ErrCheckBusy = errors.New("check is still running after an earlier timeout")
+
+// abandoned fences the checks whose run timed out but has not returned yet.
+// Nothing may run such a check or change its facts until the run returns.
+var abandoned = struct {
+	sync.Mutex
+	checks map[check.Check]bool
+}{checks: map[check.Check]bool{}}
+
+// whenIdle calls f unless a run of chk that timed out is still going, and
+// reports whether it did. f is called with the fence held, so it must be short.
+func whenIdle(chk check.Check, f func()) bool {
+	abandoned.Lock()
+	defer abandoned.Unlock()
+	if abandoned.checks[chk] {
+		return false
+	}
+	f()
+	return true
+}
+
+// useFacts hands facts to chk if it reads shared facts and is not busy with
+// a run that timed out.
+func useFacts(chk check.Check, facts check.Facts) {
+	if consumer, ok := chk.(check.FactConsumer); ok {
+		whenIdle(chk, func() { consumer.UseFacts(facts) })
+	}
+}
+
+// RunCheck executes chk bounded by shared.PerCheckTimeout.
+//
+// Checks implementing check.ContextRunner receive a context that is canceled
+// on timeout, so the commands they spawn are killed. Other checks are left to
+// finish in the background and their result is discarded. Either way the caller
+// gets an error wrapping ErrCheckTimeout and must not read the check's state.
+// Until the abandoned run returns, the check is fenced off: running it again
+// fails with ErrCheckBusy and its facts are only reset once the run returns.
+func RunCheck(ctx context.Context, chk check.Check) error {
+	if !whenIdle(chk, func() {}) {
+		return ErrCheckBusy
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, shared.PerCheckTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	left := false
+	go func() {
+		var err error
+		if runner, ok := chk.(check.ContextRunner); ok {
+			err = runner.RunContext(ctx)
+		} else {
+			err = chk.Run()
+		}
+		abandoned.Lock()
+		defer abandoned.Unlock()
+		if left {
+			// Nobody reset the facts while the run was going
+			delete(abandoned.checks, chk)
+			if consumer, ok := chk.(check.FactConsumer); ok {
+				consumer.UseFacts(nil)
+			}
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if ctx.Err() != nil {
+			// The check returned, but only because its commands were killed
+			return contextError(ctx)
+		}
+		return err
+	case <-ctx.Done():
+		abandoned.Lock()
+		defer abandoned.Unlock()
+		select {
+		case <-done:
+			// The check returned while the timeout was handled
+		default:
+			left = true
+			abandoned.checks[chk] = true
+		}
+		return contextError(ctx)
+	}
+}
+
+// contextError describes why the context of a check run is done.
+func contextError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", ErrCheckTimeout, shared.PerCheckTimeout)
+	}
+	return ctx.Err()
+}
+
+// isInterrupted reports whether err means the check was abandoned before it finished.
+func isInterrupted(err error) bool {
+	return errors.Is(err, ErrCheckTimeout) || errors.Is(err, ErrCheckBusy) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+// SlowCheck blocks in Run until released, ignoring cancellation.
+type SlowCheck struct {
+	DummyCheck
+	release chan struct{}
+}
+
+func (s *SlowCheck) Run() error {
+	<-s.release
+	return nil
+}
+
+// ContextCheck blocks in RunContext until its context is done.
+type ContextCheck struct {
+	DummyCheck
+	canceled chan struct{}
+}
+
+func (c *ContextCheck) RunContext(ctx context.Context) error {
+	<-ctx.Done()
+	close(c.canceled)
+	return nil
+}
+
+func withPerCheckTimeout(t *testing.T, timeout time.Duration) {
+	original := shared.PerCheckTimeout
+	shared.PerCheckTimeout = timeout
+	t.Cleanup(func() { shared.PerCheckTimeout = original })
+}
+
+func TestRunCheck_Success(t *testing.T) {
+	dc := &DummyCheck{runnable: true, passedVal: true}
+	assert.NoError(t, RunCheck(context.Background(), dc))
+}
+
+func TestRunCheck_Error(t *testing.T) {
+	dc := &DummyCheck{runnable: true, runErr: errors.New("boom")}
+	assert.EqualError(t, RunCheck(context.Background(), dc), "boom")
+}
+
+func TestRunCheck_Timeout(t *testing.T) {
+	withPerCheckTimeout(t, 20*time.Millisecond)
+	sc := &SlowCheck{release: make(chan struct{})}
+	defer close(sc.release)
+
+	err := RunCheck(context.Background(), sc)
+	assert.ErrorIs(t, err, ErrCheckTimeout)
+	assert.True(t, isInterrupted(err))
+}
+
+func TestRunCheck_ContextRunnerCanceled(t *testing.T) {
+	withPerCheckTimeout(t, 20*time.Millisecond)
+	cc := &ContextCheck{canceled: make(chan struct{})}
+
+	err := RunCheck(context.Background(), cc)
+	assert.ErrorIs(t, err, ErrCheckTimeout)
+	select {
+	case <-cc.canceled:
+	case <-time.After(time.Second):
+		t.Fatal("expected RunContext to observe cancellation")
+	}
+}
+
+func TestCheck_TimeoutOnlyFailsSlowCheck(t *testing.T) {
+	withPerCheckTimeout(t, 20*time.Millisecond)
+	sc := &SlowCheck{
+		DummyCheck: DummyCheck{name: "Slow", runnable: true, uuid: "uuid-slow"},
+		release:    make(chan struct{}),
+	}
+	defer close(sc.release)
+	fast := &DummyCheck{name: "Fast", runnable: true, passedVal: true, statusMsg: "ok", uuid: "uuid-fast"}
+	dummyClaims := []claims.Claim{
+		{Title: "Test Case", Checks: []check.Check{sc, fast}},
+	}
+
+	results := Check(context.Background(), dummyClaims, []string{}, nil)
+
+	assert.Len(t, results, 2)
+	assert.Equal(t, check.CheckStateError, results[0].State)
+	assert.Contains(t, results[0].Details, "timed out")
+	assert.Equal(t, check.CheckStatePassed, results[1].State)
+
+	state, found, _ := shared.GetLastState("uuid-slow")
+	assert.True(t, found)
+	assert.True(t, state.HasError)
+	assert.Contains(t, state.Details, "timed out")
+}
+
+// FactSlowCheck is a SlowCheck that reads shared facts while it runs.
+type FactSlowCheck struct {
+	SlowCheck
+	facts check.Facts
+}
+
+func (f *FactSlowCheck) UseFacts(facts check.Facts) {
+	f.facts = facts
+}
+
+func (f *FactSlowCheck) Run() error {
+	<-f.release
+	_ = f.facts
+	return nil
+}
+
+func TestCheck_AbandonedCheckIsFenced(t *testing.T) {
+	withPerCheckTimeout(t, 20*time.Millisecond)
+	fc := &FactSlowCheck{SlowCheck: SlowCheck{
+		DummyCheck: DummyCheck{name: "Slow", runnable: true, uuid: "uuid-fenced"},
+		release:    make(chan struct{}),
+	}}
+	dummyClaims := []claims.Claim{{Title: "Test Case", Checks: []check.Check{fc}}}
+
+	results := Check(context.Background(), dummyClaims, []string{}, nil)
+	assert.Contains(t, results[0].Details, "timed out")
+
+	// The abandoned run keeps its facts and the check is not run again
+	results = Check(context.Background(), dummyClaims, []string{}, nil)
+	assert.Equal(t, check.CheckStateError, results[0].State)
+	assert.Equal(t, ErrCheckBusy.Error(), results[0].Details)
+	assert.ErrorIs(t, RunCheck(context.Background(), fc), ErrCheckBusy)
+
+	// Once the run returns, its facts are reset and the check can run again
+	close(fc.release)
+	assert.Eventually(t, func() bool {
+		return whenIdle(fc, func() {})
+	}, time.Second, 5*time.Millisecond)
+	assert.Nil(t, fc.facts)
+	assert.NoError(t, RunCheck(context.Background(), fc))
+}
@@ -0,0 +1,315 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/caarlos0/log"
+)
+
+// WebhookEvent is the event of the JSON payload sent to webhooks.
+const WebhookEvent = "posture.changed"
+
+// WebhookSignatureHeader carries the HMAC-SHA256 signature of the payload.
+const WebhookSignatureHeader = "X-Pareto-Signature"
+
+// WebhookAttempts is the number of times a webhook is tried before the change is dropped.
+var WebhookAttempts = 3
+
+// WebhookRetryDelay is the delay before the first retry, doubled for every later one.
+// Can be overridden for testing
+var WebhookRetryDelay = 2 * time.Second
+
+// WebhookTimeout bounds a single delivery attempt.
+var WebhookTimeout = 10 * time.Second
+
+// WebhookDevice identifies the device in the JSON payload.
+type WebhookDevice struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+// PostureChange is the change of the failing checks between two runs.
+// Regressed checks failed in this run but not in the previous one, resolved
+// checks failed in the previous run and passed in this one. Failing lists
+// every check that failed in this run.
+type PostureChange struct {
+	Event     string        `json:"event"`
+	Time      time.Time     `json:"time"`
+	Device    WebhookDevice `json:"device"`
+	Score     int           `json:"score"`
+	Regressed []CheckResult `json:"regressed"`
+	Resolved  []CheckResult `json:"resolved"`
+	Failing   []CheckResult `json:"failing"`
+}
+
+// Changed reports whether any check started or stopped failing.
+func (c PostureChange) Changed() bool {
+	return len(c.Regressed) > 0 || len(c.Resolved) > 0
+}
+
+// isFailing reports whether a check in state counts as failing.
+func isFailing(state check.CheckState) bool {
+	return state == check.CheckStateFailed || state == check.CheckStateError
+}
+
+// newPostureChange compares the results of a run with the states recorded by
+// the previous run. Checks without a previous state and checks that are
+// disabled now are not compared.
+func newPostureChange(previous map[string]shared.LastState, results []CheckResult) PostureChange {
+	change := PostureChange{
+		Event:     WebhookEvent,
+		Time:      time.Now(),
+		Regressed: []CheckResult{},
+		Resolved:  []CheckResult{},
+		Failing:   []CheckResult{},
+	}
+	for _, result := range results {
+		if isFailing(result.State) {
+			change.Failing = append(change.Failing, result)
+		}
+		last, found := previous[result.UUID]
+		if !found || result.State == check.CheckStateDisabled {
+			continue
+		}
+		switch wasFailing := isFailing(last.State()); {
+		case isFailing(result.State) && !wasFailing:
+			change.Regressed = append(change.Regressed, result)
+		case result.State == check.CheckStatePassed && wasFailing:
+			change.Resolved = append(change.Resolved, result)
+		}
+	}
+	return change
+}
+
+// withoutSnoozed drops the checks that are snoozed at now from the regressed
+// ones, so that snoozed checks alert neither on the desktop nor in webhooks.
+// They are still listed as failing, as they still fail.
+func (c PostureChange) withoutSnoozed(now time.Time) PostureChange {
+	filtered := c
+	filtered.Regressed = filterResults(c.Regressed, func(result CheckResult) bool {
+		return shared.ActiveSnooze(result.UUID, now) == nil
+	})
+	return filtered
+}
+
+// forWebhook returns the part of the change the webhook is interested in.
+func (c PostureChange) forWebhook(hook shared.Webhook) PostureChange {
+	if len(hook.Claims) == 0 && len(hook.Checks) == 0 {
+		return c
+	}
+	matches := func(result CheckResult) bool {
+		return slices.Contains(hook.Claims, result.Claim) || slices.Contains(hook.Checks, result.UUID)
+	}
+	filtered := c
+	filtered.Regressed = filterResults(c.Regressed, matches)
+	filtered.Resolved = filterResults(c.Resolved, matches)
+	filtered.Failing = filterResults(c.Failing, matches)
+	return filtered
+}
+
+func filterResults(results []CheckResult, keep func(CheckResult) bool) []CheckResult {
+	filtered := []CheckResult{}
+	for _, result := range results {
+		if keep(result) {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// summary describes the change in one line.
+func (c PostureChange) summary() string {
+	parts := []string{}
+	if n := len(c.Regressed); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s started failing", n, plural(n, "check", "checks")))
+	}
+	if n := len(c.Resolved); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s fixed", n, plural(n, "check", "checks")))
+	}
+	return fmt.Sprintf("%s: %s, score %d/100", c.Device.Name, strings.Join(parts, " and "), c.Score)
+}
+
+// lines describes every regressed and resolved check, one per line.
+func (c PostureChange) lines() []string {
+	lines := []string{}
+	for _, result := range c.Regressed {
+		line := fmt.Sprintf("Failing: %s (%s)", result.Name, result.Claim)
+		if result.Details != "" {
+			line += ": " + result.Details
+		}
+		lines = append(lines, line)
+	}
+	for _, result := range c.Resolved {
+		lines = append(lines, fmt.Sprintf("Fixed: %s (%s)", result.Name, result.Claim))
+	}
+	return lines
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// webhookPayload renders the change in the format of the webhook.
+func webhookPayload(hook shared.Webhook, change PostureChange) ([]byte, error) {
+	switch hook.Format {
+	case "", shared.WebhookFormatJSON:
+		return json.Marshal(change)
+	case shared.WebhookFormatSlack:
+		text := "*" + change.summary() + "*"
+		for _, line := range change.lines() {
+			text += "\n• " + line
+		}
+		return json.Marshal(map[string]string{"text": text})
+	case shared.WebhookFormatTeams:
+		body := []map[string]any{{
+			"type":   "TextBlock",
+			"text":   change.summary(),
+			"weight": "Bolder",
+			"wrap":   true,
+		}}
+		for _, line := range change.lines() {
+			body = append(body, map[string]any{"type": "TextBlock", "text": line, "wrap": true})
+		}
+		return json.Marshal(map[string]any{
+			"type": "message",
+			"attachments": []map[string]any{{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]any{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body":    body,
+				},
+			}},
+		})
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q", hook.Format)
+	}
+}
+
+// SignWebhookPayload returns the signature of payload sent in WebhookSignatureHeader.
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookStatusError is returned when a webhook answers with an error status.
+type webhookStatusError struct {
+	status int
+}
+
+func (e webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook returned %d %s", e.status, http.StatusText(e.status))
+}
+
+// retryable reports whether a delivery that failed with err may succeed later.
+// Client errors other than timeouts and rate limits will not.
+func retryable(err error) bool {
+	statusErr, ok := err.(webhookStatusError)
+	if !ok || statusErr.status >= 500 {
+		return true
+	}
+	return statusErr.status == http.StatusRequestTimeout || statusErr.status == http.StatusTooManyRequests
+}
+
+// postWebhook makes a single delivery attempt.
+func postWebhook(ctx context.Context, hook shared.Webhook, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, WebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", shared.UserAgent())
+	if hook.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(hook.Secret, payload))
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return webhookStatusError{status: res.StatusCode}
+	}
+	return nil
+}
+
+// sendWebhook delivers the change to the webhook, retrying with an
+// exponential backoff while the failure is retryable.
+func sendWebhook(ctx context.Context, hook shared.Webhook, change PostureChange) error {
+	payload, err := webhookPayload(hook, change)
+	if err != nil {
+		return err
+	}
+	delay := WebhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		err = postWebhook(ctx, hook, payload)
+		if err == nil || attempt >= WebhookAttempts || !retryable(err) {
+			return err
+		}
+		log.WithError(err).Debugf("webhook delivery failed, retrying in %s", delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// webhookHost returns the host of the webhook URL, for logging without the
+// secrets many webhook URLs carry in their path.
+func webhookHost(hook shared.Webhook) string {
+	if u, err := url.Parse(hook.URL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "invalid URL"
+}
+
+// notifyWebhooks sends the change of the failing checks since the previous
+// run to every configured webhook that is interested in it. Checks that
+// started failing while snoozed are left out.
+func notifyWebhooks(ctx context.Context, change PostureChange) {
+	change = change.withoutSnoozed(time.Now())
+	if len(shared.Config.Webhooks) == 0 || !change.Changed() {
+		return
+	}
+	change.Device = WebhookDevice{UUID: shared.GetDeviceUUID(), Name: shared.DeviceName()}
+	change.Score = shared.DeviceScore(shared.GetLastStates())
+
+	var wg sync.WaitGroup
+	for _, hook := range shared.Config.Webhooks {
+		filtered := change.forWebhook(hook)
+		if !filtered.Changed() {
+			continue
+		}
+		wg.Go(func() {
+			if err := sendWebhook(ctx, hook, filtered); err != nil {
+				log.WithError(err).WithField("host", webhookHost(hook)).Warn("failed to deliver webhook")
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,216 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookServer records the requests it receives and answers them with the
+// given statuses in turn, then with 200.
+type webhookServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+	server := &webhookServer{statuses: statuses}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		server.bodies = append(server.bodies, body)
+		server.headers = append(server.headers, r.Header.Clone())
+		if len(server.statuses) > 0 {
+			w.WriteHeader(server.statuses[0])
+			server.statuses = server.statuses[1:]
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *webhookServer) received() [][]byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([][]byte{}, s.bodies...)
+}
+
+// withWebhooks configures the webhooks for the test and disables retry delays.
+func withWebhooks(t *testing.T, hooks ...shared.Webhook) {
+	t.Helper()
+	webhooks, delay, uuid := shared.Config.Webhooks, WebhookRetryDelay, shared.Config.SystemUUID
+	shared.Config.Webhooks = hooks
+	shared.Config.SystemUUID = "12345678-1234-1234-1234-123456789012"
+	WebhookRetryDelay = time.Millisecond
+	t.Cleanup(func() {
+		shared.Config.Webhooks, WebhookRetryDelay, shared.Config.SystemUUID = webhooks, delay, uuid
+	})
+}
+
+var webhookResults = []CheckResult{
+	{Claim: "Firewall & Sharing", UUID: "uuid-firewall", Name: "Firewall is on", State: check.CheckStateFailed, Details: "firewall is off"},
+	{Claim: "System Integrity", UUID: "uuid-updates", Name: "System is up to date", State: check.CheckStatePassed},
+	{Claim: "System Integrity", UUID: "uuid-new", Name: "New check", State: check.CheckStateError},
+	{Claim: "Access Security", UUID: "uuid-disabled", Name: "Disabled check", State: check.CheckStateDisabled},
+	{Claim: "Access Security", UUID: "uuid-still", Name: "Still failing", State: check.CheckStateFailed},
+}
+
+var webhookPrevious = map[string]shared.LastState{
+	"uuid-firewall": {UUID: "uuid-firewall", Passed: true},
+	"uuid-updates":  {UUID: "uuid-updates", Passed: false},
+	"uuid-disabled": {UUID: "uuid-disabled", Passed: false},
+	"uuid-still":    {UUID: "uuid-still", HasError: true},
+}
+
+func uuids(results []CheckResult) []string {
+	ids := []string{}
+	for _, result := range results {
+		ids = append(ids, result.UUID)
+	}
+	return ids
+}
+
+func TestNewPostureChange(t *testing.T) {
+	change := newPostureChange(webhookPrevious, webhookResults)
+
+	assert.True(t, change.Changed())
+	assert.Equal(t, []string{"uuid-firewall"}, uuids(change.Regressed))
+	assert.Equal(t, []string{"uuid-updates"}, uuids(change.Resolved))
+	assert.Equal(t, []string{"uuid-firewall", "uuid-new", "uuid-still"}, uuids(change.Failing))
+
+	assert.False(t, newPostureChange(nil, webhookResults).Changed(), "checks without a previous state are not compared")
+}
+
+func TestPostureChange_WithoutSnoozed(t *testing.T) {
+	snoozes := shared.Config.Snoozes
+	defer func() { shared.Config.Snoozes = snoozes }()
+	now := time.Now()
+	shared.Config.Snoozes = []shared.CheckSnooze{{Check: "uuid-firewall", Until: now.Add(time.Hour)}}
+
+	change := newPostureChange(webhookPrevious, webhookResults).withoutSnoozed(now)
+	assert.Empty(t, change.Regressed, "a snoozed check does not alert")
+	assert.Equal(t, []string{"uuid-updates"}, uuids(change.Resolved))
+	assert.Contains(t, uuids(change.Failing), "uuid-firewall", "a snoozed check still fails")
+
+	change = newPostureChange(webhookPrevious, webhookResults).withoutSnoozed(now.Add(2 * time.Hour))
+	assert.Equal(t, []string{"uuid-firewall"}, uuids(change.Regressed), "the snooze expired")
+}
+
+func TestPostureChange_ForWebhook(t *testing.T) {
+	change := newPostureChange(webhookPrevious, webhookResults)
+
+	assert.Equal(t, change, change.forWebhook(shared.Webhook{}))
+
+	byClaim := change.forWebhook(shared.Webhook{Claims: []string{"System Integrity"}})
+	assert.Empty(t, byClaim.Regressed)
+	assert.Equal(t, []string{"uuid-updates"}, uuids(byClaim.Resolved))
+	assert.Equal(t, []string{"uuid-new"}, uuids(byClaim.Failing))
+
+	byUUID := change.forWebhook(shared.Webhook{Checks: []string{"uuid-still"}})
+	assert.False(t, byUUID.Changed())
+}
+
+func TestWebhookPayload(t *testing.T) {
+	change := newPostureChange(webhookPrevious, webhookResults)
+	change.Device = WebhookDevice{UUID: "uuid", Name: "laptop"}
+	change.Score = 63
+
+	payload, err := webhookPayload(shared.Webhook{}, change)
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(payload, &decoded))
+	assert.Equal(t, WebhookEvent, decoded["event"])
+	assert.Equal(t, map[string]any{"uuid": "uuid", "name": "laptop"}, decoded["device"])
+
+	payload, err = webhookPayload(shared.Webhook{Format: shared.WebhookFormatSlack}, change)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"text": "*laptop: 1 check started failing and 1 check fixed, score 63/100*\n• Failing: Firewall is on (Firewall & Sharing): firewall is off\n• Fixed: System is up to date (System Integrity)"}`, string(payload))
+
+	payload, err = webhookPayload(shared.Webhook{Format: shared.WebhookFormatTeams}, change)
+	require.NoError(t, err)
+	assert.Contains(t, string(payload), `"contentType":"application/vnd.microsoft.card.adaptive"`)
+	assert.Contains(t, string(payload), `"text":"Fixed: System is up to date (System Integrity)"`)
+
+	_, err = webhookPayload(shared.Webhook{Format: "xml"}, change)
+	assert.ErrorContains(t, err, `unknown webhook format "xml"`)
+}
+
+func TestSendWebhook_SignsPayload(t *testing.T) {
+	withWebhooks(t)
+	server := newWebhookServer(t)
+	change := newPostureChange(webhookPrevious, webhookResults)
+
+	require.NoError(t, sendWebhook(context.Background(), shared.Webhook{URL: server.URL, Secret: "s3cret"}, change))
+
+	body := server.received()[0]
+	assert.Equal(t, SignWebhookPayload("s3cret", body), server.headers[0].Get(WebhookSignatureHeader))
+	assert.Equal(t, "sha256=", SignWebhookPayload("s3cret", body)[:7])
+	assert.Equal(t, "application/json", server.headers[0].Get("Content-Type"))
+}
+
+func TestSendWebhook_Retries(t *testing.T) {
+	withWebhooks(t)
+	change := newPostureChange(webhookPrevious, webhookResults)
+
+	server := newWebhookServer(t, http.StatusBadGateway, http.StatusTooManyRequests)
+	assert.NoError(t, sendWebhook(context.Background(), shared.Webhook{URL: server.URL}, change))
+	assert.Len(t, server.received(), 3)
+
+	server = newWebhookServer(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	assert.ErrorContains(t, sendWebhook(context.Background(), shared.Webhook{URL: server.URL}, change), "500")
+	assert.Len(t, server.received(), WebhookAttempts)
+
+	server = newWebhookServer(t, http.StatusNotFound)
+	assert.ErrorContains(t, sendWebhook(context.Background(), shared.Webhook{URL: server.URL}, change), "404")
+	assert.Len(t, server.received(), 1, "client errors are not retried")
+}
+
+func TestCheckNotifiesWebhooks(t *testing.T) {
+	withMetricsPaths(t)
+	all := newWebhookServer(t)
+	other := newWebhookServer(t)
+	withWebhooks(t,
+		shared.Webhook{URL: all.URL},
+		shared.Webhook{URL: other.URL, Claims: []string{"Other claim"}},
+	)
+	dc := &DummyCheck{name: "Webhook", runnable: true, passedVal: false, statusMsg: "failing", uuid: "uuid-webhook"}
+	run := func() {
+		Check(context.Background(), []claims.Claim{{Title: "Test Case", Checks: []check.Check{dc}}}, []string{}, nil)
+	}
+
+	run()
+	assert.Empty(t, all.received(), "nothing to compare with on the first run")
+	run()
+	assert.Empty(t, all.received(), "the failing checks did not change")
+
+	dc.passedVal = true
+	run()
+	require.Len(t, all.received(), 1)
+	var change PostureChange
+	require.NoError(t, json.Unmarshal(all.received()[0], &change))
+	assert.Equal(t, []string{"uuid-webhook"}, uuids(change.Resolved))
+	assert.Equal(t, "12345678-1234-1234-1234-123456789012", change.Device.UUID)
+	assert.Empty(t, other.received(), "the change is filtered out for the other webhook")
+
+	snoozes := shared.Config.Snoozes
+	defer func() { shared.Config.Snoozes = snoozes }()
+	shared.Config.Snoozes = []shared.CheckSnooze{{Check: "uuid-webhook", Until: time.Now().Add(time.Hour)}}
+	dc.passedVal = false
+	run()
+	assert.Len(t, all.received(), 1, "a snoozed check that starts failing does not alert")
+}
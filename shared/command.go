@@ -0,0 +1,54 @@
+package shared
+
+import (
+	"errors"
+	"sync"
+)
+
+// CommandRun is a command run by RunCommandContext and its outcome.
+type CommandRun struct {
+	Name   string
+	Args   []string
+	Output string
+	Err    error
+}
+
+var (
+	commandObserverMutex sync.RWMutex
+	commandObserver      func(CommandRun)
+)
+
+// ObserveCommands calls observer with every command RunCommandContext runs,
+// including mocked ones, until stop is called. Only one observer is active
+// at a time; stop restores the previous one.
+func ObserveCommands(observer func(CommandRun)) (stop func()) {
+	commandObserverMutex.Lock()
+	defer commandObserverMutex.Unlock()
+	previous := commandObserver
+	commandObserver = observer
+	return func() {
+		commandObserverMutex.Lock()
+		defer commandObserverMutex.Unlock()
+		commandObserver = previous
+	}
+}
+
+// observeCommand passes a command run to the active observer, if any.
+func observeCommand(name string, arg []string, output string, err error) {
+	commandObserverMutex.RLock()
+	observer := commandObserver
+	commandObserverMutex.RUnlock()
+	if observer != nil {
+		observer(CommandRun{Name: name, Args: append([]string{}, arg...), Output: output, Err: err})
+	}
+}
+
+// ExitCode returns the exit status of a command that ran and exited with a
+// non-zero status, as reported by exec.ExitError or ReplayedExitError.
+func ExitCode(err error) (code int, exited bool) {
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,37 @@
+package shared
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserveCommands(t *testing.T) {
+	mocks := RunCommandMocks
+	defer func() { RunCommandMocks = mocks }()
+	RunCommandMocks = []RunCommandMock{
+		{Command: "lsblk", Args: []string{"-f"}, Out: "sda\n"},
+		{Command: "nft", Args: []string{"list", "ruleset"}, Err: errors.New("permission denied")},
+	}
+
+	var outer, inner []CommandRun
+	stopOuter := ObserveCommands(func(run CommandRun) { outer = append(outer, run) })
+	_, _ = RunCommand("lsblk", "-f")
+
+	stopInner := ObserveCommands(func(run CommandRun) { inner = append(inner, run) })
+	_, _ = RunCommand("nft", "list", "ruleset")
+	stopInner()
+
+	_, _ = RunCommand("lsblk", "-f")
+	stopOuter()
+	_, _ = RunCommand("lsblk", "-f")
+
+	assert.Equal(t, []CommandRun{
+		{Name: "lsblk", Args: []string{"-f"}, Output: "sda\n"},
+		{Name: "lsblk", Args: []string{"-f"}, Output: "sda\n"},
+	}, outer)
+	assert.Equal(t, []CommandRun{
+		{Name: "nft", Args: []string{"list", "ruleset"}, Err: errors.New("permission denied")},
+	}, inner)
+}
@@ -3,6 +3,7 @@
 package shared
 
 import (
+	"context"
 	"errors"
 
 	"os/exec"
@@ -27,22 +28,42 @@ var RunCommandMocks []RunCommandMock
 // the combined standard output and standard error as a string. If testing is
 // enabled, it returns a predefined fixture instead of executing the command.
 func RunCommand(name string, arg ...string) (string, error) {
+	return RunCommandContext(context.Background(), name, arg...)
+}
+
+// RunCommandContext is like RunCommand but kills the command when ctx is done
+// before it completes. The command is recorded as evidence in the trail of ctx.
+func RunCommandContext(ctx context.Context, name string, arg ...string) (string, error) {
+
+	if replay := currentReplay(); replay != nil {
+		output, err := replay.command(name, arg)
+		recordCommand(ctx, name, arg, output, err)
+		observeCommand(name, arg, output, err)
+		return output, err
+	}
 
 	// Check if testing is enabled and enable harnessing
 	if testing.Testing() {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		for _, mock := range RunCommandMocks {
 			isCmd := mock.Command == name
 			isArg := strings.TrimSpace(strings.Join(mock.Args, " ")) == strings.TrimSpace(strings.Join(arg, " "))
 			if isCmd && isArg {
+				recordCommand(ctx, name, arg, mock.Out, mock.Err)
+				observeCommand(name, arg, mock.Out, mock.Err)
 				return mock.Out, mock.Err
 			}
 		}
 		return "", errors.New("RunCommand fixture not found: " + name + " " + strings.TrimSpace(strings.Join(arg, " ")))
 	}
 
-	cmd := exec.Command(name, arg...)
+	cmd := exec.CommandContext(ctx, name, arg...)
 
 	output, err := cmd.CombinedOutput()
 	log.WithField("cmd", string(name+" "+strings.TrimSpace(strings.Join(arg, " ")))).WithError(err).Debug(string(output))
+	recordCommand(ctx, name, arg, string(output), err)
+	observeCommand(name, arg, string(output), err)
 	return string(output), err
 }
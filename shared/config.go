@@ -1,6 +1,7 @@
 package shared
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -18,14 +19,38 @@ var ConfigPath string
 // Can be overridden for testing
 var CheckTimeout = 5 * time.Minute
 
+// PerCheckTimeout bounds a single check so that a hung command only fails
+// that check instead of the whole run
+// Can be overridden for testing
+var PerCheckTimeout = 1 * time.Minute
+
 type ParetoConfig struct {
 	TeamID    string
 	AuthToken string
 	TeamAPI   string
+	// TeamKeys is the JSON Web Key Set of the team API, saved when the device is
+	// linked, or empty when the team API does not publish one
+	TeamKeys string
 	// LastTeamReportSuccess stores Unix time in milliseconds of the last successful team report.
 	LastTeamReportSuccess int64
 	SystemUUID            string
 	DisableChecks         []string
+	// Checks holds the settings of each check, keyed by check UUID
+	Checks map[string]map[string]interface{}
+	// MetricsDir is the textfile collector directory that metrics are written to after each run
+	MetricsDir string
+	// Rules are declarative checks defined by the user
+	Rules []Rule
+	// RedactHostname replaces the hostname in team reports with a pseudonym
+	RedactHostname bool
+	// RedactSerial replaces the serial number in team reports with RedactedSerial
+	RedactSerial bool
+	// Webhooks are notified when the set of failing checks changes
+	Webhooks []Webhook
+	// Snoozes silence checks until they expire
+	Snoozes []CheckSnooze
+	// DisableNotifications turns off the desktop notifications about checks that started failing
+	DisableNotifications bool
 }
 
 // init initializes the configuration path based on the user's operating system
@@ -58,7 +83,11 @@ func SaveConfig() error {
 	return encoder.Encode(Config)
 }
 
+// LoadConfig loads the policy and the user configuration
 func LoadConfig() error {
+	if err := LoadPolicy(); err != nil {
+		log.WithError(err).Warn("failed to load policy")
+	}
 
 	if _, err := os.Stat(ConfigPath); os.IsNotExist(err) {
 		if err := SaveConfig(); err != nil {
@@ -90,12 +119,17 @@ func ResetConfig() {
 		LastTeamReportSuccess: 0,
 		SystemUUID:            "",
 		DisableChecks:         []string{},
+		Checks:                map[string]map[string]interface{}{},
 	}
 	SaveConfig()
 }
 
 // EnableCheck removes a check from the disabled checks list
+// Checks disabled by a policy exception cannot be enabled locally
 func EnableCheck(checkUUID string) error {
+	if policy := CheckPolicyFor(checkUUID); policy.Exception != nil {
+		return fmt.Errorf("%w until %s", ErrCheckExcepted, policy.Exception.Expires)
+	}
 	for i, check := range Config.DisableChecks {
 		if check == checkUUID {
 			Config.DisableChecks = append(Config.DisableChecks[:i], Config.DisableChecks[i+1:]...)
@@ -106,7 +140,11 @@ func EnableCheck(checkUUID string) error {
 }
 
 // DisableCheck adds a check to the disabled checks list
+// Checks required by the policy cannot be disabled locally
 func DisableCheck(checkUUID string) error {
+	if IsCheckRequired(checkUUID) {
+		return ErrCheckRequired
+	}
 	for _, check := range Config.DisableChecks {
 		if check == checkUUID {
 			return nil
@@ -116,17 +154,9 @@ func DisableCheck(checkUUID string) error {
 	return SaveConfig()
 }
 
-// IsCheckDisabled checks if a given check UUID is present in the list of disabled checks
+// IsCheckDisabled checks if a given check UUID is disabled by the policy or the config
 func IsCheckDisabled(checkUUID string) bool {
-	if len(Config.DisableChecks) == 0 {
-		return false
-	}
-	for _, check := range Config.DisableChecks {
-		if check == checkUUID {
-			return true
-		}
-	}
-	return false
+	return CheckPolicyFor(checkUUID).Disabled
 }
 
 // GetDeviceUUID returns the system UUID from the configuration
@@ -456,3 +456,24 @@ func TestGetDeviceUUID(t *testing.T) {
 		})
 	}
 }
+
+func TestSaveConfig_Rules(t *testing.T) {
+	ConfigPath = filepath.Join(t.TempDir(), "pareto.toml")
+	Config = ParetoConfig{
+		Rules: []Rule{{UUID: "rule-uuid", Name: "VPN is active", Type: "command", Command: []string{"systemctl", "is-active", "vpn"}, Pattern: "^active"}},
+	}
+	defer func() { Config = ParetoConfig{} }()
+
+	if err := SaveConfig(); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	saved := Config.Rules
+	Config = ParetoConfig{}
+	if err := LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if len(Config.Rules) != 1 || Config.Rules[0].UUID != "rule-uuid" || len(Config.Rules[0].Command) != 3 {
+		t.Errorf("expected rules %+v, got %+v", saved, Config.Rules)
+	}
+}
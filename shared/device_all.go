@@ -112,12 +112,40 @@ func CurrentReportingDevice() ReportingDevice {
 		}(),
 	}
 
+	redactDevice(&rd)
+
 	// Apply OpenAPI spec validation and constraints
 	ValidateAndPrepareDevice(&rd)
 
 	return rd
 }
 
+// RedactedSerial replaces the serial number when Config.RedactSerial is set.
+const RedactedSerial = "Redacted"
+
+// redactDevice replaces the fields that the config keeps private. The
+// hostname becomes a pseudonym derived from the machine UUID, which is
+// reported anyway, so that devices can still be told apart.
+func redactDevice(rd *ReportingDevice) {
+	if Config.RedactHostname {
+		rd.MachineName = "device-" + TruncateString(rd.MachineUUID, 8)
+	}
+	if Config.RedactSerial {
+		rd.ModelSerial = RedactedSerial
+	}
+}
+
+// DeviceName returns the hostname of the device as it is reported to the
+// team, a pseudonym when RedactHostname is set.
+func DeviceName() string {
+	rd := ReportingDevice{MachineUUID: GetDeviceUUID()}
+	if hostname, err := os.Hostname(); err == nil {
+		rd.MachineName = Sanitize(hostname)
+	}
+	redactDevice(&rd)
+	return rd.MachineName
+}
+
 type LinkingDevice struct {
 	Hostname  string `json:"hostname"`
 	OS        string `json:"os"`
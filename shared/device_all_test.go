@@ -1,6 +1,7 @@
 package shared
 
 import (
+	"os"
 	"runtime"
 	"testing"
 )
@@ -44,6 +45,27 @@ func TestCurrentReportingDevice(t *testing.T) {
 		}
 	})
 
+	t.Run("redacted hostname and serial", func(t *testing.T) {
+		defer func() {
+			Config.RedactHostname = false
+			Config.RedactSerial = false
+		}()
+		Config.RedactHostname = true
+		Config.RedactSerial = true
+
+		rd := CurrentReportingDevice()
+
+		if rd.MachineName != "device-12345678" {
+			t.Errorf("Expected MachineName %q, got %q", "device-12345678", rd.MachineName)
+		}
+		if rd.ModelSerial != RedactedSerial {
+			t.Errorf("Expected ModelSerial %q, got %q", RedactedSerial, rd.ModelSerial)
+		}
+		if rd.MachineUUID != "12345678-1234-1234-1234-123456789012" {
+			t.Errorf("Expected MachineUUID to stay, got %q", rd.MachineUUID)
+		}
+	})
+
 	t.Run("SystemDevice error returns Unknown model name", func(t *testing.T) {
 
 		rd := CurrentReportingDevice()
@@ -68,3 +90,20 @@ func TestCurrentReportingDevice(t *testing.T) {
 		}
 	})
 }
+
+func TestDeviceName(t *testing.T) {
+	defer func(uuid string) {
+		Config.SystemUUID = uuid
+		Config.RedactHostname = false
+	}(Config.SystemUUID)
+	Config.SystemUUID = "abcdef12-1234-1234-1234-123456789012"
+
+	hostname, _ := os.Hostname()
+	if name := DeviceName(); name != Sanitize(hostname) {
+		t.Errorf("Expected DeviceName %q, got %q", Sanitize(hostname), name)
+	}
+	Config.RedactHostname = true
+	if name := DeviceName(); name != "device-abcdef12" {
+		t.Errorf("Expected DeviceName %q, got %q", "device-abcdef12", name)
+	}
+}
@@ -0,0 +1,137 @@
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+)
+
+// CheckEvidence is the evidence recorded by the last run of a check.
+type CheckEvidence struct {
+	Time     time.Time        `json:"time"`
+	Evidence []check.Evidence `json:"evidence"`
+}
+
+var (
+	evidenceMutex sync.Mutex
+	EvidencePath  string
+)
+
+func init() {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	EvidencePath = filepath.Join(homeDir, ".paretosecurity.evidence")
+}
+
+// recordCommand records a command run by RunCommandContext.
+func recordCommand(ctx context.Context, name string, arg []string, output string, err error) {
+	evidence := check.Evidence{
+		Kind:    check.EvidenceCommand,
+		Subject: strings.TrimSpace(name + " " + strings.Join(arg, " ")),
+		Detail:  fmt.Sprintf("%d bytes of output", len(output)),
+	}
+	if code, exited := ExitCode(err); exited {
+		evidence.ExitCode = code
+	} else if err != nil {
+		evidence.Error = err.Error()
+	}
+	check.Record(ctx, evidence)
+}
+
+// recordFile records a file read by ReadFileContext.
+func recordFile(ctx context.Context, name string, content []byte, err error) {
+	evidence := check.Evidence{Kind: check.EvidenceFile, Subject: name}
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		evidence.Error = errNotExist
+	case err != nil:
+		evidence.Error = err.Error()
+	default:
+		evidence.Detail = fmt.Sprintf("%d bytes", len(content))
+	}
+	check.Record(ctx, evidence)
+}
+
+// SaveEvidence stores the evidence of the checks that ran, keyed by UUID,
+// keeping the evidence of the other checks from earlier runs. Runs of the
+// tray application and the CLI may overlap, so the update holds the lock
+// file of the evidence.
+func SaveEvidence(evidence map[string]CheckEvidence) error {
+	evidenceMutex.Lock()
+	defer evidenceMutex.Unlock()
+	unlock, err := LockFile(EvidencePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	all, err := loadEvidence()
+	if err != nil {
+		// Evidence only explains results, start over
+		all = map[string]CheckEvidence{}
+	}
+	maps.Copy(all, evidence)
+	data, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(EvidencePath, data, 0o600)
+}
+
+// LoadEvidence returns the evidence recorded by the last run of each check.
+func LoadEvidence() (map[string]CheckEvidence, error) {
+	evidenceMutex.Lock()
+	defer evidenceMutex.Unlock()
+	return loadEvidence()
+}
+
+func loadEvidence() (map[string]CheckEvidence, error) {
+	all := map[string]CheckEvidence{}
+	data, err := os.ReadFile(EvidencePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return all, nil
+	}
+	if err != nil {
+		return all, err
+	}
+	err = json.Unmarshal(data, &all)
+	return all, err
+}
+
+// secretPattern matches the values of arguments and assignments whose name
+// suggests a secret, such as --password=x, token: x or API_KEY=x.
+var secretPattern = regexp.MustCompile(`(?i)((?:pass(?:word|wd)?|secret|token|api[_-]?key|auth|credential)s?["']?\s*(?:=|:|\s)\s*)("[^"]*"|'[^']*'|\S+)`)
+
+// RedactEvidence replaces sensitive values in evidence before it is shown:
+// the home directory, the hostname and values of arguments that look like
+// secrets.
+func RedactEvidence(evidence check.Evidence) check.Evidence {
+	replacements := []string{}
+	if home, err := os.UserHomeDir(); err == nil && home != "" && home != "/" {
+		replacements = append(replacements, home, "~")
+	}
+	if hostname, err := os.Hostname(); err == nil && len(hostname) > 2 {
+		replacements = append(replacements, hostname, "<hostname>")
+	}
+	replacer := strings.NewReplacer(replacements...)
+	redact := func(value string) string {
+		value = replacer.Replace(value)
+		return secretPattern.ReplaceAllString(value, "${1}<redacted>")
+	}
+	evidence.Subject = redact(evidence.Subject)
+	evidence.Detail = redact(evidence.Detail)
+	evidence.Error = redact(evidence.Error)
+	return evidence
+}
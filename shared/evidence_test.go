@@ -0,0 +1,92 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommandContext_RecordsEvidence(t *testing.T) {
+	mocks := RunCommandMocks
+	defer func() { RunCommandMocks = mocks }()
+	RunCommandMocks = []RunCommandMock{
+		{Command: "lsblk", Args: []string{"-f"}, Out: "sda\nsdb\n"},
+		{Command: "nft", Args: []string{"list", "ruleset"}, Err: errors.New("permission denied")},
+	}
+	trail := &check.Trail{}
+	ctx := check.WithTrail(context.Background(), trail)
+
+	_, _ = RunCommandContext(ctx, "lsblk", "-f")
+	_, _ = RunCommandContext(ctx, "nft", "list", "ruleset")
+
+	assert.Equal(t, []check.Evidence{
+		{Kind: check.EvidenceCommand, Subject: "lsblk -f", Detail: "8 bytes of output"},
+		{Kind: check.EvidenceCommand, Subject: "nft list ruleset", Error: "permission denied", Detail: "0 bytes of output"},
+	}, trail.Entries())
+}
+
+func TestReadFileContext_RecordsEvidence(t *testing.T) {
+	mock := ReadFileMock
+	defer func() { ReadFileMock = mock }()
+	ReadFileMock = func(name string) ([]byte, error) {
+		if name == "/proc/cmdline" {
+			return []byte("quiet splash"), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	trail := &check.Trail{}
+	ctx := check.WithTrail(context.Background(), trail)
+
+	_, _ = ReadFileContext(ctx, "/proc/cmdline")
+	_, _ = ReadFileContext(ctx, "/etc/crypttab")
+
+	assert.Equal(t, []check.Evidence{
+		{Kind: check.EvidenceFile, Subject: "/proc/cmdline", Detail: "12 bytes"},
+		{Kind: check.EvidenceFile, Subject: "/etc/crypttab", Error: "does not exist"},
+	}, trail.Entries())
+}
+
+func TestSaveEvidence(t *testing.T) {
+	path := EvidencePath
+	defer func() { EvidencePath = path }()
+	EvidencePath = filepath.Join(t.TempDir(), "evidence")
+
+	all, err := LoadEvidence()
+	require.NoError(t, err)
+	assert.Empty(t, all)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	first := CheckEvidence{Time: now, Evidence: []check.Evidence{{Kind: check.EvidenceFile, Subject: "/a"}}}
+	require.NoError(t, SaveEvidence(map[string]CheckEvidence{"a": first, "b": first}))
+	second := CheckEvidence{Time: now.Add(time.Hour), Evidence: []check.Evidence{{Kind: check.EvidenceFile, Subject: "/b"}}}
+	require.NoError(t, SaveEvidence(map[string]CheckEvidence{"b": second}))
+
+	all, err = LoadEvidence()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]CheckEvidence{"a": first, "b": second}, all)
+
+	require.NoError(t, os.WriteFile(EvidencePath, []byte("{"), 0o600))
+	require.NoError(t, SaveEvidence(map[string]CheckEvidence{"b": second}), "a corrupt file is replaced")
+	all, _ = LoadEvidence()
+	assert.Len(t, all, 1)
+}
+
+func TestRedactEvidence(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	redacted := RedactEvidence(check.Evidence{
+		Kind:    check.EvidenceCommand,
+		Subject: "curl --token abc123 -u admin --password='hunter 2' " + filepath.Join(home, ".ssh", "config"),
+		Error:   "API_KEY=xyz is invalid",
+	})
+	assert.Equal(t, "curl --token <redacted> -u admin --password=<redacted> "+filepath.Join("~", ".ssh", "config"), redacted.Subject)
+	assert.Equal(t, "API_KEY=<redacted> is invalid", redacted.Error)
+}
@@ -0,0 +1,48 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// LockFile takes an exclusive advisory lock on path + ".lock", waiting for
+// other processes holding it, and returns a function that releases it. The
+// agent, the tray application and scheduled runs update the same state
+// files, so each update of a file happens under its lock.
+func LockFile(path string) (func(), error) {
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(lock); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return func() {
+		_ = unlockFile(lock)
+		lock.Close()
+	}, nil
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames
+// it over path. Each writer gets its own temporary file, so concurrent
+// writers do not corrupt each other and readers never see a partial file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
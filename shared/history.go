@@ -0,0 +1,216 @@
+package shared
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/caarlos0/log"
+)
+
+// HistoryRun is a single run of the checks as recorded in the history file.
+type HistoryRun struct {
+	Time     time.Time                   `json:"time"`
+	Version  string                      `json:"version"`
+	Duration time.Duration               `json:"duration,omitempty"`
+	Checks   map[string]check.CheckState `json:"checks"`
+}
+
+// HistoryEntry is the state of one check in a recorded run.
+type HistoryEntry struct {
+	Time    time.Time        `json:"time"`
+	Version string           `json:"version"`
+	State   check.CheckState `json:"state"`
+}
+
+// HistoryChange is a check whose state differs between two runs.
+// An empty state means the check was not part of that run.
+type HistoryChange struct {
+	UUID string           `json:"uuid"`
+	From check.CheckState `json:"from"`
+	To   check.CheckState `json:"to"`
+}
+
+// CheckTrend summarizes the history of one check. Zero times mean "never".
+type CheckTrend struct {
+	FirstFailed  time.Time `json:"firstFailed"`
+	LastPassed   time.Time `json:"lastPassed"`
+	FailingSince time.Time `json:"failingSince"`
+}
+
+var (
+	historyMutex sync.Mutex
+	HistoryPath  string
+	// MaxHistorySize bounds the history file; once exceeded the oldest runs
+	// are dropped until it is back to half of this size.
+	MaxHistorySize int64 = 1 << 20
+)
+
+func init() {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	HistoryPath = filepath.Join(homeDir, ".paretosecurity.history")
+}
+
+// AppendHistory appends a run to the history file, one JSON document per line,
+// and trims the oldest runs when the file grows past MaxHistorySize.
+func AppendHistory(run HistoryRun) error {
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	line, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(HistoryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	info, err := file.Stat()
+	file.Close()
+	if err != nil {
+		return err
+	}
+	if info.Size() > MaxHistorySize {
+		return trimHistory(MaxHistorySize / 2)
+	}
+	return nil
+}
+
+// trimHistory rewrites the history file keeping the newest lines that fit in limit bytes.
+func trimHistory(limit int64) error {
+	data, err := os.ReadFile(HistoryPath)
+	if err != nil {
+		return err
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	kept, size := 0, int64(0)
+	for i := len(lines) - 1; i >= 0; i-- {
+		if size+int64(len(lines[i])) > limit {
+			break
+		}
+		size += int64(len(lines[i]))
+		kept++
+	}
+
+	tmp := HistoryPath + ".tmp"
+	if err := os.WriteFile(tmp, bytes.Join(lines[len(lines)-kept:], nil), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, HistoryPath)
+}
+
+// LoadHistory returns all recorded runs, oldest first. A missing history file
+// yields no runs; lines that cannot be decoded are skipped.
+func LoadHistory() ([]HistoryRun, error) {
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+
+	file, err := os.Open(HistoryPath)
+	if os.IsNotExist(err) {
+		return []HistoryRun{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	runs := []HistoryRun{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), int(MaxHistorySize))
+	for scanner.Scan() {
+		var run HistoryRun
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			log.WithError(err).Debug("skipping malformed history line")
+			continue
+		}
+		runs = append(runs, run)
+	}
+	return runs, scanner.Err()
+}
+
+// CheckTimeline returns the recorded states of a check, oldest first.
+func CheckTimeline(runs []HistoryRun, uuid string) []HistoryEntry {
+	entries := []HistoryEntry{}
+	for _, run := range runs {
+		if state, ok := run.Checks[uuid]; ok {
+			entries = append(entries, HistoryEntry{Time: run.Time, Version: run.Version, State: state})
+		}
+	}
+	return entries
+}
+
+// Trend returns when a check first failed, when it last passed and, if it is
+// failing now, since when. Errored and disabled runs neither start nor end a
+// failing streak.
+func Trend(runs []HistoryRun, uuid string) CheckTrend {
+	trend := CheckTrend{}
+	for _, entry := range CheckTimeline(runs, uuid) {
+		switch entry.State {
+		case check.CheckStateFailed:
+			if trend.FirstFailed.IsZero() {
+				trend.FirstFailed = entry.Time
+			}
+			if trend.FailingSince.IsZero() {
+				trend.FailingSince = entry.Time
+			}
+		case check.CheckStatePassed:
+			trend.LastPassed = entry.Time
+			trend.FailingSince = time.Time{}
+		}
+	}
+	return trend
+}
+
+// DiffRuns returns the checks whose state changed between two runs, sorted by UUID.
+func DiffRuns(from, to HistoryRun) []HistoryChange {
+	uuids := map[string]bool{}
+	for uuid := range from.Checks {
+		uuids[uuid] = true
+	}
+	for uuid := range to.Checks {
+		uuids[uuid] = true
+	}
+
+	changes := []HistoryChange{}
+	for uuid := range uuids {
+		if from.Checks[uuid] != to.Checks[uuid] {
+			changes = append(changes, HistoryChange{UUID: uuid, From: from.Checks[uuid], To: to.Checks[uuid]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].UUID < changes[j].UUID
+	})
+	return changes
+}
+
+// LastRun returns the most recent recorded run.
+func LastRun() (HistoryRun, bool) {
+	runs, err := LoadHistory()
+	if err != nil || len(runs) == 0 {
+		return HistoryRun{}, false
+	}
+	return runs[len(runs)-1], true
+}
+
+// FailingSince returns since when a check has been failing according to the history.
+func FailingSince(uuid string) (time.Time, bool) {
+	runs, err := LoadHistory()
+	if err != nil {
+		return time.Time{}, false
+	}
+	since := Trend(runs, uuid).FailingSince
+	return since, !since.IsZero()
+}
@@ -0,0 +1,128 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/stretchr/testify/assert"
+)
+
+func historyAt(hours int, states map[string]check.CheckState) HistoryRun {
+	return HistoryRun{
+		Time:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(hours) * time.Hour),
+		Version: "1.0.0",
+		Checks:  states,
+	}
+}
+
+func TestAppendAndLoadHistory(t *testing.T) {
+	HistoryPath = filepath.Join(t.TempDir(), "history")
+
+	runs, err := LoadHistory()
+	assert.NoError(t, err)
+	assert.Empty(t, runs)
+
+	assert.NoError(t, AppendHistory(historyAt(0, map[string]check.CheckState{"a": check.CheckStatePassed})))
+	assert.NoError(t, AppendHistory(historyAt(1, map[string]check.CheckState{"a": check.CheckStateFailed})))
+
+	// A torn write must not hide the other runs
+	file, err := os.OpenFile(HistoryPath, os.O_APPEND|os.O_WRONLY, 0o600)
+	assert.NoError(t, err)
+	_, _ = file.WriteString("{\"time\":\n")
+	file.Close()
+
+	runs, err = LoadHistory()
+	assert.NoError(t, err)
+	assert.Len(t, runs, 2)
+	assert.Equal(t, check.CheckStateFailed, runs[1].Checks["a"])
+}
+
+func TestAppendHistoryTrimsOldestRuns(t *testing.T) {
+	HistoryPath = filepath.Join(t.TempDir(), "history")
+	defer func(size int64) { MaxHistorySize = size }(MaxHistorySize)
+	MaxHistorySize = 400
+
+	for i := range 20 {
+		assert.NoError(t, AppendHistory(historyAt(i, map[string]check.CheckState{"a": check.CheckStatePassed})))
+	}
+
+	info, err := os.Stat(HistoryPath)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), MaxHistorySize)
+
+	runs, err := LoadHistory()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, runs)
+	assert.Equal(t, historyAt(19, nil).Time, runs[len(runs)-1].Time)
+	assert.True(t, runs[0].Time.After(historyAt(0, nil).Time))
+}
+
+func TestTrend(t *testing.T) {
+	runs := []HistoryRun{
+		historyAt(0, map[string]check.CheckState{"a": check.CheckStateFailed}),
+		historyAt(1, map[string]check.CheckState{"a": check.CheckStatePassed}),
+		historyAt(2, map[string]check.CheckState{"b": check.CheckStatePassed}),
+		historyAt(3, map[string]check.CheckState{"a": check.CheckStateFailed}),
+		historyAt(4, map[string]check.CheckState{"a": check.CheckStateError}),
+		historyAt(5, map[string]check.CheckState{"a": check.CheckStateFailed}),
+	}
+
+	assert.Len(t, CheckTimeline(runs, "a"), 5)
+	assert.Equal(t, CheckTrend{
+		FirstFailed:  runs[0].Time,
+		LastPassed:   runs[1].Time,
+		FailingSince: runs[3].Time,
+	}, Trend(runs, "a"))
+	assert.Equal(t, CheckTrend{LastPassed: runs[2].Time}, Trend(runs, "b"))
+	assert.Equal(t, CheckTrend{}, Trend(runs, "missing"))
+}
+
+func TestDiffRuns(t *testing.T) {
+	from := historyAt(0, map[string]check.CheckState{
+		"same":    check.CheckStatePassed,
+		"changed": check.CheckStatePassed,
+		"removed": check.CheckStateFailed,
+	})
+	to := historyAt(1, map[string]check.CheckState{
+		"same":    check.CheckStatePassed,
+		"changed": check.CheckStateFailed,
+		"added":   check.CheckStateDisabled,
+	})
+
+	assert.Equal(t, []HistoryChange{
+		{UUID: "added", To: check.CheckStateDisabled},
+		{UUID: "changed", From: check.CheckStatePassed, To: check.CheckStateFailed},
+		{UUID: "removed", From: check.CheckStateFailed},
+	}, DiffRuns(from, to))
+}
+
+func TestFailingSince(t *testing.T) {
+	HistoryPath = filepath.Join(t.TempDir(), "history")
+	assert.NoError(t, AppendHistory(historyAt(0, map[string]check.CheckState{"a": check.CheckStateFailed})))
+
+	since, ok := FailingSince("a")
+	assert.True(t, ok)
+	assert.Equal(t, historyAt(0, nil).Time, since.UTC())
+
+	_, ok = FailingSince("b")
+	assert.False(t, ok)
+}
+
+func TestLastRun(t *testing.T) {
+	HistoryPath = filepath.Join(t.TempDir(), "history")
+	_, ok := LastRun()
+	assert.False(t, ok)
+
+	run := historyAt(1, map[string]check.CheckState{"a": check.CheckStatePassed})
+	run.Duration = 2 * time.Second
+	assert.NoError(t, AppendHistory(historyAt(0, nil)))
+	assert.NoError(t, AppendHistory(run))
+
+	last, ok := LastRun()
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, last.Duration)
+	assert.Equal(t, run.Time, last.Time.UTC())
+}
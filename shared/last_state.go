@@ -15,11 +15,38 @@ import (
 )
 
 type LastState struct {
-	Name     string `json:"name"`
-	UUID     string `json:"uuid"`
-	Passed   bool   `json:"state"`
-	HasError bool   `json:"hasError"`
-	Details  string `json:"details"`
+	Name     string         `json:"name"`
+	UUID     string         `json:"uuid"`
+	Passed   bool           `json:"state"`
+	HasError bool           `json:"hasError"`
+	Details  string         `json:"details"`
+	Severity check.Severity `json:"severity"`
+	Weight   int            `json:"weight"`
+}
+
+// State returns the check state recorded in the last run.
+func (s LastState) State() check.CheckState {
+	if s.HasError {
+		return check.CheckStateError
+	}
+	if s.Passed {
+		return check.CheckStatePassed
+	}
+	return check.CheckStateFailed
+}
+
+// DeviceScore returns the weighted 0-100 security score for the given states.
+// States recorded before checks declared weights count with the default weight.
+func DeviceScore(states map[string]LastState) int {
+	entries := make([]check.Scored, 0, len(states))
+	for _, state := range states {
+		weight := state.Weight
+		if weight <= 0 {
+			weight = check.SeverityWeight(state.Severity)
+		}
+		entries = append(entries, check.Scored{State: state.State(), Weight: weight})
+	}
+	return check.Score(entries)
 }
 
 var (
@@ -86,7 +113,8 @@ func PrintStates() {
 	loadStates()
 
 	fmt.Printf("Loaded %d states from %s\n", len(states), StatePath)
-	fmt.Printf("Last modified time: %s\n\n", lastModTime.Format(time.RFC3339))
+	fmt.Printf("Last modified time: %s\n", lastModTime.Format(time.RFC3339))
+	fmt.Printf("Security score: %d/100\n\n", DeviceScore(states))
 
 	data := [][]string{}
 	for uuid, state := range states {
@@ -94,13 +122,21 @@ func PrintStates() {
 		if !state.Passed {
 			stateStr = check.CheckStateFailed
 		}
-		data = append(data, []string{uuid, state.Name, string(stateStr), state.Details})
+		severity := state.Severity
+		if severity == "" {
+			severity = check.DefaultSeverity
+		}
+		stateText := string(stateStr)
+		if snooze := ActiveSnooze(uuid, time.Now()); snooze != nil {
+			stateText += " (snoozed until " + snooze.Until.Local().Format(time.DateTime) + ")"
+		}
+		data = append(data, []string{uuid, state.Name, string(severity), stateText, state.Details})
 	}
 
 	table := tablewriter.NewTable(os.Stdout,
 		tablewriter.WithRenderer(renderer.NewMarkdown()),
 	)
-	table.Header([]string{"UUID", "Name", "State", "Details"})
+	table.Header([]string{"UUID", "Name", "Severity", "State", "Details"})
 	table.Bulk(data)
 	table.Render()
 }
@@ -5,7 +5,9 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/pelletier/go-toml"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestCommitLastState_Success(t *testing.T) {
@@ -140,3 +142,20 @@ func TestAllChecksPassed(t *testing.T) {
 		})
 	}
 }
+
+func TestLastState_State(t *testing.T) {
+	assert.Equal(t, check.CheckStatePassed, LastState{Passed: true}.State())
+	assert.Equal(t, check.CheckStateFailed, LastState{Passed: false}.State())
+	assert.Equal(t, check.CheckStateError, LastState{Passed: true, HasError: true}.State())
+}
+
+func TestDeviceScore(t *testing.T) {
+	states := map[string]LastState{
+		"critical": {UUID: "critical", Passed: false, Severity: check.SeverityCritical, Weight: 10},
+		"low":      {UUID: "low", Passed: true, Severity: check.SeverityLow, Weight: 1},
+		// Recorded before weights existed, counts with the default weight
+		"legacy": {UUID: "legacy", Passed: true},
+	}
+	assert.Equal(t, 29, DeviceScore(states))
+	assert.Equal(t, 0, DeviceScore(map[string]LastState{}))
+}
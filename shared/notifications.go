@@ -0,0 +1,86 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// NotificationState records the desktop notifications about failing checks,
+// so that they can be rate limited across runs.
+type NotificationState struct {
+	// Last is when the last notification was shown
+	Last time.Time `json:"last"`
+	// Pending are checks that started failing while notifications were rate limited
+	Pending []string `json:"pending,omitempty"`
+	// Notified is when each check was last named in a notification
+	Notified map[string]time.Time `json:"notified,omitempty"`
+	// Queued are checks to name in a notification raised by the tray
+	// application, which can act on its buttons
+	Queued []string `json:"queued,omitempty"`
+}
+
+var (
+	notificationMutex     sync.Mutex
+	NotificationStatePath string
+	// TrayLockPath is the PID lock file of the tray application
+	TrayLockPath string
+)
+
+func init() {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	NotificationStatePath = filepath.Join(homeDir, ".paretosecurity.notifications")
+	TrayLockPath = filepath.Join(homeDir, ".paretosecurity-tray.lock")
+}
+
+// LoadNotificationState reads the notification state. A missing file is an
+// empty state.
+func LoadNotificationState() (NotificationState, error) {
+	notificationMutex.Lock()
+	defer notificationMutex.Unlock()
+	return loadNotificationState()
+}
+
+// UpdateNotificationState applies update to the notification state and saves
+// it. The tray application and check runs both update the state, so the
+// update holds the lock file of the state.
+func UpdateNotificationState(update func(*NotificationState)) error {
+	notificationMutex.Lock()
+	defer notificationMutex.Unlock()
+	unlock, err := LockFile(NotificationStatePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := loadNotificationState()
+	if err != nil {
+		// A corrupt state only affects rate limiting, start over
+		state = NotificationState{}
+	}
+	update(&state)
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(NotificationStatePath, data, 0o600)
+}
+
+func loadNotificationState() (NotificationState, error) {
+	state := NotificationState{}
+	data, err := os.ReadFile(NotificationStatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
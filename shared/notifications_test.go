@@ -0,0 +1,48 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationState(t *testing.T) {
+	NotificationStatePath = filepath.Join(t.TempDir(), "notifications")
+
+	state, err := LoadNotificationState()
+	assert.NoError(t, err)
+	assert.Zero(t, state.Last)
+
+	now := time.Now().Truncate(time.Second)
+	assert.NoError(t, UpdateNotificationState(func(state *NotificationState) {
+		state.Last = now
+		state.Pending = []string{"a"}
+	}))
+	assert.NoError(t, UpdateNotificationState(func(state *NotificationState) {
+		state.Notified = map[string]time.Time{"a": now}
+	}))
+
+	state, err = LoadNotificationState()
+	assert.NoError(t, err)
+	assert.True(t, now.Equal(state.Last))
+	assert.Equal(t, []string{"a"}, state.Pending)
+	assert.True(t, now.Equal(state.Notified["a"]))
+}
+
+func TestUpdateNotificationState_Corrupt(t *testing.T) {
+	NotificationStatePath = filepath.Join(t.TempDir(), "notifications")
+	assert.NoError(t, os.WriteFile(NotificationStatePath, []byte("{"), 0o600))
+
+	_, err := LoadNotificationState()
+	assert.Error(t, err)
+
+	assert.NoError(t, UpdateNotificationState(func(state *NotificationState) {
+		state.Pending = []string{"a"}
+	}))
+	state, err := LoadNotificationState()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, state.Pending)
+}
@@ -1,7 +1,11 @@
 package shared
 
 import (
+	"context"
+	"io/fs"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 )
 
@@ -16,12 +20,86 @@ var ReadFileMock func(name string) ([]byte, error)
 // from the ReadFileMocks map instead of reading from the actual file system.
 // If the file name is not found in the ReadFileMocks map, it returns an error.
 // Otherwise, it reads the file content from the file system.
+// During a replay the content comes from the recording, see StartReplay.
 func ReadFile(name string) ([]byte, error) {
+	if replay := currentReplay(); replay != nil {
+		return replay.file(name)
+	}
+	var content []byte
+	var err error
 	if testing.Testing() {
-		return ReadFileMock(name)
+		content, err = ReadFileMock(name)
+	} else {
+		content, err = os.ReadFile(name)
+	}
+	currentRecording().addFile(name, content, err)
+	return content, err
+}
+
+// ReadSystemFile is like ReadFile but always reads from the file system
+// outside of replays, for callers that have their own mocks.
+func ReadSystemFile(name string) ([]byte, error) {
+	if replay := currentReplay(); replay != nil {
+		return replay.file(name)
+	}
+	content, err := os.ReadFile(name)
+	currentRecording().addFile(name, content, err)
+	return content, err
+}
+
+// ReadFileContext is like ReadFile and records the file as evidence in the
+// trail of ctx.
+func ReadFileContext(ctx context.Context, name string) ([]byte, error) {
+	content, err := ReadFile(name)
+	recordFile(ctx, name, content, err)
+	return content, err
+}
+
+// Stat returns the file info of the named file. During a replay the info
+// comes from the recording, see StartReplay.
+func Stat(name string) (fs.FileInfo, error) {
+	if replay := currentReplay(); replay != nil {
+		return replay.stat(name)
+	}
+	info, err := os.Stat(name)
+	currentRecording().addStat(name, info, err)
+	return info, err
+}
+
+// ReadDir returns the entries of the named directory. During a replay the
+// entries come from the recording, see StartReplay.
+func ReadDir(name string) ([]fs.DirEntry, error) {
+	if replay := currentReplay(); replay != nil {
+		return replay.readDir(name)
+	}
+	entries, err := os.ReadDir(name)
+	currentRecording().addDir(name, entries, err)
+	return entries, err
+}
+
+// Glob returns the paths matching pattern, like filepath.Glob. During a
+// replay the paths come from the recording, see StartReplay.
+func Glob(pattern string) ([]string, error) {
+	if replay := currentReplay(); replay != nil {
+		return replay.glob(pattern)
+	}
+	matches, err := filepath.Glob(pattern)
+	if err == nil {
+		currentRecording().addGlob(pattern, matches)
+	}
+	return matches, err
+}
 
+// LookPath searches for an executable named name in the directories of PATH,
+// like exec.LookPath. During a replay the path comes from the recording, see
+// StartReplay.
+func LookPath(name string) (string, error) {
+	if replay := currentReplay(); replay != nil {
+		return replay.lookPath(name)
 	}
-	return os.ReadFile(name)
+	path, err := exec.LookPath(name)
+	currentRecording().addLookPath(name, path, err)
+	return path, err
 }
 
 var UserHomeDirMock func() (string, error)
@@ -0,0 +1,182 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/caarlos0/log"
+	"github.com/pelletier/go-toml"
+)
+
+// Policy is the policy set by the device administrator, it takes precedence over Config
+var Policy ParetoPolicy
+
+// PolicyPath is the location of the root-owned policy file
+var PolicyPath = defaultPolicyPath
+
+// verifyPolicyOwner rejects policy files that an unprivileged user could have written
+// Can be overridden for testing
+var verifyPolicyOwner = checkPolicyOwner
+
+// policyNow returns the current time when evaluating exceptions
+// Can be overridden for testing
+var policyNow = time.Now
+
+var (
+	// ErrCheckRequired is returned when disabling a check the policy requires
+	ErrCheckRequired = errors.New("check is required by the policy")
+	// ErrCheckExcepted is returned when enabling a check disabled by a policy exception
+	ErrCheckExcepted = errors.New("check is disabled by a policy exception")
+)
+
+// ParetoPolicy is the team baseline read from PolicyPath.
+//
+// Example:
+//
+//	Required = ["2e46c89a-5461-4865-a92e-3b799c12034a"]
+//
+//	[[Exceptions]]
+//	Check = "2e46c89a-5461-4865-a92e-3b799c12034a"
+//	Expires = "2026-12-31"
+//	Justification = "Build server, replaced in Q1"
+//
+//	[Parameters."2e46c89a-5461-4865-a92e-3b799c12034a"]
+//	MinReleaseAge = "168h"
+type ParetoPolicy struct {
+	// Required checks run even if they are disabled in the user config
+	Required []string
+	// Exceptions disable checks until they expire
+	Exceptions []PolicyException
+	// Parameters holds the check parameters enforced by the policy, keyed by check UUID
+	Parameters map[string]map[string]interface{}
+	// Rules are declarative checks defined by the administrator, they may require root
+	Rules []Rule
+	// TeamKeys pins the JSON Web Key Set that device auth tokens must be signed with
+	TeamKeys string
+}
+
+// PolicyException disables a check until Expires, for the given Justification.
+type PolicyException struct {
+	Check         string
+	Expires       string
+	Justification string
+}
+
+// ExpiresAt parses Expires, either a date or an RFC 3339 timestamp. A date
+// expires at the end of that day in local time.
+func (e PolicyException) ExpiresAt() (time.Time, error) {
+	if date, err := time.ParseInLocation(time.DateOnly, e.Expires, time.Local); err == nil {
+		return date.AddDate(0, 0, 1), nil
+	}
+	return time.Parse(time.RFC3339, e.Expires)
+}
+
+// Active reports whether the exception has not expired yet. Exceptions without
+// a valid expiry are never active.
+func (e PolicyException) Active() bool {
+	expires, err := e.ExpiresAt()
+	if err != nil {
+		return false
+	}
+	return policyNow().Before(expires)
+}
+
+// PolicySource tells where the decision to run a check comes from.
+type PolicySource string
+
+const (
+	SourceDefault PolicySource = ""
+	SourceConfig  PolicySource = "config"
+	SourcePolicy  PolicySource = "policy"
+)
+
+// CheckPolicy is the effective setting of a check after applying the policy
+// and the user config.
+type CheckPolicy struct {
+	Disabled  bool
+	Required  bool
+	Source    PolicySource
+	Exception *PolicyException
+	// Snooze is set while the user snoozed the check, which still runs
+	Snooze *CheckSnooze
+}
+
+// Describe returns a short explanation of the setting, or an empty string
+// when the check runs by default.
+func (p CheckPolicy) Describe() string {
+	switch {
+	case p.Exception != nil:
+		return fmt.Sprintf("Disabled by policy exception until %s: %s", p.Exception.Expires, p.Exception.Justification)
+	case p.Required:
+		return "Required by policy"
+	case p.Disabled:
+		return "Disabled by the config file"
+	case p.Snooze != nil:
+		return fmt.Sprintf("Snoozed until %s: %s", p.Snooze.Until.Local().Format(time.DateTime), p.Snooze.Reason)
+	}
+	return ""
+}
+
+// CheckPolicyFor returns the effective setting of a check. An active policy
+// exception disables the check, a required check runs even if the user
+// disabled it and otherwise the user config decides. A check the user
+// snoozed runs as usual.
+func CheckPolicyFor(checkUUID string) CheckPolicy {
+	for _, exception := range Policy.Exceptions {
+		if exception.Check == checkUUID && exception.Active() {
+			return CheckPolicy{Disabled: true, Required: IsCheckRequired(checkUUID), Source: SourcePolicy, Exception: &exception}
+		}
+	}
+	if IsCheckRequired(checkUUID) {
+		return CheckPolicy{Required: true, Source: SourcePolicy}
+	}
+	if slices.Contains(Config.DisableChecks, checkUUID) {
+		return CheckPolicy{Disabled: true, Source: SourceConfig}
+	}
+	if snooze := ActiveSnooze(checkUUID, policyNow()); snooze != nil {
+		return CheckPolicy{Source: SourceConfig, Snooze: snooze}
+	}
+	return CheckPolicy{}
+}
+
+// IsCheckRequired checks if the policy marks the check as mandatory
+func IsCheckRequired(checkUUID string) bool {
+	return slices.Contains(Policy.Required, checkUUID)
+}
+
+// LoadPolicy reads the policy from PolicyPath. A missing policy file leaves
+// the policy empty; a policy file writable by unprivileged users is ignored.
+func LoadPolicy() error {
+	Policy = ParetoPolicy{}
+
+	info, err := os.Stat(PolicyPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := verifyPolicyOwner(info); err != nil {
+		return fmt.Errorf("ignoring policy %s: %w", PolicyPath, err)
+	}
+
+	content, err := os.ReadFile(PolicyPath)
+	if err != nil {
+		return err
+	}
+	var policy ParetoPolicy
+	if err := toml.Unmarshal(content, &policy); err != nil {
+		return fmt.Errorf("failed to parse policy %s: %w", PolicyPath, err)
+	}
+	for _, exception := range policy.Exceptions {
+		if _, err := exception.ExpiresAt(); err != nil {
+			log.WithField("check", exception.Check).WithField("expires", exception.Expires).Warn("Ignoring policy exception with an invalid expiry")
+		}
+	}
+	Policy = policy
+	log.WithField("path", PolicyPath).Debug("Loaded policy")
+	return nil
+}
@@ -0,0 +1,139 @@
+package shared
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPolicy = `
+Required = ["required-uuid", "waived-uuid"]
+
+[[Exceptions]]
+Check = "waived-uuid"
+Expires = "2025-06-30"
+Justification = "Replacement laptop on order"
+
+[[Exceptions]]
+Check = "expired-uuid"
+Expires = "2025-01-31T12:00:00Z"
+Justification = "Old exception"
+
+[Parameters."required-uuid"]
+MinReleaseAge = "168h"
+`
+
+// withPolicy writes content to a temporary policy file and loads it as of 2025-06-01.
+func withPolicy(t *testing.T, content string) {
+	t.Helper()
+	PolicyPath = filepath.Join(t.TempDir(), "policy.toml")
+	ConfigPath = filepath.Join(t.TempDir(), "pareto.toml")
+	assert.NoError(t, os.WriteFile(PolicyPath, []byte(content), 0o644))
+	verifyPolicyOwner = func(os.FileInfo) error { return nil }
+	policyNow = func() time.Time { return time.Date(2025, 6, 1, 0, 0, 0, 0, time.Local) }
+	t.Cleanup(func() {
+		verifyPolicyOwner = checkPolicyOwner
+		policyNow = time.Now
+		Policy = ParetoPolicy{}
+		Config.DisableChecks = nil
+	})
+	assert.NoError(t, LoadPolicy())
+}
+
+func TestLoadPolicy(t *testing.T) {
+	withPolicy(t, testPolicy)
+
+	assert.Equal(t, []string{"required-uuid", "waived-uuid"}, Policy.Required)
+	assert.Len(t, Policy.Exceptions, 2)
+	assert.Equal(t, "168h", Policy.Parameters["required-uuid"]["MinReleaseAge"])
+}
+
+func TestLoadPolicy_Missing(t *testing.T) {
+	Policy = ParetoPolicy{Required: []string{"stale"}}
+	PolicyPath = filepath.Join(t.TempDir(), "missing.toml")
+
+	assert.NoError(t, LoadPolicy())
+	assert.Empty(t, Policy.Required)
+}
+
+func TestLoadPolicy_Invalid(t *testing.T) {
+	PolicyPath = filepath.Join(t.TempDir(), "policy.toml")
+	assert.NoError(t, os.WriteFile(PolicyPath, []byte("Required = ["), 0o644))
+	verifyPolicyOwner = func(os.FileInfo) error { return nil }
+	defer func() { verifyPolicyOwner = checkPolicyOwner }()
+
+	assert.Error(t, LoadPolicy())
+	assert.Empty(t, Policy.Required)
+}
+
+func TestLoadPolicy_UntrustedOwner(t *testing.T) {
+	PolicyPath = filepath.Join(t.TempDir(), "policy.toml")
+	assert.NoError(t, os.WriteFile(PolicyPath, []byte(`Required = ["uuid"]`), 0o644))
+	verifyPolicyOwner = func(os.FileInfo) error { return errors.New("the file is not owned by root") }
+	defer func() { verifyPolicyOwner = checkPolicyOwner }()
+
+	err := LoadPolicy()
+	assert.ErrorContains(t, err, "not owned by root")
+	assert.False(t, IsCheckRequired("uuid"))
+}
+
+func TestCheckPolicyFor(t *testing.T) {
+	withPolicy(t, testPolicy)
+	Config.DisableChecks = []string{"required-uuid", "local-uuid", "expired-uuid"}
+
+	required := CheckPolicyFor("required-uuid")
+	assert.False(t, required.Disabled, "local config cannot disable a required check")
+	assert.True(t, required.Required)
+	assert.Equal(t, SourcePolicy, required.Source)
+	assert.Equal(t, "Required by policy", required.Describe())
+
+	waived := CheckPolicyFor("waived-uuid")
+	assert.True(t, waived.Disabled)
+	assert.Equal(t, SourcePolicy, waived.Source)
+	assert.Equal(t, "Disabled by policy exception until 2025-06-30: Replacement laptop on order", waived.Describe())
+
+	expired := CheckPolicyFor("expired-uuid")
+	assert.True(t, expired.Disabled, "an expired exception falls back to the config")
+	assert.Equal(t, SourceConfig, expired.Source)
+
+	local := CheckPolicyFor("local-uuid")
+	assert.Equal(t, CheckPolicy{Disabled: true, Source: SourceConfig}, local)
+	assert.Equal(t, "Disabled by the config file", local.Describe())
+
+	assert.Equal(t, CheckPolicy{}, CheckPolicyFor("other-uuid"))
+	assert.True(t, IsCheckDisabled("waived-uuid"))
+	assert.False(t, IsCheckDisabled("required-uuid"))
+}
+
+func TestPolicyException_Active(t *testing.T) {
+	policyNow = func() time.Time { return time.Date(2025, 6, 30, 23, 0, 0, 0, time.Local) }
+	defer func() { policyNow = time.Now }()
+
+	assert.True(t, PolicyException{Expires: "2025-06-30"}.Active(), "a date lasts until the end of the day")
+	assert.False(t, PolicyException{Expires: "2025-06-29"}.Active())
+	assert.False(t, PolicyException{Expires: "next week"}.Active())
+	assert.False(t, PolicyException{}.Active())
+}
+
+func TestDisableCheck_Required(t *testing.T) {
+	withPolicy(t, testPolicy)
+
+	assert.ErrorIs(t, DisableCheck("required-uuid"), ErrCheckRequired)
+	assert.Empty(t, Config.DisableChecks)
+
+	assert.NoError(t, DisableCheck("local-uuid"))
+	assert.Equal(t, []string{"local-uuid"}, Config.DisableChecks)
+}
+
+func TestEnableCheck_Excepted(t *testing.T) {
+	withPolicy(t, testPolicy)
+
+	err := EnableCheck("waived-uuid")
+	assert.ErrorIs(t, err, ErrCheckExcepted)
+	assert.ErrorContains(t, err, "until 2025-06-30")
+	assert.NoError(t, EnableCheck("expired-uuid"))
+}
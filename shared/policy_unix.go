@@ -0,0 +1,27 @@
+//go:build unix
+
+package shared
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const defaultPolicyPath = "/etc/paretosecurity/policy.toml"
+
+// checkPolicyOwner requires the policy file to be owned by root and not
+// writable by group or others.
+func checkPolicyOwner(info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return errors.New("cannot determine the owner of the file")
+	}
+	if stat.Uid != 0 {
+		return errors.New("the file is not owned by root")
+	}
+	if info.Mode().Perm()&0o022 != 0 {
+		return errors.New("the file is writable by group or others")
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPolicyOwner_WorldWritable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.toml")
+	assert.NoError(t, os.WriteFile(path, []byte(""), 0o600))
+	assert.NoError(t, os.Chmod(path, 0o666))
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+
+	assert.Error(t, checkPolicyOwner(info))
+}
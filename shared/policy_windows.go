@@ -0,0 +1,16 @@
+//go:build windows
+
+package shared
+
+import (
+	"os"
+	"path/filepath"
+)
+
+var defaultPolicyPath = filepath.Join(os.Getenv("ProgramData"), "ParetoSecurity", "policy.toml")
+
+// checkPolicyOwner relies on the ACLs of ProgramData, where only
+// administrators can modify files created by an administrator.
+func checkPolicyOwner(info os.FileInfo) error {
+	return nil
+}
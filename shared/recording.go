@@ -0,0 +1,517 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/check"
+)
+
+// RecordingVersion is the version of the recording format written by this build.
+// Version 2 added file lookups, directory listings, globs and executables.
+const RecordingVersion = 2
+
+// errNotExist is how a missing file is described in evidence and recordings.
+const errNotExist = "does not exist"
+
+// Recording holds the interactions of a run with the system, so that the run
+// can be replayed on another machine or in a test: every command run by
+// RunCommand, every file read by ReadFile, looked up by Stat or listed by
+// ReadDir, every Glob, every executable looked up by LookPath and every port
+// probe, in order, along with the results of the checks.
+type Recording struct {
+	Version     int                `json:"version"`
+	Agent       string             `json:"agent"`
+	OS          string             `json:"os"`
+	Time        time.Time          `json:"time"`
+	Commands    []RecordedCommand  `json:"commands"`
+	Files       []RecordedFile     `json:"files"`
+	Stats       []RecordedStat     `json:"stats,omitempty"`
+	Dirs        []RecordedDir      `json:"dirs,omitempty"`
+	Globs       []RecordedGlob     `json:"globs,omitempty"`
+	Executables []RecordedLookPath `json:"executables,omitempty"`
+	Ports       []RecordedPort     `json:"ports"`
+	Results     []RecordedResult   `json:"results"`
+
+	mutex   sync.Mutex
+	cursors map[string]int
+}
+
+// RecordedCommand is a command and its outcome.
+type RecordedCommand struct {
+	Command  string   `json:"command"`
+	Args     []string `json:"args"`
+	Output   string   `json:"output"`
+	ExitCode int      `json:"exitCode,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// RecordedFile is a file read and its content. Error is "does not exist" for
+// missing files.
+type RecordedFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RecordedStat is a file looked up and its mode and size. Error is "does not
+// exist" for missing files.
+type RecordedStat struct {
+	Path  string      `json:"path"`
+	Mode  fs.FileMode `json:"mode,omitempty"`
+	Size  int64       `json:"size,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// RecordedDir is a directory listed and its entries. Error is "does not
+// exist" for missing directories.
+type RecordedDir struct {
+	Path    string             `json:"path"`
+	Entries []RecordedDirEntry `json:"entries"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// RecordedDirEntry is an entry of a listed directory and its type.
+type RecordedDirEntry struct {
+	Name string      `json:"name"`
+	Type fs.FileMode `json:"type,omitempty"`
+}
+
+// RecordedGlob is a glob pattern and the paths it matched.
+type RecordedGlob struct {
+	Pattern string   `json:"pattern"`
+	Matches []string `json:"matches"`
+}
+
+// RecordedLookPath is an executable looked up in PATH and where it was
+// found. Error is set when it was not found.
+type RecordedLookPath struct {
+	Name  string `json:"name"`
+	Path  string `json:"path,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// RecordedPort is a port probe and whether the port was open.
+type RecordedPort struct {
+	Port  int    `json:"port"`
+	Proto string `json:"proto"`
+	Open  bool   `json:"open"`
+}
+
+// RecordedResult is the result of a check in the recorded run.
+type RecordedResult struct {
+	UUID    string           `json:"uuid"`
+	State   check.CheckState `json:"state"`
+	Details string           `json:"details,omitempty"`
+}
+
+// ReplayedExitError is returned by replayed commands that exited with a
+// non-zero status. Like exec.ExitError it has an ExitCode method, see ExitCode.
+type ReplayedExitError struct {
+	Code int
+}
+
+func (e *ReplayedExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the exit status of the command.
+func (e *ReplayedExitError) ExitCode() int {
+	return e.Code
+}
+
+var (
+	recordingMutex  sync.RWMutex
+	activeRecording *Recording
+	activeReplay    *Recording
+)
+
+// NewRecording returns an empty recording of this device.
+func NewRecording() *Recording {
+	return &Recording{
+		Version:  RecordingVersion,
+		Agent:    Version,
+		OS:       runtime.GOOS,
+		Time:     time.Now(),
+		Commands: []RecordedCommand{},
+		Files:    []RecordedFile{},
+		Ports:    []RecordedPort{},
+		Results:  []RecordedResult{},
+	}
+}
+
+// LoadRecording reads a recording saved with Save.
+func LoadRecording(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	recording := &Recording{}
+	if err := json.Unmarshal(data, recording); err != nil {
+		return nil, fmt.Errorf("invalid recording %s: %w", path, err)
+	}
+	if recording.Version > RecordingVersion {
+		return nil, fmt.Errorf("recording %s has version %d, this build reads up to version %d", path, recording.Version, RecordingVersion)
+	}
+	return recording, nil
+}
+
+// Save writes the recording to path. The recording holds command outputs and
+// file contents as they are, so it is only readable by the current user.
+func (r *Recording) Save(path string) error {
+	r.mutex.Lock()
+	data, err := json.MarshalIndent(r, "", "  ")
+	r.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// StartRecording adds the interactions with the system to recording until
+// stop is called.
+func StartRecording(recording *Recording) (stop func()) {
+	stopCommands := ObserveCommands(recording.addCommand)
+	recordingMutex.Lock()
+	defer recordingMutex.Unlock()
+	activeRecording = recording
+	return func() {
+		stopCommands()
+		recordingMutex.Lock()
+		defer recordingMutex.Unlock()
+		activeRecording = nil
+	}
+}
+
+// StartReplay answers commands, file reads and lookups, globs, executable
+// lookups and port probes from recording until stop is called, instead of the
+// system and the test mocks. Commands that were not recorded fail, files,
+// directories and executables that were not recorded do not exist, globs that
+// were not recorded match nothing and ports that were not recorded are
+// closed. Repeated interactions are answered in the recorded order, and the
+// last answer is repeated once they run out.
+func StartReplay(recording *Recording) (stop func()) {
+	recordingMutex.Lock()
+	defer recordingMutex.Unlock()
+	activeReplay = recording
+	return func() {
+		recordingMutex.Lock()
+		defer recordingMutex.Unlock()
+		activeReplay = nil
+	}
+}
+
+// ReplayForTest replays the recording at path for the rest of the test.
+func ReplayForTest(tb testing.TB, path string) *Recording {
+	tb.Helper()
+	recording, err := LoadRecording(path)
+	if err != nil {
+		tb.Fatalf("failed to load recording: %v", err)
+	}
+	tb.Cleanup(StartReplay(recording))
+	return recording
+}
+
+// Replaying reports whether a replay is active. Checks that talk to web
+// services cannot be answered from a recording and fail during a replay.
+func Replaying() bool {
+	return currentReplay() != nil
+}
+
+func currentRecording() *Recording {
+	recordingMutex.RLock()
+	defer recordingMutex.RUnlock()
+	return activeRecording
+}
+
+func currentReplay() *Recording {
+	recordingMutex.RLock()
+	defer recordingMutex.RUnlock()
+	return activeReplay
+}
+
+// RecordPort adds a port probe to the active recording, if any.
+func RecordPort(port int, proto string, open bool) {
+	if r := currentRecording(); r != nil {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.Ports = append(r.Ports, RecordedPort{Port: port, Proto: proto, Open: open})
+	}
+}
+
+// ReplayPort answers a port probe from the active replay. replayed is false
+// when no replay is active.
+func ReplayPort(port int, proto string) (open bool, replayed bool) {
+	r := currentReplay()
+	if r == nil {
+		return false, false
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, probe := range r.Ports {
+		if probe.Port == port && probe.Proto == proto {
+			return probe.Open, true
+		}
+	}
+	return false, true
+}
+
+// SetResults stores the results of the recorded run.
+func (r *Recording) SetResults(results []RecordedResult) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Results = results
+}
+
+// addCommand adds a command run to the recording.
+func (r *Recording) addCommand(run CommandRun) {
+	command := RecordedCommand{Command: run.Name, Args: run.Args, Output: run.Output}
+	if code, exited := ExitCode(run.Err); exited {
+		command.ExitCode = code
+	} else if run.Err != nil {
+		command.Error = run.Err.Error()
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Commands = append(r.Commands, command)
+}
+
+// addFile adds a file read to the recording, if r is not nil.
+func (r *Recording) addFile(name string, content []byte, err error) {
+	if r == nil {
+		return
+	}
+	file := RecordedFile{Path: name, Content: string(content), Error: recordedError(err)}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Files = append(r.Files, file)
+}
+
+// recordedError describes err in a recording.
+func recordedError(err error) string {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return errNotExist
+	case err != nil:
+		return err.Error()
+	}
+	return ""
+}
+
+// addStat adds a file lookup to the recording, if r is not nil.
+func (r *Recording) addStat(name string, info fs.FileInfo, err error) {
+	if r == nil {
+		return
+	}
+	stat := RecordedStat{Path: name, Error: recordedError(err)}
+	if err == nil {
+		stat.Mode, stat.Size = info.Mode(), info.Size()
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Stats = append(r.Stats, stat)
+}
+
+// addDir adds a directory listing to the recording, if r is not nil.
+func (r *Recording) addDir(name string, entries []fs.DirEntry, err error) {
+	if r == nil {
+		return
+	}
+	dir := RecordedDir{Path: name, Entries: []RecordedDirEntry{}, Error: recordedError(err)}
+	for _, entry := range entries {
+		dir.Entries = append(dir.Entries, RecordedDirEntry{Name: entry.Name(), Type: entry.Type()})
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Dirs = append(r.Dirs, dir)
+}
+
+// addGlob adds a glob to the recording, if r is not nil.
+func (r *Recording) addGlob(pattern string, matches []string) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Globs = append(r.Globs, RecordedGlob{Pattern: pattern, Matches: append([]string{}, matches...)})
+}
+
+// addLookPath adds an executable lookup to the recording, if r is not nil.
+func (r *Recording) addLookPath(name, path string, err error) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Executables = append(r.Executables, RecordedLookPath{Name: name, Path: path, Error: recordedError(err)})
+}
+
+// next returns the index of the next answer among the count recorded
+// answers to the interaction key, or -1 if there are none.
+func (r *Recording) next(key string, count int) int {
+	if count == 0 {
+		return -1
+	}
+	if r.cursors == nil {
+		r.cursors = map[string]int{}
+	}
+	index := min(r.cursors[key], count-1)
+	r.cursors[key]++
+	return index
+}
+
+// command answers a command from the recording.
+func (r *Recording) command(name string, arg []string) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	line := strings.TrimSpace(name + " " + strings.Join(arg, " "))
+	matches := []RecordedCommand{}
+	for _, command := range r.Commands {
+		if strings.TrimSpace(command.Command+" "+strings.Join(command.Args, " ")) == line {
+			matches = append(matches, command)
+		}
+	}
+	index := r.next("command "+line, len(matches))
+	if index < 0 {
+		return "", errors.New("command was not recorded: " + line)
+	}
+	command := matches[index]
+	switch {
+	case command.ExitCode != 0:
+		return command.Output, &ReplayedExitError{Code: command.ExitCode}
+	case command.Error != "":
+		return command.Output, errors.New(command.Error)
+	}
+	return command.Output, nil
+}
+
+// file answers a file read from the recording.
+func (r *Recording) file(name string) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	matches := []RecordedFile{}
+	for _, file := range r.Files {
+		if file.Path == name {
+			matches = append(matches, file)
+		}
+	}
+	index := r.next("file "+name, len(matches))
+	if index < 0 || matches[index].Error == errNotExist {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if matches[index].Error != "" {
+		return nil, errors.New(matches[index].Error)
+	}
+	return []byte(matches[index].Content), nil
+}
+
+// replayedFile is a file or directory entry answered from a recording.
+type replayedFile struct {
+	name string
+	mode fs.FileMode
+	size int64
+}
+
+func (f replayedFile) Name() string               { return filepath.Base(f.name) }
+func (f replayedFile) Size() int64                { return f.size }
+func (f replayedFile) Mode() fs.FileMode          { return f.mode }
+func (f replayedFile) ModTime() time.Time         { return time.Time{} }
+func (f replayedFile) IsDir() bool                { return f.mode.IsDir() }
+func (f replayedFile) Sys() any                   { return nil }
+func (f replayedFile) Type() fs.FileMode          { return f.mode.Type() }
+func (f replayedFile) Info() (fs.FileInfo, error) { return f, nil }
+
+// stat answers a file lookup from the recording.
+func (r *Recording) stat(name string) (fs.FileInfo, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	matches := []RecordedStat{}
+	for _, stat := range r.Stats {
+		if stat.Path == name {
+			matches = append(matches, stat)
+		}
+	}
+	index := r.next("stat "+name, len(matches))
+	if index < 0 || matches[index].Error == errNotExist {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	if matches[index].Error != "" {
+		return nil, errors.New(matches[index].Error)
+	}
+	return replayedFile{name: name, mode: matches[index].Mode, size: matches[index].Size}, nil
+}
+
+// readDir answers a directory listing from the recording.
+func (r *Recording) readDir(name string) ([]fs.DirEntry, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	matches := []RecordedDir{}
+	for _, dir := range r.Dirs {
+		if dir.Path == name {
+			matches = append(matches, dir)
+		}
+	}
+	index := r.next("dir "+name, len(matches))
+	if index < 0 || matches[index].Error == errNotExist {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := []fs.DirEntry{}
+	for _, entry := range matches[index].Entries {
+		entries = append(entries, replayedFile{name: entry.Name, mode: entry.Type})
+	}
+	if matches[index].Error != "" {
+		return entries, errors.New(matches[index].Error)
+	}
+	return entries, nil
+}
+
+// glob answers a glob from the recording.
+func (r *Recording) glob(pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	matches := []RecordedGlob{}
+	for _, glob := range r.Globs {
+		if glob.Pattern == pattern {
+			matches = append(matches, glob)
+		}
+	}
+	index := r.next("glob "+pattern, len(matches))
+	if index < 0 {
+		return nil, nil
+	}
+	return matches[index].Matches, nil
+}
+
+// lookPath answers an executable lookup from the recording.
+func (r *Recording) lookPath(name string) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	matches := []RecordedLookPath{}
+	for _, executable := range r.Executables {
+		if executable.Name == name {
+			matches = append(matches, executable)
+		}
+	}
+	index := r.next("executable "+name, len(matches))
+	if index < 0 || matches[index].Error != "" {
+		return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+	return matches[index].Path, nil
+}
@@ -0,0 +1,171 @@
+package shared
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecording_RecordAndReplay(t *testing.T) {
+	mocks, readFileMock := RunCommandMocks, ReadFileMock
+	defer func() { RunCommandMocks, ReadFileMock = mocks, readFileMock }()
+	RunCommandMocks = []RunCommandMock{
+		{Command: "ufw", Args: []string{"status"}, Out: "Status: active\n"},
+		{Command: "nft", Args: []string{"list", "ruleset"}, Out: "denied\n", Err: &ReplayedExitError{Code: 4}},
+		{Command: "missing", Err: errors.New("executable file not found")},
+	}
+	ReadFileMock = func(name string) ([]byte, error) {
+		if name == "/etc/crypttab" {
+			return []byte("root UUID=1 none luks\n"), nil
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	recording := NewRecording()
+	stop := StartRecording(recording)
+	_, _ = RunCommand("ufw", "status")
+	_, _ = RunCommand("nft", "list", "ruleset")
+	_, _ = RunCommand("missing")
+	_, _ = ReadFile("/etc/crypttab")
+	_, _ = ReadFile("/etc/missing")
+	RecordPort(22, "tcp", true)
+	stop()
+	recording.SetResults([]RecordedResult{{UUID: "uuid", State: check.CheckStatePassed}})
+
+	// Interactions after stop are not recorded
+	_, _ = RunCommand("ufw", "status")
+	assert.Len(t, recording.Commands, 3)
+
+	path := filepath.Join(t.TempDir(), "recording.json")
+	require.NoError(t, recording.Save(path))
+
+	// Replay without any mocks
+	RunCommandMocks, ReadFileMock = nil, nil
+	loaded := ReplayForTest(t, path)
+	assert.Equal(t, recording.Results, loaded.Results)
+
+	output, err := RunCommand("ufw", "status")
+	assert.NoError(t, err)
+	assert.Equal(t, "Status: active\n", output)
+
+	output, err = RunCommand("nft", "list", "ruleset")
+	assert.Equal(t, "denied\n", output)
+	code, exited := ExitCode(err)
+	assert.True(t, exited)
+	assert.Equal(t, 4, code)
+
+	_, err = RunCommand("missing")
+	assert.EqualError(t, err, "executable file not found")
+
+	_, err = RunCommand("lsblk")
+	assert.EqualError(t, err, "command was not recorded: lsblk")
+
+	content, err := ReadFile("/etc/crypttab")
+	assert.NoError(t, err)
+	assert.Equal(t, "root UUID=1 none luks\n", string(content))
+
+	_, err = ReadFile("/etc/missing")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = ReadFile("/etc/not-recorded")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	open, replayed := ReplayPort(22, "tcp")
+	assert.True(t, replayed)
+	assert.True(t, open)
+	open, _ = ReplayPort(80, "tcp")
+	assert.False(t, open)
+}
+
+func TestRecording_RepeatedCommands(t *testing.T) {
+	recording := &Recording{Commands: []RecordedCommand{
+		{Command: "systemctl", Args: []string{"is-active", "sshd"}, Output: "activating\n", ExitCode: 3},
+		{Command: "systemctl", Args: []string{"is-active", "sshd"}, Output: "active\n"},
+	}}
+	defer StartReplay(recording)()
+
+	for _, expected := range []string{"activating\n", "active\n", "active\n"} {
+		output, _ := RunCommand("systemctl", "is-active", "sshd")
+		assert.Equal(t, expected, output)
+	}
+}
+
+func TestRecording_ReplaysTOML(t *testing.T) {
+	recording := &Recording{Files: []RecordedFile{
+		{Path: "/etc/greetd/config.toml", Content: "[initial_session]\nuser = \"jane\"\n"},
+	}}
+	defer StartReplay(recording)()
+
+	value, found := GetTOMLSectionKey("/etc/greetd/config.toml", "initial_session", "user")
+	assert.True(t, found)
+	assert.Equal(t, "jane", value)
+}
+
+func TestLoadRecording_Version(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": 99}`), 0o600))
+
+	_, err := LoadRecording(path)
+	assert.ErrorContains(t, err, "has version 99")
+}
+
+func TestRecording_RecordsLookups(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "conf.d"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conf.d", "a.conf"), []byte("a"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tool"), []byte("#!/bin/sh\n"), 0o755))
+	t.Setenv("PATH", dir)
+
+	recording := NewRecording()
+	stop := StartRecording(recording)
+	_, _ = Stat(filepath.Join(dir, "conf.d"))
+	_, _ = Stat(filepath.Join(dir, "missing"))
+	_, _ = ReadDir(filepath.Join(dir, "conf.d"))
+	_, _ = Glob(filepath.Join(dir, "conf.d", "*.conf"))
+	_, _ = LookPath("tool")
+	_, _ = LookPath("missing-tool")
+	stop()
+	path := filepath.Join(t.TempDir(), "recording.json")
+	require.NoError(t, recording.Save(path))
+
+	// Replay after the files are gone
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "conf.d")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "tool")))
+	ReplayForTest(t, path)
+	assert.True(t, Replaying())
+
+	info, err := Stat(filepath.Join(dir, "conf.d"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	_, err = Stat(filepath.Join(dir, "missing"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = Stat(filepath.Join(dir, "not-recorded"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	entries, err := ReadDir(filepath.Join(dir, "conf.d"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "a.conf", entries[0].Name())
+	_, err = ReadDir(dir)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	matches, err := Glob(filepath.Join(dir, "conf.d", "*.conf"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "conf.d", "a.conf")}, matches)
+	matches, _ = Glob("/etc/*.conf")
+	assert.Empty(t, matches)
+
+	found, err := LookPath("tool")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "tool"), found)
+	_, err = LookPath("missing-tool")
+	assert.ErrorIs(t, err, exec.ErrNotFound)
+	_, err = LookPath("not-recorded")
+	assert.ErrorIs(t, err, exec.ErrNotFound)
+}
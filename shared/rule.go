@@ -0,0 +1,46 @@
+package shared
+
+// Rule defines a declarative check in the config or the policy. Type selects
+// the condition the check passes on:
+//
+//   - "file": the file at Path matches the regular expression Pattern
+//   - "toml": Key in Section of the TOML file at Path equals Value, or exists when Value is empty
+//   - "command": the output of Command matches the regular expression Pattern
+//   - "port": Port is not listening for Protocol, "tcp" by default
+//   - "sysctl": the kernel parameter Key equals Value
+//
+// Negate inverts the condition. Rules that require root are only accepted
+// from the policy, as they run through the root helper. Slug identifies the
+// check on the command line and defaults to "rules." followed by the
+// slugified Name.
+//
+// Example:
+//
+//	[[Rules]]
+//	UUID = "4c1a3a5e-1a9e-4f4e-9d55-8a0b7c6e2f10"
+//	Name = "IP forwarding is disabled"
+//	Claim = "Firewall & Sharing"
+//	Type = "sysctl"
+//	Key = "net.ipv4.ip_forward"
+//	Value = "0"
+type Rule struct {
+	UUID          string
+	Slug          string
+	Name          string
+	Claim         string
+	PassedMessage string
+	FailedMessage string
+	Severity      string
+	RequiresRoot  bool
+
+	Type     string
+	Path     string
+	Section  string
+	Key      string
+	Value    string
+	Pattern  string
+	Command  []string
+	Port     int
+	Protocol string
+	Negate   bool
+}
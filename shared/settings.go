@@ -0,0 +1,31 @@
+package shared
+
+import (
+	"github.com/ParetoSecurity/agent/check"
+	"github.com/caarlos0/log"
+)
+
+// CheckSetting returns a setting of a check. Parameters set by the policy
+// take precedence over the user config; def is returned when neither sets the
+// key or the configured value does not have the type of def. Checks the
+// policy requires ignore the user config, so a user cannot relax them.
+func CheckSetting[T any](checkUUID, key string, def T) T {
+	raw, found := Policy.Parameters[checkUUID][key]
+	if !found {
+		raw, found = Config.Checks[checkUUID][key]
+		if found && IsCheckRequired(checkUUID) {
+			log.WithField("check", checkUUID).WithField("setting", key).Warn("Ignoring user setting of a check required by the policy")
+			return def
+		}
+	}
+	if !found {
+		return def
+	}
+
+	value, err := check.ConvertSetting(raw, def)
+	if err != nil {
+		log.WithError(err).WithField("check", checkUUID).WithField("setting", key).Warn("Ignoring invalid check setting")
+		return def
+	}
+	return value.(T)
+}
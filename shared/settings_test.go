@@ -0,0 +1,70 @@
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSetting(t *testing.T) {
+	ConfigPath = filepath.Join(t.TempDir(), "pareto.toml")
+	assert.NoError(t, os.WriteFile(ConfigPath, []byte(`
+[Checks."ssh-uuid"]
+AllowedPorts = [2222]
+MinRSABits = "lots"
+
+[Checks."ssh-uuid".Ports]
+22 = "SSH"
+
+[Checks."pm-uuid"]
+MinReleaseAge = "3d"
+`), 0o600))
+	Config = ParetoConfig{}
+	Policy = ParetoPolicy{Parameters: map[string]map[string]interface{}{"pm-uuid": {"MinReleaseAge": "14d"}}}
+	defer func() {
+		Config = ParetoConfig{}
+		Policy = ParetoPolicy{}
+	}()
+	assert.NoError(t, LoadConfig())
+	Policy = ParetoPolicy{Parameters: map[string]map[string]interface{}{"pm-uuid": {"MinReleaseAge": "14d"}}}
+
+	assert.Equal(t, []int{2222}, CheckSetting("ssh-uuid", "AllowedPorts", []int{}))
+	assert.Equal(t, map[int]string{22: "SSH"}, CheckSetting("ssh-uuid", "Ports", map[int]string{}))
+	assert.Equal(t, 2048, CheckSetting("ssh-uuid", "MinRSABits", 2048), "invalid values fall back to the default")
+	assert.Equal(t, 2048, CheckSetting("other-uuid", "MinRSABits", 2048))
+	assert.Equal(t, 14*24*time.Hour, CheckSetting("pm-uuid", "MinReleaseAge", time.Duration(0)), "the policy takes precedence")
+}
+
+func TestCheckSetting_Required(t *testing.T) {
+	Config = ParetoConfig{Checks: map[string]map[string]interface{}{
+		"ports-uuid": {"AllowedPorts": []interface{}{int64(0)}, "Ports": map[string]interface{}{}},
+	}}
+	Policy = ParetoPolicy{
+		Required:   []string{"ports-uuid"},
+		Parameters: map[string]map[string]interface{}{"ports-uuid": {"AllowedPorts": []interface{}{int64(22)}}},
+	}
+	defer func() {
+		Config = ParetoConfig{}
+		Policy = ParetoPolicy{}
+	}()
+
+	assert.Equal(t, []int{22}, CheckSetting("ports-uuid", "AllowedPorts", []int{}), "the policy takes precedence")
+	assert.Equal(t, map[int]string{22: "SSH"}, CheckSetting("ports-uuid", "Ports", map[int]string{22: "SSH"}), "user settings of required checks are ignored")
+
+	Policy.Required = nil
+	assert.Equal(t, map[int]string{}, CheckSetting("ports-uuid", "Ports", map[int]string{22: "SSH"}))
+}
+
+func TestSaveConfig_CheckSettings(t *testing.T) {
+	ConfigPath = filepath.Join(t.TempDir(), "pareto.toml")
+	Config = ParetoConfig{Checks: map[string]map[string]interface{}{"uuid": {"GracePeriod": "240h"}}}
+	defer func() { Config = ParetoConfig{} }()
+	assert.NoError(t, SaveConfig())
+
+	Config = ParetoConfig{}
+	assert.NoError(t, LoadConfig())
+	assert.Equal(t, 240*time.Hour, CheckSetting("uuid", "GracePeriod", time.Duration(0)))
+}
@@ -141,3 +141,22 @@ func TestOnlyInstance(t *testing.T) {
 		}
 	})
 }
+
+func TestInstanceRunning(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "test.lock")
+	if InstanceRunning(lockPath) {
+		t.Error("expected no instance without a lock file")
+	}
+	if err := os.WriteFile(lockPath, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !InstanceRunning(lockPath) {
+		t.Error("expected the instance holding the lock to run")
+	}
+	if err := os.WriteFile(lockPath, []byte("not a pid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if InstanceRunning(lockPath) {
+		t.Error("expected no instance with an invalid lock file")
+	}
+}
@@ -6,9 +6,24 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"syscall"
 )
 
+// InstanceRunning reports whether the process holding the PID lock file at
+// lockPath is running.
+func InstanceRunning(lockPath string) bool {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return false
+	}
+	return isProcessRunning(pid)
+}
+
 // OnlyInstance ensures that only one instance of the application is running by using a PID lock file.
 func OnlyInstance(lockPath string) error {
 	currentPID := os.Getpid()
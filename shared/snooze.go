@@ -0,0 +1,96 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// MaxSnooze bounds how long a check can be snoozed, so that a snooze does
+// not become a permanent way to disable a check.
+const MaxSnooze = 30 * 24 * time.Hour
+
+// ErrInvalidSnooze is returned for a snooze that is not positive or longer than MaxSnooze
+var ErrInvalidSnooze = fmt.Errorf("snooze must be longer than zero and at most %s", MaxSnooze)
+
+// CheckSnooze silences a check until Until, for Reason. A snoozed check still
+// runs and records its real state, it is only shown as snoozed and does not
+// raise notifications.
+//
+// Example:
+//
+//	[[Snoozes]]
+//	Check = "2e46c89a-5461-4865-a92e-3b799c12034a"
+//	Until = 2025-06-04T10:00:00Z
+//	Reason = "Waiting for the vendor fix"
+type CheckSnooze struct {
+	Check  string
+	Until  time.Time
+	Reason string
+}
+
+// Active reports whether the snooze has not expired at now.
+func (s CheckSnooze) Active(now time.Time) bool {
+	return now.Before(s.Until)
+}
+
+// ActiveSnooze returns the snooze of the check that is active at now, or nil.
+func ActiveSnooze(checkUUID string, now time.Time) *CheckSnooze {
+	for _, snooze := range Config.Snoozes {
+		if snooze.Check == checkUUID && snooze.Active(now) {
+			return &snooze
+		}
+	}
+	return nil
+}
+
+// SnoozeCheck snoozes a check for the given duration, replacing an earlier
+// snooze of the check. Checks required by the policy cannot be snoozed.
+func SnoozeCheck(checkUUID string, duration time.Duration, reason string) error {
+	if IsCheckRequired(checkUUID) {
+		return ErrCheckRequired
+	}
+	if duration <= 0 || duration > MaxSnooze {
+		return ErrInvalidSnooze
+	}
+	Config.Snoozes = slices.DeleteFunc(Config.Snoozes, func(snooze CheckSnooze) bool {
+		return snooze.Check == checkUUID
+	})
+	Config.Snoozes = append(Config.Snoozes, CheckSnooze{
+		Check:  checkUUID,
+		Until:  policyNow().Add(duration).Truncate(time.Second),
+		Reason: reason,
+	})
+	return SaveConfig()
+}
+
+// UnsnoozeCheck removes the snooze of a check.
+func UnsnoozeCheck(checkUUID string) error {
+	before := len(Config.Snoozes)
+	Config.Snoozes = slices.DeleteFunc(Config.Snoozes, func(snooze CheckSnooze) bool {
+		return snooze.Check == checkUUID
+	})
+	if len(Config.Snoozes) == before {
+		return errors.New("check is not snoozed")
+	}
+	return SaveConfig()
+}
+
+// ExpireSnoozes removes the snoozes that expired at now from the config and
+// returns the checks they silenced.
+func ExpireSnoozes(now time.Time) ([]string, error) {
+	expired := []string{}
+	for _, snooze := range Config.Snoozes {
+		if !snooze.Active(now) {
+			expired = append(expired, snooze.Check)
+		}
+	}
+	if len(expired) == 0 {
+		return expired, nil
+	}
+	Config.Snoozes = slices.DeleteFunc(Config.Snoozes, func(snooze CheckSnooze) bool {
+		return !snooze.Active(now)
+	})
+	return expired, SaveConfig()
+}
@@ -0,0 +1,62 @@
+package shared
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pelletier/go-toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnoozeCheck(t *testing.T) {
+	withPolicy(t, testPolicy)
+	t.Cleanup(func() { Config.Snoozes = nil })
+	now := policyNow()
+
+	assert.ErrorIs(t, SnoozeCheck("required-uuid", time.Hour, "busy"), ErrCheckRequired)
+	assert.ErrorIs(t, SnoozeCheck("local-uuid", 0, "busy"), ErrInvalidSnooze)
+	assert.ErrorIs(t, SnoozeCheck("local-uuid", MaxSnooze+time.Hour, "busy"), ErrInvalidSnooze)
+
+	assert.NoError(t, SnoozeCheck("local-uuid", time.Hour, "busy"))
+	assert.NoError(t, SnoozeCheck("local-uuid", 72*time.Hour, "waiting for the vendor fix"))
+	assert.Len(t, Config.Snoozes, 1, "snoozing again replaces the snooze")
+
+	policy := CheckPolicyFor("local-uuid")
+	assert.False(t, policy.Disabled, "a snoozed check still runs")
+	assert.Equal(t, SourceConfig, policy.Source)
+	assert.Equal(t, "waiting for the vendor fix", policy.Snooze.Reason)
+	assert.Contains(t, policy.Describe(), "Snoozed until ")
+
+	// The snooze is saved in the config
+	data, err := os.ReadFile(ConfigPath)
+	assert.NoError(t, err)
+	var saved ParetoConfig
+	assert.NoError(t, toml.Unmarshal(data, &saved))
+	assert.Len(t, saved.Snoozes, 1)
+	assert.True(t, saved.Snoozes[0].Until.Equal(now.Add(72*time.Hour)))
+
+	assert.Nil(t, ActiveSnooze("local-uuid", now.Add(72*time.Hour)))
+	assert.NoError(t, UnsnoozeCheck("local-uuid"))
+	assert.Error(t, UnsnoozeCheck("local-uuid"))
+	assert.Equal(t, CheckPolicy{}, CheckPolicyFor("local-uuid"))
+}
+
+func TestExpireSnoozes(t *testing.T) {
+	withPolicy(t, testPolicy)
+	now := policyNow()
+	Config.Snoozes = []CheckSnooze{
+		{Check: "expired-uuid", Until: now.Add(-time.Minute)},
+		{Check: "local-uuid", Until: now.Add(time.Hour)},
+	}
+	t.Cleanup(func() { Config.Snoozes = nil })
+
+	expired, err := ExpireSnoozes(now)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"expired-uuid"}, expired)
+	assert.Len(t, Config.Snoozes, 1)
+
+	expired, err = ExpireSnoozes(now)
+	assert.NoError(t, err)
+	assert.Empty(t, expired)
+}
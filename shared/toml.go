@@ -2,7 +2,6 @@ package shared
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/pelletier/go-toml"
 )
@@ -17,11 +16,7 @@ func GetTOMLSectionKey(filepath, section, key string) (string, bool) {
 	if GetTOMLSectionKeyMock != nil {
 		return GetTOMLSectionKeyMock(filepath, section, key)
 	}
-	if _, err := os.Stat(filepath); os.IsNotExist(err) {
-		return "", false
-	}
-
-	content, err := os.ReadFile(filepath)
+	content, err := ReadSystemFile(filepath)
 	if err != nil {
 		return "", false
 	}
@@ -0,0 +1,34 @@
+package shared
+
+// Webhook formats supported by Webhook.Format.
+const (
+	WebhookFormatJSON  = "json"
+	WebhookFormatSlack = "slack"
+	WebhookFormatTeams = "teams"
+)
+
+// Webhook is a sink that is notified when the set of failing checks changes
+// between two check runs. Format selects the payload:
+//
+//   - "json": the change as a JSON document, the default
+//   - "slack": a message for a Slack incoming webhook
+//   - "teams": an Adaptive Card message for a Microsoft Teams workflow
+//
+// When Secret is set, the payload is signed with HMAC-SHA256 and the
+// signature is sent in the X-Pareto-Signature header as "sha256=<hex>".
+// Claims and Checks limit the sink to changes of the named claims or check
+// UUIDs; a sink without either receives every change.
+//
+// Example:
+//
+//	[[Webhooks]]
+//	URL = "https://hooks.slack.com/services/T000/B000/XXXX"
+//	Format = "slack"
+//	Claims = ["Firewall & Sharing", "System Integrity"]
+type Webhook struct {
+	URL    string
+	Format string
+	Secret string
+	Claims []string
+	Checks []string
+}
@@ -2,12 +2,9 @@ package team
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"runtime"
-	"strings"
 	"time"
 
 	"github.com/ParetoSecurity/agent/shared"
@@ -111,52 +108,19 @@ func EnrollDevice(inviteID string, host string) error {
 
 	log.WithField("auth", resp.Auth).Debug("Device enrolled successfully")
 
-	// Extract team ID from auth token (JWT)
-	teamID, err := extractTeamIDFromAuth(resp.Auth)
+	// Verify the auth token with the keys of the team API the device enrolled
+	// with, the keys of a previous team do not apply
+	claims, keys, err := enrollAuth(ctx, enrollURL, resp.Auth)
 	if err != nil {
-		log.WithError(err).Error("Failed to extract team ID from auth token")
-		return err
+		log.WithError(err).Error("Failed to verify the auth token")
+		return fmt.Errorf("enrollment failed: %w", err)
 	}
 
 	// Update config
-	shared.Config.TeamID = teamID
+	shared.Config.TeamID = claims.TeamID
 	shared.Config.AuthToken = resp.Auth
 	shared.Config.TeamAPI = enrollURL
+	shared.Config.TeamKeys = keys
 
 	return nil
 }
-
-// extractTeamIDFromAuth extracts the team ID from the JWT auth token
-func extractTeamIDFromAuth(auth string) (string, error) {
-	// The auth token is a JWT that contains the team ID
-	// For now, we'll parse it without verification since we just received it from the server
-	// In production, you might want to verify the signature
-
-	// Simple JWT parsing without verification
-	// JWT format: header.payload.signature
-	parts := strings.Split(auth, ".")
-	if len(parts) != 3 {
-		return "", errors.New("invalid auth token format")
-	}
-
-	// Decode the payload (base64url)
-	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
-	if err != nil {
-		return "", fmt.Errorf("failed to decode auth token: %w", err)
-	}
-
-	// Parse JSON payload to extract team ID
-	var claims struct {
-		TeamID string `json:"team_id"`
-	}
-
-	if err := json.Unmarshal(payload, &claims); err != nil {
-		return "", fmt.Errorf("failed to parse auth token claims: %w", err)
-	}
-
-	if claims.TeamID == "" {
-		return "", errors.New("team ID not found in auth token")
-	}
-
-	return claims.TeamID, nil
-}
@@ -1,39 +1,16 @@
 package team
 
 import (
+	"bytes"
 	"errors"
 	"testing"
 
 	"github.com/ParetoSecurity/agent/shared"
+	"github.com/ParetoSecurity/agent/team/teamtest"
 	"github.com/h2non/gock"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestExtractTeamIDFromAuth(t *testing.T) {
-	t.Run("valid JWT with team_id", func(t *testing.T) {
-		// JWT payload: {"team_id":"test-team-123","sub":"user@example.com"}
-		auth := "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ0ZWFtX2lkIjoidGVzdC10ZWFtLTEyMyIsInN1YiI6InVzZXJAZXhhbXBsZS5jb20ifQ.signature"
-		teamID, err := extractTeamIDFromAuth(auth)
-		assert.NoError(t, err)
-		assert.Equal(t, "test-team-123", teamID)
-	})
-
-	t.Run("invalid JWT format", func(t *testing.T) {
-		auth := "invalid.jwt"
-		_, err := extractTeamIDFromAuth(auth)
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "invalid auth token format")
-	})
-
-	t.Run("JWT without team_id", func(t *testing.T) {
-		// JWT payload: {"sub":"user@example.com"}
-		auth := "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJzdWIiOiJ1c2VyQGV4YW1wbGUuY29tIn0.signature"
-		_, err := extractTeamIDFromAuth(auth)
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "team ID not found")
-	})
-}
-
 func TestEnrollDevice(t *testing.T) {
 	defer gock.Off()
 
@@ -41,22 +18,31 @@ func TestEnrollDevice(t *testing.T) {
 	originalTeamAPI := shared.Config.TeamAPI
 	originalTeamID := shared.Config.TeamID
 	originalAuthToken := shared.Config.AuthToken
+	originalTeamKeys := shared.Config.TeamKeys
 	defer func() {
 		shared.Config.TeamAPI = originalTeamAPI
 		shared.Config.TeamID = originalTeamID
 		shared.Config.AuthToken = originalAuthToken
+		shared.Config.TeamKeys = originalTeamKeys
 	}()
+	issuer := teamtest.NewIssuer("enroll-key")
 
 	t.Run("successful enrollment", func(t *testing.T) {
 		gock.New("https://cloud.paretosecurity.com").
 			Post("/api/v1/team/enroll").
 			Reply(200).
 			JSON(map[string]string{
-				"auth": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ0ZWFtX2lkIjoidGVzdC10ZWFtLTEyMyIsInN1YiI6InVzZXJAZXhhbXBsZS5jb20ifQ.signature",
+				"auth": issuer.Token("test-team-123"),
 			})
+		gock.New("https://cloud.paretosecurity.com").
+			Get(KeySetPath).
+			Reply(200).
+			Body(bytes.NewReader(issuer.KeySet()))
 
 		err := EnrollDevice("test-invite-123", "")
 		assert.NoError(t, err)
+		assert.Equal(t, "test-team-123", shared.Config.TeamID)
+		assert.JSONEq(t, string(issuer.KeySet()), shared.Config.TeamKeys)
 	})
 
 	t.Run("custom host", func(t *testing.T) {
@@ -64,13 +50,54 @@ func TestEnrollDevice(t *testing.T) {
 			Post("/api/v1/team/enroll").
 			Reply(200).
 			JSON(map[string]string{
-				"auth": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ0ZWFtX2lkIjoidGVzdC10ZWFtLTEyMyIsInN1YiI6InVzZXJAZXhhbXBsZS5jb20ifQ.signature",
+				"auth": issuer.Token("test-team-123"),
 			})
+		gock.New("https://custom.api.com").
+			Get(KeySetPath).
+			Reply(200).
+			Body(bytes.NewReader(issuer.KeySet()))
 
 		err := EnrollDevice("test-invite-123", "https://custom.api.com")
 		assert.NoError(t, err)
 	})
 
+	t.Run("team API without a key set", func(t *testing.T) {
+		shared.Config.TeamID = "previous-team"
+		shared.Config.TeamKeys = string(issuer.KeySet())
+		gock.New("https://cloud.paretosecurity.com").
+			Post("/api/v1/team/enroll").
+			Reply(200).
+			JSON(map[string]string{
+				"auth": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ0ZWFtX2lkIjoidGVzdC10ZWFtLTEyMyIsInN1YiI6InVzZXJAZXhhbXBsZS5jb20ifQ.signature",
+			})
+		gock.New("https://cloud.paretosecurity.com").
+			Get(KeySetPath).
+			Reply(404)
+
+		err := EnrollDevice("test-invite-123", "")
+		assert.ErrorIs(t, err, errNoKeySet)
+		assert.Equal(t, "previous-team", shared.Config.TeamID, "the device stays linked to its team")
+		assert.JSONEq(t, string(issuer.KeySet()), shared.Config.TeamKeys)
+	})
+
+	t.Run("unsigned token", func(t *testing.T) {
+		shared.Config.TeamID = "previous-team"
+		gock.New("https://cloud.paretosecurity.com").
+			Post("/api/v1/team/enroll").
+			Reply(200).
+			JSON(map[string]string{
+				"auth": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ0ZWFtX2lkIjoidGVzdC10ZWFtLTEyMyIsInN1YiI6InVzZXJAZXhhbXBsZS5jb20ifQ.signature",
+			})
+		gock.New("https://cloud.paretosecurity.com").
+			Get(KeySetPath).
+			Reply(200).
+			Body(bytes.NewReader(issuer.KeySet()))
+
+		err := EnrollDevice("test-invite-123", "")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+		assert.Equal(t, "previous-team", shared.Config.TeamID)
+	})
+
 	t.Run("empty invite ID", func(t *testing.T) {
 		err := EnrollDevice("", "")
 		assert.Error(t, err)
@@ -0,0 +1,273 @@
+package team
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caarlos0/log"
+
+	shared "github.com/ParetoSecurity/agent/shared"
+)
+
+// QueuedReport is a report waiting in the outbox for delivery.
+type QueuedReport struct {
+	Method            string          `json:"method"`
+	SignificantChange string          `json:"significantChange,omitempty"`
+	Payload           json.RawMessage `json:"payload"`
+	Queued            time.Time       `json:"queued"`
+	Attempts          int             `json:"attempts"`
+	NextAttempt       time.Time       `json:"nextAttempt"`
+	LastError         string          `json:"lastError,omitempty"`
+}
+
+// Outbox holds the reports that have not been delivered yet, oldest first.
+// Every report describes the whole device, so a newer report replaces a
+// queued one sent with the same method and at most one report per method is
+// kept.
+type Outbox struct {
+	Reports []QueuedReport `json:"reports"`
+}
+
+var (
+	outboxMutex sync.Mutex
+	OutboxPath  string
+	// RetryBackoff is the delay before retrying a failed report; it doubles
+	// with every failed attempt
+	RetryBackoff = time.Minute
+	// MaxRetryBackoff caps the delay between retries of a failed report
+	MaxRetryBackoff = time.Hour
+	// ReconnectInterval is how often ReplayOnReconnect looks for network changes
+	ReconnectInterval = 30 * time.Second
+)
+
+var errInvalidOutbox = errors.New("invalid outbox")
+
+func init() {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "."
+	}
+	OutboxPath = filepath.Join(homeDir, ".paretosecurity.outbox")
+}
+
+// LoadOutbox returns the queued reports. A missing outbox file is an empty outbox.
+func LoadOutbox() (Outbox, error) {
+	outboxMutex.Lock()
+	defer outboxMutex.Unlock()
+	return loadOutbox()
+}
+
+// ClearOutbox drops all queued reports, for example when the device is unlinked.
+func ClearOutbox() error {
+	outboxMutex.Lock()
+	defer outboxMutex.Unlock()
+	unlock, err := shared.LockFile(OutboxPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := os.Remove(OutboxPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// FlushOutbox delivers the queued reports in order and returns what is left
+// in the outbox. Reports still backing off after a failed attempt are skipped
+// unless force is set. Delivery stops at the first failure, whose error is
+// returned, and the failed report is retried after an exponential backoff.
+func FlushOutbox(force bool) (Outbox, error) {
+	outbox, err := LoadOutbox()
+	if err != nil {
+		return outbox, err
+	}
+
+	var flushErr error
+	for _, report := range outbox.Reports {
+		if !force && time.Now().Before(report.NextAttempt) {
+			continue
+		}
+		// The outbox is not locked while sending, so other processes may
+		// queue newer reports in the meantime
+		sendErr := sendReport(report)
+		err := updateOutbox(func(outbox *Outbox) {
+			index := slices.IndexFunc(outbox.Reports, func(queued QueuedReport) bool {
+				return queued.Method == report.Method && queued.Queued.Equal(report.Queued)
+			})
+			if index < 0 {
+				return
+			}
+			if sendErr == nil {
+				outbox.Reports = slices.Delete(outbox.Reports, index, index+1)
+				return
+			}
+			queued := &outbox.Reports[index]
+			queued.Attempts++
+			queued.LastError = sendErr.Error()
+			queued.NextAttempt = time.Now().Add(retryBackoff(queued.Attempts))
+		})
+		if err != nil {
+			log.WithError(err).Warn("failed to update the report outbox")
+		}
+		if sendErr != nil {
+			flushErr = sendErr
+			break
+		}
+	}
+
+	remaining, err := LoadOutbox()
+	if err != nil {
+		return remaining, err
+	}
+	return remaining, flushErr
+}
+
+// ReplayOnReconnect retries the queued reports until ctx is done: as soon as
+// the network addresses of the device change, for example after joining a
+// network, and otherwise whenever their backoff has passed.
+func ReplayOnReconnect(ctx context.Context) {
+	ticker := time.NewTicker(ReconnectInterval)
+	defer ticker.Stop()
+
+	network := networkFingerprint()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := networkFingerprint()
+		reconnected := current != network && current != ""
+		network = current
+
+		outbox, err := LoadOutbox()
+		if err != nil || len(outbox.Reports) == 0 {
+			continue
+		}
+		// The device may have been linked or unlinked since the process started
+		if err := shared.LoadConfig(); err != nil {
+			log.WithError(err).Warn("failed to reload config")
+		}
+		if !shared.IsLinked() {
+			continue
+		}
+		if reconnected {
+			log.Info("Network changed, delivering queued reports")
+		}
+		if _, err := FlushOutbox(reconnected); err != nil {
+			log.WithError(err).Debug("Queued reports are still undelivered")
+		}
+	}
+}
+
+// queueReport adds report to the outbox, replacing a queued report sent with
+// the same method. The failed attempts of the replaced report are kept, but
+// the new report is due right away.
+func queueReport(report QueuedReport) error {
+	return updateOutbox(func(outbox *Outbox) {
+		index := slices.IndexFunc(outbox.Reports, func(queued QueuedReport) bool {
+			return queued.Method == report.Method
+		})
+		if index >= 0 {
+			report.Attempts = outbox.Reports[index].Attempts
+			report.LastError = outbox.Reports[index].LastError
+			outbox.Reports = slices.Delete(outbox.Reports, index, index+1)
+		}
+		outbox.Reports = append(outbox.Reports, report)
+	})
+}
+
+// retryBackoff returns the delay before the next attempt after attempts failed ones.
+func retryBackoff(attempts int) time.Duration {
+	backoff := RetryBackoff
+	for i := 1; i < attempts && backoff < MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, MaxRetryBackoff)
+}
+
+// updateOutbox applies update to the outbox file while holding the lock of
+// this process and the lock file shared with other processes, so the tray
+// application and a scheduled run do not lose each other's reports. An
+// outbox that cannot be decoded is replaced.
+func updateOutbox(update func(*Outbox)) error {
+	outboxMutex.Lock()
+	defer outboxMutex.Unlock()
+	unlock, err := shared.LockFile(OutboxPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	outbox, err := loadOutbox()
+	if errors.Is(err, errInvalidOutbox) {
+		log.WithError(err).Warn("Replacing the report outbox")
+	} else if err != nil {
+		return err
+	}
+	update(&outbox)
+	return saveOutbox(outbox)
+}
+
+func loadOutbox() (Outbox, error) {
+	outbox := Outbox{Reports: []QueuedReport{}}
+	data, err := os.ReadFile(OutboxPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return outbox, nil
+	}
+	if err != nil {
+		return outbox, err
+	}
+	if err := json.Unmarshal(data, &outbox); err != nil {
+		return Outbox{Reports: []QueuedReport{}}, fmt.Errorf("%w %s: %w", errInvalidOutbox, OutboxPath, err)
+	}
+	return outbox, nil
+}
+
+// saveOutbox writes the outbox atomically, removing the file once it is empty.
+func saveOutbox(outbox Outbox) error {
+	if len(outbox.Reports) == 0 {
+		if err := os.Remove(OutboxPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(outbox)
+	if err != nil {
+		return err
+	}
+	return shared.WriteFileAtomic(OutboxPath, data, 0o600)
+}
+
+// networkFingerprint identifies the addresses of the network interfaces that
+// are up, so that joining or leaving a network changes it.
+func networkFingerprint() string {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	addresses := []string{}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			addresses = append(addresses, iface.Name+"="+addr.String())
+		}
+	}
+	slices.Sort(addresses)
+	return strings.Join(addresses, ",")
+}
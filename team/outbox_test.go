@@ -0,0 +1,141 @@
+package team
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withOutbox links the device to a test team and points the config and the
+// outbox at temporary files.
+func withOutbox(t *testing.T) {
+	t.Helper()
+	originalConfig := shared.Config
+	originalConfigPath := shared.ConfigPath
+	originalOutboxPath := OutboxPath
+	t.Cleanup(func() {
+		shared.Config = originalConfig
+		shared.ConfigPath = originalConfigPath
+		OutboxPath = originalOutboxPath
+		gock.Off()
+	})
+	dir := t.TempDir()
+	shared.ConfigPath = dir + "/pareto.toml"
+	OutboxPath = dir + "/outbox"
+	shared.Config.TeamID = "testTeam"
+	shared.Config.AuthToken = "testToken"
+	shared.Config.TeamAPI = ""
+	shared.Config.LastTeamReportSuccess = 0
+}
+
+func TestReportToTeam_Offline(t *testing.T) {
+	withOutbox(t)
+
+	gock.New(defaultReportURL).
+		Patch("/api/v1/team/testTeam/device").
+		ReplyError(errors.New("network is unreachable"))
+	assert.Error(t, ReportToTeam(false))
+
+	outbox, err := LoadOutbox()
+	require.NoError(t, err)
+	require.Len(t, outbox.Reports, 1)
+	queued := outbox.Reports[0]
+	assert.Equal(t, http.MethodPatch, queued.Method)
+	assert.Len(t, queued.SignificantChange, 64)
+	assert.Equal(t, 1, queued.Attempts)
+	assert.Contains(t, queued.LastError, "network is unreachable")
+	assert.True(t, queued.NextAttempt.After(time.Now()))
+	assert.Zero(t, shared.Config.LastTeamReportSuccess)
+
+	// Backing off, so nothing is sent unless forced
+	outbox, err = FlushOutbox(false)
+	assert.NoError(t, err)
+	assert.Len(t, outbox.Reports, 1)
+
+	gock.New(defaultReportURL).
+		Patch("/api/v1/team/testTeam/device").
+		Reply(200).
+		BodyString(`{"status": "ok"}`)
+	outbox, err = FlushOutbox(true)
+	assert.NoError(t, err)
+	assert.Empty(t, outbox.Reports)
+	assert.NotZero(t, shared.Config.LastTeamReportSuccess)
+	assert.True(t, gock.IsDone())
+
+	_, err = os.Stat(OutboxPath)
+	assert.True(t, os.IsNotExist(err), "the empty outbox is removed")
+}
+
+func TestReportToTeam_Coalesces(t *testing.T) {
+	withOutbox(t)
+
+	gock.New(defaultReportURL).
+		Patch("/api/v1/team/testTeam/device").
+		Times(2).
+		ReplyError(errors.New("captive portal"))
+	assert.Error(t, ReportToTeam(false))
+	assert.Error(t, ReportToTeam(false))
+
+	outbox, err := LoadOutbox()
+	require.NoError(t, err)
+	require.Len(t, outbox.Reports, 1, "only the newest report is kept")
+	assert.Equal(t, 2, outbox.Reports[0].Attempts, "the attempts of the replaced report are kept")
+	assert.True(t, gock.IsDone())
+}
+
+func TestFlushOutbox_StopsAtFirstFailure(t *testing.T) {
+	withOutbox(t)
+	now := time.Now()
+	require.NoError(t, saveOutbox(Outbox{Reports: []QueuedReport{
+		{Method: http.MethodPut, Payload: []byte(`{}`), Queued: now.Add(-time.Hour)},
+		{Method: http.MethodPatch, Payload: []byte(`{}`), Queued: now},
+	}}))
+
+	gock.New(defaultReportURL).
+		Put("/api/v1/team/testTeam/device").
+		Reply(500)
+	outbox, err := FlushOutbox(false)
+	assert.Error(t, err)
+	require.Len(t, outbox.Reports, 2)
+	assert.Equal(t, 1, outbox.Reports[0].Attempts)
+	assert.Equal(t, 0, outbox.Reports[1].Attempts, "reports after the failed one are not attempted")
+	assert.True(t, gock.IsDone())
+}
+
+func TestRetryBackoff(t *testing.T) {
+	assert.Equal(t, RetryBackoff, retryBackoff(1))
+	assert.Equal(t, 2*RetryBackoff, retryBackoff(2))
+	assert.Equal(t, 8*RetryBackoff, retryBackoff(4))
+	assert.Equal(t, MaxRetryBackoff, retryBackoff(100))
+}
+
+func TestClearOutbox(t *testing.T) {
+	withOutbox(t)
+	require.NoError(t, queueReport(QueuedReport{Method: http.MethodPatch, Payload: []byte(`{}`), Queued: time.Now()}))
+
+	assert.NoError(t, ClearOutbox())
+	assert.NoError(t, ClearOutbox(), "clearing an empty outbox is not an error")
+	outbox, err := LoadOutbox()
+	assert.NoError(t, err)
+	assert.Empty(t, outbox.Reports)
+}
+
+func TestLoadOutbox_Invalid(t *testing.T) {
+	withOutbox(t)
+	require.NoError(t, os.WriteFile(OutboxPath, []byte("not json"), 0o600))
+
+	_, err := LoadOutbox()
+	assert.ErrorContains(t, err, "invalid outbox")
+
+	require.NoError(t, queueReport(QueuedReport{Method: http.MethodPatch, Payload: []byte(`{}`), Queued: time.Now()}))
+	outbox, err := LoadOutbox()
+	assert.NoError(t, err)
+	assert.Len(t, outbox.Reports, 1, "the invalid outbox is replaced")
+}
@@ -0,0 +1,121 @@
+package team
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	shared "github.com/ParetoSecurity/agent/shared"
+)
+
+// redactedHeader replaces secret header values in a preview.
+const redactedHeader = "<redacted>"
+
+// ReportPreview is the request ReportToTeam would send, with the auth token
+// redacted, and a description of every field of the payload.
+type ReportPreview struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Payload json.RawMessage   `json:"payload"`
+	Fields  []ReportField     `json:"fields"`
+}
+
+// ReportField describes a field of the payload, named by its JSON path.
+type ReportField struct {
+	Path        string          `json:"path"`
+	Value       json.RawMessage `json:"value,omitempty"`
+	Description string          `json:"description"`
+}
+
+// reportFields describes the fields of Report and ReportingDevice by name.
+// The per-check maps are described as a whole.
+var reportFields = map[string]string{
+	"passedCount":       "Number of checks that passed in the last run",
+	"failedCount":       "Number of checks that failed or errored in the last run",
+	"disabledCount":     "Number of checks that are disabled or cannot run on this device",
+	"version":           "Version of the agent",
+	"significantChange": "Hash of the failed and disabled check UUIDs, so the dashboard can tell when they change",
+	"state":             "State of every check by check UUID: pass, fail, error or off; no check details are sent",
+	"severity":          "Severity of every check by check UUID",
+	"score":             "Device score from 0 to 100, weighted by check",
+	"snoozed":           "Checks snoozed with `config snooze` by check UUID, with the end of the snooze and the reason given",
+	"device":            "The device the report is about",
+	"machineUUID":       "Random identifier of the device, generated by the agent",
+	"machineName":       "Hostname of the device, a pseudonym when RedactHostname is set",
+	"auth":              "Unused, always empty",
+	"macOSVersion":      "Version of macOS",
+	"linuxOSVersion":    "Name and version of the Linux distribution",
+	"windowsOSVersion":  "Edition and version of Windows",
+	"modelName":         "Hardware model of the device",
+	"modelSerial":       "Hardware serial number, " + shared.RedactedSerial + " when RedactSerial is set",
+}
+
+// PreviewReport builds the report ReportToTeam would send right now, from
+// the state of the last check run, without sending it.
+func PreviewReport(initial bool) (ReportPreview, error) {
+	method, report := buildReport(initial)
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return ReportPreview{}, err
+	}
+	teamID := shared.Config.TeamID
+	if teamID == "" {
+		teamID = "<team>"
+	}
+	fields, err := describeFields(payload)
+	if err != nil {
+		return ReportPreview{}, err
+	}
+	return ReportPreview{
+		Method: method,
+		URL:    fmt.Sprintf("%s/api/v1/team/%s/device", teamAPIURL(), teamID),
+		Headers: map[string]string{
+			"X-Device-Auth": redactedHeader,
+			"User-Agent":    shared.UserAgent(),
+			"Content-Type":  "application/json",
+		},
+		Payload: payload,
+		Fields:  fields,
+	}, nil
+}
+
+// describeFields lists the fields of payload in JSON path order, descending
+// into nested objects that are not described as a whole.
+func describeFields(payload []byte) ([]ReportField, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &object); err != nil {
+		return nil, err
+	}
+	fields := []ReportField{}
+	var walk func(prefix string, object map[string]json.RawMessage)
+	walk = func(prefix string, object map[string]json.RawMessage) {
+		for _, name := range slices.Sorted(maps.Keys(object)) {
+			path := prefix + name
+			value := object[name]
+			var nested map[string]json.RawMessage
+			if strings.HasPrefix(strings.TrimSpace(string(value)), "{") && json.Unmarshal(value, &nested) == nil && descendInto(path) {
+				fields = append(fields, ReportField{Path: path, Description: fieldDescription(path)})
+				walk(path+".", nested)
+				continue
+			}
+			fields = append(fields, ReportField{Path: path, Value: value, Description: fieldDescription(path)})
+		}
+	}
+	walk("", object)
+	return fields, nil
+}
+
+// descendInto reports whether the fields of the object at path are listed one by one.
+func descendInto(path string) bool {
+	return path != "state" && path != "severity" && path != "snoozed"
+}
+
+// fieldDescription returns the description of the field at path. Fields are
+// described by name, as the device is the payload of an initial report and
+// nested in later ones.
+func fieldDescription(path string) string {
+	return reportFields[path[strings.LastIndex(path, ".")+1:]]
+}
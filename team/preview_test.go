@@ -0,0 +1,66 @@
+package team
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewReport(t *testing.T) {
+	config := shared.Config
+	defer func() { shared.Config = config }()
+	shared.Config.TeamID = "team-1"
+	shared.Config.AuthToken = "secret-token"
+	shared.Config.TeamAPI = ""
+	snoozed := claims.All[0].Checks[0].UUID()
+	shared.Config.Snoozes = []shared.CheckSnooze{{Check: snoozed, Until: time.Now().Add(time.Hour), Reason: "busy"}}
+
+	preview, err := PreviewReport(false)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPatch, preview.Method)
+	assert.Equal(t, defaultReportURL+"/api/v1/team/team-1/device", preview.URL)
+	assert.Equal(t, redactedHeader, preview.Headers["X-Device-Auth"])
+	assert.NotContains(t, string(preview.Payload), "secret-token")
+
+	var report Report
+	require.NoError(t, json.Unmarshal(preview.Payload, &report))
+	assert.Equal(t, "test-hostname", report.Device.MachineName)
+	assert.Equal(t, "busy", report.Snoozed[snoozed].Reason)
+
+	paths := []string{}
+	for _, field := range preview.Fields {
+		paths = append(paths, field.Path)
+		assert.NotEmpty(t, field.Description, "field %s is not described", field.Path)
+	}
+	assert.Contains(t, paths, "device.machineName")
+	assert.Contains(t, paths, "state")
+	assert.Contains(t, paths, "snoozed")
+	assert.NotContains(t, paths, "state.check1", "per-check maps are described as a whole")
+}
+
+func TestPreviewReport_InitialRedacted(t *testing.T) {
+	config := shared.Config
+	defer func() { shared.Config = config }()
+	shared.Config.TeamID = ""
+	shared.Config.RedactHostname = true
+	shared.Config.RedactSerial = true
+
+	preview, err := PreviewReport(true)
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPut, preview.Method)
+	assert.Contains(t, preview.URL, "/api/v1/team/<team>/device")
+
+	var device shared.ReportingDevice
+	require.NoError(t, json.Unmarshal(preview.Payload, &device))
+	assert.Equal(t, "device-12345678", device.MachineName)
+	assert.Equal(t, shared.RedactedSerial, device.ModelSerial)
+	for _, field := range preview.Fields {
+		assert.NotEmpty(t, field.Description, "field %s is not described", field.Path)
+	}
+}
@@ -28,6 +28,15 @@ type Report struct {
 	Version           string                      `json:"version"`
 	SignificantChange string                      `json:"significantChange"`
 	State             map[string]check.CheckState `json:"state"`
+	Severity          map[string]check.Severity   `json:"severity"`
+	Score             int                         `json:"score"`
+	Snoozed           map[string]SnoozedCheck     `json:"snoozed,omitempty"`
+}
+
+// SnoozedCheck is a check the user snoozed. Its state is reported as usual.
+type SnoozedCheck struct {
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason"`
 }
 
 // NowReport compiles and returns a Report that summarizes the results of all runnable checks.
@@ -40,6 +49,9 @@ func NowReport(all []claims.Claim) Report {
 	disabledSeed := device.MachineUUID
 	failedSeed := device.MachineUUID
 	checkStates := make(map[string]check.CheckState)
+	checkSeverities := make(map[string]check.Severity)
+	snoozed := make(map[string]SnoozedCheck)
+	scored := []check.Scored{}
 	lastCheckStates := shared.GetLastStates()
 
 	for _, claim := range all {
@@ -65,6 +77,11 @@ func NowReport(all []claims.Claim) Report {
 				disabledSeed += checkS.UUID()
 				checkStates[checkS.UUID()] = check.CheckStateDisabled
 			}
+			checkSeverities[checkS.UUID()] = check.SeverityOf(checkS)
+			if snooze := shared.ActiveSnooze(checkS.UUID(), time.Now()); snooze != nil {
+				snoozed[checkS.UUID()] = SnoozedCheck{Until: snooze.Until, Reason: snooze.Reason}
+			}
+			scored = append(scored, check.Scored{State: checkStates[checkS.UUID()], Weight: check.WeightOf(checkS)})
 		}
 	}
 
@@ -77,49 +94,89 @@ func NowReport(all []claims.Claim) Report {
 		Version:           shared.Version,
 		SignificantChange: hex.EncodeToString(significantChange[:]),
 		State:             checkStates,
+		Severity:          checkSeverities,
+		Score:             check.Score(scored),
+		Snoozed:           snoozed,
 	}
 }
 
-// ReportAndSave generates a report and saves it to the configuration file.
+// ReportToTeam queues a report for the team and delivers the queued reports.
+// An initial report registers the device, later ones report the check states.
+// Reports that cannot be delivered stay in the outbox and are retried on the
+// next run, see FlushOutbox.
 func ReportToTeam(initial bool) error {
-	var report interface{}
+	method, report := buildReport(initial)
+	significantChange := ""
+	if nowReport, ok := report.(Report); ok {
+		significantChange = nowReport.SignificantChange
+	}
+	log.WithField("report", spew.Sdump(report)).
+		WithField("method", method).
+		Debug("Queueing report")
 
-	res := ""
-	errRes := ""
-	method := http.MethodPatch
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	queued := QueuedReport{
+		Method:            method,
+		SignificantChange: significantChange,
+		Payload:           payload,
+		Queued:            time.Now(),
+	}
+	if err := queueReport(queued); err != nil {
+		log.WithError(err).Warn("failed to queue report, sending it directly")
+		return sendReport(queued)
+	}
+	_, err = FlushOutbox(false)
+	return err
+}
+
+// buildReport returns the method and payload of a report: the device for an
+// initial report, the check states otherwise.
+func buildReport(initial bool) (string, interface{}) {
 	if initial {
-		method = http.MethodPut
-		report = shared.CurrentReportingDevice()
-	} else {
-		report = NowReport(claims.All)
+		return http.MethodPut, shared.CurrentReportingDevice()
 	}
+	return http.MethodPatch, NowReport(claims.All)
+}
+
+// teamAPIURL returns the TeamAPI from the config if set, otherwise the default.
+func teamAPIURL() string {
+	if shared.Config.TeamAPI != "" {
+		return shared.Config.TeamAPI
+	}
+	return defaultReportURL
+}
+
+// sendReport delivers a queued report to the team.
+func sendReport(report QueuedReport) error {
+	res := ""
+	errRes := ""
 
 	// Create a context with a timeout for the request
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Use TeamAPI from config if available, otherwise use default
-	reportURL := defaultReportURL
-	if shared.Config.TeamAPI != "" {
-		reportURL = shared.Config.TeamAPI
-	}
+	reportURL := teamAPIURL()
 
-	log.WithField("report", spew.Sdump(report)).
-		WithField("method", method).
+	log.WithField("method", report.Method).
+		WithField("queued", report.Queued).
 		WithField("teamID", shared.Config.TeamID).
 		WithField("reportURL", reportURL).
 		Debug("Reporting to team")
 	requestURL := fmt.Sprintf("%s/api/v1/team/%s/device", reportURL, shared.Config.TeamID)
 	log.WithField("url", requestURL).
-		WithField("method", method).
+		WithField("method", report.Method).
 		Debug("Making API request")
 
 	err := requests.URL(reportURL).
 		Pathf("/api/v1/team/%s/device", shared.Config.TeamID).
-		Method(method).
+		Method(report.Method).
 		Header("X-Device-Auth", shared.Config.AuthToken).
 		Header("User-Agent", shared.UserAgent()).
-		BodyJSON(&report).
+		BodyBytes(report.Payload).
+		ContentType("application/json").
 		ToString(&res).
 		AddValidator(
 			requests.ValidatorHandler(
@@ -136,7 +193,8 @@ func ReportToTeam(initial bool) error {
 	log.WithField("response", res).Debug("API Response")
 
 	if err := updateConfigFromDeviceAuthResponse(res); err != nil {
-		return err
+		// The report was delivered, only the new auth token is refused
+		log.WithError(err).Error("Failed to update the device auth")
 	}
 
 	shared.Config.LastTeamReportSuccess = time.Now().UnixMilli()
@@ -146,6 +204,9 @@ func ReportToTeam(initial bool) error {
 	return nil
 }
 
+// updateConfigFromDeviceAuthResponse adopts the auth token returned by the
+// team API, for example when the device was moved to another team. The token
+// must verify, see renewAuth, so that a forged response cannot move the device.
 func updateConfigFromDeviceAuthResponse(res string) error {
 	var response DeviceEnrollmentResponse
 	if err := json.Unmarshal([]byte(res), &response); err != nil {
@@ -155,12 +216,17 @@ func updateConfigFromDeviceAuthResponse(res string) error {
 		return nil
 	}
 
-	teamID, err := extractTeamIDFromAuth(response.Auth)
+	claims, err := renewAuth(response.Auth)
 	if err != nil {
-		return fmt.Errorf("failed to parse moved device auth: %w", err)
+		return fmt.Errorf("refusing the new auth token, the device stays in team %s: %w", shared.Config.TeamID, err)
+	}
+	if claims.TeamID != shared.Config.TeamID {
+		log.WithField("from", shared.Config.TeamID).
+			WithField("to", claims.TeamID).
+			Info("Device moved to another team")
 	}
 
 	shared.Config.AuthToken = response.Auth
-	shared.Config.TeamID = teamID
+	shared.Config.TeamID = claims.TeamID
 	return nil
 }
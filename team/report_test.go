@@ -5,10 +5,12 @@ import (
 	"os"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/claims"
 	shared "github.com/ParetoSecurity/agent/shared"
+	"github.com/ParetoSecurity/agent/team/teamtest"
 	"github.com/h2non/gock"
 )
 
@@ -156,6 +158,14 @@ func TestNowReportCounts(t *testing.T) {
 		t.Errorf("Expected check4 state = error, got %s", state)
 	}
 
+	// One of three evaluated checks passed, all with the default weight
+	if report.Score != 33 {
+		t.Errorf("Expected Score = 33, got %d", report.Score)
+	}
+	if severity := report.Severity["check1"]; severity != check.DefaultSeverity {
+		t.Errorf("Expected check1 severity = %s, got %s", check.DefaultSeverity, severity)
+	}
+
 	// The SignificantChange should be a valid hex string of length 64.
 	if len(report.SignificantChange) != 64 {
 		t.Errorf("Expected SignificantChange to have length 64, got %d", len(report.SignificantChange))
@@ -169,13 +179,16 @@ func TestReportToTeam(t *testing.T) {
 	// Save original config
 	originalTeamAPI := shared.Config.TeamAPI
 	originalConfigPath := shared.ConfigPath
+	originalOutboxPath := OutboxPath
 	defer func() {
 		shared.Config.TeamAPI = originalTeamAPI
 		shared.ConfigPath = originalConfigPath
+		OutboxPath = originalOutboxPath
 	}()
 
 	tempDir := t.TempDir()
 	shared.ConfigPath = tempDir + "/pareto.toml"
+	OutboxPath = tempDir + "/outbox"
 
 	shared.Config.TeamID = "testTeam"
 	shared.Config.AuthToken = "testToken"
@@ -222,8 +235,11 @@ func TestReportToTeam(t *testing.T) {
 
 	gock.Clean()
 
-	// Test moved device auth handling.
-	movedAuth := "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJ0ZWFtX2lkIjoibmV3VGVhbSIsInN1YiI6InVzZXJAZXhhbXBsZS5jb20ifQ.signature"
+	// Test moved device auth handling, the token verifies with the saved keys.
+	issuer := teamtest.NewIssuer("report-key")
+	shared.Config.TeamKeys = string(issuer.KeySet())
+	defer func() { shared.Config.TeamKeys = "" }()
+	movedAuth := issuer.Token("newTeam")
 	shared.Config.TeamID = "oldTeam"
 	shared.Config.AuthToken = "oldToken"
 	shared.Config.LastTeamReportSuccess = 0
@@ -254,6 +270,30 @@ func TestReportToTeam(t *testing.T) {
 
 	gock.Clean()
 
+	// Test a forged moved device auth, the device stays in its team.
+	gock.New(defaultReportURL).
+		Patch("/api/v1/team/" + shared.Config.TeamID + "/device").
+		Reply(200).
+		JSON(map[string]string{
+			"auth": teamtest.NewIssuer("report-key").Token("attackerTeam"),
+		})
+
+	err = ReportToTeam(false)
+	if err != nil {
+		t.Fatalf("ReportToTeam (forged moved device) failed: %v", err)
+	}
+	if shared.Config.TeamID != "newTeam" {
+		t.Fatalf("expected TeamID to stay newTeam, got %s", shared.Config.TeamID)
+	}
+	if shared.Config.AuthToken != movedAuth {
+		t.Fatalf("expected AuthToken to stay unchanged")
+	}
+
+	if !gock.IsDone() {
+		t.Errorf("pending mocks: %v", gock.Pending())
+	}
+	gock.Clean()
+
 	// Test API error handling.
 	gock.New(defaultReportURL).
 		Patch("/api/v1/team/" + shared.Config.TeamID + "/device").
@@ -285,3 +325,26 @@ func TestReportToTeam(t *testing.T) {
 	}
 	gock.Clean()
 }
+
+func TestNowReportSnoozed(t *testing.T) {
+	config := shared.Config
+	defer func() { shared.Config = config }()
+	until := time.Now().Add(time.Hour).Truncate(time.Second)
+	shared.Config.Snoozes = []shared.CheckSnooze{
+		{Check: "snoozed", Until: until, Reason: "waiting for the vendor fix"},
+		{Check: "expired", Until: time.Now().Add(-time.Hour), Reason: "old"},
+	}
+
+	report := NowReport([]claims.Claim{{Title: "Test", Checks: []check.Check{
+		&dummyCheck{uuid: "snoozed", runnable: true},
+		&dummyCheck{uuid: "expired", runnable: true},
+	}}})
+
+	if len(report.Snoozed) != 1 {
+		t.Fatalf("Expected one snoozed check, got %v", report.Snoozed)
+	}
+	snoozed := report.Snoozed["snoozed"]
+	if !snoozed.Until.Equal(until) || snoozed.Reason != "waiting for the vendor fix" {
+		t.Errorf("Unexpected snooze in the report: %+v", snoozed)
+	}
+}
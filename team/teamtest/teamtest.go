@@ -0,0 +1,180 @@
+// Package teamtest provides a stand-in for the team API in tests: an issuer
+// of signed device auth tokens and an HTTP server that enrolls devices,
+// accepts reports and publishes the issuer's key set.
+package teamtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Audience is the audience of the tokens issued by default, matching
+// team.DeviceTokenAudience.
+const Audience = "paretosecurity-agent"
+
+// Issuer signs device auth tokens with an ES256 key.
+type Issuer struct {
+	KeyID string
+	key   *ecdsa.PrivateKey
+}
+
+// NewIssuer returns an issuer with a fresh key.
+func NewIssuer(keyID string) *Issuer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return &Issuer{KeyID: keyID, key: key}
+}
+
+// KeySet returns the JSON Web Key Set that verifies the issuer's tokens.
+func (i *Issuer) KeySet() []byte {
+	keys, _ := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "EC",
+		"crv": "P-256",
+		"kid": i.KeyID,
+		"alg": "ES256",
+		"use": "sig",
+		"x":   base64.RawURLEncoding.EncodeToString(i.key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(i.key.Y.FillBytes(make([]byte, 32))),
+	}}})
+	return keys
+}
+
+// Token returns a valid token for teamID that expires in a day.
+func (i *Issuer) Token(teamID string) string {
+	now := time.Now()
+	return i.Sign(map[string]any{
+		"team_id": teamID,
+		"sub":     "device@example.com",
+		"aud":     Audience,
+		"iat":     now.Unix(),
+		"exp":     now.Add(24 * time.Hour).Unix(),
+	})
+}
+
+// Sign returns a token with claims, signed with the issuer's key.
+func (i *Issuer) Sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "typ": "JWT", "kid": i.KeyID})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, i.key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// LegacyToken returns a token for teamID signed like those of a team API
+// without a key set, with HS256 and a secret only the API knows.
+func LegacyToken(teamID string) string {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, _ := json.Marshal(map[string]any{"team_id": teamID, "sub": "device@example.com"})
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte("team-api-secret"))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Request is a request received by the Server.
+type Request struct {
+	Method string
+	Path   string
+	Body   []byte
+}
+
+// Server is a team API stand-in. Enrollment returns a token of Issuer for
+// TeamID; reports are accepted and answered with NextAuth, once, when set.
+type Server struct {
+	*httptest.Server
+	Issuer *Issuer
+	TeamID string
+	// Legacy makes the server a team API without a key set, which enrolls
+	// devices with a LegacyToken
+	Legacy bool
+
+	mutex    sync.Mutex
+	nextAuth string
+	requests []Request
+}
+
+// NewServer starts a server for teamID that is closed when the test ends.
+func NewServer(t testing.TB, teamID string) *Server {
+	server := &Server{Issuer: NewIssuer("test-key"), TeamID: teamID}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		server.record(r)
+		if server.Legacy {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(server.Issuer.KeySet())
+	})
+	mux.HandleFunc("POST /api/v1/team/enroll", func(w http.ResponseWriter, r *http.Request) {
+		server.record(r)
+		if server.Legacy {
+			writeJSON(w, map[string]string{"auth": LegacyToken(server.TeamID)})
+			return
+		}
+		writeJSON(w, map[string]string{"auth": server.Issuer.Token(server.TeamID)})
+	})
+	device := func(w http.ResponseWriter, r *http.Request) {
+		server.record(r)
+		server.mutex.Lock()
+		auth := server.nextAuth
+		server.nextAuth = ""
+		server.mutex.Unlock()
+		if auth != "" {
+			writeJSON(w, map[string]string{"auth": auth})
+			return
+		}
+		writeJSON(w, map[string]string{"status": "ok"})
+	}
+	mux.HandleFunc("PUT /api/v1/team/{team}/device", device)
+	mux.HandleFunc("PATCH /api/v1/team/{team}/device", device)
+
+	server.Server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// RespondWithAuth makes the server answer the next report with auth, as the
+// team API does when the device was moved to another team.
+func (s *Server) RespondWithAuth(auth string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.nextAuth = auth
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Request{}, s.requests...)
+}
+
+func (s *Server) record(r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Body: body})
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,414 @@
+package team
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/caarlos0/log"
+	"github.com/carlmjohnson/requests"
+)
+
+// Device auth tokens are JWTs issued by the team API. How far the agent
+// trusts them depends on what the team API publishes:
+//
+//   - A team API that publishes a JSON Web Key Set at KeySetPath signs tokens
+//     with one of supportedAlgorithms. The key set is fetched when the device
+//     is linked and pinned in the config; every later token must verify with
+//     the pinned keys, and new keys are only trusted after linking again.
+//   - A team API without a key set, which answers KeySetPath with 404 and
+//     signs tokens with a secret of its own, is trusted as before: the token
+//     received when linking is accepted as it came from the team API over
+//     HTTPS. Such a device keeps its team, later tokens that name another
+//     team are refused until the device is linked again.
+//
+// Keys pinned by the policy always apply and are never fetched.
+
+// DeviceTokenAudience is the audience of device auth tokens. Tokens without
+// an audience are accepted.
+const DeviceTokenAudience = "paretosecurity-agent"
+
+// KeySetPath is where a team API that signs device auth tokens with public
+// keys publishes them, as a JSON Web Key Set.
+const KeySetPath = "/.well-known/jwks.json"
+
+// supportedAlgorithms are the JWS algorithms of device auth tokens verified
+// with a key set. Symmetric and unsigned tokens are rejected, as the agent
+// only holds public keys.
+var supportedAlgorithms = []string{"ES256", "RS256", "EdDSA"}
+
+// TokenLeeway is the clock skew tolerated when checking the times of a token.
+var TokenLeeway = 5 * time.Minute
+
+var (
+	// ErrInvalidToken is returned for auth tokens that do not verify
+	ErrInvalidToken = errors.New("invalid auth token")
+	// errUnknownKey is returned for auth tokens signed with a key missing from the key set
+	errUnknownKey = errors.New("unknown signing key")
+	// errNoKeySet is returned when the team API does not publish a key set
+	errNoKeySet = errors.New("the team API does not publish a key set")
+)
+
+// DeviceClaims are the claims of a device auth token.
+type DeviceClaims struct {
+	TeamID    string   `json:"team_id"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// audience is the aud claim, a single string or a list of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// JSONWebKey is a public key of a KeySet. Only EC P-256, RSA and Ed25519 keys
+// are supported.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// KeySet is a JSON Web Key Set.
+type KeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// ParseKeySet decodes a JSON Web Key Set.
+func ParseKeySet(data []byte) (KeySet, error) {
+	var keys KeySet
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return KeySet{}, fmt.Errorf("invalid key set: %w", err)
+	}
+	if len(keys.Keys) == 0 {
+		return KeySet{}, errors.New("invalid key set: no keys")
+	}
+	return keys, nil
+}
+
+// VerifyDeviceToken verifies the JWS signature of token against keys and
+// validates its claims at now, see validate. All errors wrap ErrInvalidToken.
+func VerifyDeviceToken(token string, keys KeySet, now time.Time) (DeviceClaims, error) {
+	claims, err := verifyDeviceToken(token, keys, now)
+	if err != nil {
+		return DeviceClaims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+func verifyDeviceToken(token string, keys KeySet, now time.Time) (DeviceClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return DeviceClaims{}, errors.New("invalid auth token format")
+	}
+	var header struct {
+		Algorithm string   `json:"alg"`
+		KeyID     string   `json:"kid"`
+		Critical  []string `json:"crit"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return DeviceClaims{}, fmt.Errorf("invalid header: %w", err)
+	}
+	if !slices.Contains(supportedAlgorithms, header.Algorithm) {
+		return DeviceClaims{}, fmt.Errorf("unsupported signing algorithm %q", header.Algorithm)
+	}
+	if len(header.Critical) > 0 {
+		return DeviceClaims{}, fmt.Errorf("unsupported critical header parameters %v", header.Critical)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return DeviceClaims{}, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	key, err := keys.find(header.KeyID, header.Algorithm)
+	if err != nil {
+		return DeviceClaims{}, err
+	}
+	if err := verifySignature(header.Algorithm, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return DeviceClaims{}, err
+	}
+
+	return decodeClaims(parts[1], now)
+}
+
+// unverifiedDeviceToken returns the claims of a token issued by a team API
+// without a key set, validated like those of a verified token. The signature
+// is not checked. All errors wrap ErrInvalidToken.
+func unverifiedDeviceToken(token string, now time.Time) (DeviceClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return DeviceClaims{}, fmt.Errorf("%w: invalid auth token format", ErrInvalidToken)
+	}
+	claims, err := decodeClaims(parts[1], now)
+	if err != nil {
+		return DeviceClaims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// decodeClaims decodes the claims of a token and validates them at now: they
+// must name a team and, when set, the audience must include
+// DeviceTokenAudience, the token must not be expired or issued in the future.
+func decodeClaims(segment string, now time.Time) (DeviceClaims, error) {
+	var claims DeviceClaims
+	if err := decodeSegment(segment, &claims); err != nil {
+		return DeviceClaims{}, fmt.Errorf("invalid claims: %w", err)
+	}
+	if len(claims.Audience) > 0 && !slices.Contains(claims.Audience, DeviceTokenAudience) {
+		return DeviceClaims{}, fmt.Errorf("token is not meant for %s", DeviceTokenAudience)
+	}
+	if expires := time.Unix(claims.ExpiresAt, 0); claims.ExpiresAt != 0 && now.After(expires.Add(TokenLeeway)) {
+		return DeviceClaims{}, fmt.Errorf("token expired at %s", expires.Format(time.RFC3339))
+	}
+	if issued := time.Unix(claims.IssuedAt, 0); claims.IssuedAt != 0 && issued.After(now.Add(TokenLeeway)) {
+		return DeviceClaims{}, fmt.Errorf("token is issued in the future, at %s", issued.Format(time.RFC3339))
+	}
+	if claims.TeamID == "" {
+		return DeviceClaims{}, errors.New("team ID not found in auth token")
+	}
+	return claims, nil
+}
+
+// find returns the public key with kid that can verify alg. Without kid the
+// only key of the set is used.
+func (s KeySet) find(kid, alg string) (crypto.PublicKey, error) {
+	candidates := []JSONWebKey{}
+	for _, key := range s.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if key.Algorithm != "" && key.Algorithm != alg {
+			continue
+		}
+		if kid == "" || key.KeyID == kid {
+			candidates = append(candidates, key)
+		}
+	}
+	switch {
+	case len(candidates) == 0:
+		return nil, fmt.Errorf("%w %q for %s", errUnknownKey, kid, alg)
+	case len(candidates) > 1:
+		return nil, errors.New("token does not name its signing key")
+	}
+	return candidates[0].publicKey()
+}
+
+// publicKey decodes the key.
+func (k JSONWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, errX := decodeInt(k.X)
+		y, errY := decodeInt(k.Y)
+		if err := errors.Join(errX, errY); err != nil {
+			return nil, fmt.Errorf("invalid EC key %q: %w", k.KeyID, err)
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("invalid EC key %q: point is not on the curve", k.KeyID)
+		}
+		return key, nil
+	case "RSA":
+		n, errN := decodeInt(k.N)
+		e, errE := decodeInt(k.E)
+		if err := errors.Join(errN, errE); err != nil {
+			return nil, fmt.Errorf("invalid RSA key %q: %w", k.KeyID, err)
+		}
+		if n.BitLen() < 2048 || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid RSA key %q: too weak", k.KeyID)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if k.Curve != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid OKP key %q", k.KeyID)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
+
+// verifySignature checks a JWS signature made with one of supportedAlgorithms.
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	digest := sha256.Sum256(signed)
+	valid := false
+	switch alg {
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			break
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		valid = ecdsa.Verify(ecKey, digest[:], r, s)
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		valid = ok && rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) == nil
+	case "EdDSA":
+		edKey, ok := key.(ed25519.PublicKey)
+		valid = ok && ed25519.Verify(edKey, signed, signature)
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	if !valid {
+		return errors.New("signature does not verify")
+	}
+	return nil
+}
+
+// policyKeys returns the keys pinned by the policy, if any.
+func policyKeys() (KeySet, bool, error) {
+	if shared.Policy.TeamKeys == "" {
+		return KeySet{}, false, nil
+	}
+	keys, err := ParseKeySet([]byte(shared.Policy.TeamKeys))
+	if err != nil {
+		return KeySet{}, true, fmt.Errorf("policy TeamKeys: %w", err)
+	}
+	return keys, true, nil
+}
+
+// enrollAuth verifies the auth token received when linking the device to the
+// team API at apiURL. It returns the claims of the token and the key set to
+// pin in the config, which is empty when the policy pins the keys or the team
+// API does not publish any. A team API without a key set is only trusted to
+// link a device again to the team it was linked to before team APIs published
+// keys, never to link a new device or move one to another team.
+func enrollAuth(ctx context.Context, apiURL, token string) (DeviceClaims, string, error) {
+	if strings.Count(token, ".") != 2 {
+		return DeviceClaims{}, "", fmt.Errorf("%w: invalid auth token format", ErrInvalidToken)
+	}
+	if keys, pinned, err := policyKeys(); pinned {
+		if err != nil {
+			return DeviceClaims{}, "", err
+		}
+		claims, err := VerifyDeviceToken(token, keys, time.Now())
+		return claims, "", err
+	}
+
+	raw, err := fetchKeySet(ctx, apiURL)
+	if errors.Is(err, errNoKeySet) {
+		if shared.Config.TeamID == "" || shared.Config.TeamKeys != "" {
+			return DeviceClaims{}, "", fmt.Errorf("%w, so the auth token cannot be verified and the device cannot be linked", err)
+		}
+		log.WithField("team", shared.Config.TeamID).Warn("The team API does not publish signing keys, trusting the auth token it sent for the team the device is linked to")
+		claims, err := unverifiedDeviceToken(token, time.Now())
+		if err == nil && claims.TeamID != shared.Config.TeamID {
+			return DeviceClaims{}, "", fmt.Errorf("%w: the team API does not publish signing keys, so it cannot link the device to team %s", ErrInvalidToken, claims.TeamID)
+		}
+		return claims, "", err
+	}
+	if err != nil {
+		return DeviceClaims{}, "", err
+	}
+	keys, err := ParseKeySet(raw)
+	if err != nil {
+		return DeviceClaims{}, "", err
+	}
+	claims, err := VerifyDeviceToken(token, keys, time.Now())
+	if err != nil {
+		return DeviceClaims{}, "", err
+	}
+	return claims, string(raw), nil
+}
+
+// renewAuth verifies an auth token the team API sent to a linked device. It
+// must verify with the keys pinned by the policy or saved when the device was
+// linked; the keys are never fetched again, so a token signed with a new key
+// requires linking the device again. A device linked to a team API without a
+// key set only accepts tokens for its current team.
+func renewAuth(token string) (DeviceClaims, error) {
+	if keys, pinned, err := policyKeys(); pinned {
+		if err != nil {
+			return DeviceClaims{}, err
+		}
+		return VerifyDeviceToken(token, keys, time.Now())
+	}
+	if shared.Config.TeamKeys == "" {
+		claims, err := unverifiedDeviceToken(token, time.Now())
+		if err == nil && claims.TeamID != shared.Config.TeamID {
+			return DeviceClaims{}, fmt.Errorf("%w: the team API did not publish signing keys when the device was linked, so it cannot move the device to team %s, link the device again", ErrInvalidToken, claims.TeamID)
+		}
+		return claims, err
+	}
+
+	keys, err := ParseKeySet([]byte(shared.Config.TeamKeys))
+	if err != nil {
+		return DeviceClaims{}, fmt.Errorf("saved team keys: %w", err)
+	}
+	claims, err := VerifyDeviceToken(token, keys, time.Now())
+	if errors.Is(err, errUnknownKey) {
+		return DeviceClaims{}, fmt.Errorf("%w, link the device again to trust new team keys", err)
+	}
+	return claims, err
+}
+
+// fetchKeySet downloads the key set published by the team API at apiURL. It
+// returns errNoKeySet when the team API does not publish one.
+func fetchKeySet(ctx context.Context, apiURL string) ([]byte, error) {
+	var raw bytes.Buffer
+	err := requests.URL(apiURL).
+		Path(KeySetPath).
+		Header("User-Agent", shared.UserAgent()).
+		ToBytesBuffer(&raw).
+		Fetch(ctx)
+	if requests.HasStatusErr(err, http.StatusNotFound) {
+		return nil, errNoKeySet
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the team keys: %w", err)
+	}
+	return raw.Bytes(), nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func decodeInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid integer encoding")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
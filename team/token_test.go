@@ -0,0 +1,329 @@
+package team
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ParetoSecurity/agent/shared"
+	"github.com/ParetoSecurity/agent/team/teamtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustKeySet(t *testing.T, data []byte) KeySet {
+	t.Helper()
+	keys, err := ParseKeySet(data)
+	require.NoError(t, err)
+	return keys
+}
+
+// signWith signs claims with alg and sign, for algorithms teamtest does not issue.
+func signWith(t *testing.T, alg, kid string, claims map[string]any, sign func([]byte) []byte) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func validClaims() map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"team_id": "team-1",
+		"aud":     []string{"dashboard", DeviceTokenAudience},
+		"iat":     now.Unix(),
+		"exp":     now.Add(time.Hour).Unix(),
+	}
+}
+
+func TestVerifyDeviceToken(t *testing.T) {
+	issuer := teamtest.NewIssuer("key-1")
+	keys := mustKeySet(t, issuer.KeySet())
+	now := time.Now()
+
+	claims, err := VerifyDeviceToken(issuer.Token("team-1"), keys, now)
+	require.NoError(t, err)
+	assert.Equal(t, "team-1", claims.TeamID)
+	assert.Equal(t, "device@example.com", claims.Subject)
+
+	withClaims := func(change func(map[string]any)) string {
+		claims := validClaims()
+		change(claims)
+		return issuer.Sign(claims)
+	}
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"list audience", withClaims(func(map[string]any) {}), ""},
+		{"no audience", withClaims(func(c map[string]any) { delete(c, "aud") }), ""},
+		{"no times", withClaims(func(c map[string]any) { delete(c, "exp"); delete(c, "iat") }), ""},
+		{"malformed", "malformed.token", "invalid auth token format"},
+		{"other key", teamtest.NewIssuer("key-1").Token("team-1"), "signature does not verify"},
+		{"unknown key", teamtest.NewIssuer("key-2").Token("team-1"), "unknown signing key"},
+		{"tampered", issuer.Token("team-1")[:20] + "x" + issuer.Token("team-1")[21:], "invalid"},
+		{"expired", withClaims(func(c map[string]any) { c["exp"] = now.Add(-time.Hour).Unix() }), "token expired"},
+		{"issued in the future", withClaims(func(c map[string]any) { c["iat"] = now.Add(time.Hour).Unix() }), "issued in the future"},
+		{"other audience", withClaims(func(c map[string]any) { c["aud"] = "dashboard" }), "not meant for"},
+		{"no team", withClaims(func(c map[string]any) { delete(c, "team_id") }), "team ID not found"},
+		{"unsigned", signWith(t, "none", "key-1", validClaims(), func([]byte) []byte { return nil }), "unsupported signing algorithm"},
+		{"symmetric", signWith(t, "HS256", "", validClaims(), func([]byte) []byte { return []byte("mac") }), "unsupported signing algorithm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifyDeviceToken(tt.token, keys, now)
+			if tt.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidToken)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestVerifyDeviceToken_RSAAndEd25519(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	encode := base64.RawURLEncoding.EncodeToString
+	keys := KeySet{Keys: []JSONWebKey{
+		{KeyType: "RSA", KeyID: "rsa", N: encode(rsaKey.N.Bytes()), E: encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{KeyType: "OKP", KeyID: "ed", Curve: "Ed25519", X: encode(edPublic)},
+	}}
+
+	rsaToken := signWith(t, "RS256", "rsa", validClaims(), func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+		return signature
+	})
+	_, err = VerifyDeviceToken(rsaToken, keys, time.Now())
+	assert.NoError(t, err)
+
+	edToken := signWith(t, "EdDSA", "ed", validClaims(), func(signed []byte) []byte {
+		return ed25519.Sign(edPrivate, signed)
+	})
+	_, err = VerifyDeviceToken(edToken, keys, time.Now())
+	assert.NoError(t, err)
+
+	// The algorithm must match the key named by the token
+	confused := signWith(t, "EdDSA", "rsa", validClaims(), func(signed []byte) []byte {
+		return ed25519.Sign(edPrivate, signed)
+	})
+	_, err = VerifyDeviceToken(confused, keys, time.Now())
+	assert.ErrorContains(t, err, "signature does not verify")
+}
+
+func TestParseKeySet(t *testing.T) {
+	_, err := ParseKeySet([]byte(`{"keys": []}`))
+	assert.ErrorContains(t, err, "no keys")
+	_, err = ParseKeySet([]byte(`not json`))
+	assert.ErrorContains(t, err, "invalid key set")
+}
+
+// withTeamKeys resets the saved and pinned team keys for the test.
+func withTeamKeys(t *testing.T) {
+	t.Helper()
+	config, policy := shared.Config, shared.Policy
+	t.Cleanup(func() {
+		shared.Config, shared.Policy = config, policy
+	})
+	shared.Config.TeamKeys = ""
+	shared.Policy.TeamKeys = ""
+}
+
+func keySetRequests(server *teamtest.Server) int {
+	count := 0
+	for _, request := range server.Requests() {
+		if request.Path == KeySetPath {
+			count++
+		}
+	}
+	return count
+}
+
+func TestEnrollAuth_PinsPublishedKeys(t *testing.T) {
+	withTeamKeys(t)
+	server := teamtest.NewServer(t, "team-1")
+
+	claims, keys, err := enrollAuth(context.Background(), server.URL, server.Issuer.Token("team-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "team-1", claims.TeamID)
+	assert.JSONEq(t, string(server.Issuer.KeySet()), keys)
+
+	// A team API that publishes keys has to sign with them
+	_, _, err = enrollAuth(context.Background(), server.URL, teamtest.LegacyToken("team-1"))
+	assert.ErrorContains(t, err, "unsupported signing algorithm")
+}
+
+func TestEnrollAuth_LegacyTeamAPI(t *testing.T) {
+	withTeamKeys(t)
+	server := teamtest.NewServer(t, "team-1")
+	server.Legacy = true
+	teamID := shared.Config.TeamID
+	t.Cleanup(func() { shared.Config.TeamID = teamID })
+
+	// A device is never linked for the first time without verifying the token
+	shared.Config.TeamID = ""
+	_, _, err := enrollAuth(context.Background(), server.URL, teamtest.LegacyToken("team-1"))
+	assert.ErrorIs(t, err, errNoKeySet)
+
+	// A device linked before team APIs published keys may link to its team again
+	shared.Config.TeamID = "team-1"
+	claims, keys, err := enrollAuth(context.Background(), server.URL, teamtest.LegacyToken("team-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "team-1", claims.TeamID)
+	assert.Empty(t, keys)
+
+	_, _, err = enrollAuth(context.Background(), server.URL, teamtest.LegacyToken("attacker"))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, _, err = enrollAuth(context.Background(), server.URL, "not.a-token.at-all")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// A device linked with keys never falls back to unverified tokens
+	shared.Config.TeamKeys = string(teamtest.NewIssuer("key-1").KeySet())
+	_, _, err = enrollAuth(context.Background(), server.URL, teamtest.LegacyToken("team-1"))
+	assert.ErrorIs(t, err, errNoKeySet)
+}
+
+func TestEnrollAuth_KeySetUnavailable(t *testing.T) {
+	withTeamKeys(t)
+	server := teamtest.NewServer(t, "team-1")
+	token := server.Issuer.Token("team-1")
+	server.Close()
+
+	_, _, err := enrollAuth(context.Background(), server.URL, token)
+	assert.ErrorContains(t, err, "failed to fetch the team keys")
+}
+
+func TestRenewAuth_SavedKeys(t *testing.T) {
+	withTeamKeys(t)
+	issuer := teamtest.NewIssuer("key-1")
+	shared.Config.TeamKeys = string(issuer.KeySet())
+
+	claims, err := renewAuth(issuer.Token("team-2"))
+	require.NoError(t, err)
+	assert.Equal(t, "team-2", claims.TeamID)
+
+	// Signed with another key under the same key ID
+	_, err = renewAuth(teamtest.NewIssuer("key-1").Token("attacker"))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// New keys are not fetched, they are only trusted after linking again
+	_, err = renewAuth(teamtest.NewIssuer("key-2").Token("team-2"))
+	assert.ErrorContains(t, err, "link the device again")
+	assert.JSONEq(t, string(issuer.KeySet()), shared.Config.TeamKeys)
+}
+
+func TestRenewAuth_LegacyLink(t *testing.T) {
+	withTeamKeys(t)
+	teamID := shared.Config.TeamID
+	t.Cleanup(func() { shared.Config.TeamID = teamID })
+	shared.Config.TeamID = "team-1"
+
+	claims, err := renewAuth(teamtest.LegacyToken("team-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "team-1", claims.TeamID)
+
+	_, err = renewAuth(teamtest.LegacyToken("attacker"))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.ErrorContains(t, err, "link the device again")
+}
+
+func TestRenewAuth_PolicyPinsKeys(t *testing.T) {
+	withTeamKeys(t)
+	pinned := teamtest.NewIssuer("pinned")
+	shared.Policy.TeamKeys = string(pinned.KeySet())
+	server := teamtest.NewServer(t, "team-1")
+
+	_, err := renewAuth(server.Issuer.Token("team-1"))
+	assert.ErrorContains(t, err, "unknown signing key")
+	_, _, err = enrollAuth(context.Background(), server.URL, server.Issuer.Token("team-1"))
+	assert.ErrorContains(t, err, "unknown signing key")
+	assert.Zero(t, keySetRequests(server), "keys pinned by the policy are never fetched")
+
+	_, err = renewAuth(pinned.Token("team-1"))
+	assert.NoError(t, err)
+	_, keys, err := enrollAuth(context.Background(), server.URL, pinned.Token("team-1"))
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestEnrollAndMove(t *testing.T) {
+	withTeamKeys(t)
+	withOutbox(t)
+	shared.Config.TeamID = ""
+	shared.Config.AuthToken = ""
+	server := teamtest.NewServer(t, "team-1")
+
+	require.NoError(t, EnrollDevice("invite-1", server.URL))
+	assert.Equal(t, "team-1", shared.Config.TeamID)
+	assert.Equal(t, server.URL, shared.Config.TeamAPI)
+
+	// The team API moves the device with a token it signed
+	moved := server.Issuer.Token("team-2")
+	server.RespondWithAuth(moved)
+	require.NoError(t, ReportToTeam(false))
+	assert.Equal(t, "team-2", shared.Config.TeamID)
+	assert.Equal(t, moved, shared.Config.AuthToken)
+
+	// A token signed by anyone else is refused
+	server.RespondWithAuth(teamtest.NewIssuer(server.Issuer.KeyID).Token("attacker"))
+	require.NoError(t, ReportToTeam(false), "the report itself was delivered")
+	assert.Equal(t, "team-2", shared.Config.TeamID)
+	assert.Equal(t, moved, shared.Config.AuthToken)
+
+	methods := []string{}
+	for _, request := range server.Requests() {
+		methods = append(methods, request.Method+" "+request.Path)
+	}
+	assert.Equal(t, []string{
+		http.MethodPost + " /api/v1/team/enroll",
+		http.MethodGet + " " + KeySetPath,
+		http.MethodPatch + " /api/v1/team/team-1/device",
+		http.MethodPatch + " /api/v1/team/team-2/device",
+	}, methods)
+}
+
+func TestEnrollLegacyTeamAPI(t *testing.T) {
+	withTeamKeys(t)
+	withOutbox(t)
+	shared.Config.TeamID = ""
+	shared.Config.AuthToken = ""
+	server := teamtest.NewServer(t, "team-1")
+	server.Legacy = true
+
+	assert.ErrorIs(t, EnrollDevice("invite-1", server.URL), errNoKeySet)
+	assert.Empty(t, shared.Config.TeamID)
+
+	// The device was linked to the team before the team API published keys
+	shared.Config.TeamID = "team-1"
+	require.NoError(t, EnrollDevice("invite-1", server.URL))
+	assert.Equal(t, "team-1", shared.Config.TeamID)
+	assert.Empty(t, shared.Config.TeamKeys)
+
+	// Renewed tokens for the same team are adopted, moves are refused
+	renewed := teamtest.LegacyToken("team-1") + "renewed"
+	server.RespondWithAuth(renewed)
+	require.NoError(t, ReportToTeam(false))
+	assert.Equal(t, renewed, shared.Config.AuthToken)
+
+	server.RespondWithAuth(teamtest.LegacyToken("attacker"))
+	require.NoError(t, ReportToTeam(false))
+	assert.Equal(t, "team-1", shared.Config.TeamID)
+	assert.Equal(t, renewed, shared.Config.AuthToken)
+}
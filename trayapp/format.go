@@ -7,6 +7,21 @@ import (
 	"github.com/ParetoSecurity/agent/shared"
 )
 
+// failingFor returns a human-readable duration for how long a check has been failing.
+func failingFor(t time.Duration) string {
+	switch {
+	case t < time.Hour:
+		return "less than an hour"
+	case t < time.Hour*2:
+		return "1 hour"
+	case t < time.Hour*24:
+		return fmt.Sprintf("%d hours", int(t.Hours()))
+	case t < time.Hour*48:
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", int(t.Hours()/24))
+}
+
 // lastUpdated calculates and returns a human-readable string representing the time elapsed since the last modification.
 func lastUpdated() string {
 	if shared.GetModifiedTime().IsZero() {
@@ -57,3 +72,16 @@ func lastUpdated() string {
 		return fmt.Sprintf("%dw %dd ago", weeks, remainingDays)
 	}
 }
+
+// policyLabel returns a short reason why a check is disabled.
+func policyLabel(policy shared.CheckPolicy) string {
+	if policy.Exception != nil {
+		return fmt.Sprintf("exception until %s", policy.Exception.Expires)
+	}
+	return "disabled"
+}
+
+// snoozeLabel returns a short note until when a check is snoozed.
+func snoozeLabel(snooze shared.CheckSnooze) string {
+	return "snoozed until " + snooze.Until.Local().Format("2006-01-02 15:04")
+}
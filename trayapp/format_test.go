@@ -115,3 +115,18 @@ func TestLastUpdated(t *testing.T) {
 		})
 	}
 }
+
+func TestFailingFor(t *testing.T) {
+	testCases := map[time.Duration]string{
+		10 * time.Minute: "less than an hour",
+		90 * time.Minute: "1 hour",
+		5 * time.Hour:    "5 hours",
+		30 * time.Hour:   "1 day",
+		73 * time.Hour:   "3 days",
+	}
+	for duration, expected := range testCases {
+		if actual := failingFor(duration); actual != expected {
+			t.Fatalf("failingFor(%s): expected %q, got %q", duration, expected, actual)
+		}
+	}
+}
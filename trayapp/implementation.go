@@ -1,6 +1,7 @@
 package trayapp
 
 import (
+	"context"
 	"time"
 
 	"fyne.io/systray"
@@ -25,6 +26,14 @@ func (r *RealStateManager) GetLastState(uuid string) (shared.LastState, bool, er
 	return shared.GetLastState(uuid)
 }
 
+func (r *RealStateManager) FailingSince(uuid string) (time.Time, bool) {
+	return shared.FailingSince(uuid)
+}
+
+func (r *RealStateManager) CheckPolicy(uuid string) shared.CheckPolicy {
+	return shared.CheckPolicyFor(uuid)
+}
+
 func (r *RealStateManager) IsLinked() bool {
 	return shared.IsLinked()
 }
@@ -171,6 +180,10 @@ func (r *RealNotifier) Toast(message string) {
 	notify.Toast(message)
 }
 
+func (r *RealNotifier) Serve(ctx context.Context, notifications <-chan notify.Notification, handle func(key string)) error {
+	return notify.Serve(ctx, notifications, handle)
+}
+
 type RealThemeSubscriber struct{}
 
 func (r *RealThemeSubscriber) SubscribeToThemeChanges(ch chan<- bool) {
@@ -1,8 +1,10 @@
 package trayapp
 
 import (
+	"context"
 	"time"
 
+	"github.com/ParetoSecurity/agent/notify"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/fsnotify/fsnotify"
 )
@@ -15,6 +17,8 @@ type CommandRunner interface {
 // StateManager interface for managing application state
 type StateManager interface {
 	GetLastState(uuid string) (shared.LastState, bool, error)
+	FailingSince(uuid string) (time.Time, bool)
+	CheckPolicy(uuid string) shared.CheckPolicy
 	IsLinked() bool
 	StatePath() string
 	GetModifiedTime() time.Time
@@ -74,6 +78,7 @@ type SystemdManager interface {
 // Notifier interface for notifications
 type Notifier interface {
 	Toast(message string)
+	Serve(ctx context.Context, notifications <-chan notify.Notification, handle func(key string)) error
 }
 
 // ThemeSubscriber interface for theme changes
@@ -1,12 +1,17 @@
 package trayapp
 
 import (
+	"context"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"time"
 
 	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/notify"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
 	"github.com/fsnotify/fsnotify"
@@ -25,6 +30,7 @@ type TrayApp struct {
 	iconProvider    IconProvider
 	startupManager  StartupManager
 	broadcaster     *shared.Broadcaster
+	notifications   chan notify.Notification
 }
 
 // NewTrayApp creates a new TrayApp with production dependencies
@@ -41,6 +47,7 @@ func NewTrayApp() *TrayApp {
 		iconProvider:    &RealIconProvider{},
 		startupManager:  &RealStartupManager{},
 		broadcaster:     shared.NewBroadcaster(),
+		notifications:   make(chan notify.Notification, 4),
 	}
 }
 
@@ -70,6 +77,7 @@ func NewTrayAppWithDependencies(
 		iconProvider:    iconProvider,
 		startupManager:  startupManager,
 		broadcaster:     broadcaster,
+		notifications:   make(chan notify.Notification, 4),
 	}
 }
 
@@ -104,15 +112,31 @@ func (t *TrayApp) updateCheck(chk check.Check, mCheck MenuItem) {
 		return
 	}
 
-	if found {
-		mCheck.Enable()
-		mCheck.SetTitle(fmt.Sprintf("%s %s", t.checkStatusToIcon(checkStatus.Passed, checkStatus.HasError), chk.Name()))
+	policy := t.stateManager.CheckPolicy(chk.UUID())
+	if policy.Disabled {
+		mCheck.Disable()
+		mCheck.SetTitle(fmt.Sprintf("⏸️ %s (%s)", chk.Name(), policyLabel(policy)))
 		return
 	}
-	// Check is runnable but no data found yet - enable it so it's clickable
-	mCheck.Enable()
-	mCheck.SetTitle(chk.Name())
 
+	title := chk.Name()
+	if found {
+		title = fmt.Sprintf("%s %s", t.checkStatusToIcon(checkStatus.Passed, checkStatus.HasError), chk.Name())
+		if !checkStatus.Passed && !checkStatus.HasError {
+			if since, ok := t.stateManager.FailingSince(chk.UUID()); ok {
+				title = fmt.Sprintf("%s (failing for %s)", title, failingFor(time.Since(since)))
+			}
+		}
+	}
+	if policy.Snooze != nil {
+		title = fmt.Sprintf("%s (%s)", title, snoozeLabel(*policy.Snooze))
+	}
+	if policy.Required {
+		title = fmt.Sprintf("%s 🔒", title)
+	}
+	// Runnable checks are clickable even before they have data
+	mCheck.Enable()
+	mCheck.SetTitle(title)
 }
 
 // updateClaim updates the status of a claim in the menu
@@ -391,20 +415,8 @@ func (t *TrayApp) OnReady() {
 			go func(chk check.Check, mCheck MenuItem) {
 				for range mCheck.ClickedCh() {
 					log.WithField("check", chk.Name()).Info("Opening check URL")
-					arch := "check-linux"
-					if runtime.GOOS == "windows" {
-						arch = "check-windows"
-					}
-
 					checkStatus, found, _ := t.stateManager.GetLastState(chk.UUID())
-
-					var targetURL string
-					if found && checkStatus.HasError {
-						targetURL = "https://paretosecurity.com/docs/linux/check-error"
-					} else {
-						targetURL = fmt.Sprintf("https://paretosecurity.com/%s/%s?details=%s", arch, chk.UUID(), url.QueryEscape(chk.Status()))
-					}
-
+					targetURL := checkURL(chk.UUID(), chk.Status(), found && checkStatus.HasError)
 					if err := t.browserOpener.OpenURL(targetURL); err != nil {
 						log.WithError(err).Error("failed to open check URL")
 					}
@@ -442,9 +454,151 @@ func (t *TrayApp) OnReady() {
 	}(rcheck)
 	t.systemTray.AddSeparator()
 	t.addQuitItem()
+	go func() {
+		if err := t.notifier.Serve(context.Background(), t.notifications, t.handleNotificationAction); err != nil {
+			log.WithError(err).Warn("Failed to serve notifications")
+		}
+	}()
 	log.Info("System tray setup complete")
 	// watch for changes in the state file
 	go t.watch()
+	// raise the notifications queued by check runs
+	t.raiseQueued()
+	go t.watchNotifications()
+}
+
+// checkURL returns the page that explains a check and how to fix it.
+func checkURL(uuid, details string, hasError bool) string {
+	if hasError {
+		return "https://paretosecurity.com/docs/linux/check-error"
+	}
+	arch := "check-linux"
+	if runtime.GOOS == "windows" {
+		arch = "check-windows"
+	}
+	return fmt.Sprintf("https://paretosecurity.com/%s/%s?details=%s", arch, uuid, url.QueryEscape(details))
+}
+
+// handleNotificationAction performs the action clicked in a notification
+// about checks that started failing.
+func (t *TrayApp) handleNotificationAction(key string) {
+	kind, uuids, ok := notify.ParseActionKey(key)
+	if !ok {
+		return
+	}
+	log.WithField("action", kind).WithField("checks", uuids).Info("Notification action invoked")
+	if !knownChecks(uuids) {
+		log.WithField("checks", uuids).Warn("Ignoring notification action on unknown checks")
+		return
+	}
+	switch kind {
+	case notify.ActionDetails:
+		if len(uuids) != 1 {
+			t.openConsole()
+			return
+		}
+		state, found, _ := t.stateManager.GetLastState(uuids[0])
+		if err := t.browserOpener.OpenURL(checkURL(uuids[0], state.Details, found && state.HasError)); err != nil {
+			log.WithError(err).Error("failed to open check URL")
+		}
+	case notify.ActionSnooze:
+		for _, uuid := range uuids {
+			if _, err := t.commandRunner.RunCommand(t.stateManager.SelfExe(), "config", "snooze", uuid, "--for", "24h", "--reason", "Snoozed from a notification"); err != nil {
+				log.WithError(err).WithField("check", uuid).Error("failed to snooze check")
+				t.notifier.Toast("Failed to snooze the check, please check the logs for more information.")
+			}
+		}
+		t.broadcaster.Send()
+	case notify.ActionDisable:
+		for _, uuid := range uuids {
+			if _, err := t.commandRunner.RunCommand(t.stateManager.SelfExe(), "config", "disable", uuid); err != nil {
+				log.WithError(err).WithField("check", uuid).Error("failed to disable check")
+				t.notifier.Toast("Failed to disable the check, please check the logs for more information.")
+			}
+		}
+		t.broadcaster.Send()
+	}
+}
+
+// knownChecks reports whether each UUID is exactly the UUID of a check, so
+// that a forged action cannot pass patterns to the config commands.
+func knownChecks(uuids []string) bool {
+	for _, uuid := range uuids {
+		known := slices.ContainsFunc(claims.All, func(claim claims.Claim) bool {
+			return slices.ContainsFunc(claim.Checks, func(chk check.Check) bool {
+				return chk.UUID() == uuid
+			})
+		})
+		if !known {
+			return false
+		}
+	}
+	return true
+}
+
+// raiseQueued raises a notification about the checks that check runs queued
+// for the tray, which can act on the notification's buttons.
+func (t *TrayApp) raiseQueued() {
+	state, err := shared.LoadNotificationState()
+	if err != nil || len(state.Queued) == 0 {
+		return
+	}
+	var queued []string
+	err = shared.UpdateNotificationState(func(state *shared.NotificationState) {
+		queued, state.Queued = state.Queued, nil
+	})
+	if err != nil {
+		log.WithError(err).Warn("Failed to take the queued notifications")
+		return
+	}
+	checks := []shared.LastState{}
+	for _, uuid := range queued {
+		if state, found, _ := t.stateManager.GetLastState(uuid); found {
+			checks = append(checks, state)
+		}
+	}
+	if len(checks) == 0 {
+		return
+	}
+	select {
+	case t.notifications <- notify.Regressions(checks):
+	default:
+		log.Warn("Notifications are not being raised, dropping notification")
+	}
+}
+
+// watchNotifications raises the notifications queued by check runs. The
+// directory of the notification state is watched, as the state file is
+// replaced on every update.
+func (t *TrayApp) watchNotifications() {
+	watcher, err := t.fileWatcher.NewWatcher()
+	if err != nil {
+		log.WithError(err).Error("Failed to create file watcher")
+		return
+	}
+	defer watcher.Close()
+
+	dir := filepath.Dir(shared.NotificationStatePath)
+	if err := watcher.Add(dir); err != nil {
+		log.WithError(err).WithField("path", dir).Error("Failed to add notification state directory to watcher")
+		return
+	}
+	for {
+		select {
+		case event, ok := <-watcher.Events():
+			if !ok {
+				return
+			}
+			if event.Name == shared.NotificationStatePath && event.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+				t.raiseQueued()
+			}
+		case err, ok := <-watcher.Errors():
+			if !ok {
+				return
+			}
+			log.WithError(err).Error("File watcher error")
+		}
+	}
 }
 
 // watch monitors the state file for changes
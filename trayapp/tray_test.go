@@ -1,15 +1,20 @@
 package trayapp
 
 import (
+	"context"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/claims"
+	"github.com/ParetoSecurity/agent/notify"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/fsnotify/fsnotify"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // Mock implementations for testing
@@ -38,6 +43,16 @@ func (m *MockStateManager) GetLastState(uuid string) (shared.LastState, bool, er
 	return arguments.Get(0).(shared.LastState), arguments.Bool(1), arguments.Error(2)
 }
 
+func (m *MockStateManager) FailingSince(uuid string) (time.Time, bool) {
+	args := m.Called(uuid)
+	return args.Get(0).(time.Time), args.Bool(1)
+}
+
+func (m *MockStateManager) CheckPolicy(uuid string) shared.CheckPolicy {
+	args := m.Called(uuid)
+	return args.Get(0).(shared.CheckPolicy)
+}
+
 func (m *MockStateManager) IsLinked() bool {
 	arguments := m.Called()
 	return arguments.Bool(0)
@@ -220,6 +235,10 @@ func (m *MockNotifier) Toast(message string) {
 	m.Called(message)
 }
 
+func (m *MockNotifier) Serve(ctx context.Context, notifications <-chan notify.Notification, handle func(key string)) error {
+	return m.Called(ctx, notifications, handle).Error(0)
+}
+
 type MockThemeSubscriber struct {
 	mock.Mock
 }
@@ -313,6 +332,7 @@ func TestTrayApp_updateCheck(t *testing.T) {
 		// Test case: check is runnable and found
 		checkState := shared.LastState{Passed: true, HasError: false}
 		mockStateManager.On("GetLastState", "test-uuid").Return(checkState, true, nil)
+		mockStateManager.On("CheckPolicy", "test-uuid").Return(shared.CheckPolicy{})
 		mockMenuItem.On("Enable").Return()
 		mockMenuItem.On("SetTitle", "✅ Test Check").Return()
 
@@ -369,6 +389,7 @@ func TestTrayApp_updateCheck(t *testing.T) {
 
 		// Test case: check not found but runnable - should be enabled
 		mockStateManager.On("GetLastState", "test-uuid").Return(shared.LastState{}, false, nil)
+		mockStateManager.On("CheckPolicy", "test-uuid").Return(shared.CheckPolicy{})
 		mockMenuItem.On("Enable").Return()
 		mockMenuItem.On("SetTitle", "Test Check").Return()
 
@@ -398,6 +419,7 @@ func TestTrayApp_updateCheck(t *testing.T) {
 		// Test case: check has error
 		checkState := shared.LastState{Passed: false, HasError: true}
 		mockStateManager.On("GetLastState", "test-uuid").Return(checkState, true, nil)
+		mockStateManager.On("CheckPolicy", "test-uuid").Return(shared.CheckPolicy{})
 		mockMenuItem.On("Enable").Return()
 		mockMenuItem.On("SetTitle", "⚠️ Test Check").Return()
 
@@ -407,6 +429,114 @@ func TestTrayApp_updateCheck(t *testing.T) {
 		mockMenuItem.AssertExpectations(t)
 		mockCheck.AssertExpectations(t)
 	})
+
+	t.Run("check is failing with history", func(t *testing.T) {
+		mockStateManager := &MockStateManager{}
+		mockMenuItem := NewMockMenuItem()
+		mockBroadcaster := shared.NewBroadcaster()
+
+		trayApp := NewTrayAppWithDependencies(
+			nil, mockStateManager, nil, nil, nil, nil, nil, nil, nil, nil, mockBroadcaster,
+		)
+
+		mockCheck := &MockCheck{}
+		mockCheck.On("UUID").Return("test-uuid")
+		mockCheck.On("Name").Return("Test Check")
+		mockCheck.On("IsRunnable").Return(true)
+
+		checkState := shared.LastState{Passed: false, HasError: false}
+		mockStateManager.On("GetLastState", "test-uuid").Return(checkState, true, nil)
+		mockStateManager.On("CheckPolicy", "test-uuid").Return(shared.CheckPolicy{})
+		mockStateManager.On("FailingSince", "test-uuid").Return(time.Now().Add(-73*time.Hour), true)
+		mockMenuItem.On("Enable").Return()
+		mockMenuItem.On("SetTitle", "❌ Test Check (failing for 3 days)").Return()
+
+		trayApp.updateCheck(mockCheck, mockMenuItem)
+
+		mockStateManager.AssertExpectations(t)
+		mockMenuItem.AssertExpectations(t)
+	})
+
+	t.Run("check is disabled by a policy exception", func(t *testing.T) {
+		mockStateManager := &MockStateManager{}
+		mockMenuItem := NewMockMenuItem()
+		mockBroadcaster := shared.NewBroadcaster()
+
+		trayApp := NewTrayAppWithDependencies(
+			nil, mockStateManager, nil, nil, nil, nil, nil, nil, nil, nil, mockBroadcaster,
+		)
+
+		mockCheck := &MockCheck{}
+		mockCheck.On("UUID").Return("test-uuid")
+		mockCheck.On("Name").Return("Test Check")
+		mockCheck.On("IsRunnable").Return(true)
+
+		policy := shared.CheckPolicy{
+			Disabled:  true,
+			Source:    shared.SourcePolicy,
+			Exception: &shared.PolicyException{Check: "test-uuid", Expires: "2030-01-31"},
+		}
+		mockStateManager.On("GetLastState", "test-uuid").Return(shared.LastState{}, false, nil)
+		mockStateManager.On("CheckPolicy", "test-uuid").Return(policy)
+		mockMenuItem.On("Disable").Return()
+		mockMenuItem.On("SetTitle", "⏸️ Test Check (exception until 2030-01-31)").Return()
+
+		trayApp.updateCheck(mockCheck, mockMenuItem)
+
+		mockStateManager.AssertExpectations(t)
+		mockMenuItem.AssertExpectations(t)
+	})
+
+	t.Run("check is required by policy", func(t *testing.T) {
+		mockStateManager := &MockStateManager{}
+		mockMenuItem := NewMockMenuItem()
+		mockBroadcaster := shared.NewBroadcaster()
+
+		trayApp := NewTrayAppWithDependencies(
+			nil, mockStateManager, nil, nil, nil, nil, nil, nil, nil, nil, mockBroadcaster,
+		)
+
+		mockCheck := &MockCheck{}
+		mockCheck.On("UUID").Return("test-uuid")
+		mockCheck.On("Name").Return("Test Check")
+		mockCheck.On("IsRunnable").Return(true)
+
+		checkState := shared.LastState{Passed: true}
+		mockStateManager.On("GetLastState", "test-uuid").Return(checkState, true, nil)
+		mockStateManager.On("CheckPolicy", "test-uuid").Return(shared.CheckPolicy{Required: true, Source: shared.SourcePolicy})
+		mockMenuItem.On("Enable").Return()
+		mockMenuItem.On("SetTitle", "✅ Test Check 🔒").Return()
+
+		trayApp.updateCheck(mockCheck, mockMenuItem)
+
+		mockStateManager.AssertExpectations(t)
+		mockMenuItem.AssertExpectations(t)
+	})
+
+	t.Run("check is snoozed", func(t *testing.T) {
+		mockStateManager := &MockStateManager{}
+		mockMenuItem := NewMockMenuItem()
+
+		trayApp := NewTrayAppWithDependencies(
+			nil, mockStateManager, nil, nil, nil, nil, nil, nil, nil, nil, shared.NewBroadcaster(),
+		)
+
+		mockCheck := &MockCheck{}
+		mockCheck.On("UUID").Return("test-uuid")
+		mockCheck.On("Name").Return("Test Check")
+		mockCheck.On("IsRunnable").Return(true)
+
+		until := time.Date(2030, 1, 2, 15, 4, 0, 0, time.Local)
+		mockStateManager.On("GetLastState", "test-uuid").Return(shared.LastState{HasError: true}, true, nil)
+		mockStateManager.On("CheckPolicy", "test-uuid").Return(shared.CheckPolicy{Source: shared.SourceConfig, Snooze: &shared.CheckSnooze{Until: until}})
+		mockMenuItem.On("Enable").Return()
+		mockMenuItem.On("SetTitle", "⚠️ Test Check (snoozed until 2030-01-02 15:04)").Return()
+
+		trayApp.updateCheck(mockCheck, mockMenuItem)
+
+		mockStateManager.AssertExpectations(t)
+		mockMenuItem.AssertExpectations(t)
+	})
 }
 
 func TestTrayApp_updateClaim(t *testing.T) {
@@ -954,3 +1084,113 @@ func TestTrayApp_WatchWithEvents(t *testing.T) {
 		mockStateManager.AssertExpectations(t)
 	})
 }
+
+func TestTrayApp_handleNotificationAction(t *testing.T) {
+	uuids := []string{}
+	for _, claim := range claims.All {
+		for _, chk := range claim.Checks {
+			uuids = append(uuids, chk.UUID())
+		}
+	}
+	require.GreaterOrEqual(t, len(uuids), 2)
+
+	t.Run("shows the details of a check", func(t *testing.T) {
+		mockStateManager := &MockStateManager{}
+		mockBrowserOpener := &MockBrowserOpener{}
+		trayApp := NewTrayAppWithDependencies(
+			nil, mockStateManager, mockBrowserOpener, nil, nil, nil, nil, nil, nil, nil, shared.NewBroadcaster(),
+		)
+		mockStateManager.On("GetLastState", uuids[0]).Return(shared.LastState{Details: "off"}, true, nil).Once()
+		mockBrowserOpener.On("OpenURL", checkURL(uuids[0], "off", false)).Return(nil).Once()
+
+		trayApp.handleNotificationAction(notify.ActionKey(notify.ActionDetails, uuids[0]))
+
+		mockStateManager.AssertExpectations(t)
+		mockBrowserOpener.AssertExpectations(t)
+	})
+
+	t.Run("snoozes the checks for a day", func(t *testing.T) {
+		mockCommandRunner := &MockCommandRunner{}
+		mockStateManager := &MockStateManager{}
+		trayApp := NewTrayAppWithDependencies(
+			mockCommandRunner, mockStateManager, nil, nil, nil, nil, nil, nil, nil, nil, shared.NewBroadcaster(),
+		)
+		mockStateManager.On("SelfExe").Return("/test/exe")
+		for _, uuid := range uuids[:2] {
+			mockCommandRunner.On("RunCommand", "/test/exe", "config", "snooze", uuid, "--for", "24h", "--reason", "Snoozed from a notification").Return("", nil).Once()
+		}
+
+		trayApp.handleNotificationAction(notify.ActionKey(notify.ActionSnooze, uuids[0], uuids[1]))
+
+		mockCommandRunner.AssertExpectations(t)
+	})
+
+	t.Run("disables the check", func(t *testing.T) {
+		mockCommandRunner := &MockCommandRunner{}
+		mockStateManager := &MockStateManager{}
+		mockNotifier := &MockNotifier{}
+		trayApp := NewTrayAppWithDependencies(
+			mockCommandRunner, mockStateManager, nil, nil, nil, nil, mockNotifier, nil, nil, nil, shared.NewBroadcaster(),
+		)
+		mockStateManager.On("SelfExe").Return("/test/exe")
+		mockCommandRunner.On("RunCommand", "/test/exe", "config", "disable", uuids[0]).Return("", assert.AnError).Once()
+		mockNotifier.On("Toast", "Failed to disable the check, please check the logs for more information.").Return().Once()
+
+		trayApp.handleNotificationAction(notify.ActionKey(notify.ActionDisable, uuids[0]))
+
+		mockCommandRunner.AssertExpectations(t)
+		mockNotifier.AssertExpectations(t)
+	})
+
+	t.Run("ignores actions on unknown checks", func(t *testing.T) {
+		mockCommandRunner := &MockCommandRunner{}
+		trayApp := NewTrayAppWithDependencies(
+			mockCommandRunner, nil, nil, nil, nil, nil, nil, nil, nil, nil, shared.NewBroadcaster(),
+		)
+		trayApp.handleNotificationAction(notify.ActionKey(notify.ActionDisable, "*"))
+		trayApp.handleNotificationAction(notify.ActionKey(notify.ActionSnooze, uuids[0], strings.ToUpper(uuids[1])))
+		trayApp.handleNotificationAction(notify.ActionKey(notify.ActionDisable, uuids[0][:8]))
+
+		mockCommandRunner.AssertNotCalled(t, "RunCommand")
+	})
+
+	t.Run("ignores actions of other applications", func(t *testing.T) {
+		trayApp := NewTrayAppWithDependencies(
+			nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, shared.NewBroadcaster(),
+		)
+		trayApp.handleNotificationAction("default")
+	})
+}
+
+func TestTrayApp_raiseQueued(t *testing.T) {
+	path := shared.NotificationStatePath
+	shared.NotificationStatePath = filepath.Join(t.TempDir(), "notifications")
+	defer func() { shared.NotificationStatePath = path }()
+
+	mockStateManager := &MockStateManager{}
+	trayApp := NewTrayAppWithDependencies(
+		nil, mockStateManager, nil, nil, nil, nil, nil, nil, nil, nil, shared.NewBroadcaster(),
+	)
+	require.NoError(t, shared.UpdateNotificationState(func(state *shared.NotificationState) {
+		state.Queued = []string{"uuid-1", "uuid-gone"}
+	}))
+	mockStateManager.On("GetLastState", "uuid-1").Return(shared.LastState{UUID: "uuid-1", Name: "Check A"}, true, nil).Once()
+	mockStateManager.On("GetLastState", "uuid-gone").Return(shared.LastState{}, false, nil).Once()
+
+	trayApp.raiseQueued()
+
+	select {
+	case notification := <-trayApp.notifications:
+		assert.Equal(t, "• Check A", notification.Body)
+	default:
+		t.Fatal("no notification raised")
+	}
+	state, err := shared.LoadNotificationState()
+	require.NoError(t, err)
+	assert.Empty(t, state.Queued)
+
+	// Nothing is queued any more
+	trayApp.raiseQueued()
+	assert.Empty(t, trayApp.notifications)
+	mockStateManager.AssertExpectations(t)
+}
@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"runtime"
@@ -52,8 +53,8 @@ func runSingleCheck(claimIdx, checkIdx int, checkResult checkResult) tea.Cmd {
 		if !result.Check.IsRunnable() || shared.IsCheckDisabled(result.Check.UUID()) {
 			result.Status = "Disabled"
 			result.HasError = false
-			if shared.IsCheckDisabled(result.Check.UUID()) {
-				result.Details = "Disabled by config"
+			if policy := shared.CheckPolicyFor(result.Check.UUID()); policy.Disabled {
+				result.Details = policy.Describe()
 			} else {
 				result.Details = result.Check.Status()
 			}
@@ -96,7 +97,7 @@ func runCheckDirectly(result checkResult) checkResult {
 	} else {
 		// Run check directly
 		log.Debug("Running check directly")
-		if err := result.Check.Run(); err != nil {
+		if err := runner.RunCheck(context.Background(), result.Check); err != nil {
 			hasError = true
 			log.WithError(err).Errorf("Check execution failed: %s", result.Check.Name())
 			result.Status = "Error"
@@ -125,6 +126,8 @@ func runCheckDirectly(result checkResult) checkResult {
 		Passed:   result.Passed,
 		HasError: result.HasError,
 		Details:  result.Details,
+		Severity: check.SeverityOf(result.Check),
+		Weight:   check.WeightOf(result.Check),
 	})
 
 	return result
@@ -145,9 +148,9 @@ func runAllChecks(checks []checkResult) tea.Cmd {
 			if !result.Check.IsRunnable() || shared.IsCheckDisabled(result.Check.UUID()) {
 				result.Status = "Disabled"
 				result.HasError = false
-				if shared.IsCheckDisabled(result.Check.UUID()) {
-					result.Details = "Disabled by config"
-					log.Debugf("Check disabled by config: %s", result.Check.Name())
+				if policy := shared.CheckPolicyFor(result.Check.UUID()); policy.Disabled {
+					result.Details = policy.Describe()
+					log.Debugf("Check disabled by %s: %s", policy.Source, result.Check.Name())
 				} else {
 					result.Details = result.Check.Status()
 					log.Debugf("Check not runnable: %s - %s", result.Check.Name(), result.Details)
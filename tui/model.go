@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	chk "github.com/ParetoSecurity/agent/check"
 	"github.com/ParetoSecurity/agent/claims"
 	"github.com/ParetoSecurity/agent/shared"
 	"github.com/caarlos0/log"
@@ -59,8 +60,11 @@ func initialModel() *model {
 			}
 
 			// Check if disabled
-			if !chk.IsRunnable() || shared.IsCheckDisabled(chk.UUID()) {
+			if policy := shared.CheckPolicyFor(chk.UUID()); !chk.IsRunnable() || policy.Disabled {
 				result.Status = "Disabled"
+				if policy.Disabled {
+					result.Details = policy.Describe()
+				}
 				group.DisabledCount++
 				group.NotRunCount-- // Remove from not run count
 			}
@@ -156,13 +160,24 @@ func (m *model) rebuildDisplayItems() {
 					statusText = ""
 				}
 
+				// Mark checks the policy does not allow to disable and snoozed checks
+				details := check.Details
+				policy := shared.CheckPolicyFor(check.Check.UUID())
+				if policy.Required && !policy.Disabled {
+					details = "[required] " + details
+				}
+				if policy.Snooze != nil {
+					details = fmt.Sprintf("[snoozed until %s] %s", policy.Snooze.Until.Local().Format(time.DateTime), details)
+				}
+
 				m.displayItems = append(m.displayItems, displayItem{
 					IsHeader:   false,
 					ClaimIndex: claimIdx,
 					CheckIndex: checkIdx,
 					Text:       check.Check.Name(),
 					StatusText: statusText,
-					Details:    check.Details,
+					Details:    details,
+					Severity:   chk.SeverityOf(check.Check),
 					Indented:   true,
 				})
 			}
@@ -231,6 +246,28 @@ func (m *model) updateModelState(results []checkResult, claimIdx int) {
 	}
 }
 
+// score returns the weighted security score of the current results
+func (m *model) score() int {
+	var entries []chk.Scored
+	for _, claim := range m.claims {
+		for _, result := range claim.Checks {
+			var state chk.CheckState
+			switch result.Status {
+			case "Pass":
+				state = chk.CheckStatePassed
+			case "Fail":
+				state = chk.CheckStateFailed
+			case "Error":
+				state = chk.CheckStateError
+			default:
+				continue
+			}
+			entries = append(entries, chk.Scored{State: state, Weight: chk.WeightOf(result.Check)})
+		}
+	}
+	return chk.Score(entries)
+}
+
 // Init implements the tea.Model interface
 func (m *model) Init() tea.Cmd {
 	// Test log to verify logging is working
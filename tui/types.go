@@ -37,6 +37,7 @@ type displayItem struct {
 	Text       string
 	StatusText string
 	Details    string
+	Severity   check.Severity
 	Indented   bool
 }
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/ParetoSecurity/agent/check"
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -39,6 +40,21 @@ func styleStatus(statusText string, isRunning bool) string {
 	}
 }
 
+// styleSeverity renders a severity label padded to width
+func styleSeverity(severity check.Severity, width int) string {
+	color := "8" // Dark Gray
+	switch severity {
+	case check.SeverityCritical:
+		color = "9" // Bright Red
+	case check.SeverityHigh:
+		color = "1" // Red
+	case check.SeverityMedium:
+		color = "3" // Yellow
+	}
+	label := fmt.Sprintf("%-*s", width, strings.ToUpper(string(severity)))
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Render(label)
+}
+
 func (m *model) View() string {
 	if m.viewport.width == 0 {
 		return "Loading..."
@@ -112,9 +128,10 @@ func (m *model) View() string {
 		} else {
 			// Individual check - calculate available space for details dynamically
 			checkNameWidth := 25
+			severityWidth := 8
 			statusTextWidth := 18
-			fixedWidth := 1 + 2 + checkNameWidth + 1 + statusTextWidth + 1 // indicator + indent + name + spaces + status + space
-			availableDetailsWidth := m.viewport.width - fixedWidth - 4     // Leave some margin
+			fixedWidth := 1 + 2 + checkNameWidth + 1 + severityWidth + 1 + statusTextWidth + 1 // indicator + indent + name + severity + status + spaces
+			availableDetailsWidth := m.viewport.width - fixedWidth - 4                         // Leave some margin
 
 			// Ensure minimum width and truncate if needed
 			if availableDetailsWidth < 20 {
@@ -126,10 +143,11 @@ func (m *model) View() string {
 				details = details[:availableDetailsWidth-3] + "..."
 			}
 
-			line = fmt.Sprintf("%s %s%-25s %-18s %s",
+			line = fmt.Sprintf("%s %s%-25s %s %-18s %s",
 				indicator,
 				indent,
 				item.Text,
+				styleSeverity(item.Severity, severityWidth),
 				statusText,
 				details)
 		}
@@ -248,7 +266,8 @@ func (m *model) View() string {
 	}
 
 	passStyle, failStyle, errorStyle, disabledStyle := getStatusStyles()
-	stats := fmt.Sprintf("Total: %d | %s | %s | %s | %s",
+	stats := fmt.Sprintf("Score: %d/100 | Total: %d | %s | %s | %s | %s",
+		m.score(),
 		totalChecks,
 		passStyle.Render(fmt.Sprintf("Pass: %d", passed)),
 		failStyle.Render(fmt.Sprintf("Fail: %d", failed)),